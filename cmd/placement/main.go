@@ -49,6 +49,7 @@ func newPlacementCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(hub.NewPlacementController())
+	cmd.AddCommand(hub.NewPlacementSimulate())
 
 	return cmd
 }
@@ -5,15 +5,42 @@ import (
 	"github.com/spf13/cobra"
 
 	controllers "open-cluster-management.io/ocm/pkg/placement/controllers"
+	"open-cluster-management.io/ocm/pkg/placement/simulator"
 	"open-cluster-management.io/ocm/pkg/version"
 )
 
 func NewPlacementController() *cobra.Command {
-	cmd := controllercmd.
-		NewControllerCommandConfig("placement", version.Get(), controllers.RunControllerManager).
-		NewCommand()
+	o := controllers.NewOptions()
+	cmdConfig := controllercmd.
+		NewControllerCommandConfig("placement", version.Get(), o.RunControllerManager)
+	cmd := cmdConfig.NewCommand()
 	cmd.Use = "controller"
 	cmd.Short = "Start the Placement Scheduling Controller"
+	// all the replicas are active in sharded mode
+	cmd.PreRun = func(cmd *cobra.Command, args []string) {
+		if o.EnableSharding {
+			cmdConfig.DisableLeaderElection = true
+		}
+	}
 
+	o.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func NewPlacementSimulate() *cobra.Command {
+	o := simulator.NewOptions()
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate the scheduling of placements with manifests in local files",
+		Long: "Schedule the placements with the real scheduler against the ManagedClusters, ManagedClusterSets, " +
+			"ManagedClusterSetBindings, PlacementDecisions, AddOnPlacementScores and ManagedClusterAddOns loaded from " +
+			"local files or directories, and print the decisions with the filter and score details. No hub is required.",
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			return o.Run(c.Context(), c.OutOrStdout())
+		},
+	}
+
+	o.AddFlags(cmd.Flags())
 	return cmd
 }
@@ -2,24 +2,78 @@ package hub
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/server/mux"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/events"
 
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterscheme "open-cluster-management.io/api/client/cluster/clientset/versioned/scheme"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 
 	scheduling "open-cluster-management.io/ocm/pkg/placement/controllers/scheduling"
+	"open-cluster-management.io/ocm/pkg/placement/controllers/sharding"
 	"open-cluster-management.io/ocm/pkg/placement/debugger"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/extender"
 )
 
+// Options defines the flags of the placement controller.
+type Options struct {
+	// EnableSharding runs the controller in sharded mode, in which multiple replicas schedule the
+	// placements in different namespaces. Leader election, placement slots and preemption are disabled
+	// in sharded mode.
+	EnableSharding bool
+	// ShardLeaseNamespace is the namespace of the Leases of the replicas, defaults to the namespace of
+	// the controller.
+	ShardLeaseNamespace string
+	// ShardLeaseDuration is the duration of the Leases of the replicas.
+	ShardLeaseDuration time.Duration
+	// SchedulerExtenderConfig is the file of the scheduling extender configuration. No extender is
+	// called if it is empty.
+	SchedulerExtenderConfig string
+}
+
+// NewOptions returns the flags with default values.
+func NewOptions() *Options {
+	return &Options{
+		ShardLeaseDuration: 30 * time.Second,
+	}
+}
+
+// AddFlags registers the flags of the placement controller.
+func (o *Options) AddFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.EnableSharding, "enable-sharding", o.EnableSharding,
+		"Run multiple replicas of the controller, each schedules the placements in a consistent hash slice "+
+			"of the namespaces. Leader election, placement slots and preemption are disabled in sharded mode.")
+	flags.StringVar(&o.ShardLeaseNamespace, "shard-lease-namespace", o.ShardLeaseNamespace,
+		"The namespace of the Leases of the replicas in sharded mode, defaults to the namespace of the controller.")
+	flags.DurationVar(&o.ShardLeaseDuration, "shard-lease-duration", o.ShardLeaseDuration,
+		"The duration of the Leases of the replicas in sharded mode. A replica takes over the namespaces of a "+
+			"stopped replica after the duration.")
+	flags.StringVar(&o.SchedulerExtenderConfig, "scheduler-extender-config", o.SchedulerExtenderConfig,
+		"The file of the scheduling extender configuration. The extenders filter and prioritize the clusters "+
+			"of placements over http after the built-in plugins.")
+}
+
 // RunControllerManager starts the controllers on hub to make placement decisions.
 func RunControllerManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+	return NewOptions().RunControllerManager(ctx, controllerContext)
+}
+
+// RunControllerManager starts the controllers on hub to make placement decisions with the options.
+func (o *Options) RunControllerManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
 	kubeConf := controllerContext.KubeConfig
 	kubeConf.QPS = 50
 	kubeConf.Burst = 100
@@ -28,12 +82,18 @@ func RunControllerManager(ctx context.Context, controllerContext *controllercmd.
 		return err
 	}
 
+	addOnClient, err := addonclient.NewForConfig(controllerContext.KubeConfig)
+	if err != nil {
+		return err
+	}
+
 	kubeClient, err := kubernetes.NewForConfig(controllerContext.KubeConfig)
 	if err != nil {
 		return err
 	}
 
 	clusterInformers := clusterinformers.NewSharedInformerFactory(clusterClient, 10*time.Minute)
+	addOnInformers := addoninformers.NewSharedInformerFactory(addOnClient, 10*time.Minute)
 
 	broadcaster := events.NewBroadcaster(&events.EventSinkImpl{Interface: kubeClient.EventsV1()})
 
@@ -41,25 +101,42 @@ func RunControllerManager(ctx context.Context, controllerContext *controllercmd.
 
 	recorder := broadcaster.NewRecorder(clusterscheme.Scheme, "placementController")
 
-	scheduler := scheduling.NewPluginScheduler(
-		scheduling.NewSchedulerHandler(
-			clusterClient,
-			clusterInformers.Cluster().V1beta1().PlacementDecisions().Lister(),
-			clusterInformers.Cluster().V1alpha1().AddOnPlacementScores().Lister(),
-			clusterInformers.Cluster().V1().ManagedClusters().Lister(),
-			recorder),
-	)
+	handle := scheduling.NewSchedulerHandler(
+		clusterClient,
+		clusterInformers.Cluster().V1beta1().PlacementDecisions().Lister(),
+		clusterInformers.Cluster().V1alpha1().AddOnPlacementScores().Lister(),
+		clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+		addOnInformers.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+		recorder)
+
+	extenders, err := o.newExtenders(handle)
+	if err != nil {
+		return err
+	}
+	scheduler := scheduling.NewPluginScheduler(handle, extenders...)
 
 	if controllerContext.Server != nil {
 		debug := debugger.NewDebugger(
 			scheduler,
 			clusterInformers.Cluster().V1beta1().Placements(),
+			clusterInformers.Cluster().V1beta1().PlacementDecisions(),
 			clusterInformers.Cluster().V1().ManagedClusters(),
+			clusterInformers.Cluster().V1beta2().ManagedClusterSets(),
+			clusterInformers.Cluster().V1beta2().ManagedClusterSetBindings(),
 		)
 
 		installDebugger(controllerContext.Server.Handler.NonGoRestfulMux, debug)
 	}
 
+	var shardOwner scheduling.ShardOwner
+	if o.EnableSharding {
+		shardManager, err := o.newShardManager(ctx, controllerContext, kubeClient)
+		if err != nil {
+			return err
+		}
+		shardOwner = shardManager
+	}
+
 	schedulingController := scheduling.NewSchedulingController(
 		clusterClient,
 		clusterInformers.Cluster().V1().ManagedClusters(),
@@ -68,11 +145,14 @@ func RunControllerManager(ctx context.Context, controllerContext *controllercmd.
 		clusterInformers.Cluster().V1beta1().Placements(),
 		clusterInformers.Cluster().V1beta1().PlacementDecisions(),
 		clusterInformers.Cluster().V1alpha1().AddOnPlacementScores(),
+		addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
 		scheduler,
+		shardOwner,
 		controllerContext.EventRecorder, recorder,
 	)
 
 	go clusterInformers.Start(ctx.Done())
+	go addOnInformers.Start(ctx.Done())
 
 	go schedulingController.Run(ctx, 1)
 
@@ -80,6 +160,52 @@ func RunControllerManager(ctx context.Context, controllerContext *controllercmd.
 	return nil
 }
 
+// newShardManager starts the shard manager with the Leases in the lease namespace. The identity of the
+// replica is the hostname, which is the pod name in a deployment.
+func (o *Options) newShardManager(
+	ctx context.Context,
+	controllerContext *controllercmd.ControllerContext,
+	kubeClient kubernetes.Interface,
+) (*sharding.ShardManager, error) {
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	namespace := o.ShardLeaseNamespace
+	if len(namespace) == 0 {
+		namespace = controllerContext.OperatorNamespace
+	}
+
+	leaseInformers := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute,
+		kubeinformers.WithNamespace(namespace),
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = sharding.ShardLeaseLabel
+		}),
+	)
+	leaseInformer := leaseInformers.Coordination().V1().Leases()
+	shardManager := sharding.NewShardManager(
+		identity, namespace, o.ShardLeaseDuration, kubeClient.CoordinationV1(), leaseInformer.Lister())
+
+	go leaseInformers.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), leaseInformer.Informer().HasSynced) {
+		return nil, fmt.Errorf("failed to wait for the lease informer to sync")
+	}
+	go shardManager.Run(ctx)
+	return shardManager, nil
+}
+
+// newExtenders returns the scheduling extenders in the extender configuration file.
+func (o *Options) newExtenders(handle plugins.Handle) ([]*extender.Extender, error) {
+	if len(o.SchedulerExtenderConfig) == 0 {
+		return nil, nil
+	}
+	config, err := extender.LoadConfig(o.SchedulerExtenderConfig)
+	if err != nil {
+		return nil, err
+	}
+	return extender.NewExtenders(handle, config)
+}
+
 func installDebugger(mux *mux.PathRecorderMux, d *debugger.Debugger) {
 	mux.HandlePrefix(debugger.DebugPath, http.HandlerFunc(d.Handler))
 }
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	// register the workqueue metrics provider, so the depth of the scheduling queue is exposed as
+	// workqueue_depth{name="SchedulingController"}.
+	_ "k8s.io/component-base/metrics/prometheus/workqueue"
+)
+
+const subsystem = "placement_scheduler"
+
+const (
+	// ExtensionPointFilter and ExtensionPointScore are the values of the extension_point label
+	// of the plugin duration metric.
+	ExtensionPointFilter = "Filter"
+	ExtensionPointScore  = "Score"
+
+	// ClustersFeasible, ClustersSelected and ClustersUnscheduled are the values of the type label
+	// of the placement clusters metric.
+	ClustersFeasible    = "feasible"
+	ClustersSelected    = "selected"
+	ClustersUnscheduled = "unscheduled"
+
+	// ResultSuccess and ResultError are the values of the result label of the scheduling duration metric.
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+var (
+	// SchedulingDuration is the end-to-end latency of scheduling a placement.
+	SchedulingDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      subsystem,
+			Name:           "scheduling_duration_seconds",
+			Help:           "End-to-end latency in seconds of scheduling a placement.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+
+	// PluginDuration is the duration of running a filter or prioritizer plugin.
+	PluginDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      subsystem,
+			Name:           "plugin_duration_seconds",
+			Help:           "Duration in seconds of running a plugin at an extension point.",
+			Buckets:        metrics.ExponentialBuckets(0.0001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"plugin", "extension_point"},
+	)
+
+	// PlacementClusters is the number of feasible, selected and unscheduled clusters of a placement
+	// in the latest scheduling.
+	PlacementClusters = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "placement_clusters",
+			Help:           "Number of feasible, selected and unscheduled clusters of a placement in the latest scheduling.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"namespace", "placement", "type"},
+	)
+
+	// DecisionsAdded and DecisionsRemoved count the clusters added to and removed from the
+	// decisions of a placement.
+	DecisionsAdded = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      subsystem,
+			Name:           "decisions_added_total",
+			Help:           "Number of clusters added to the decisions of a placement.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"namespace", "placement"},
+	)
+	DecisionsRemoved = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      subsystem,
+			Name:           "decisions_removed_total",
+			Help:           "Number of clusters removed from the decisions of a placement.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"namespace", "placement"},
+	)
+
+	registerOnce sync.Once
+)
+
+func init() {
+	Register()
+}
+
+// Register registers the placement scheduler metrics into the legacy registry, which is served
+// by the metrics endpoint of the controller.
+func Register() {
+	registerOnce.Do(func() {
+		legacyregistry.MustRegister(SchedulingDuration)
+		legacyregistry.MustRegister(PluginDuration)
+		legacyregistry.MustRegister(PlacementClusters)
+		legacyregistry.MustRegister(DecisionsAdded)
+		legacyregistry.MustRegister(DecisionsRemoved)
+	})
+}
+
+// ObserveSchedulingDuration records the end-to-end latency of scheduling a placement since start.
+func ObserveSchedulingDuration(start time.Time, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultError
+	}
+	SchedulingDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// ObservePluginDuration records the duration of running a plugin at an extension point since start.
+func ObservePluginDuration(plugin, extensionPoint string, start time.Time) {
+	PluginDuration.WithLabelValues(plugin, extensionPoint).Observe(time.Since(start).Seconds())
+}
+
+// RecordPlacementClusters records the number of feasible, selected and unscheduled clusters of a placement.
+func RecordPlacementClusters(namespace, name string, feasible, selected, unscheduled int) {
+	PlacementClusters.WithLabelValues(namespace, name, ClustersFeasible).Set(float64(feasible))
+	PlacementClusters.WithLabelValues(namespace, name, ClustersSelected).Set(float64(selected))
+	PlacementClusters.WithLabelValues(namespace, name, ClustersUnscheduled).Set(float64(unscheduled))
+}
+
+// RecordDecisionChanges increases the counters of the clusters added to and removed from the
+// decisions of a placement.
+func RecordDecisionChanges(namespace, name string, added, removed int) {
+	if added > 0 {
+		DecisionsAdded.WithLabelValues(namespace, name).Add(float64(added))
+	}
+	if removed > 0 {
+		DecisionsRemoved.WithLabelValues(namespace, name).Add(float64(removed))
+	}
+}
+
+// DeletePlacementMetrics removes the metrics of a deleted placement.
+func DeletePlacementMetrics(namespace, name string) {
+	for _, t := range []string{ClustersFeasible, ClustersSelected, ClustersUnscheduled} {
+		PlacementClusters.Delete(map[string]string{"namespace": namespace, "placement": name, "type": t})
+	}
+	DecisionsAdded.Delete(map[string]string{"namespace": namespace, "placement": name})
+	DecisionsRemoved.Delete(map[string]string{"namespace": namespace, "placement": name})
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestRecordPlacementClusters(t *testing.T) {
+	RecordPlacementClusters("ns1", "placement1", 5, 3, 1)
+	RecordPlacementClusters("ns1", "placement2", 2, 2, 0)
+
+	expected := `
+# HELP placement_scheduler_placement_clusters [ALPHA] Number of feasible, selected and unscheduled clusters of a placement in the latest scheduling.
+# TYPE placement_scheduler_placement_clusters gauge
+placement_scheduler_placement_clusters{namespace="ns1",placement="placement1",type="feasible"} 5
+placement_scheduler_placement_clusters{namespace="ns1",placement="placement1",type="selected"} 3
+placement_scheduler_placement_clusters{namespace="ns1",placement="placement1",type="unscheduled"} 1
+placement_scheduler_placement_clusters{namespace="ns1",placement="placement2",type="feasible"} 2
+placement_scheduler_placement_clusters{namespace="ns1",placement="placement2",type="selected"} 2
+placement_scheduler_placement_clusters{namespace="ns1",placement="placement2",type="unscheduled"} 0
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expected),
+		"placement_scheduler_placement_clusters"); err != nil {
+		t.Error(err)
+	}
+
+	DeletePlacementMetrics("ns1", "placement1")
+	DeletePlacementMetrics("ns1", "placement2")
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(""),
+		"placement_scheduler_placement_clusters"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecordDecisionChanges(t *testing.T) {
+	defer DeletePlacementMetrics("ns1", "placement1")
+
+	RecordDecisionChanges("ns1", "placement1", 3, 0)
+	RecordDecisionChanges("ns1", "placement1", 1, 2)
+
+	cases := []struct {
+		name     string
+		value    func() (float64, error)
+		expected float64
+	}{
+		{
+			name: "added",
+			value: func() (float64, error) {
+				return testutil.GetCounterMetricValue(DecisionsAdded.WithLabelValues("ns1", "placement1"))
+			},
+			expected: 4,
+		},
+		{
+			name: "removed",
+			value: func() (float64, error) {
+				return testutil.GetCounterMetricValue(DecisionsRemoved.WithLabelValues("ns1", "placement1"))
+			},
+			expected: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := c.value()
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestObserveDurations(t *testing.T) {
+	start := time.Now()
+	ObserveSchedulingDuration(start, nil)
+	ObserveSchedulingDuration(start, fmt.Errorf("failed"))
+	ObservePluginDuration("Predicate", ExtensionPointFilter, start)
+	ObservePluginDuration("Predicate", ExtensionPointFilter, start)
+	ObservePluginDuration("Balance", ExtensionPointScore, start)
+
+	cases := []struct {
+		name     string
+		metric   string
+		labels   map[string]string
+		expected uint64
+	}{
+		{
+			name:     "scheduling succeeded",
+			metric:   "placement_scheduler_scheduling_duration_seconds",
+			labels:   map[string]string{"result": ResultSuccess},
+			expected: 1,
+		},
+		{
+			name:     "scheduling failed",
+			metric:   "placement_scheduler_scheduling_duration_seconds",
+			labels:   map[string]string{"result": ResultError},
+			expected: 1,
+		},
+		{
+			name:     "filter plugin",
+			metric:   "placement_scheduler_plugin_duration_seconds",
+			labels:   map[string]string{"plugin": "Predicate", "extension_point": ExtensionPointFilter},
+			expected: 2,
+		},
+		{
+			name:     "score plugin",
+			metric:   "placement_scheduler_plugin_duration_seconds",
+			labels:   map[string]string{"plugin": "Balance", "extension_point": ExtensionPointScore},
+			expected: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			vec, err := testutil.GetHistogramVecFromGatherer(legacyregistry.DefaultGatherer, c.metric, c.labels)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if count := vec.GetAggregatedSampleCount(); count != c.expected {
+				t.Errorf("expected %d samples, but got %d", c.expected, count)
+			}
+		})
+	}
+}
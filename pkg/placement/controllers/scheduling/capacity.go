@@ -0,0 +1,332 @@
+package scheduling
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	clusterlisterv1beta1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+)
+
+const (
+	// PlacementSlotsLabel is the label on ManagedCluster which defines the total weight of the placements
+	// the cluster can be selected by. The PlacementSlotsClaim is used if the label is not set. A cluster
+	// without the label or the claim has unlimited slots. The slots are not counted in sharded mode.
+	PlacementSlotsLabel = "cluster.open-cluster-management.io/placement-slots"
+	PlacementSlotsClaim = "placement-slots.open-cluster-management.io"
+
+	// PlacementWeightAnnotation is the annotation on Placement which defines the number of slots the
+	// placement takes on each selected cluster. Defaults to 1.
+	PlacementWeightAnnotation = "cluster.open-cluster-management.io/experimental-placement-weight"
+
+	// PlacementPriorityAnnotation is the annotation on Placement which defines its priority when the
+	// slots of a cluster are contended. A placement takes the slots of the placements with lower priority
+	// in the same namespace on the clusters it selects, and those placements are rescheduled. The slots
+	// taken by the placements in the other namespaces are never preempted, since the priority is set by
+	// the placement owners. Defaults to 0.
+	PlacementPriorityAnnotation = "cluster.open-cluster-management.io/experimental-placement-priority"
+
+	placementCapacityName = "PlacementCapacity"
+
+	// decisionsByCluster is an index of placementdecisions by the names of the decided clusters
+	decisionsByCluster = "decisionsByCluster"
+)
+
+// clusterHolder is a placement having a cluster in its decisions.
+type clusterHolder struct {
+	placement *clusterapiv1beta1.Placement
+	priority  int32
+	weight    int64
+}
+
+// preemptibleBy returns true if the slots of the holder can be taken by the placement with the given
+// priority. Only the placements in the same namespace with lower priority are preemptible.
+func (h clusterHolder) preemptibleBy(placement *clusterapiv1beta1.Placement, priority int32) bool {
+	return h.placement.Namespace == placement.Namespace && h.priority < priority
+}
+
+// getClusterSlots returns the slots defined in the label or claim of the cluster, and false if the cluster
+// has unlimited slots.
+func getClusterSlots(cluster *clusterapiv1.ManagedCluster) (int64, bool) {
+	value, ok := cluster.Labels[PlacementSlotsLabel]
+	if !ok {
+		value, ok = plugins.GetClusterClaims(cluster)[PlacementSlotsClaim]
+	}
+	if !ok {
+		return 0, false
+	}
+
+	slots, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || slots < 0 {
+		klog.V(4).Infof("Ignore the incorrect placement slots %q of cluster %s", value, cluster.Name)
+		return 0, false
+	}
+	return slots, true
+}
+
+// getPlacementPriority returns the priority defined in the annotation of the placement.
+func getPlacementPriority(placement *clusterapiv1beta1.Placement) (int32, error) {
+	value, ok := placement.GetAnnotations()[PlacementPriorityAnnotation]
+	if !ok || len(value) == 0 {
+		return 0, nil
+	}
+
+	priority, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse annotation %s: %v", PlacementPriorityAnnotation, err)
+	}
+	return int32(priority), nil
+}
+
+// getPlacementWeight returns the weight defined in the annotation of the placement.
+func getPlacementWeight(placement *clusterapiv1beta1.Placement) (int64, error) {
+	value, ok := placement.GetAnnotations()[PlacementWeightAnnotation]
+	if !ok || len(value) == 0 {
+		return 1, nil
+	}
+
+	weight, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse annotation %s: %v", PlacementWeightAnnotation, err)
+	}
+	if weight < 1 {
+		return 0, fmt.Errorf("annotation %s should be greater than 0", PlacementWeightAnnotation)
+	}
+	return weight, nil
+}
+
+// indexDecisionsByCluster indexes placementdecisions by the names of the decided clusters.
+func indexDecisionsByCluster(obj interface{}) ([]string, error) {
+	decision, ok := obj.(*clusterapiv1beta1.PlacementDecision)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not a PlacementDecision", obj)
+	}
+
+	keys := sets.NewString()
+	for _, d := range decision.Status.Decisions {
+		keys.Insert(d.ClusterName)
+	}
+	return keys.List(), nil
+}
+
+// decisionObserved returns true if the cluster is in the placementdecisions of the placement in the informer.
+func decisionObserved(placement *clusterapiv1beta1.Placement, clusterName string, placementDecisionIndexer cache.Indexer) bool {
+	if placementDecisionIndexer == nil {
+		return false
+	}
+	objs, err := placementDecisionIndexer.ByIndex(decisionsByCluster, clusterName)
+	if err != nil {
+		klog.V(4).Infof("Unable to get placementdecisions of cluster %s: %v", clusterName, err)
+		return false
+	}
+	for _, obj := range objs {
+		decision := obj.(*clusterapiv1beta1.PlacementDecision)
+		if decision.Namespace == placement.Namespace && decision.Labels[clusterapiv1beta1.PlacementLabel] == placement.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// getClusterHolders returns the placements other than the given one having the cluster in their
+// decisions, sorted by priority from low to high. The placements with the same priority are sorted by
+// creation time from new to old.
+func getClusterHolders(
+	placement *clusterapiv1beta1.Placement,
+	clusterName string,
+	placementLister clusterlisterv1beta1.PlacementLister,
+	placementDecisionIndexer cache.Indexer,
+) []clusterHolder {
+	if placementDecisionIndexer == nil {
+		return nil
+	}
+	objs, err := placementDecisionIndexer.ByIndex(decisionsByCluster, clusterName)
+	if err != nil {
+		klog.V(4).Infof("Unable to get placementdecisions of cluster %s: %v", clusterName, err)
+		return nil
+	}
+
+	visited := sets.NewString()
+	holders := []clusterHolder{}
+	for _, obj := range objs {
+		decision := obj.(*clusterapiv1beta1.PlacementDecision)
+		placementName, ok := decision.Labels[clusterapiv1beta1.PlacementLabel]
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s", decision.Namespace, placementName)
+		if visited.Has(key) || (decision.Namespace == placement.Namespace && placementName == placement.Name) {
+			continue
+		}
+		visited.Insert(key)
+
+		holder, err := placementLister.Placements(decision.Namespace).Get(placementName)
+		if err != nil {
+			continue
+		}
+		// the defaults are used if the annotations of the other placement are incorrect, it is reported
+		// as misconfigured by itself.
+		priority, _ := getPlacementPriority(holder)
+		weight, err := getPlacementWeight(holder)
+		if err != nil {
+			weight = 1
+		}
+		holders = append(holders, clusterHolder{placement: holder, priority: priority, weight: weight})
+	}
+
+	sort.SliceStable(holders, func(i, j int) bool {
+		if holders[i].priority != holders[j].priority {
+			return holders[i].priority < holders[j].priority
+		}
+		return holders[j].placement.CreationTimestamp.Before(&holders[i].placement.CreationTimestamp)
+	})
+	return holders
+}
+
+// validateShardedCapacity returns a Misconfigured status if the placement defines the priority or weight
+// in sharded mode, in which the slots and preemption are disabled.
+func validateShardedCapacity(placement *clusterapiv1beta1.Placement) *framework.Status {
+	for _, key := range []string{PlacementPriorityAnnotation, PlacementWeightAnnotation} {
+		if _, ok := placement.GetAnnotations()[key]; ok {
+			return framework.NewStatus(placementCapacityName, framework.Misconfigured,
+				fmt.Sprintf("annotation %s is not supported since placement slots are disabled in sharded mode", key))
+		}
+	}
+	return framework.NewStatus(placementCapacityName, framework.Success, "")
+}
+
+// DecisionsByClusterIndexers returns the indexers of placementdecisions required to count the slots used
+// on each cluster.
+func DecisionsByClusterIndexers() cache.Indexers {
+	return cache.Indexers{decisionsByCluster: indexDecisionsByCluster}
+}
+
+// GetClustersWithCapacity returns the clusters with enough slots for the placement, and the clusters
+// excluded. The slots taken by the preemptible placements are considered free since the placement can
+// preempt them. The placementDecisionIndexer should index placementdecisions with DecisionsByClusterIndexers.
+func GetClustersWithCapacity(
+	placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster,
+	placementLister clusterlisterv1beta1.PlacementLister,
+	placementDecisionIndexer cache.Indexer,
+) ([]*clusterapiv1.ManagedCluster, []ExcludedCluster, *framework.Status) {
+	priority, err := getPlacementPriority(placement)
+	if err != nil {
+		return nil, nil, framework.NewStatus(placementCapacityName, framework.Misconfigured, err.Error())
+	}
+	weight, err := getPlacementWeight(placement)
+	if err != nil {
+		return nil, nil, framework.NewStatus(placementCapacityName, framework.Misconfigured, err.Error())
+	}
+
+	matched := []*clusterapiv1.ManagedCluster{}
+	excluded := []ExcludedCluster{}
+	for _, cluster := range clusters {
+		slots, ok := getClusterSlots(cluster)
+		if !ok {
+			matched = append(matched, cluster)
+			continue
+		}
+
+		var used int64
+		for _, holder := range getClusterHolders(placement, cluster.Name, placementLister, placementDecisionIndexer) {
+			if !holder.preemptibleBy(placement, priority) {
+				used += holder.weight
+			}
+		}
+		if used+weight > slots {
+			excluded = append(excluded, ExcludedCluster{
+				ClusterName: cluster.Name,
+				Plugin:      placementCapacityName,
+				Reason: fmt.Sprintf("%d of %d slots are taken by placements which cannot be preempted, %d required",
+					used, slots, weight),
+			})
+			continue
+		}
+		matched = append(matched, cluster)
+	}
+	return matched, excluded, framework.NewStatus(placementCapacityName, framework.Success, "")
+}
+
+// preempt finds the preemptible placements exceeding the slots of the clusters in the decisions, records
+// the preemption and enqueues them to be rescheduled. It is called after the decisions are written, and only
+// preempts on the clusters whose decisions are observed in the informer.
+func (c *schedulingController) preempt(
+	placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster,
+	decisions []clusterapiv1beta1.ClusterDecision,
+	queue workqueue.RateLimitingInterface,
+) {
+	// ignore the errors, the placement is misconfigured and has no decision in this case
+	priority, _ := getPlacementPriority(placement)
+	weight, err := getPlacementWeight(placement)
+	if err != nil {
+		return
+	}
+
+	decided := sets.NewString()
+	for _, d := range decisions {
+		decided.Insert(d.ClusterName)
+	}
+
+	preempted := sets.NewString()
+	for _, cluster := range clusters {
+		if !decided.Has(cluster.Name) {
+			continue
+		}
+		slots, ok := getClusterSlots(cluster)
+		if !ok {
+			continue
+		}
+
+		// the preempted placements count the slots with the placementdecisions in the informer, so they are
+		// only requeued once the decisions of the placement on the cluster are observed. The placement is
+		// requeued by the events of its placementdecisions and preempts them then.
+		if !decisionObserved(placement, cluster.Name, c.placementDecisionIndexer) {
+			klog.V(4).Infof("Placement %s/%s waits for its decision on cluster %s to preempt other placements",
+				placement.Namespace, placement.Name, cluster.Name)
+			continue
+		}
+
+		holders := getClusterHolders(placement, cluster.Name, c.placementLister, c.placementDecisionIndexer)
+		used := weight
+		for _, holder := range holders {
+			used += holder.weight
+		}
+		for _, holder := range holders {
+			if used <= slots {
+				break
+			}
+			if !holder.preemptibleBy(placement, priority) {
+				continue
+			}
+			used -= holder.weight
+
+			key := fmt.Sprintf("%s/%s", holder.placement.Namespace, holder.placement.Name)
+			klog.V(4).Infof("Placement %s/%s preempts placement %s on cluster %s", placement.Namespace, placement.Name, key, cluster.Name)
+			c.recorder.Eventf(
+				holder.placement, placement, corev1.EventTypeWarning,
+				"ClusterPreempt", "ClusterPreempted",
+				"Cluster %s is preempted by placement %s with priority %d", cluster.Name, placement.Name, priority)
+			c.recorder.Eventf(
+				placement, holder.placement, corev1.EventTypeNormal,
+				"ClusterPreempt", "ClusterPreempted",
+				"Cluster %s of placement %s with priority %d is preempted", cluster.Name, key, holder.priority)
+			if queue != nil && !preempted.Has(key) {
+				queue.Add(key)
+			}
+			preempted.Insert(key)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package scheduling
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	kevents "k8s.io/client-go/tools/events"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+const capacityTestNamespace = "ns1"
+
+func newCapacityPlacement(name, priority, weight string) *clusterapiv1beta1.Placement {
+	annotations := map[string]string{}
+	if len(priority) > 0 {
+		annotations[PlacementPriorityAnnotation] = priority
+	}
+	if len(weight) > 0 {
+		annotations[PlacementWeightAnnotation] = weight
+	}
+	return testinghelpers.NewPlacementWithAnnotations(capacityTestNamespace, name, annotations).Build()
+}
+
+func newCapacityTestController() (*schedulingController, []*clusterapiv1.ManagedCluster) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").WithLabel(PlacementSlotsLabel, "2").Build(),
+		testinghelpers.NewManagedCluster("cluster2").WithLabel(PlacementSlotsLabel, "1").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+		testinghelpers.NewManagedCluster("cluster4").WithClaim(PlacementSlotsClaim, "1").Build(),
+		// the incorrect slots are ignored
+		testinghelpers.NewManagedCluster("cluster5").WithLabel(PlacementSlotsLabel, "abc").Build(),
+		testinghelpers.NewManagedCluster("cluster6").WithLabel(PlacementSlotsLabel, "1").Build(),
+	}
+	decisionsOf := func(namespace, placementName string, clusterNames ...string) runtime.Object {
+		return testinghelpers.NewPlacementDecision(namespace, placementDecisionName(placementName, 1)).
+			WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+			WithDecisions(clusterNames...).Build()
+	}
+	initObjs := []runtime.Object{
+		newCapacityPlacement("low", "-1", ""),
+		newCapacityPlacement("high", "10", ""),
+		newCapacityPlacement("test", "", ""),
+		// the placement in the other namespace is never preempted regardless of its priority
+		testinghelpers.NewPlacementWithAnnotations("ns2", "other", map[string]string{PlacementPriorityAnnotation: "-10"}).Build(),
+		decisionsOf(capacityTestNamespace, "low", "cluster1", "cluster2", "cluster5"),
+		decisionsOf(capacityTestNamespace, "high", "cluster1", "cluster4"),
+		decisionsOf("ns2", "other", "cluster6"),
+		// the decisions of the placement itself are not counted
+		decisionsOf(capacityTestNamespace, "test", "cluster1", "cluster2", "cluster4"),
+	}
+
+	clusterInformerFactory := newClusterInformerFactory(clusterfake.NewSimpleClientset(), initObjs...)
+	return &schedulingController{
+		placementLister:          clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+		placementDecisionLister:  clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+		placementDecisionIndexer: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetIndexer(),
+		recorder:                 kevents.NewFakeRecorder(100),
+	}, clusters
+}
+
+func TestGetClustersWithCapacity(t *testing.T) {
+	cases := []struct {
+		name                 string
+		placement            *clusterapiv1beta1.Placement
+		expectedClusterNames []string
+		expectedExcluded     []ExcludedCluster
+		expectedCode         framework.Code
+	}{
+		{
+			name:                 "default priority and weight",
+			placement:            newCapacityPlacement("test", "", ""),
+			expectedClusterNames: []string{"cluster1", "cluster2", "cluster3", "cluster5"},
+			expectedExcluded: []ExcludedCluster{
+				{
+					ClusterName: "cluster4",
+					Plugin:      placementCapacityName,
+					Reason:      "1 of 1 slots are taken by placements which cannot be preempted, 1 required",
+				},
+				{
+					ClusterName: "cluster6",
+					Plugin:      placementCapacityName,
+					Reason:      "1 of 1 slots are taken by placements which cannot be preempted, 1 required",
+				},
+			},
+		},
+		{
+			name:                 "highest priority with weight",
+			placement:            newCapacityPlacement("test", "20", "2"),
+			expectedClusterNames: []string{"cluster1", "cluster3", "cluster5"},
+			expectedExcluded: []ExcludedCluster{
+				{
+					ClusterName: "cluster2",
+					Plugin:      placementCapacityName,
+					Reason:      "0 of 1 slots are taken by placements which cannot be preempted, 2 required",
+				},
+				{
+					ClusterName: "cluster4",
+					Plugin:      placementCapacityName,
+					Reason:      "0 of 1 slots are taken by placements which cannot be preempted, 2 required",
+				},
+				{
+					ClusterName: "cluster6",
+					Plugin:      placementCapacityName,
+					Reason:      "1 of 1 slots are taken by placements which cannot be preempted, 2 required",
+				},
+			},
+		},
+		{
+			name:                 "lowest priority",
+			placement:            newCapacityPlacement("test", "-5", ""),
+			expectedClusterNames: []string{"cluster3", "cluster5"},
+			expectedExcluded: []ExcludedCluster{
+				{
+					ClusterName: "cluster1",
+					Plugin:      placementCapacityName,
+					Reason:      "2 of 2 slots are taken by placements which cannot be preempted, 1 required",
+				},
+				{
+					ClusterName: "cluster2",
+					Plugin:      placementCapacityName,
+					Reason:      "1 of 1 slots are taken by placements which cannot be preempted, 1 required",
+				},
+				{
+					ClusterName: "cluster4",
+					Plugin:      placementCapacityName,
+					Reason:      "1 of 1 slots are taken by placements which cannot be preempted, 1 required",
+				},
+				{
+					ClusterName: "cluster6",
+					Plugin:      placementCapacityName,
+					Reason:      "1 of 1 slots are taken by placements which cannot be preempted, 1 required",
+				},
+			},
+		},
+		{
+			name:         "invalid priority",
+			placement:    newCapacityPlacement("test", "abc", ""),
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name:         "invalid weight",
+			placement:    newCapacityPlacement("test", "", "0"),
+			expectedCode: framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl, clusters := newCapacityTestController()
+			actual, excluded, status := GetClustersWithCapacity(c.placement, clusters, ctrl.placementLister, ctrl.placementDecisionIndexer)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+
+			actualNames := []string{}
+			for _, cluster := range actual {
+				actualNames = append(actualNames, cluster.Name)
+			}
+			if !reflect.DeepEqual(actualNames, c.expectedClusterNames) {
+				t.Errorf("expected clusters %v, but got %v", c.expectedClusterNames, actualNames)
+			}
+			if !reflect.DeepEqual(excluded, c.expectedExcluded) {
+				t.Errorf("expected excluded clusters %v, but got %v", c.expectedExcluded, excluded)
+			}
+		})
+	}
+}
+
+func TestPreempt(t *testing.T) {
+	cases := []struct {
+		name           string
+		placement      *clusterapiv1beta1.Placement
+		decisions      []string
+		expectedKeys   []string
+		expectedEvents []string
+	}{
+		{
+			name:      "preempt placements with lower priority",
+			placement: newCapacityPlacement("test", "5", ""),
+			decisions: []string{"cluster1", "cluster2", "cluster3", "cluster4"},
+			expectedKeys: []string{
+				"ns1/low",
+			},
+			expectedEvents: []string{
+				"Warning ClusterPreempt Cluster cluster1 is preempted by placement test with priority 5",
+				"Normal ClusterPreempt Cluster cluster1 of placement ns1/low with priority -1 is preempted",
+				"Warning ClusterPreempt Cluster cluster2 is preempted by placement test with priority 5",
+				"Normal ClusterPreempt Cluster cluster2 of placement ns1/low with priority -1 is preempted",
+			},
+		},
+		{
+			name:      "no preemption before the decisions are observed",
+			placement: newCapacityPlacement("new", "5", ""),
+			decisions: []string{"cluster1", "cluster2"},
+		},
+		{
+			name:      "no preemption with enough slots",
+			placement: newCapacityPlacement("test", "5", ""),
+			decisions: []string{"cluster3", "cluster5"},
+		},
+		{
+			name:      "no preemption of placements in other namespaces",
+			placement: newCapacityPlacement("test", "5", ""),
+			decisions: []string{"cluster6"},
+		},
+		{
+			name:      "no preemption of placements with higher or equal priority",
+			placement: newCapacityPlacement("test", "-1", ""),
+			decisions: []string{"cluster1", "cluster2", "cluster4"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl, clusters := newCapacityTestController()
+			syncCtx := testingcommon.NewFakeSyncContext(t, "fake")
+
+			decisions := []clusterapiv1beta1.ClusterDecision{}
+			for _, clusterName := range c.decisions {
+				decisions = append(decisions, clusterapiv1beta1.ClusterDecision{ClusterName: clusterName})
+			}
+			ctrl.preempt(c.placement, clusters, decisions, syncCtx.Queue())
+
+			if syncCtx.Queue().Len() != len(c.expectedKeys) {
+				t.Fatalf("expected %d queued placements, but got %d", len(c.expectedKeys), syncCtx.Queue().Len())
+			}
+			for _, expected := range c.expectedKeys {
+				key, _ := syncCtx.Queue().Get()
+				if key != expected {
+					t.Errorf("expected queued placement %q, but got %q", expected, key)
+				}
+			}
+
+			recorder := ctrl.recorder.(*kevents.FakeRecorder)
+			actualEvents := []string{}
+			for len(recorder.Events) > 0 {
+				actualEvents = append(actualEvents, <-recorder.Events)
+			}
+			if len(actualEvents) != len(c.expectedEvents) {
+				t.Fatalf("expected events %v, but got %v", c.expectedEvents, actualEvents)
+			}
+			if len(c.expectedEvents) > 0 && !reflect.DeepEqual(actualEvents, c.expectedEvents) {
+				t.Errorf("expected events %v, but got %v", c.expectedEvents, actualEvents)
+			}
+		})
+	}
+}
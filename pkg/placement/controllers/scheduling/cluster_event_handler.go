@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"reflect"
 
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	cache "k8s.io/client-go/tools/cache"
 
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
 )
 
 type clusterEventHandler struct {
@@ -23,16 +27,24 @@ func (h *clusterEventHandler) OnUpdate(oldObj, newObj interface{}) {
 	if !ok {
 		return
 	}
-	h.enqueuer.enqueueCluster(newObj)
-
-	if oldObj == nil {
-		return
-	}
 	oldCluster, ok := oldObj.(*clusterapiv1.ManagedCluster)
 	if !ok {
+		h.enqueuer.enqueueCluster(newObj)
+		return
+	}
+
+	// if only the labels or claims of the cluster change and the clustersets it belongs to remain
+	// the same, only the placements referencing the changed labels or claims are impacted.
+	if attributes, ok := changedClusterAttributes(oldCluster, newCluster); ok &&
+		!h.enqueuer.clusterSetsChanged(oldCluster, newCluster) {
+		if attributes.Len() > 0 {
+			h.enqueuer.enqueueClusterAttributeChange(newCluster, attributes)
+		}
 		return
 	}
 
+	h.enqueuer.enqueueCluster(newObj)
+
 	// if the cluster labels changes, process the original clusterset
 	if !reflect.DeepEqual(newCluster.Labels, oldCluster.Labels) {
 		h.enqueuer.enqueueCluster(oldCluster)
@@ -49,3 +61,50 @@ func (h *clusterEventHandler) OnDelete(obj interface{}) {
 		utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
 	}
 }
+
+// changedClusterAttributes returns the index keys of the labels and claims which are different between
+// the old and new cluster. It returns false if anything else impacting the scheduling changes as well,
+// like the spec, conditions, allocatable resources or placement slots of the cluster.
+func changedClusterAttributes(oldCluster, newCluster *clusterapiv1.ManagedCluster) (sets.String, bool) {
+	if !oldCluster.DeletionTimestamp.Equal(newCluster.DeletionTimestamp) ||
+		!apiequality.Semantic.DeepEqual(oldCluster.Spec, newCluster.Spec) {
+		return nil, false
+	}
+
+	oldStatus, newStatus := oldCluster.Status.DeepCopy(), newCluster.Status.DeepCopy()
+	oldStatus.ClusterClaims, newStatus.ClusterClaims = nil, nil
+	if !apiequality.Semantic.DeepEqual(oldStatus, newStatus) {
+		return nil, false
+	}
+
+	attributes := sets.NewString()
+	for key, value := range oldCluster.Labels {
+		if newValue, ok := newCluster.Labels[key]; !ok || newValue != value {
+			attributes.Insert(clusterLabelIndexKey(key))
+		}
+	}
+	for key := range newCluster.Labels {
+		if _, ok := oldCluster.Labels[key]; !ok {
+			attributes.Insert(clusterLabelIndexKey(key))
+		}
+	}
+
+	oldClaims, newClaims := plugins.GetClusterClaims(oldCluster), plugins.GetClusterClaims(newCluster)
+	for name, value := range oldClaims {
+		if newValue, ok := newClaims[name]; !ok || newValue != value {
+			attributes.Insert(clusterClaimIndexKey(name))
+		}
+	}
+	for name := range newClaims {
+		if _, ok := oldClaims[name]; !ok {
+			attributes.Insert(clusterClaimIndexKey(name))
+		}
+	}
+
+	// the slots of the cluster impact the placements selecting the cluster by any attribute
+	if attributes.Has(clusterLabelIndexKey(PlacementSlotsLabel)) || attributes.Has(clusterClaimIndexKey(PlacementSlotsClaim)) {
+		return nil, false
+	}
+
+	return attributes, true
+}
@@ -11,6 +11,8 @@ import (
 	"k8s.io/client-go/util/workqueue"
 
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 	clusterapiv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 
 	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
@@ -226,13 +228,91 @@ func TestOnClusterUpdate(t *testing.T) {
 				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").WithLabel("cloud", "Amazon").Build(),
 			oldObj: testinghelpers.NewManagedCluster("cluster1").
 				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").WithLabel("cloud", "google").Build(),
+			initObjs: []runtime.Object{
+				testinghelpers.NewClusterSet("clusterset1").Build(),
+				testinghelpers.NewClusterSet("clusterset2").Build(),
+				testinghelpers.NewClusterSetBinding("ns1", "clusterset1"),
+				testinghelpers.NewClusterSetBinding("ns2", "clusterset2"),
+				testinghelpers.NewPlacement("ns1", "placement1").AddPredicate(
+					&metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Amazon"}}, nil).Build(),
+				testinghelpers.NewPlacement("ns1", "placement2").Build(),
+				testinghelpers.NewPlacement("ns1", "placement3").
+					AddSpreadConstraint("cloud", clusterapiv1beta1.TopologyKeyTypeLabel, 1, clusterapiv1beta1.DoNotSchedule).Build(),
+				testinghelpers.NewPlacement("ns1", "placement4").AddPredicate(
+					&metav1.LabelSelector{MatchLabels: map[string]string{"vendor": "OpenShift"}}, nil).Build(),
+				testinghelpers.NewPlacement("ns2", "placement5").AddPredicate(
+					&metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Amazon"}}, nil).Build(),
+			},
+			queuedKeys: []string{
+				"ns1/placement1",
+				"ns1/placement3",
+			},
+		},
+		{
+			name: "claim change only",
+			newObj: testinghelpers.NewManagedCluster("cluster1").
+				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").WithClaim("region", "us-east-1").Build(),
+			oldObj: testinghelpers.NewManagedCluster("cluster1").
+				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").WithClaim("region", "us-west-1").Build(),
+			initObjs: []runtime.Object{
+				testinghelpers.NewClusterSet("clusterset1").Build(),
+				testinghelpers.NewClusterSetBinding("ns1", "clusterset1"),
+				testinghelpers.NewPlacement("ns1", "placement1").AddPredicate(nil, &clusterapiv1beta1.ClusterClaimSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "region", Operator: metav1.LabelSelectorOpIn, Values: []string{"us-east-1"}},
+					},
+				}).Build(),
+				testinghelpers.NewPlacement("ns1", "placement2").AddPredicate(
+					&metav1.LabelSelector{MatchLabels: map[string]string{"region": "us-east-1"}}, nil).Build(),
+			},
+			queuedKeys: []string{
+				"ns1/placement1",
+			},
+		},
+		{
+			name: "placement slots change",
+			newObj: testinghelpers.NewManagedCluster("cluster1").
+				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").WithLabel(PlacementSlotsLabel, "3").Build(),
+			oldObj: testinghelpers.NewManagedCluster("cluster1").
+				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").WithLabel(PlacementSlotsLabel, "2").Build(),
 			initObjs: []runtime.Object{
 				testinghelpers.NewClusterSet("clusterset1").Build(),
 				testinghelpers.NewClusterSetBinding("ns1", "clusterset1"),
 				testinghelpers.NewPlacement("ns1", "placement1").Build(),
+				testinghelpers.NewPlacement("ns1", "placement2").AddPredicate(
+					&metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Amazon"}}, nil).Build(),
 			},
 			queuedKeys: []string{
 				"ns1/placement1",
+				"ns1/placement2",
+			},
+		},
+		{
+			name: "taints change",
+			newObj: testinghelpers.NewManagedCluster("cluster1").
+				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").
+				WithTaint(&clusterapiv1.Taint{Key: "key1", Effect: clusterapiv1.TaintEffectNoSelect}).Build(),
+			oldObj: testinghelpers.NewManagedCluster("cluster1").
+				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").Build(),
+			initObjs: []runtime.Object{
+				testinghelpers.NewClusterSet("clusterset1").Build(),
+				testinghelpers.NewClusterSetBinding("ns1", "clusterset1"),
+				testinghelpers.NewPlacement("ns1", "placement1").Build(),
+			},
+			queuedKeys: []string{
+				"ns1/placement1",
+			},
+		},
+		{
+			name: "nothing change",
+			newObj: testinghelpers.NewManagedCluster("cluster1").
+				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").Build(),
+			oldObj: testinghelpers.NewManagedCluster("cluster1").
+				WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").Build(),
+			initObjs: []runtime.Object{
+				testinghelpers.NewClusterSet("clusterset1").Build(),
+				testinghelpers.NewClusterSetBinding("ns1", "clusterset1"),
+				testinghelpers.NewPlacement("ns1", "placement1").Build(),
 			},
 		},
 		{
@@ -0,0 +1,201 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	clusterlisterv1beta1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+)
+
+const (
+	// CompositePlacementAnnotation is the annotation on Placement which computes the candidate clusters
+	// from the decisions of the other placements in the same namespace. The value is a json encoded
+	// CompositeExpression, for example {"difference":[{"placement":"prod"},{"placement":"canary"}]}.
+	// The candidate clusters are then filtered and prioritized by the placement as usual.
+	CompositePlacementAnnotation = "cluster.open-cluster-management.io/experimental-composite-placement"
+
+	compositePlacementName = "CompositePlacement"
+)
+
+// CompositeExpression defines a set of clusters computed from the decisions of placements. Exactly one
+// of the fields should be set.
+type CompositeExpression struct {
+	// Placement is the name of a placement, the set is the clusters in its decisions.
+	Placement string `json:"placement,omitempty"`
+	// Union is the clusters in any of the sets.
+	Union []CompositeExpression `json:"union,omitempty"`
+	// Intersection is the clusters in all of the sets.
+	Intersection []CompositeExpression `json:"intersection,omitempty"`
+	// Difference is the clusters in the first set but not in any of the others.
+	Difference []CompositeExpression `json:"difference,omitempty"`
+}
+
+// validate checks exactly one field is set in each expression.
+func (e *CompositeExpression) validate() error {
+	numOfFields := 0
+	for _, set := range []bool{len(e.Placement) > 0, len(e.Union) > 0, len(e.Intersection) > 0, len(e.Difference) > 0} {
+		if set {
+			numOfFields++
+		}
+	}
+	if numOfFields != 1 {
+		return fmt.Errorf("exactly one of placement, union, intersection and difference should be set")
+	}
+
+	for _, expressions := range [][]CompositeExpression{e.Union, e.Intersection, e.Difference} {
+		for i := range expressions {
+			if err := expressions[i].validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReferencedPlacements returns the names of all the placements in the expression.
+func (e *CompositeExpression) ReferencedPlacements() sets.String {
+	names := sets.NewString()
+	if len(e.Placement) > 0 {
+		names.Insert(e.Placement)
+	}
+	for _, expressions := range [][]CompositeExpression{e.Union, e.Intersection, e.Difference} {
+		for i := range expressions {
+			names = names.Union(expressions[i].ReferencedPlacements())
+		}
+	}
+	return names
+}
+
+// evaluate returns the clusters of the expression with the decided clusters of each placement.
+func (e *CompositeExpression) evaluate(decisions map[string]sets.String) sets.String {
+	switch {
+	case len(e.Placement) > 0:
+		return sets.NewString(decisions[e.Placement].UnsortedList()...)
+	case len(e.Union) > 0:
+		result := sets.NewString()
+		for i := range e.Union {
+			result = result.Union(e.Union[i].evaluate(decisions))
+		}
+		return result
+	case len(e.Intersection) > 0:
+		result := e.Intersection[0].evaluate(decisions)
+		for i := range e.Intersection[1:] {
+			result = result.Intersection(e.Intersection[i+1].evaluate(decisions))
+		}
+		return result
+	case len(e.Difference) > 0:
+		result := e.Difference[0].evaluate(decisions)
+		for i := range e.Difference[1:] {
+			result = result.Difference(e.Difference[i+1].evaluate(decisions))
+		}
+		return result
+	}
+	return sets.NewString()
+}
+
+// GetCompositeExpression returns the CompositeExpression defined in the annotation of the placement, or
+// nil if the placement is not a composite placement.
+func GetCompositeExpression(placement *clusterapiv1beta1.Placement) (*CompositeExpression, error) {
+	value, ok := placement.GetAnnotations()[CompositePlacementAnnotation]
+	if !ok || len(value) == 0 {
+		return nil, nil
+	}
+
+	expression := &CompositeExpression{}
+	if err := json.Unmarshal([]byte(value), expression); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", CompositePlacementAnnotation, err)
+	}
+	if err := expression.validate(); err != nil {
+		return nil, fmt.Errorf("incorrect annotation %s: %v", CompositePlacementAnnotation, err)
+	}
+	if expression.ReferencedPlacements().Has(placement.Name) {
+		return nil, fmt.Errorf("placement %s should not be composed of itself", placement.Name)
+	}
+	return expression, nil
+}
+
+// GetCompositeClusters returns the available clusters in the candidate set computed from the decisions of
+// the placements the composite placement references. The clusters are returned without change if the
+// placement is not a composite placement, and no cluster is returned if the placement is misconfigured.
+func GetCompositeClusters(
+	placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster,
+	placementLister clusterlisterv1beta1.PlacementLister,
+	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister,
+) ([]*clusterapiv1.ManagedCluster, *framework.Status) {
+	expression, err := GetCompositeExpression(placement)
+	if err != nil {
+		return nil, framework.NewStatus(compositePlacementName, framework.Misconfigured, err.Error())
+	}
+	if expression == nil {
+		return clusters, framework.NewStatus(compositePlacementName, framework.Success, "")
+	}
+
+	if err := checkCompositeCycle(placement, placementLister); err != nil {
+		return nil, framework.NewStatus(compositePlacementName, framework.Misconfigured, err.Error())
+	}
+
+	decisions := map[string]sets.String{}
+	for _, name := range expression.ReferencedPlacements().List() {
+		referenced, err := placementLister.Placements(placement.Namespace).Get(name)
+		if errors.IsNotFound(err) {
+			return nil, framework.NewStatus(compositePlacementName, framework.Misconfigured,
+				fmt.Sprintf("placement %s referenced by annotation %s is not found", name, CompositePlacementAnnotation))
+		}
+		if err != nil {
+			return nil, framework.NewStatus(compositePlacementName, framework.Error, err.Error())
+		}
+		decisions[name] = getDecisionClusterNames(placementDecisionLister, referenced)
+	}
+
+	candidates := expression.evaluate(decisions)
+	matched := []*clusterapiv1.ManagedCluster{}
+	for _, cluster := range clusters {
+		if candidates.Has(cluster.Name) {
+			matched = append(matched, cluster)
+		}
+	}
+	return matched, framework.NewStatus(compositePlacementName, framework.Success, "")
+}
+
+// checkCompositeCycle returns an error if the placement is composed of itself through the other composite
+// placements, for example A is composed of B while B is composed of A.
+func checkCompositeCycle(placement *clusterapiv1beta1.Placement, placementLister clusterlisterv1beta1.PlacementLister) error {
+	visited := sets.NewString()
+	var visit func(p *clusterapiv1beta1.Placement, path []string) error
+	visit = func(p *clusterapiv1beta1.Placement, path []string) error {
+		// ignore the error, the referenced placement is reported as misconfigured by itself
+		expression, _ := GetCompositeExpression(p)
+		if expression == nil {
+			return nil
+		}
+		for _, name := range expression.ReferencedPlacements().List() {
+			if name == placement.Name {
+				return fmt.Errorf("placement %s is composed of itself: %s",
+					placement.Name, strings.Join(append(path, name), " -> "))
+			}
+			if visited.Has(name) {
+				continue
+			}
+			visited.Insert(name)
+
+			referenced, err := placementLister.Placements(placement.Namespace).Get(name)
+			if err != nil {
+				continue
+			}
+			if err := visit(referenced, append(path, name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(placement, []string{placement.Name})
+}
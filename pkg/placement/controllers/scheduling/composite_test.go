@@ -0,0 +1,203 @@
+package scheduling
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+func newCompositePlacement(namespace, name, expression string) *clusterapiv1beta1.Placement {
+	return testinghelpers.NewPlacementWithAnnotations(namespace, name, map[string]string{
+		CompositePlacementAnnotation: expression,
+	}).Build()
+}
+
+func TestGetCompositeExpression(t *testing.T) {
+	cases := []struct {
+		name                 string
+		placement            *clusterapiv1beta1.Placement
+		expectedNil          bool
+		expectedPlacements   []string
+		expectedErrSubstring string
+	}{
+		{
+			name:        "not a composite placement",
+			placement:   testinghelpers.NewPlacement("ns1", "p1").Build(),
+			expectedNil: true,
+		},
+		{
+			name: "nested expression",
+			placement: newCompositePlacement("ns1", "p1",
+				`{"difference":[{"union":[{"placement":"a"},{"placement":"b"}]},{"intersection":[{"placement":"c"},{"placement":"a"}]}]}`),
+			expectedPlacements: []string{"a", "b", "c"},
+		},
+		{
+			name:                 "invalid json",
+			placement:            newCompositePlacement("ns1", "p1", `{"union":`),
+			expectedErrSubstring: "failed to parse annotation",
+		},
+		{
+			name:                 "empty expression",
+			placement:            newCompositePlacement("ns1", "p1", `{}`),
+			expectedErrSubstring: "exactly one of",
+		},
+		{
+			name:                 "multiple fields",
+			placement:            newCompositePlacement("ns1", "p1", `{"placement":"a","union":[{"placement":"b"}]}`),
+			expectedErrSubstring: "exactly one of",
+		},
+		{
+			name:                 "invalid nested expression",
+			placement:            newCompositePlacement("ns1", "p1", `{"union":[{"placement":"a"},{}]}`),
+			expectedErrSubstring: "exactly one of",
+		},
+		{
+			name:                 "composed of itself",
+			placement:            newCompositePlacement("ns1", "p1", `{"union":[{"placement":"a"},{"placement":"p1"}]}`),
+			expectedErrSubstring: "should not be composed of itself",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expression, err := GetCompositeExpression(c.placement)
+			if len(c.expectedErrSubstring) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.expectedErrSubstring) {
+					t.Errorf("expected error containing %q, but got %v", c.expectedErrSubstring, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if c.expectedNil {
+				if expression != nil {
+					t.Errorf("expected nil expression, but got %v", expression)
+				}
+				return
+			}
+			if actual := expression.ReferencedPlacements().List(); !reflect.DeepEqual(actual, c.expectedPlacements) {
+				t.Errorf("expected referenced placements %v, but got %v", c.expectedPlacements, actual)
+			}
+		})
+	}
+}
+
+func TestGetCompositeClusters(t *testing.T) {
+	namespace := "ns1"
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+		testinghelpers.NewManagedCluster("cluster4").Build(),
+	}
+	decisionsOf := func(placementName string, clusterNames ...string) runtime.Object {
+		return testinghelpers.NewPlacementDecision(namespace, placementDecisionName(placementName, 1)).
+			WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+			WithDecisions(clusterNames...).Build()
+	}
+	initObjs := []runtime.Object{
+		testinghelpers.NewPlacement(namespace, "prod").Build(),
+		testinghelpers.NewPlacement(namespace, "canary").Build(),
+		testinghelpers.NewPlacement(namespace, "gpu").Build(),
+		decisionsOf("prod", "cluster1", "cluster2", "cluster3"),
+		decisionsOf("canary", "cluster1"),
+		// cluster5 is not available to the composite placement
+		decisionsOf("gpu", "cluster2", "cluster4", "cluster5"),
+		newCompositePlacement(namespace, "cycle-a", `{"placement":"cycle-b"}`),
+		newCompositePlacement(namespace, "cycle-b", `{"union":[{"placement":"prod"},{"placement":"cycle-c"}]}`),
+		newCompositePlacement(namespace, "cycle-c", `{"placement":"cycle-a"}`),
+	}
+
+	cases := []struct {
+		name                 string
+		placement            *clusterapiv1beta1.Placement
+		expectedClusterNames []string
+		expectedCode         framework.Code
+		expectedMessage      string
+	}{
+		{
+			name:                 "not a composite placement",
+			placement:            testinghelpers.NewPlacement(namespace, "test").Build(),
+			expectedClusterNames: []string{"cluster1", "cluster2", "cluster3", "cluster4"},
+		},
+		{
+			name:                 "union",
+			placement:            newCompositePlacement(namespace, "test", `{"union":[{"placement":"canary"},{"placement":"gpu"}]}`),
+			expectedClusterNames: []string{"cluster1", "cluster2", "cluster4"},
+		},
+		{
+			name:                 "intersection",
+			placement:            newCompositePlacement(namespace, "test", `{"intersection":[{"placement":"prod"},{"placement":"gpu"}]}`),
+			expectedClusterNames: []string{"cluster2"},
+		},
+		{
+			name:                 "difference",
+			placement:            newCompositePlacement(namespace, "test", `{"difference":[{"placement":"prod"},{"placement":"canary"},{"placement":"gpu"}]}`),
+			expectedClusterNames: []string{"cluster3"},
+		},
+		{
+			name:         "invalid expression",
+			placement:    newCompositePlacement(namespace, "test", `{}`),
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name:            "referenced placement not found",
+			placement:       newCompositePlacement(namespace, "test", `{"placement":"notfound"}`),
+			expectedCode:    framework.Misconfigured,
+			expectedMessage: "placement notfound referenced by annotation " + CompositePlacementAnnotation + " is not found",
+		},
+		{
+			name:            "cycle",
+			placement:       newCompositePlacement(namespace, "cycle-a", `{"placement":"cycle-b"}`),
+			expectedCode:    framework.Misconfigured,
+			expectedMessage: "placement cycle-a is composed of itself: cycle-a -> cycle-b -> cycle-c -> cycle-a",
+		},
+		{
+			name:                 "composed of a placement in cycle",
+			placement:            newCompositePlacement(namespace, "test", `{"placement":"cycle-b"}`),
+			expectedClusterNames: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterInformerFactory := newClusterInformerFactory(clusterfake.NewSimpleClientset(), initObjs...)
+			actual, status := GetCompositeClusters(
+				c.placement,
+				clusters,
+				clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+				clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+			)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if len(c.expectedMessage) > 0 && status.Message() != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, status.Message())
+			}
+			if status.IsError() {
+				if len(actual) != 0 {
+					t.Errorf("expected no cluster, but got %d", len(actual))
+				}
+				return
+			}
+
+			actualNames := []string{}
+			for _, cluster := range actual {
+				actualNames = append(actualNames, cluster.Name)
+			}
+			if !reflect.DeepEqual(actualNames, c.expectedClusterNames) {
+				t.Errorf("expected clusters %v, but got %v", c.expectedClusterNames, actualNames)
+			}
+		})
+	}
+}
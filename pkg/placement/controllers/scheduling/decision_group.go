@@ -0,0 +1,302 @@
+package scheduling
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+)
+
+const (
+	// DecisionStrategyAnnotation is the annotation on Placement which defines how the selected
+	// clusters are divided into decision groups. The value is a json encoded DecisionStrategy.
+	DecisionStrategyAnnotation = "cluster.open-cluster-management.io/experimental-decision-strategy"
+
+	// DecisionGroupIndexLabel is the label on PlacementDecision which records the index of the
+	// decision group the PlacementDecision belongs to.
+	DecisionGroupIndexLabel = "cluster.open-cluster-management.io/decision-group-index"
+
+	// DecisionGroupNameLabel is the label on PlacementDecision which records the name of the
+	// decision group the PlacementDecision belongs to.
+	DecisionGroupNameLabel = "cluster.open-cluster-management.io/decision-group-name"
+
+	decisionStrategyName = "DecisionStrategy"
+)
+
+// DecisionStrategy divides the selected clusters of a placement into decision groups.
+type DecisionStrategy struct {
+	GroupStrategy GroupStrategy `json:"groupStrategy,omitempty"`
+}
+
+// GroupStrategy defines the decision groups of a placement.
+type GroupStrategy struct {
+	// DecisionGroups defines the named groups of clusters selected by a cluster selector.
+	// A cluster belongs to the first group it matches. Clusters not matching any group
+	// are put into the default group with an empty name.
+	DecisionGroups []DecisionGroup `json:"decisionGroups,omitempty"`
+
+	// ClustersPerDecisionGroup is the max number of clusters in a decision group. It can be
+	// an absolute number or a percentage of the selected clusters. A group with more clusters
+	// is split into multiple decision groups with the same name.
+	ClustersPerDecisionGroup intstr.IntOrString `json:"clustersPerDecisionGroup,omitempty"`
+}
+
+// DecisionGroup defines a named group of clusters.
+type DecisionGroup struct {
+	GroupName            string                            `json:"groupName"`
+	GroupClusterSelector clusterapiv1beta1.ClusterSelector `json:"groupClusterSelector"`
+}
+
+// clusterDecisionGroup contains the cluster decisions of one decision group.
+type clusterDecisionGroup struct {
+	groupName        string
+	groupIndex       int
+	clusterDecisions []clusterapiv1beta1.ClusterDecision
+}
+
+// groupSelector is a prebuilt label/claim selector of a decision group.
+type groupSelector struct {
+	name          string
+	labelSelector labels.Selector
+	claimSelector labels.Selector
+}
+
+// getDecisionStrategy returns the DecisionStrategy defined in the annotation of the placement.
+func getDecisionStrategy(placement *clusterapiv1beta1.Placement) (*DecisionStrategy, *framework.Status) {
+	strategy := &DecisionStrategy{}
+	value, ok := placement.GetAnnotations()[DecisionStrategyAnnotation]
+	if !ok || len(value) == 0 {
+		return strategy, framework.NewStatus(decisionStrategyName, framework.Success, "")
+	}
+
+	if err := json.Unmarshal([]byte(value), strategy); err != nil {
+		return strategy, framework.NewStatus(decisionStrategyName, framework.Misconfigured,
+			fmt.Sprintf("failed to parse annotation %s: %v", DecisionStrategyAnnotation, err))
+	}
+
+	groupNames := map[string]bool{}
+	for _, group := range strategy.GroupStrategy.DecisionGroups {
+		if len(group.GroupName) == 0 {
+			return strategy, framework.NewStatus(decisionStrategyName, framework.Misconfigured, "groupName of decision group is required")
+		}
+		if groupNames[group.GroupName] {
+			return strategy, framework.NewStatus(decisionStrategyName, framework.Misconfigured,
+				fmt.Sprintf("duplicated decision group %s", group.GroupName))
+		}
+		groupNames[group.GroupName] = true
+	}
+
+	if _, err := getClustersPerDecisionGroup(strategy.GroupStrategy.ClustersPerDecisionGroup, 1); err != nil {
+		return strategy, framework.NewStatus(decisionStrategyName, framework.Misconfigured, err.Error())
+	}
+
+	return strategy, framework.NewStatus(decisionStrategyName, framework.Success, "")
+}
+
+// getClustersPerDecisionGroup returns the max number of clusters in each decision group, 0 means no limitation.
+func getClustersPerDecisionGroup(clustersPerDecisionGroup intstr.IntOrString, numOfDecisions int) (int, error) {
+	if clustersPerDecisionGroup.Type == intstr.String && len(clustersPerDecisionGroup.StrVal) == 0 {
+		return 0, nil
+	}
+
+	value, err := intstr.GetScaledValueFromIntOrPercent(&clustersPerDecisionGroup, numOfDecisions, true)
+	if err != nil {
+		return 0, fmt.Errorf("incorrect clustersPerDecisionGroup: %v", err)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("clustersPerDecisionGroup should not be negative")
+	}
+	if clustersPerDecisionGroup.Type == intstr.String && value == 0 {
+		// 0 means no limitation for an absolute number, while a percentage should be positive
+		// to avoid "0%" being mistaken for either no limitation or 1 cluster in a group.
+		if percent, _ := intstr.GetScaledValueFromIntOrPercent(&clustersPerDecisionGroup, 100, true); percent == 0 {
+			return 0, fmt.Errorf("clustersPerDecisionGroup percentage should be greater than 0%%")
+		}
+		// a positive percentage always has at least 1 cluster in a group
+		value = 1
+	}
+	return value, nil
+}
+
+// generateDecisionGroups divides the cluster decisions into decision groups with the decision strategy
+// of the placement. The clusters in existing PlacementDecisions stay in the same decision group as long
+// as they still match it, so clusters do not hop between groups when the placement is rescheduled.
+func (c *schedulingController) generateDecisionGroups(
+	placement *clusterapiv1beta1.Placement,
+	clusterDecisions []clusterapiv1beta1.ClusterDecision,
+) ([]clusterDecisionGroup, *framework.Status) {
+	strategy, status := getDecisionStrategy(placement)
+	if status.IsError() {
+		return nil, status
+	}
+
+	// prebuild the selectors of the decision groups
+	selectors := []groupSelector{}
+	for _, group := range strategy.GroupStrategy.DecisionGroups {
+		labelSelector, err := metav1.LabelSelectorAsSelector(&group.GroupClusterSelector.LabelSelector)
+		if err != nil {
+			return nil, framework.NewStatus(decisionStrategyName, framework.Misconfigured, err.Error())
+		}
+		claimSelector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+			MatchExpressions: group.GroupClusterSelector.ClaimSelector.MatchExpressions,
+		})
+		if err != nil {
+			return nil, framework.NewStatus(decisionStrategyName, framework.Misconfigured, err.Error())
+		}
+		selectors = append(selectors, groupSelector{
+			name:          group.GroupName,
+			labelSelector: labelSelector,
+			claimSelector: claimSelector,
+		})
+	}
+
+	clustersPerGroup, err := getClustersPerDecisionGroup(strategy.GroupStrategy.ClustersPerDecisionGroup, len(clusterDecisions))
+	if err != nil {
+		return nil, framework.NewStatus(decisionStrategyName, framework.Misconfigured, err.Error())
+	}
+	if clustersPerGroup == 0 {
+		clustersPerGroup = math.MaxInt32
+	}
+
+	// divide cluster names by group name, the default group is the last one
+	groupNames := []string{}
+	for _, s := range selectors {
+		groupNames = append(groupNames, s.name)
+	}
+	groupNames = append(groupNames, "")
+	clustersByGroup := map[string][]string{}
+	for _, decision := range clusterDecisions {
+		groupName := c.matchDecisionGroup(decision.ClusterName, selectors)
+		clustersByGroup[groupName] = append(clustersByGroup[groupName], decision.ClusterName)
+	}
+
+	existingGroups := c.getExistingDecisionGroups(placement)
+
+	groups := []clusterDecisionGroup{}
+	for _, groupName := range groupNames {
+		for _, clusterNames := range splitDecisionGroup(clustersByGroup[groupName], existingGroups[groupName], clustersPerGroup) {
+			group := clusterDecisionGroup{
+				groupName:        groupName,
+				groupIndex:       len(groups),
+				clusterDecisions: []clusterapiv1beta1.ClusterDecision{},
+			}
+			for _, clusterName := range clusterNames {
+				group.clusterDecisions = append(group.clusterDecisions, clusterapiv1beta1.ClusterDecision{ClusterName: clusterName})
+			}
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, framework.NewStatus(decisionStrategyName, framework.Success, "")
+}
+
+// matchDecisionGroup returns the name of the first decision group the cluster matches.
+func (c *schedulingController) matchDecisionGroup(clusterName string, selectors []groupSelector) string {
+	if len(selectors) == 0 {
+		return ""
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	if err != nil {
+		return ""
+	}
+
+	claims := plugins.GetClusterClaims(cluster)
+	for _, s := range selectors {
+		if s.labelSelector.Matches(labels.Set(cluster.Labels)) && s.claimSelector.Matches(labels.Set(claims)) {
+			return s.name
+		}
+	}
+	return ""
+}
+
+// getExistingDecisionGroups returns the clusters of each existing decision group, indexed by group name
+// and then by group index.
+func (c *schedulingController) getExistingDecisionGroups(placement *clusterapiv1beta1.Placement) map[string]map[int][]string {
+	existingGroups := map[string]map[int][]string{}
+
+	placementDecisions, err := c.placementDecisionLister.PlacementDecisions(placement.Namespace).List(
+		labels.SelectorFromSet(labels.Set{clusterapiv1beta1.PlacementLabel: placement.Name}))
+	if err != nil {
+		return existingGroups
+	}
+
+	for _, placementDecision := range placementDecisions {
+		groupIndex, err := strconv.Atoi(placementDecision.Labels[DecisionGroupIndexLabel])
+		if err != nil {
+			continue
+		}
+		groupName := placementDecision.Labels[DecisionGroupNameLabel]
+		if _, ok := existingGroups[groupName]; !ok {
+			existingGroups[groupName] = map[int][]string{}
+		}
+		for _, d := range placementDecision.Status.Decisions {
+			existingGroups[groupName][groupIndex] = append(existingGroups[groupName][groupIndex], d.ClusterName)
+		}
+	}
+
+	return existingGroups
+}
+
+// splitDecisionGroup splits the clusters of a group into chunks with at most clustersPerGroup clusters.
+// The clusters in existing chunks are kept in place, and the other clusters are appended to the chunks
+// with free room before new chunks are created.
+func splitDecisionGroup(clusterNames []string, existingChunks map[int][]string, clustersPerGroup int) [][]string {
+	sort.Strings(clusterNames)
+	current := map[string]bool{}
+	for _, name := range clusterNames {
+		current[name] = true
+	}
+
+	existingIndexes := []int{}
+	for index := range existingChunks {
+		existingIndexes = append(existingIndexes, index)
+	}
+	sort.Ints(existingIndexes)
+
+	chunks := [][]string{}
+	assigned := map[string]bool{}
+	for _, index := range existingIndexes {
+		chunk := []string{}
+		existing := existingChunks[index]
+		sort.Strings(existing)
+		for _, name := range existing {
+			if current[name] && !assigned[name] && len(chunk) < clustersPerGroup {
+				chunk = append(chunk, name)
+				assigned[name] = true
+			}
+		}
+		if len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	for _, name := range clusterNames {
+		if assigned[name] {
+			continue
+		}
+		added := false
+		for i := range chunks {
+			if len(chunks[i]) < clustersPerGroup {
+				chunks[i] = append(chunks[i], name)
+				added = true
+				break
+			}
+		}
+		if !added {
+			chunks = append(chunks, []string{name})
+		}
+	}
+
+	return chunks
+}
@@ -0,0 +1,161 @@
+package scheduling
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+func TestGenerateDecisionGroups(t *testing.T) {
+	placementNamespace := "ns1"
+	placementName := "placement1"
+
+	clusters := []runtime.Object{
+		testinghelpers.NewManagedCluster("cluster1").WithLabel("env", "canary").Build(),
+		testinghelpers.NewManagedCluster("cluster2").WithLabel("env", "prod").Build(),
+		testinghelpers.NewManagedCluster("cluster3").WithLabel("env", "prod").Build(),
+		testinghelpers.NewManagedCluster("cluster4").WithLabel("env", "prod").Build(),
+		testinghelpers.NewManagedCluster("cluster5").Build(),
+	}
+
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		initObjs         []runtime.Object
+		clusterDecisions []clusterapiv1beta1.ClusterDecision
+		expectedGroups   []clusterDecisionGroup
+		expectedCode     framework.Code
+	}{
+		{
+			name:             "no decision strategy",
+			clusterDecisions: newClusterDecisions(3),
+			expectedGroups: []clusterDecisionGroup{
+				{groupIndex: 0, clusterDecisions: newClusterDecisions(3)},
+			},
+		},
+		{
+			name: "groups by cluster selector",
+			annotations: map[string]string{
+				DecisionStrategyAnnotation: `{"groupStrategy":{"decisionGroups":[{"groupName":"canary","groupClusterSelector":{"labelSelector":{"matchLabels":{"env":"canary"}}}}]}}`,
+			},
+			clusterDecisions: newClusterDecisions(5),
+			expectedGroups: []clusterDecisionGroup{
+				{groupName: "canary", groupIndex: 0, clusterDecisions: newClusterDecisionsFromNames("cluster1")},
+				{groupIndex: 1, clusterDecisions: newClusterDecisionsFromNames("cluster2", "cluster3", "cluster4", "cluster5")},
+			},
+		},
+		{
+			name: "groups by count",
+			annotations: map[string]string{
+				DecisionStrategyAnnotation: `{"groupStrategy":{"clustersPerDecisionGroup":2}}`,
+			},
+			clusterDecisions: newClusterDecisions(5),
+			expectedGroups: []clusterDecisionGroup{
+				{groupIndex: 0, clusterDecisions: newClusterDecisionsFromNames("cluster1", "cluster2")},
+				{groupIndex: 1, clusterDecisions: newClusterDecisionsFromNames("cluster3", "cluster4")},
+				{groupIndex: 2, clusterDecisions: newClusterDecisionsFromNames("cluster5")},
+			},
+		},
+		{
+			name: "groups by cluster selector and percentage",
+			annotations: map[string]string{
+				DecisionStrategyAnnotation: `{"groupStrategy":{"decisionGroups":[{"groupName":"canary","groupClusterSelector":{"labelSelector":{"matchLabels":{"env":"canary"}}}}],"clustersPerDecisionGroup":"40%"}}`,
+			},
+			clusterDecisions: newClusterDecisions(5),
+			expectedGroups: []clusterDecisionGroup{
+				{groupName: "canary", groupIndex: 0, clusterDecisions: newClusterDecisionsFromNames("cluster1")},
+				{groupIndex: 1, clusterDecisions: newClusterDecisionsFromNames("cluster2", "cluster3")},
+				{groupIndex: 2, clusterDecisions: newClusterDecisionsFromNames("cluster4", "cluster5")},
+			},
+		},
+		{
+			name: "keep clusters in existing groups",
+			annotations: map[string]string{
+				DecisionStrategyAnnotation: `{"groupStrategy":{"clustersPerDecisionGroup":2}}`,
+			},
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 1)).
+					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
+					WithDecisions("cluster1", "cluster3").Build(),
+				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 2)).
+					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "1").
+					WithLabel(DecisionGroupNameLabel, "").
+					WithDecisions("cluster4").Build(),
+			},
+			clusterDecisions: newClusterDecisions(5),
+			expectedGroups: []clusterDecisionGroup{
+				{groupIndex: 0, clusterDecisions: newClusterDecisionsFromNames("cluster1", "cluster3")},
+				{groupIndex: 1, clusterDecisions: newClusterDecisionsFromNames("cluster4", "cluster2")},
+				{groupIndex: 2, clusterDecisions: newClusterDecisionsFromNames("cluster5")},
+			},
+		},
+		{
+			name: "invalid annotation",
+			annotations: map[string]string{
+				DecisionStrategyAnnotation: `{"groupStrategy":`,
+			},
+			clusterDecisions: newClusterDecisions(5),
+			expectedCode:     framework.Misconfigured,
+		},
+		{
+			name: "zero percentage",
+			annotations: map[string]string{
+				DecisionStrategyAnnotation: `{"groupStrategy":{"clustersPerDecisionGroup":"0%"}}`,
+			},
+			clusterDecisions: newClusterDecisions(5),
+			expectedCode:     framework.Misconfigured,
+		},
+		{
+			name: "duplicated group name",
+			annotations: map[string]string{
+				DecisionStrategyAnnotation: `{"groupStrategy":{"decisionGroups":[{"groupName":"canary"},{"groupName":"canary"}]}}`,
+			},
+			clusterDecisions: newClusterDecisions(5),
+			expectedCode:     framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			placement := testinghelpers.NewPlacementWithAnnotations(placementNamespace, placementName, c.annotations).Build()
+			initObjs := append(c.initObjs, clusters...)
+			clusterClient := clusterfake.NewSimpleClientset(initObjs...)
+			clusterInformerFactory := newClusterInformerFactory(clusterClient, initObjs...)
+
+			ctrl := schedulingController{
+				clusterClient:           clusterClient,
+				clusterLister:           clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				placementDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+			}
+
+			groups, status := ctrl.generateDecisionGroups(placement, c.clusterDecisions)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+			if !reflect.DeepEqual(groups, c.expectedGroups) {
+				t.Errorf("expected decision groups %v, but got %v", c.expectedGroups, groups)
+			}
+		})
+	}
+}
+
+func newClusterDecisionsFromNames(clusterNames ...string) []clusterapiv1beta1.ClusterDecision {
+	decisions := []clusterapiv1beta1.ClusterDecision{}
+	for _, name := range clusterNames {
+		decisions = append(decisions, clusterapiv1beta1.ClusterDecision{ClusterName: name})
+	}
+	return decisions
+}
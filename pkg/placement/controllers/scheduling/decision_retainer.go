@@ -0,0 +1,175 @@
+package scheduling
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/tainttoleration"
+)
+
+const (
+	// DecisionGracePeriodAnnotation is the annotation on Placement which defines the minimum time
+	// a cluster stays in the decisions after it is not selected by the scheduler anymore, for example
+	// "5m". The cluster is removed immediately if it is not available to the placement or it has a
+	// NoSelect taint not tolerated by the placement. The grace period restarts if the controller restarts
+	// or the placement namespace moves to another replica in sharded mode.
+	DecisionGracePeriodAnnotation = "cluster.open-cluster-management.io/experimental-decision-grace-period"
+
+	decisionGracePeriodName = "DecisionGracePeriod"
+)
+
+var RetainClock = clock.Clock(clock.RealClock{})
+
+// decisionRetainer records the time since when each decided cluster is not selected by the scheduler.
+// The records are kept in memory only, so they are lost once the controller restarts or, in sharded mode,
+// the placement namespace moves to another replica. The grace period of the retained clusters restarts on
+// the new owner in this case, which keeps the clusters longer but never removes them earlier.
+type decisionRetainer struct {
+	lock sync.Mutex
+	// unselectedSince is indexed by the placement key and then by the cluster name
+	unselectedSince map[string]map[string]time.Time
+}
+
+func newDecisionRetainer() *decisionRetainer {
+	return &decisionRetainer{
+		unselectedSince: map[string]map[string]time.Time{},
+	}
+}
+
+// retainResult contains the decisions after the clusters in grace period are retained.
+type retainResult struct {
+	decisions []clusterapiv1beta1.ClusterDecision
+	// retained contains the remaining grace period of each retained cluster
+	retained     map[string]time.Duration
+	requeueAfter *time.Duration
+}
+
+// getDecisionGracePeriod returns the grace period defined in the annotation of the placement.
+func getDecisionGracePeriod(placement *clusterapiv1beta1.Placement) (time.Duration, *framework.Status) {
+	value, ok := placement.GetAnnotations()[DecisionGracePeriodAnnotation]
+	if !ok || len(value) == 0 {
+		return 0, framework.NewStatus(decisionGracePeriodName, framework.Success, "")
+	}
+
+	gracePeriod, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, framework.NewStatus(decisionGracePeriodName, framework.Misconfigured,
+			fmt.Sprintf("failed to parse annotation %s: %v", DecisionGracePeriodAnnotation, err))
+	}
+	if gracePeriod < 0 {
+		return 0, framework.NewStatus(decisionGracePeriodName, framework.Misconfigured,
+			fmt.Sprintf("annotation %s should not be negative", DecisionGracePeriodAnnotation))
+	}
+	return gracePeriod, framework.NewStatus(decisionGracePeriodName, framework.Success, "")
+}
+
+// retain keeps the previously decided clusters which are not selected by the scheduler anymore in the
+// decisions until the grace period expires. If the placement has numberOfClusters, the retained clusters
+// replace the newly selected clusters with the lowest scores, so the total number does not change.
+func (r *decisionRetainer) retain(
+	placement *clusterapiv1beta1.Placement,
+	gracePeriod time.Duration,
+	previous sets.String,
+	clusters []*clusterapiv1.ManagedCluster,
+	scheduleResult ScheduleResult,
+) retainResult {
+	result := retainResult{decisions: scheduleResult.Decisions(), retained: map[string]time.Duration{}}
+	key := fmt.Sprintf("%s/%s", placement.Namespace, placement.Name)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if gracePeriod == 0 {
+		delete(r.unselectedSince, key)
+		return result
+	}
+
+	decided := sets.NewString()
+	for _, d := range result.decisions {
+		decided.Insert(d.ClusterName)
+	}
+	available := sets.NewString()
+	for _, cluster := range clusters {
+		available.Insert(cluster.Name)
+	}
+	tainted := sets.NewString()
+	for _, e := range scheduleResult.ExcludedClusters() {
+		if e.Plugin == (&tainttoleration.TaintToleration{}).Name() || e.Plugin == clusterPinningName {
+			tainted.Insert(e.ClusterName)
+		}
+	}
+
+	now := RetainClock.Now()
+	unselectedSince := map[string]time.Time{}
+	for _, clusterName := range previous.Difference(decided).List() {
+		// the cluster is infeasible, remove it immediately
+		if !available.Has(clusterName) || tainted.Has(clusterName) {
+			continue
+		}
+
+		since, ok := r.unselectedSince[key][clusterName]
+		if !ok {
+			since = now
+		}
+		if gracePeriod-now.Sub(since) > 0 {
+			unselectedSince[clusterName] = since
+		}
+	}
+
+	// the retained clusters replace the newly selected clusters with the lowest scores to keep
+	// the number of decisions. The retained clusters are dropped as well if there are still too
+	// many decisions, for example when the numberOfClusters is decreased.
+	decisions := append([]clusterapiv1beta1.ClusterDecision{}, result.decisions...)
+	retainedNames := sets.StringKeySet(unselectedSince).List()
+	if placement.Spec.NumberOfClusters != nil {
+		exceeded := len(decisions) + len(retainedNames) - int(*placement.Spec.NumberOfClusters)
+		for i := len(decisions) - 1; i >= 0 && exceeded > 0; i-- {
+			if previous.Has(decisions[i].ClusterName) {
+				continue
+			}
+			decisions = append(decisions[:i], decisions[i+1:]...)
+			exceeded--
+		}
+		switch {
+		case exceeded < 0:
+			exceeded = 0
+		case exceeded > len(retainedNames):
+			exceeded = len(retainedNames)
+		}
+		for _, clusterName := range retainedNames[len(retainedNames)-exceeded:] {
+			delete(unselectedSince, clusterName)
+		}
+		retainedNames = retainedNames[:len(retainedNames)-exceeded]
+	}
+
+	if len(unselectedSince) == 0 {
+		delete(r.unselectedSince, key)
+		return result
+	}
+	r.unselectedSince[key] = unselectedSince
+
+	for _, clusterName := range retainedNames {
+		remaining := gracePeriod - now.Sub(unselectedSince[clusterName])
+		result.retained[clusterName] = remaining
+		result.requeueAfter = setRequeueAfter(result.requeueAfter, &remaining)
+		decisions = append(decisions, clusterapiv1beta1.ClusterDecision{ClusterName: clusterName})
+	}
+	result.decisions = decisions
+
+	return result
+}
+
+// forget removes the records of the placement.
+func (r *decisionRetainer) forget(placementKey string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.unselectedSince, placementKey)
+}
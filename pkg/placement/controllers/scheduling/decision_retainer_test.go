@@ -0,0 +1,176 @@
+package scheduling
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+func TestRetainDecisions(t *testing.T) {
+	placementNamespace := "ns1"
+	placementName := "placement1"
+	fakeTime := time.Date(2022, time.January, 01, 0, 0, 0, 0, time.UTC)
+
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+		testinghelpers.NewManagedCluster("cluster4").Build(),
+	}
+
+	cases := []struct {
+		name                 string
+		placement            *clusterapiv1beta1.Placement
+		gracePeriod          time.Duration
+		unselectedSince      map[string]time.Time
+		previous             []string
+		result               *scheduleResult
+		expectedDecisions    []string
+		expectedRequeueAfter *time.Duration
+	}{
+		{
+			name:              "no grace period",
+			placement:         testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).Build(),
+			previous:          []string{"cluster1", "cluster2"},
+			result:            &scheduleResult{scheduledDecisions: newClusterDecisionsFromNames("cluster1", "cluster3")},
+			expectedDecisions: []string{"cluster1", "cluster3"},
+		},
+		{
+			name:                 "retain cluster in grace period",
+			placement:            testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).Build(),
+			gracePeriod:          5 * time.Minute,
+			previous:             []string{"cluster1", "cluster2"},
+			result:               &scheduleResult{scheduledDecisions: newClusterDecisionsFromNames("cluster1", "cluster3")},
+			expectedDecisions:    []string{"cluster1", "cluster2"},
+			expectedRequeueAfter: pointer.Duration(5 * time.Minute),
+		},
+		{
+			name:                 "retain cluster unselected before",
+			placement:            testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).Build(),
+			gracePeriod:          5 * time.Minute,
+			unselectedSince:      map[string]time.Time{"cluster2": fakeTime.Add(-3 * time.Minute)},
+			previous:             []string{"cluster1", "cluster2"},
+			result:               &scheduleResult{scheduledDecisions: newClusterDecisionsFromNames("cluster1", "cluster3")},
+			expectedDecisions:    []string{"cluster1", "cluster2"},
+			expectedRequeueAfter: pointer.Duration(2 * time.Minute),
+		},
+		{
+			name:              "grace period expired",
+			placement:         testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).Build(),
+			gracePeriod:       5 * time.Minute,
+			unselectedSince:   map[string]time.Time{"cluster2": fakeTime.Add(-5 * time.Minute)},
+			previous:          []string{"cluster1", "cluster2"},
+			result:            &scheduleResult{scheduledDecisions: newClusterDecisionsFromNames("cluster1", "cluster3")},
+			expectedDecisions: []string{"cluster1", "cluster3"},
+		},
+		{
+			name:              "remove unavailable cluster immediately",
+			placement:         testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).Build(),
+			gracePeriod:       5 * time.Minute,
+			previous:          []string{"cluster1", "cluster5"},
+			result:            &scheduleResult{scheduledDecisions: newClusterDecisionsFromNames("cluster1", "cluster3")},
+			expectedDecisions: []string{"cluster1", "cluster3"},
+		},
+		{
+			name:        "remove tainted cluster immediately",
+			placement:   testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).Build(),
+			gracePeriod: 5 * time.Minute,
+			previous:    []string{"cluster1", "cluster2"},
+			result: &scheduleResult{
+				scheduledDecisions: newClusterDecisionsFromNames("cluster1", "cluster3"),
+				excluded:           []ExcludedCluster{{ClusterName: "cluster2", Plugin: "TaintToleration"}},
+			},
+			expectedDecisions: []string{"cluster1", "cluster3"},
+		},
+		{
+			name:                 "retain cluster without number of clusters",
+			placement:            testinghelpers.NewPlacement(placementNamespace, placementName).Build(),
+			gracePeriod:          5 * time.Minute,
+			previous:             []string{"cluster1", "cluster2"},
+			result:               &scheduleResult{scheduledDecisions: newClusterDecisionsFromNames("cluster1", "cluster3")},
+			expectedDecisions:    []string{"cluster1", "cluster3", "cluster2"},
+			expectedRequeueAfter: pointer.Duration(5 * time.Minute),
+		},
+		{
+			name:              "number of clusters decreased",
+			placement:         testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(1).Build(),
+			gracePeriod:       5 * time.Minute,
+			previous:          []string{"cluster1", "cluster2"},
+			result:            &scheduleResult{scheduledDecisions: newClusterDecisionsFromNames("cluster1")},
+			expectedDecisions: []string{"cluster1"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			RetainClock = testingclock.NewFakeClock(fakeTime)
+			r := newDecisionRetainer()
+			if c.unselectedSince != nil {
+				r.unselectedSince[placementNamespace+"/"+placementName] = c.unselectedSince
+			}
+
+			result := r.retain(c.placement, c.gracePeriod, sets.NewString(c.previous...), clusters, c.result)
+			actual := []string{}
+			for _, d := range result.decisions {
+				actual = append(actual, d.ClusterName)
+			}
+			if !reflect.DeepEqual(actual, c.expectedDecisions) {
+				t.Errorf("expected decisions %v, but got %v", c.expectedDecisions, actual)
+			}
+			if !reflect.DeepEqual(result.requeueAfter, c.expectedRequeueAfter) {
+				t.Errorf("expected requeue after %v, but got %v", c.expectedRequeueAfter, result.requeueAfter)
+			}
+		})
+	}
+}
+
+func TestGetDecisionGracePeriod(t *testing.T) {
+	cases := []struct {
+		name                string
+		annotations         map[string]string
+		expectedGracePeriod time.Duration
+		expectedCode        framework.Code
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:                "valid grace period",
+			annotations:         map[string]string{DecisionGracePeriodAnnotation: "5m"},
+			expectedGracePeriod: 5 * time.Minute,
+		},
+		{
+			name:         "invalid grace period",
+			annotations:  map[string]string{DecisionGracePeriodAnnotation: "five minutes"},
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name:         "negative grace period",
+			annotations:  map[string]string{DecisionGracePeriodAnnotation: "-5m"},
+			expectedCode: framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			placement := testinghelpers.NewPlacementWithAnnotations("ns1", "placement1", c.annotations).Build()
+			gracePeriod, status := getDecisionGracePeriod(placement)
+			if status.Code() != c.expectedCode {
+				t.Errorf("expected code %v, but got %v", c.expectedCode, status.Code())
+			}
+			if gracePeriod != c.expectedGracePeriod {
+				t.Errorf("expected grace period %v, but got %v", c.expectedGracePeriod, gracePeriod)
+			}
+		})
+	}
+}
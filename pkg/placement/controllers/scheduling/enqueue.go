@@ -3,12 +3,14 @@ package scheduling
 import (
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	addonapiv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterinformerv1beta1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta1"
 	clusterinformerv1beta2 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta2"
@@ -17,6 +19,10 @@ import (
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 	clusterapiv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+
+	"open-cluster-management.io/ocm/pkg/placement/plugins/addonhealth"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/claim"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/placementaffinity"
 )
 
 const (
@@ -26,6 +32,10 @@ const (
 	placementsByClusterSetBinding  = "placementsByClusterSet"
 	clustersetBindingsByClusterSet = "clustersetBindingsByClusterSet"
 	placementsByScore              = "placementsByScore"
+	placementsByAffinityPlacement  = "placementsByAffinityPlacement"
+	placementsByClusterAttribute   = "placementsByClusterAttribute"
+	placementsByAddOn              = "placementsByAddOn"
+	placementsByCompositePlacement = "placementsByCompositePlacement"
 )
 
 type enqueuer struct {
@@ -45,8 +55,12 @@ func newEnqueuer(
 	placementInformer clusterinformerv1beta1.PlacementInformer,
 	clusterSetBindingInformer clusterinformerv1beta2.ManagedClusterSetBindingInformer) *enqueuer {
 	err := placementInformer.Informer().AddIndexers(cache.Indexers{
-		placementsByScore:             indexPlacementsByScore,
-		placementsByClusterSetBinding: indexPlacementByClusterSetBinding,
+		placementsByScore:              indexPlacementsByScore,
+		placementsByClusterSetBinding:  indexPlacementByClusterSetBinding,
+		placementsByAffinityPlacement:  indexPlacementsByAffinityPlacement,
+		placementsByClusterAttribute:   indexPlacementsByClusterAttribute,
+		placementsByAddOn:              indexPlacementsByAddOn,
+		placementsByCompositePlacement: indexPlacementsByCompositePlacement,
 	})
 	if err != nil {
 		runtime.HandleError(err)
@@ -152,6 +166,94 @@ func (e *enqueuer) enqueueCluster(obj interface{}) {
 	}
 }
 
+// enqueueClusterAttributeChange enqueues the placements which reference the changed labels or claims
+// of the cluster and are bound to any clusterset the cluster belongs to. The attributes are keys
+// returned by clusterLabelIndexKey and clusterClaimIndexKey.
+func (e *enqueuer) enqueueClusterAttributeChange(cluster *clusterapiv1.ManagedCluster, attributes sets.String) {
+	clusterSets, err := clusterapiv1beta2.GetClusterSetsOfCluster(cluster, e.clusterSetLister)
+	if err != nil {
+		klog.V(4).Infof("Unable to get clusterSets of cluster %q: %w", cluster.GetName(), err)
+		return
+	}
+
+	// find all the bindings of the clustersets, a placement is only impacted if it is bound to one of them.
+	bindingKeys := sets.NewString()
+	bindingNamespaces := sets.NewString()
+	for _, clusterSet := range clusterSets {
+		bindingObjs, err := e.clusterSetBindingIndexer.ByIndex(clustersetBindingsByClusterSet, clusterSet.Name)
+		if err != nil {
+			klog.V(4).Infof("Unable to get clusterSetBindings of clusterset %q: %w", clusterSet.Name, err)
+			continue
+		}
+		for _, bindingObj := range bindingObjs {
+			binding := bindingObj.(*clusterapiv1beta2.ManagedClusterSetBinding)
+			bindingKeys.Insert(fmt.Sprintf("%s/%s", binding.Namespace, clusterSet.Name))
+			bindingNamespaces.Insert(binding.Namespace)
+		}
+	}
+	if bindingKeys.Len() == 0 {
+		return
+	}
+
+	enqueued := sets.NewString()
+	for _, attribute := range attributes.List() {
+		objs, err := e.placementIndexer.ByIndex(placementsByClusterAttribute, attribute)
+		if err != nil {
+			runtime.HandleError(err)
+			return
+		}
+
+		for _, o := range objs {
+			placement := o.(*clusterapiv1beta1.Placement)
+			key := fmt.Sprintf("%s/%s", placement.Namespace, placement.Name)
+			if enqueued.Has(key) || !isPlacementBound(placement, bindingKeys, bindingNamespaces) {
+				continue
+			}
+			enqueued.Insert(key)
+			klog.V(4).Infof("enqueue placement %s, because of %s of cluster %s", key, attribute, cluster.Name)
+			e.enqueuePlacementFunc(placement, e.queue)
+		}
+	}
+}
+
+// isPlacementBound returns true if the placement selects clusters from any of the given bindings.
+func isPlacementBound(placement *clusterapiv1beta1.Placement, bindingKeys, bindingNamespaces sets.String) bool {
+	keys, _ := indexPlacementByClusterSetBinding(placement)
+	for _, key := range keys {
+		if key == fmt.Sprintf("%s/%s", placement.Namespace, anyClusterSet) {
+			if bindingNamespaces.Has(placement.Namespace) {
+				return true
+			}
+			continue
+		}
+		if bindingKeys.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterSetsChanged returns true if the old and new cluster belong to different clustersets.
+func (e *enqueuer) clusterSetsChanged(oldCluster, newCluster *clusterapiv1.ManagedCluster) bool {
+	oldClusterSets, err := clusterapiv1beta2.GetClusterSetsOfCluster(oldCluster, e.clusterSetLister)
+	if err != nil {
+		return true
+	}
+	newClusterSets, err := clusterapiv1beta2.GetClusterSetsOfCluster(newCluster, e.clusterSetLister)
+	if err != nil {
+		return true
+	}
+
+	oldNames, newNames := sets.NewString(), sets.NewString()
+	for _, clusterSet := range oldClusterSets {
+		oldNames.Insert(clusterSet.Name)
+	}
+	for _, clusterSet := range newClusterSets {
+		newNames.Insert(clusterSet.Name)
+	}
+	return !oldNames.Equal(newNames)
+}
+
 func (e *enqueuer) enqueuePlacementScore(obj interface{}) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
@@ -171,19 +273,91 @@ func (e *enqueuer) enqueuePlacementScore(obj interface{}) {
 		return
 	}
 
-	// filter the namespace of placement based on cluster. Find all related clustersetbinding
-	// to the cluster at first. Only enqueue placement when its namespace is in the valid namespaces
-	// of clustersetbindings.
-	filteredBindingNamespaces := sets.NewString()
-	cluster, err := e.clusterLister.Get(namespace)
+	// filter the namespace of placement based on cluster. Only enqueue placement when its namespace
+	// is in the valid namespaces of clustersetbindings.
+	filteredBindingNamespaces := e.getBindingNamespacesOfCluster(namespace)
+
+	for _, o := range objs {
+		placement := o.(*clusterapiv1beta1.Placement)
+		if filteredBindingNamespaces.Has(placement.Namespace) {
+			klog.V(4).Infof("enqueue placement %s/%s, because of score %s", placement.Namespace, placement.Name, key)
+			e.enqueuePlacementFunc(placement, e.queue)
+		}
+	}
+}
+
+// enqueueAddOn enqueues the placements requiring the addon, whose namespace has a clustersetbinding
+// to any clusterset the cluster of the addon belongs to.
+func (e *enqueuer) enqueueAddOn(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	clusterName, addOnName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	objs, err := e.placementIndexer.ByIndex(placementsByAddOn, addOnName)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	if len(objs) == 0 {
+		return
+	}
+
+	filteredBindingNamespaces := e.getBindingNamespacesOfCluster(clusterName)
+	for _, o := range objs {
+		placement := o.(*clusterapiv1beta1.Placement)
+		if filteredBindingNamespaces.Has(placement.Namespace) {
+			klog.V(4).Infof("enqueue placement %s/%s, because of addon %s", placement.Namespace, placement.Name, key)
+			e.enqueuePlacementFunc(placement, e.queue)
+		}
+	}
+}
+
+// addOnConditionsChanged returns true if the status of any condition of the addon changes. The other
+// changes of the addon, like the heartbeat of the lease or the config references, do not impact the
+// scheduling.
+func addOnConditionsChanged(oldObj, newObj interface{}) bool {
+	oldAddOn, ok := oldObj.(*addonapiv1alpha1.ManagedClusterAddOn)
+	if !ok {
+		return true
+	}
+	newAddOn, ok := newObj.(*addonapiv1alpha1.ManagedClusterAddOn)
+	if !ok {
+		return true
+	}
+
+	if len(oldAddOn.Status.Conditions) != len(newAddOn.Status.Conditions) {
+		return true
+	}
+	for _, condition := range newAddOn.Status.Conditions {
+		oldCondition := meta.FindStatusCondition(oldAddOn.Status.Conditions, condition.Type)
+		if oldCondition == nil || oldCondition.Status != condition.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// getBindingNamespacesOfCluster returns the namespaces of the clustersetbindings bound to any clusterset
+// the cluster belongs to.
+func (e *enqueuer) getBindingNamespacesOfCluster(clusterName string) sets.String {
+	bindingNamespaces := sets.NewString()
+	cluster, err := e.clusterLister.Get(clusterName)
 	if err != nil {
-		klog.V(4).Infof("Unable to get cluster %s: %w", namespace, err)
+		klog.V(4).Infof("Unable to get cluster %s: %w", clusterName, err)
 	}
 
 	clusterSets, err := clusterapiv1beta2.GetClusterSetsOfCluster(cluster, e.clusterSetLister)
 	if err != nil {
 		klog.V(4).Infof("Unable to get clusterSets of cluster %q: %w", cluster.GetName(), err)
-		return
+		return bindingNamespaces
 	}
 
 	for _, clusterset := range clusterSets {
@@ -195,16 +369,57 @@ func (e *enqueuer) enqueuePlacementScore(obj interface{}) {
 
 		for _, bindingObj := range bindingObjs {
 			binding := bindingObj.(*clusterapiv1beta2.ManagedClusterSetBinding)
-			filteredBindingNamespaces.Insert(binding.Namespace)
+			bindingNamespaces.Insert(binding.Namespace)
 		}
 	}
+	return bindingNamespaces
+}
+
+// enqueuePlacementDecision enqueues the placements which have affinity or anti-affinity to the
+// placement of the placementdecision, or are composed of it.
+func (e *enqueuer) enqueuePlacementDecision(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	var decision *clusterapiv1beta1.PlacementDecision
+	switch t := obj.(type) {
+	case *clusterapiv1beta1.PlacementDecision:
+		decision = t
+	case cache.DeletedFinalStateUnknown:
+		decision, _ = t.Obj.(*clusterapiv1beta1.PlacementDecision)
+	}
+	if decision == nil {
+		runtime.HandleError(fmt.Errorf("obj %T is not a PlacementDecision", obj))
+		return
+	}
+
+	placementName, ok := decision.Labels[clusterapiv1beta1.PlacementLabel]
+	if !ok {
+		return
+	}
+
+	placementKey := fmt.Sprintf("%s/%s", decision.Namespace, placementName)
+	objs, err := e.placementIndexer.ByIndex(placementsByAffinityPlacement, placementKey)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	compositeObjs, err := e.placementIndexer.ByIndex(placementsByCompositePlacement, placementKey)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	objs = append(objs, compositeObjs...)
 
 	for _, o := range objs {
 		placement := o.(*clusterapiv1beta1.Placement)
-		if filteredBindingNamespaces.Has(placement.Namespace) {
-			klog.V(4).Infof("enqueue placement %s/%s, because of score %s", placement.Namespace, placement.Name, key)
-			e.enqueuePlacementFunc(placement, e.queue)
-		}
+		klog.V(4).Infof("enqueue placement %s/%s, because of placementdecision %s", placement.Namespace, placement.Name, key)
+		e.enqueuePlacementFunc(placement, e.queue)
 	}
 }
 
@@ -250,6 +465,125 @@ func indexPlacementsByScore(obj interface{}) ([]string, error) {
 	return keys, nil
 }
 
+func indexPlacementsByAffinityPlacement(obj interface{}) ([]string, error) {
+	placement, ok := obj.(*clusterapiv1beta1.Placement)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not a Placement", obj)
+	}
+
+	// ignore the error, the placement will be reported as misconfigured when it is scheduled
+	terms, _ := placementaffinity.GetPlacementAffinityTerms(placement)
+
+	var keys []string
+	for _, name := range placementaffinity.ReferencedPlacements(terms) {
+		keys = append(keys, fmt.Sprintf("%s/%s", placement.Namespace, name))
+	}
+
+	return keys, nil
+}
+
+// indexPlacementsByCompositePlacement indexes composite placements by the placements they are composed of.
+func indexPlacementsByCompositePlacement(obj interface{}) ([]string, error) {
+	placement, ok := obj.(*clusterapiv1beta1.Placement)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not a Placement", obj)
+	}
+
+	// ignore the error, the placement will be reported as misconfigured when it is scheduled
+	expression, _ := GetCompositeExpression(placement)
+	if expression == nil {
+		return nil, nil
+	}
+
+	var keys []string
+	for _, name := range expression.ReferencedPlacements().List() {
+		keys = append(keys, fmt.Sprintf("%s/%s", placement.Namespace, name))
+	}
+
+	return keys, nil
+}
+
+// indexPlacementsByAddOn indexes placements by the names of the addons they require.
+func indexPlacementsByAddOn(obj interface{}) ([]string, error) {
+	placement, ok := obj.(*clusterapiv1beta1.Placement)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not a Placement", obj)
+	}
+
+	// ignore the error, the placement will be reported as misconfigured when it is scheduled
+	requiredAddOns, _ := addonhealth.GetRequiredAddOns(placement)
+
+	var keys []string
+	for _, required := range requiredAddOns {
+		keys = append(keys, required.Name)
+	}
+
+	return keys, nil
+}
+
+// clusterLabelIndexKey returns the index key of placements referencing a cluster label.
+func clusterLabelIndexKey(key string) string {
+	return "label/" + key
+}
+
+// clusterClaimIndexKey returns the index key of placements referencing a cluster claim.
+func clusterClaimIndexKey(name string) string {
+	return "claim/" + name
+}
+
+// indexPlacementsByClusterAttribute indexes placements by the cluster labels and claims referenced in
+// the predicates, spread constraints, claim prioritizers and decision groups. The scheduling result of
+// a placement does not change with a cluster label or claim it does not reference.
+func indexPlacementsByClusterAttribute(obj interface{}) ([]string, error) {
+	placement, ok := obj.(*clusterapiv1beta1.Placement)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not a Placement", obj)
+	}
+
+	keys := sets.NewString()
+	addClusterSelector := func(selector clusterapiv1beta1.ClusterSelector) {
+		for key := range selector.LabelSelector.MatchLabels {
+			keys.Insert(clusterLabelIndexKey(key))
+		}
+		for _, expr := range selector.LabelSelector.MatchExpressions {
+			keys.Insert(clusterLabelIndexKey(expr.Key))
+		}
+		for _, expr := range selector.ClaimSelector.MatchExpressions {
+			keys.Insert(clusterClaimIndexKey(expr.Key))
+		}
+	}
+
+	for _, predicate := range placement.Spec.Predicates {
+		addClusterSelector(predicate.RequiredClusterSelector)
+	}
+
+	for _, term := range placement.Spec.SpreadPolicy.SpreadConstraints {
+		switch term.TopologyKeyType {
+		case clusterapiv1beta1.TopologyKeyTypeLabel:
+			keys.Insert(clusterLabelIndexKey(term.TopologyKey))
+		case clusterapiv1beta1.TopologyKeyTypeClaim:
+			keys.Insert(clusterClaimIndexKey(term.TopologyKey))
+		}
+	}
+
+	for _, config := range placement.Spec.PrioritizerPolicy.Configurations {
+		if config.ScoreCoordinate == nil || config.ScoreCoordinate.Type != clusterapiv1beta1.ScoreCoordinateTypeBuiltIn {
+			continue
+		}
+		if claimName, ok := claim.ReferencedClaim(config.ScoreCoordinate.BuiltIn); ok {
+			keys.Insert(clusterClaimIndexKey(claimName))
+		}
+	}
+
+	// ignore the error, the placement will be reported as misconfigured when it is scheduled
+	strategy, _ := getDecisionStrategy(placement)
+	for _, group := range strategy.GroupStrategy.DecisionGroups {
+		addClusterSelector(group.GroupClusterSelector)
+	}
+
+	return keys.List(), nil
+}
+
 func indexClusterSetBindingByClusterSet(obj interface{}) ([]string, error) {
 	binding, ok := obj.(*clusterapiv1beta2.ManagedClusterSetBinding)
 	if !ok {
@@ -21,20 +21,30 @@ import (
 
 	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
 	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/addonhealth"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/placementaffinity"
 )
 
 func newClusterInformerFactory(clusterClient clusterclient.Interface, objects ...runtime.Object) clusterinformers.SharedInformerFactory {
 	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, time.Minute*10)
 
 	clusterInformerFactory.Cluster().V1beta1().Placements().Informer().AddIndexers(cache.Indexers{
-		placementsByScore:             indexPlacementsByScore,
-		placementsByClusterSetBinding: indexPlacementByClusterSetBinding,
+		placementsByScore:              indexPlacementsByScore,
+		placementsByClusterSetBinding:  indexPlacementByClusterSetBinding,
+		placementsByAffinityPlacement:  indexPlacementsByAffinityPlacement,
+		placementsByClusterAttribute:   indexPlacementsByClusterAttribute,
+		placementsByAddOn:              indexPlacementsByAddOn,
+		placementsByCompositePlacement: indexPlacementsByCompositePlacement,
 	})
 
 	clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings().Informer().AddIndexers(cache.Indexers{
 		clustersetBindingsByClusterSet: indexClusterSetBindingByClusterSet,
 	})
 
+	clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().AddIndexers(cache.Indexers{
+		decisionsByCluster: indexDecisionsByCluster,
+	})
+
 	clusterStore := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore()
 	clusterSetStore := clusterInformerFactory.Cluster().V1beta2().ManagedClusterSets().Informer().GetStore()
 	clusterSetBindingStore := clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings().Informer().GetStore()
@@ -395,3 +405,313 @@ func TestEnqueuePlacementsByScore(t *testing.T) {
 		})
 	}
 }
+
+func TestEnqueuePlacementsByAddOn(t *testing.T) {
+	requireAddOn := func(namespace, name, addOnName string) *clusterapiv1beta1.Placement {
+		return testinghelpers.NewPlacementWithAnnotations(namespace, name, map[string]string{
+			addonhealth.RequiredAddOnsAnnotation: `[{"name":"` + addOnName + `"}]`,
+		}).Build()
+	}
+
+	cases := []struct {
+		name       string
+		addOn      interface{}
+		initObjs   []runtime.Object
+		queuedKeys []string
+	}{
+		{
+			name:  "enqueue placements requiring the addon",
+			addOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").Build(),
+			initObjs: []runtime.Object{
+				requireAddOn("ns1", "placement1", "addon1"),
+				requireAddOn("ns1", "placement2", "addon2"),
+				requireAddOn("ns2", "placement3", "addon1"),
+				requireAddOn("ns3", "placement4", "addon1"),
+				testinghelpers.NewPlacement("ns1", "placement5").Build(),
+				testinghelpers.NewManagedCluster("cluster1").WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").Build(),
+				testinghelpers.NewClusterSet("clusterset1").Build(),
+				testinghelpers.NewClusterSet("clusterset2").Build(),
+				testinghelpers.NewClusterSetBinding("ns1", "clusterset1"),
+				testinghelpers.NewClusterSetBinding("ns2", "clusterset1"),
+				testinghelpers.NewClusterSetBinding("ns3", "clusterset2"),
+			},
+			queuedKeys: []string{
+				"ns1/placement1",
+				"ns2/placement3",
+			},
+		},
+		{
+			name:  "cluster not found",
+			addOn: testinghelpers.NewManagedClusterAddOn("cluster2", "addon1").Build(),
+			initObjs: []runtime.Object{
+				requireAddOn("ns1", "placement1", "addon1"),
+				testinghelpers.NewClusterSet("clusterset1").Build(),
+				testinghelpers.NewClusterSetBinding("ns1", "clusterset1"),
+			},
+		},
+		{
+			name: "tombstone",
+			addOn: cache.DeletedFinalStateUnknown{
+				Key: "cluster1/addon1",
+				Obj: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").Build(),
+			},
+			initObjs: []runtime.Object{
+				requireAddOn("ns1", "placement1", "addon1"),
+				testinghelpers.NewManagedCluster("cluster1").WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").Build(),
+				testinghelpers.NewClusterSet("clusterset1").Build(),
+				testinghelpers.NewClusterSetBinding("ns1", "clusterset1"),
+			},
+			queuedKeys: []string{
+				"ns1/placement1",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.initObjs...)
+			clusterInformerFactory := newClusterInformerFactory(clusterClient, c.initObjs...)
+
+			syncCtx := testingcommon.NewFakeSyncContext(t, "fake")
+			q := newEnqueuer(
+				syncCtx.Queue(),
+				clusterInformerFactory.Cluster().V1().ManagedClusters(),
+				clusterInformerFactory.Cluster().V1beta2().ManagedClusterSets(),
+				clusterInformerFactory.Cluster().V1beta1().Placements(),
+				clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings(),
+			)
+			queuedKeys := sets.NewString()
+			fakeEnqueuePlacement := func(obj interface{}, queue workqueue.RateLimitingInterface) {
+				key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+				queuedKeys.Insert(key)
+			}
+			q.enqueuePlacementFunc = fakeEnqueuePlacement
+			q.enqueueAddOn(c.addOn)
+
+			expectedQueuedKeys := sets.NewString(c.queuedKeys...)
+			if !queuedKeys.Equal(expectedQueuedKeys) {
+				t.Errorf("expected queued placements %q, but got %s", strings.Join(expectedQueuedKeys.List(), ","), strings.Join(queuedKeys.List(), ","))
+			}
+		})
+	}
+}
+
+func TestAddOnConditionsChanged(t *testing.T) {
+	cases := []struct {
+		name     string
+		oldAddOn interface{}
+		newAddOn interface{}
+		expected bool
+	}{
+		{
+			name:     "no condition",
+			oldAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").Build(),
+			newAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").Build(),
+		},
+		{
+			name:     "condition added",
+			oldAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").Build(),
+			newAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").WithCondition("Available", metav1.ConditionTrue).Build(),
+			expected: true,
+		},
+		{
+			name:     "condition status changed",
+			oldAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").WithCondition("Available", metav1.ConditionTrue).Build(),
+			newAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").WithCondition("Available", metav1.ConditionUnknown).Build(),
+			expected: true,
+		},
+		{
+			name:     "condition type changed",
+			oldAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").WithCondition("Available", metav1.ConditionTrue).Build(),
+			newAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").WithCondition("Degraded", metav1.ConditionTrue).Build(),
+			expected: true,
+		},
+		{
+			name: "condition message changed",
+			oldAddOn: func() interface{} {
+				addOn := testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").WithCondition("Available", metav1.ConditionTrue).Build()
+				addOn.Status.Conditions[0].Message = "old"
+				return addOn
+			}(),
+			newAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").WithCondition("Available", metav1.ConditionTrue).Build(),
+		},
+		{
+			name:     "not an addon",
+			oldAddOn: "addon1",
+			newAddOn: testinghelpers.NewManagedClusterAddOn("cluster1", "addon1").Build(),
+			expected: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := addOnConditionsChanged(c.oldAddOn, c.newAddOn); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEnqueuePlacementsByAffinityPlacement(t *testing.T) {
+	affinityAnnotation := map[string]string{
+		placementaffinity.PlacementAffinityAnnotation: `{"requiredAntiAffinity":["primary"]}`,
+	}
+
+	cases := []struct {
+		name              string
+		placementDecision interface{}
+		initObjs          []runtime.Object
+		queuedKeys        []string
+	}{
+		{
+			name: "enqueue placements by placementdecision",
+			placementDecision: testinghelpers.NewPlacementDecision("ns1", placementDecisionName("primary", 1)).
+				WithLabel(clusterapiv1beta1.PlacementLabel, "primary").Build(),
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacement("ns1", "primary").Build(),
+				testinghelpers.NewPlacementWithAnnotations("ns1", "dr", affinityAnnotation).Build(),
+				testinghelpers.NewPlacementWithAnnotations("ns2", "dr", affinityAnnotation).Build(),
+				testinghelpers.NewPlacement("ns1", "other").Build(),
+			},
+			queuedKeys: []string{
+				"ns1/dr",
+			},
+		},
+		{
+			name: "enqueue composite placements by placementdecision",
+			placementDecision: testinghelpers.NewPlacementDecision("ns1", placementDecisionName("primary", 1)).
+				WithLabel(clusterapiv1beta1.PlacementLabel, "primary").Build(),
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacement("ns1", "primary").Build(),
+				testinghelpers.NewPlacementWithAnnotations("ns1", "dr", affinityAnnotation).Build(),
+				testinghelpers.NewPlacementWithAnnotations("ns1", "rest", map[string]string{
+					CompositePlacementAnnotation: `{"difference":[{"placement":"all"},{"placement":"primary"}]}`,
+				}).Build(),
+				testinghelpers.NewPlacementWithAnnotations("ns1", "other", map[string]string{
+					CompositePlacementAnnotation: `{"placement":"all"}`,
+				}).Build(),
+			},
+			queuedKeys: []string{
+				"ns1/dr",
+				"ns1/rest",
+			},
+		},
+		{
+			name: "placementdecision without placement label",
+			placementDecision: testinghelpers.NewPlacementDecision("ns1", placementDecisionName("primary", 1)).
+				Build(),
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacementWithAnnotations("ns1", "dr", affinityAnnotation).Build(),
+			},
+		},
+		{
+			name: "tombstone",
+			placementDecision: cache.DeletedFinalStateUnknown{
+				Key: "ns1/primary-decision-1",
+				Obj: testinghelpers.NewPlacementDecision("ns1", placementDecisionName("primary", 1)).
+					WithLabel(clusterapiv1beta1.PlacementLabel, "primary").Build(),
+			},
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacementWithAnnotations("ns1", "dr", affinityAnnotation).Build(),
+			},
+			queuedKeys: []string{
+				"ns1/dr",
+			},
+		},
+		{
+			name:              "invalid resource type",
+			placementDecision: "invalid resource type",
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacementWithAnnotations("ns1", "dr", affinityAnnotation).Build(),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.initObjs...)
+			clusterInformerFactory := newClusterInformerFactory(clusterClient, c.initObjs...)
+
+			syncCtx := testingcommon.NewFakeSyncContext(t, "fake")
+			q := newEnqueuer(
+				syncCtx.Queue(),
+				clusterInformerFactory.Cluster().V1().ManagedClusters(),
+				clusterInformerFactory.Cluster().V1beta2().ManagedClusterSets(),
+				clusterInformerFactory.Cluster().V1beta1().Placements(),
+				clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings(),
+			)
+			queuedKeys := sets.NewString()
+			fakeEnqueuePlacement := func(obj interface{}, queue workqueue.RateLimitingInterface) {
+				key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+				queuedKeys.Insert(key)
+			}
+			q.enqueuePlacementFunc = fakeEnqueuePlacement
+			q.enqueuePlacementDecision(c.placementDecision)
+
+			expectedQueuedKeys := sets.NewString(c.queuedKeys...)
+			if !queuedKeys.Equal(expectedQueuedKeys) {
+				t.Errorf("expected queued placements %q, but got %s", strings.Join(expectedQueuedKeys.List(), ","), strings.Join(queuedKeys.List(), ","))
+			}
+		})
+	}
+}
+
+func TestIndexPlacementsByClusterAttribute(t *testing.T) {
+	cases := []struct {
+		name         string
+		placement    *clusterapiv1beta1.Placement
+		expectedKeys []string
+	}{
+		{
+			name:         "no reference",
+			placement:    testinghelpers.NewPlacement("ns1", "placement1").Build(),
+			expectedKeys: []string{},
+		},
+		{
+			name: "predicates",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").AddPredicate(
+				&metav1.LabelSelector{
+					MatchLabels: map[string]string{"cloud": "Amazon"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "vendor", Operator: metav1.LabelSelectorOpExists},
+					},
+				},
+				&clusterapiv1beta1.ClusterClaimSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "region", Operator: metav1.LabelSelectorOpExists},
+					},
+				},
+			).Build(),
+			expectedKeys: []string{"claim/region", "label/cloud", "label/vendor"},
+		},
+		{
+			name: "spread constraints and claim prioritizer",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				AddSpreadConstraint("zone", clusterapiv1beta1.TopologyKeyTypeClaim, 1, clusterapiv1beta1.DoNotSchedule).
+				AddSpreadConstraint("cloud", clusterapiv1beta1.TopologyKeyTypeLabel, 1, clusterapiv1beta1.DoNotSchedule).
+				WithPrioritizerConfig("ClaimValueAscending:cost", 1).
+				WithPrioritizerConfig("Balance", 1).Build(),
+			expectedKeys: []string{"claim/cost", "claim/zone", "label/cloud"},
+		},
+		{
+			name: "decision groups",
+			placement: testinghelpers.NewPlacementWithAnnotations("ns1", "placement1", map[string]string{
+				DecisionStrategyAnnotation: `{"groupStrategy":{"decisionGroups":[{"groupName":"canary",` +
+					`"groupClusterSelector":{"labelSelector":{"matchLabels":{"canary":"true"}}}}]}}`,
+			}).Build(),
+			expectedKeys: []string{"label/canary"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			keys, err := indexPlacementsByClusterAttribute(c.placement)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !sets.NewString(keys...).Equal(sets.NewString(c.expectedKeys...)) {
+				t.Errorf("expected keys %v, but got %v", c.expectedKeys, keys)
+			}
+		})
+	}
+}
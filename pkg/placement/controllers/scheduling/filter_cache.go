@@ -0,0 +1,175 @@
+package scheduling
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+)
+
+// maxFilterCacheKeys is the max number of cache keys kept in the filter cache. The least recently
+// used key is evicted once the limit is reached.
+const maxFilterCacheKeys = 128
+
+// filterCache caches the results of the cacheable filters per cluster. A cached result is reused as long
+// as the cache key of the placement is the same and the resourceVersion of the cluster does not change.
+// The resourceVersion is used instead of the generation, since the status of a cluster, like the claims
+// and allocatable resources, may change the filter result as well. The clusters without resourceVersion,
+// like the hypothetical clusters simulated by the debugger, are never cached.
+type filterCache struct {
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// filterCacheEntry contains the cached results of one cache key, keyed by cluster name.
+type filterCacheEntry struct {
+	key     string
+	results map[string]filterCacheResult
+}
+
+type filterCacheResult struct {
+	resourceVersion string
+	passed          bool
+	reason          string
+}
+
+func newFilterCache() *filterCache {
+	return &filterCache{
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// filter runs the filter against the clusters without a cached result only, and merges the results
+// with the cached ones. The order of the filtered clusters is kept the same as the input.
+func (c *filterCache) filter(
+	ctx context.Context,
+	f plugins.CacheableFilter,
+	placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster,
+) (plugins.PluginFilterResult, *framework.Status) {
+	cacheKey := f.CacheKey(placement)
+	if len(cacheKey) == 0 {
+		return f.Filter(ctx, placement, clusters)
+	}
+	key := f.Name() + "/" + cacheKey
+
+	cached, missed := c.lookup(key, clusters)
+	if len(missed) == 0 {
+		return mergeFilterResults(clusters, cached, plugins.PluginFilterResult{}),
+			framework.NewStatus(f.Name(), framework.Success, "")
+	}
+
+	result, status := f.Filter(ctx, placement, missed)
+	if status.Code() != framework.Success {
+		return result, status
+	}
+
+	c.store(key, clusters, missed, result)
+	return mergeFilterResults(clusters, cached, result), status
+}
+
+// lookup returns the cached results of the clusters in the same order, and the clusters without a cached
+// result. The result of a cluster without a cached result is left empty.
+func (c *filterCache) lookup(key string, clusters []*clusterapiv1.ManagedCluster) (
+	[]filterCacheResult, []*clusterapiv1.ManagedCluster) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cached := make([]filterCacheResult, len(clusters))
+	element, ok := c.entries[key]
+	if !ok {
+		return cached, clusters
+	}
+	c.lru.MoveToFront(element)
+	entry := element.Value.(*filterCacheEntry)
+
+	missed := []*clusterapiv1.ManagedCluster{}
+	for i, cluster := range clusters {
+		result, ok := entry.results[cluster.Name]
+		if !ok || len(cluster.ResourceVersion) == 0 || result.resourceVersion != cluster.ResourceVersion {
+			missed = append(missed, cluster)
+			continue
+		}
+		cached[i] = result
+	}
+	return cached, missed
+}
+
+// store caches the filter result of the missed clusters. Clusters without resourceVersion are not cached.
+func (c *filterCache) store(key string, clusters, missed []*clusterapiv1.ManagedCluster, result plugins.PluginFilterResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		element = c.lru.PushFront(&filterCacheEntry{key: key, results: map[string]filterCacheResult{}})
+		c.entries[key] = element
+		if c.lru.Len() > maxFilterCacheKeys {
+			oldest := c.lru.Back()
+			c.lru.Remove(oldest)
+			delete(c.entries, oldest.Value.(*filterCacheEntry).key)
+		}
+	}
+	entry := element.Value.(*filterCacheEntry)
+
+	// drop the results of the clusters which no longer exist
+	if len(entry.results) > 2*len(clusters) {
+		current := map[string]filterCacheResult{}
+		for _, cluster := range clusters {
+			if r, ok := entry.results[cluster.Name]; ok {
+				current[cluster.Name] = r
+			}
+		}
+		entry.results = current
+	}
+
+	passed := map[string]bool{}
+	for _, cluster := range result.Filtered {
+		passed[cluster.Name] = true
+	}
+	for _, cluster := range missed {
+		if len(cluster.ResourceVersion) == 0 {
+			continue
+		}
+		entry.results[cluster.Name] = filterCacheResult{
+			resourceVersion: cluster.ResourceVersion,
+			passed:          passed[cluster.Name],
+			reason:          result.Reasons[cluster.Name],
+		}
+	}
+}
+
+// mergeFilterResults merges the cached results and the result of the missed clusters.
+func mergeFilterResults(clusters []*clusterapiv1.ManagedCluster, cached []filterCacheResult,
+	result plugins.PluginFilterResult) plugins.PluginFilterResult {
+	passed := make(map[string]bool, len(result.Filtered))
+	for _, cluster := range result.Filtered {
+		passed[cluster.Name] = true
+	}
+
+	merged := plugins.PluginFilterResult{
+		Filtered: make([]*clusterapiv1.ManagedCluster, 0, len(clusters)),
+		Reasons:  make(map[string]string, len(clusters)-len(result.Filtered)),
+	}
+	for name, reason := range result.Reasons {
+		merged.Reasons[name] = reason
+	}
+	for i, cluster := range clusters {
+		// a cached result always has a resourceVersion
+		hit := len(cached[i].resourceVersion) > 0
+		switch {
+		case hit && cached[i].passed, !hit && passed[cluster.Name]:
+			merged.Filtered = append(merged.Filtered, cluster)
+		case hit && len(cached[i].reason) > 0:
+			merged.Reasons[cluster.Name] = cached[i].reason
+		}
+	}
+	return merged
+}
@@ -0,0 +1,181 @@
+package scheduling
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/predicate"
+)
+
+// countingFilter records the clusters passed to the wrapped filter.
+type countingFilter struct {
+	plugins.CacheableFilter
+	filtered []string
+}
+
+func (f *countingFilter) Filter(ctx context.Context, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginFilterResult, *framework.Status) {
+	for _, cluster := range clusters {
+		f.filtered = append(f.filtered, cluster.Name)
+	}
+	return f.CacheableFilter.Filter(ctx, placement, clusters)
+}
+
+func newClusterWithVersion(name, resourceVersion, cloud string) *clusterapiv1.ManagedCluster {
+	cluster := testinghelpers.NewManagedCluster(name).WithLabel("cloud", cloud).Build()
+	cluster.ResourceVersion = resourceVersion
+	return cluster
+}
+
+func TestFilterCache(t *testing.T) {
+	amazon := testinghelpers.NewPlacement("ns1", "placement1").AddPredicate(
+		&metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Amazon"}}, nil).Build()
+	google := testinghelpers.NewPlacement("ns1", "placement2").AddPredicate(
+		&metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Google"}}, nil).Build()
+
+	cache := newFilterCache()
+	f := &countingFilter{CacheableFilter: predicate.New(nil)}
+
+	cases := []struct {
+		name             string
+		placement        *clusterapiv1beta1.Placement
+		clusters         []*clusterapiv1.ManagedCluster
+		expectedFiltered []string
+		expectedReasons  []string
+		expectedRun      []string
+	}{
+		{
+			name:      "no cached result",
+			placement: amazon,
+			clusters: []*clusterapiv1.ManagedCluster{
+				newClusterWithVersion("cluster1", "1", "Amazon"),
+				newClusterWithVersion("cluster2", "1", "Google"),
+				newClusterWithVersion("cluster3", "1", "Amazon"),
+			},
+			expectedFiltered: []string{"cluster1", "cluster3"},
+			expectedReasons:  []string{"cluster2"},
+			expectedRun:      []string{"cluster1", "cluster2", "cluster3"},
+		},
+		{
+			name:      "all cached",
+			placement: amazon,
+			clusters: []*clusterapiv1.ManagedCluster{
+				newClusterWithVersion("cluster1", "1", "Amazon"),
+				newClusterWithVersion("cluster2", "1", "Google"),
+				newClusterWithVersion("cluster3", "1", "Amazon"),
+			},
+			expectedFiltered: []string{"cluster1", "cluster3"},
+			expectedReasons:  []string{"cluster2"},
+		},
+		{
+			name:      "cluster changed",
+			placement: amazon,
+			clusters: []*clusterapiv1.ManagedCluster{
+				newClusterWithVersion("cluster1", "1", "Amazon"),
+				newClusterWithVersion("cluster2", "2", "Amazon"),
+				newClusterWithVersion("cluster3", "1", "Amazon"),
+			},
+			expectedFiltered: []string{"cluster1", "cluster2", "cluster3"},
+			expectedReasons:  []string{},
+			expectedRun:      []string{"cluster2"},
+		},
+		{
+			name:      "cluster without resourceVersion",
+			placement: amazon,
+			clusters: []*clusterapiv1.ManagedCluster{
+				newClusterWithVersion("cluster1", "1", "Amazon"),
+				newClusterWithVersion("cluster4", "", "Amazon"),
+			},
+			expectedFiltered: []string{"cluster1", "cluster4"},
+			expectedReasons:  []string{},
+			expectedRun:      []string{"cluster4"},
+		},
+		{
+			name:      "overridden cluster without resourceVersion",
+			placement: amazon,
+			clusters: []*clusterapiv1.ManagedCluster{
+				newClusterWithVersion("cluster1", "", "Google"),
+			},
+			expectedFiltered: []string{},
+			expectedReasons:  []string{"cluster1"},
+			expectedRun:      []string{"cluster1"},
+		},
+		{
+			name:      "cached result not changed by the overridden cluster",
+			placement: amazon,
+			clusters: []*clusterapiv1.ManagedCluster{
+				newClusterWithVersion("cluster1", "1", "Amazon"),
+			},
+			expectedFiltered: []string{"cluster1"},
+			expectedReasons:  []string{},
+		},
+		{
+			name:      "different predicates",
+			placement: google,
+			clusters: []*clusterapiv1.ManagedCluster{
+				newClusterWithVersion("cluster1", "1", "Amazon"),
+				newClusterWithVersion("cluster2", "2", "Amazon"),
+			},
+			expectedFiltered: []string{},
+			expectedReasons:  []string{"cluster1", "cluster2"},
+			expectedRun:      []string{"cluster1", "cluster2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f.filtered = nil
+			result, status := cache.filter(context.TODO(), f, c.placement, c.clusters)
+			if status.Code() != framework.Success {
+				t.Fatalf("unexpected status: %v", status)
+			}
+
+			filtered := []string{}
+			for _, cluster := range result.Filtered {
+				filtered = append(filtered, cluster.Name)
+			}
+			if !reflect.DeepEqual(filtered, c.expectedFiltered) {
+				t.Errorf("expected filtered clusters %v, but got %v", c.expectedFiltered, filtered)
+			}
+			if len(result.Reasons) != len(c.expectedReasons) {
+				t.Errorf("expected reasons of %v, but got %v", c.expectedReasons, result.Reasons)
+			}
+			for _, name := range c.expectedReasons {
+				if len(result.Reasons[name]) == 0 {
+					t.Errorf("expected reason of cluster %s", name)
+				}
+			}
+			if !reflect.DeepEqual(f.filtered, c.expectedRun) {
+				t.Errorf("expected filter to run with clusters %v, but got %v", c.expectedRun, f.filtered)
+			}
+		})
+	}
+}
+
+func TestFilterCacheEviction(t *testing.T) {
+	cache := newFilterCache()
+	f := predicate.New(nil)
+	clusters := []*clusterapiv1.ManagedCluster{newClusterWithVersion("cluster1", "1", "Amazon")}
+
+	for i := 0; i <= maxFilterCacheKeys; i++ {
+		placement := testinghelpers.NewPlacement("ns1", "placement1").AddPredicate(
+			&metav1.LabelSelector{MatchLabels: map[string]string{"index": fmt.Sprintf("value%d", i)}}, nil).Build()
+		if _, status := cache.filter(context.TODO(), f, placement, clusters); status.Code() != framework.Success {
+			t.Fatalf("unexpected status: %v", status)
+		}
+	}
+
+	if cache.lru.Len() != maxFilterCacheKeys || len(cache.entries) != maxFilterCacheKeys {
+		t.Errorf("expected %d cache keys, but got %d", maxFilterCacheKeys, cache.lru.Len())
+	}
+}
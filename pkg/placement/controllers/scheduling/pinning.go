@@ -0,0 +1,102 @@
+package scheduling
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+)
+
+const (
+	// PinnedClustersAnnotation is the annotation on Placement with a comma separated list of cluster names
+	// which are always selected by the placement, regardless of the filters, scores and spread constraints.
+	// The pinned clusters should still belong to the clustersets bound to the placement namespace, and they
+	// count towards the numberOfClusters of the placement.
+	PinnedClustersAnnotation = "cluster.open-cluster-management.io/experimental-pinned-clusters"
+
+	// ExcludedClustersAnnotation is the annotation on Placement with a comma separated list of cluster names
+	// which are never selected by the placement. The excluded clusters are removed from the decisions
+	// immediately, even if the placement has a decision grace period.
+	ExcludedClustersAnnotation = "cluster.open-cluster-management.io/experimental-excluded-clusters"
+
+	clusterPinningName = "ClusterPinning"
+)
+
+// clusterPinning is the clusters pinned to and excluded from a placement.
+type clusterPinning struct {
+	pinned   []string
+	excluded sets.String
+}
+
+// getClusterPinning returns the clusters pinned to and excluded from the placement by the annotations.
+func getClusterPinning(placement *clusterapiv1beta1.Placement) (*clusterPinning, *framework.Status) {
+	pinning := &clusterPinning{
+		pinned:   parseClusterNames(placement.GetAnnotations()[PinnedClustersAnnotation]),
+		excluded: sets.NewString(parseClusterNames(placement.GetAnnotations()[ExcludedClustersAnnotation])...),
+	}
+
+	if conflicts := pinning.excluded.Intersection(sets.NewString(pinning.pinned...)); conflicts.Len() > 0 {
+		return nil, framework.NewStatus(clusterPinningName, framework.Misconfigured,
+			fmt.Sprintf("clusters %s should not be both pinned and excluded", strings.Join(conflicts.List(), ",")))
+	}
+	return pinning, framework.NewStatus(clusterPinningName, framework.Success, "")
+}
+
+// parseClusterNames returns the unique cluster names in a comma separated list in order.
+func parseClusterNames(value string) []string {
+	names := []string{}
+	visited := sets.NewString()
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 || visited.Has(name) {
+			continue
+		}
+		visited.Insert(name)
+		names = append(names, name)
+	}
+	return names
+}
+
+// split divides the available clusters into the pinned clusters and the candidates to be filtered and
+// prioritized. The excluded clusters and the pinned clusters which are not available are returned with
+// the reasons.
+func (p *clusterPinning) split(clusters []*clusterapiv1.ManagedCluster) (
+	pinned, candidates []*clusterapiv1.ManagedCluster, excluded []ExcludedCluster) {
+	candidates = []*clusterapiv1.ManagedCluster{}
+	pinnedNames := sets.NewString(p.pinned...)
+	available := map[string]*clusterapiv1.ManagedCluster{}
+	for _, cluster := range clusters {
+		switch {
+		case p.excluded.Has(cluster.Name):
+			excluded = append(excluded, ExcludedCluster{
+				ClusterName: cluster.Name,
+				Plugin:      clusterPinningName,
+				Reason:      fmt.Sprintf("excluded by annotation %s", ExcludedClustersAnnotation),
+			})
+		case pinnedNames.Has(cluster.Name):
+			available[cluster.Name] = cluster
+		default:
+			candidates = append(candidates, cluster)
+		}
+	}
+
+	// keep the pinned clusters in the order of the annotation
+	for _, name := range p.pinned {
+		cluster, ok := available[name]
+		if !ok {
+			excluded = append(excluded, ExcludedCluster{
+				ClusterName: name,
+				Plugin:      clusterPinningName,
+				Reason:      "pinned but not in the bound clustersets",
+			})
+			continue
+		}
+		pinned = append(pinned, cluster)
+	}
+	return pinned, candidates, excluded
+}
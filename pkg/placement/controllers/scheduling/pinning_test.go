@@ -0,0 +1,61 @@
+package scheduling
+
+import (
+	"reflect"
+	"testing"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+func TestGetClusterPinning(t *testing.T) {
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		expectedPinned   []string
+		expectedExcluded []string
+		expectedCode     framework.Code
+	}{
+		{
+			name:             "no annotation",
+			expectedPinned:   []string{},
+			expectedExcluded: []string{},
+		},
+		{
+			name: "pinned and excluded clusters",
+			annotations: map[string]string{
+				PinnedClustersAnnotation:   "cluster3, cluster1,,cluster3",
+				ExcludedClustersAnnotation: "cluster2 ,cluster4",
+			},
+			expectedPinned:   []string{"cluster3", "cluster1"},
+			expectedExcluded: []string{"cluster2", "cluster4"},
+		},
+		{
+			name: "cluster both pinned and excluded",
+			annotations: map[string]string{
+				PinnedClustersAnnotation:   "cluster1,cluster2",
+				ExcludedClustersAnnotation: "cluster2",
+			},
+			expectedCode: framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			placement := testinghelpers.NewPlacementWithAnnotations("ns1", "placement1", c.annotations).Build()
+			pinning, status := getClusterPinning(placement)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+			if !reflect.DeepEqual(pinning.pinned, c.expectedPinned) {
+				t.Errorf("expected pinned clusters %v, but got %v", c.expectedPinned, pinning.pinned)
+			}
+			if !reflect.DeepEqual(pinning.excluded.List(), c.expectedExcluded) {
+				t.Errorf("expected excluded clusters %v, but got %v", c.expectedExcluded, pinning.excluded.List())
+			}
+		})
+	}
+}
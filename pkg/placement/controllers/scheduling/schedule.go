@@ -7,9 +7,11 @@ import (
 	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	kevents "k8s.io/client-go/tools/events"
 	"k8s.io/klog/v2"
 
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterlisterv1alpha1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1alpha1"
@@ -18,9 +20,15 @@ import (
 	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 
 	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/controllers/metrics"
 	"open-cluster-management.io/ocm/pkg/placement/plugins"
 	"open-cluster-management.io/ocm/pkg/placement/plugins/addon"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/addonhealth"
 	"open-cluster-management.io/ocm/pkg/placement/plugins/balance"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/claim"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/extender"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/minresource"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/placementaffinity"
 	"open-cluster-management.io/ocm/pkg/placement/plugins/predicate"
 	"open-cluster-management.io/ocm/pkg/placement/plugins/resource"
 	"open-cluster-management.io/ocm/pkg/placement/plugins/steady"
@@ -32,6 +40,13 @@ const (
 	PrioritizerSteady                    string = "Steady"
 	PrioritizerResourceAllocatableCPU    string = "ResourceAllocatableCPU"
 	PrioritizerResourceAllocatableMemory string = "ResourceAllocatableMemory"
+
+	PrioritizerResourceLeastAllocatableCPU    string = "ResourceLeastAllocatableCPU"
+	PrioritizerResourceLeastAllocatableMemory string = "ResourceLeastAllocatableMemory"
+	PrioritizerResourceAllocatableRatioCPU    string = "ResourceAllocatableRatioCPU"
+	PrioritizerResourceAllocatableRatioMemory string = "ResourceAllocatableRatioMemory"
+	PrioritizerResourceBalancedAllocation     string = "ResourceBalancedAllocation"
+	PrioritizerPlacementAffinity              string = "PlacementAffinity"
 )
 
 // PrioritizerScore defines the score for each cluster
@@ -59,6 +74,15 @@ type ScheduleResult interface {
 	// Decisions returns the decisions of the schedule
 	Decisions() []clusterapiv1beta1.ClusterDecision
 
+	// SpreadResults returns results for each spread constraint
+	SpreadResults() []SpreadResult
+
+	// ExcludedClusters returns the clusters not selected and the reasons
+	ExcludedClusters() []ExcludedCluster
+
+	// PinnedClusters returns the clusters selected because they are pinned to the placement
+	PinnedClusters() []string
+
 	// NumOfUnscheduled returns the number of unscheduled.
 	NumOfUnscheduled() int
 
@@ -71,12 +95,23 @@ type FilterResult struct {
 	FilteredClusters []string `json:"filteredClusters"`
 }
 
+// ExcludedCluster records the plugin which excludes a cluster from the decisions and the reason.
+type ExcludedCluster struct {
+	ClusterName string `json:"clusterName"`
+	Plugin      string `json:"plugin"`
+	Reason      string `json:"reason"`
+}
+
 // PrioritizerResult defines the result of one prioritizer,
 // include name, weight, and score of each cluster.
 type PrioritizerResult struct {
 	Name   string           `json:"name"`
 	Weight int32            `json:"weight"`
 	Scores PrioritizerScore `json:"scores"`
+	// StaleClusters are the clusters whose scores are missing or expired with the reasons, and
+	// StalePolicy is the policy giving their scores.
+	StaleClusters map[string]string `json:"staleClusters,omitempty"`
+	StalePolicy   string            `json:"stalePolicy,omitempty"`
 }
 
 // ScheduleResult is the result for a certain schedule.
@@ -88,6 +123,9 @@ type scheduleResult struct {
 	filteredRecords map[string][]*clusterapiv1.ManagedCluster
 	scoreRecords    []PrioritizerResult
 	scoreSum        PrioritizerScore
+	spreadRecords   []SpreadResult
+	excluded        []ExcludedCluster
+	pinned          []string
 	requeueAfter    *time.Duration
 }
 
@@ -96,6 +134,7 @@ type schedulerHandler struct {
 	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister
 	scoreLister             clusterlisterv1alpha1.AddOnPlacementScoreLister
 	clusterLister           clusterlisterv1.ManagedClusterLister
+	addOnLister             addonlisterv1alpha1.ManagedClusterAddOnLister
 	clusterClient           clusterclient.Interface
 }
 
@@ -104,6 +143,7 @@ func NewSchedulerHandler(
 	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister,
 	scoreLister clusterlisterv1alpha1.AddOnPlacementScoreLister,
 	clusterLister clusterlisterv1.ManagedClusterLister,
+	addOnLister addonlisterv1alpha1.ManagedClusterAddOnLister,
 	recorder kevents.EventRecorder) plugins.Handle {
 
 	return &schedulerHandler{
@@ -111,6 +151,7 @@ func NewSchedulerHandler(
 		placementDecisionLister: placementDecisionLister,
 		scoreLister:             scoreLister,
 		clusterLister:           clusterLister,
+		addOnLister:             addOnLister,
 		clusterClient:           clusterClient,
 	}
 }
@@ -131,6 +172,10 @@ func (s *schedulerHandler) ClusterLister() clusterlisterv1.ManagedClusterLister
 	return s.clusterLister
 }
 
+func (s *schedulerHandler) AddOnLister() addonlisterv1alpha1.ManagedClusterAddOnLister {
+	return s.addOnLister
+}
+
 func (s *schedulerHandler) ClusterClient() clusterclient.Interface {
 	return s.clusterClient
 }
@@ -153,17 +198,62 @@ type pluginScheduler struct {
 	handle             plugins.Handle
 	filters            []plugins.Filter
 	prioritizerWeights map[clusterapiv1beta1.ScoreCoordinate]int32
+	// extenders are the extender prioritizers keyed by the builtIn name
+	extenders   map[string]plugins.Prioritizer
+	filterCache *filterCache
 }
 
-func NewPluginScheduler(handle plugins.Handle) *pluginScheduler {
-	return &pluginScheduler{
+// NewPluginScheduler returns the scheduler with the built-in plugins and the scheduling extenders. The
+// extender filters run after the built-in filters, and the extender prioritizers are enabled with their
+// default weights, which a placement can override with the builtIn name Extender/<name>.
+func NewPluginScheduler(handle plugins.Handle, extenders ...*extender.Extender) *pluginScheduler {
+	s := &pluginScheduler{
 		handle: handle,
 		filters: []plugins.Filter{
 			predicate.New(handle),
 			tainttoleration.New(handle),
+			minresource.New(handle),
+			placementaffinity.New(handle),
+			addonhealth.New(handle),
 		},
 		prioritizerWeights: defaultPrioritizerConfig,
+		extenders:          map[string]plugins.Prioritizer{},
+		filterCache:        newFilterCache(),
+	}
+	if len(extenders) == 0 {
+		return s
+	}
+
+	s.prioritizerWeights = map[clusterapiv1beta1.ScoreCoordinate]int32{}
+	for k, v := range defaultPrioritizerConfig {
+		s.prioritizerWeights[k] = v
+	}
+	for _, e := range extenders {
+		if e.IsFilter() {
+			s.filters = append(s.filters, e)
+		}
+		if e.IsPrioritizer() {
+			s.extenders[e.Name()] = e
+			s.prioritizerWeights[clusterapiv1beta1.ScoreCoordinate{
+				Type:    clusterapiv1beta1.ScoreCoordinateTypeBuiltIn,
+				BuiltIn: e.Name(),
+			}] = e.Weight()
+		}
+	}
+	return s
+}
+
+// filter runs the filter plugin, the result of a cacheable filter is served from the filter cache.
+func (s *pluginScheduler) filter(
+	ctx context.Context,
+	f plugins.Filter,
+	placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster,
+) (plugins.PluginFilterResult, *framework.Status) {
+	if cf, ok := f.(plugins.CacheableFilter); ok && s.filterCache != nil {
+		return s.filterCache.filter(ctx, cf, placement, clusters)
 	}
+	return f.Filter(ctx, placement, clusters)
 }
 
 func (s *pluginScheduler) Schedule(
@@ -171,7 +261,6 @@ func (s *pluginScheduler) Schedule(
 	placement *clusterapiv1beta1.Placement,
 	clusters []*clusterapiv1.ManagedCluster,
 ) (ScheduleResult, *framework.Status) {
-	filtered := clusters
 	finalStatus := framework.NewStatus("", framework.Success, "")
 
 	results := &scheduleResult{
@@ -179,11 +268,25 @@ func (s *pluginScheduler) Schedule(
 		scoreRecords:    []PrioritizerResult{},
 	}
 
+	// set aside the pinned clusters and remove the excluded clusters before filtering
+	pinning, status := getClusterPinning(placement)
+	if status.IsError() {
+		return results, status
+	}
+	pinned, filtered, excluded := pinning.split(clusters)
+	results.excluded = append(results.excluded, excluded...)
+	for _, cluster := range pinned {
+		results.pinned = append(results.pinned, cluster.Name)
+	}
+
 	// filter clusters
 	filterPipline := []string{}
 
 	for _, f := range s.filters {
-		filterResult, status := f.Filter(ctx, placement, filtered)
+		start := time.Now()
+		filterResult, status := s.filter(ctx, f, placement, filtered)
+		metrics.ObservePluginDuration(f.Name(), metrics.ExtensionPointFilter, start)
+		results.excluded = append(results.excluded, getExcludedClusters(f.Name(), filtered, filterResult)...)
 		filtered = filterResult.Filtered
 
 		switch {
@@ -212,7 +315,7 @@ func (s *pluginScheduler) Schedule(
 	}
 
 	// 2. Generate prioritizers for each placement whose weight != 0.
-	prioritizers, status := getPrioritizers(weights, s.handle)
+	prioritizers, status := getPrioritizers(weights, s.extenders, s.handle)
 	switch {
 	case status.IsError():
 		return results, status
@@ -226,9 +329,12 @@ func (s *pluginScheduler) Schedule(
 	for _, cluster := range filtered {
 		scoreSum[cluster.Name] = 0
 	}
+	scoreExcluded := map[string]ExcludedCluster{}
 	for sc, p := range prioritizers {
 		// Get cluster score.
+		start := time.Now()
 		scoreResult, status := p.Score(ctx, placement, filtered)
+		metrics.ObservePluginDuration(p.Name(), metrics.ExtensionPointScore, start)
 		score := scoreResult.Scores
 
 		switch {
@@ -241,7 +347,18 @@ func (s *pluginScheduler) Schedule(
 
 		// Record prioritizer score and weight
 		weight := weights[sc]
-		results.scoreRecords = append(results.scoreRecords, PrioritizerResult{Name: p.Name(), Weight: weight, Scores: score})
+		results.scoreRecords = append(results.scoreRecords, PrioritizerResult{
+			Name:          p.Name(),
+			Weight:        weight,
+			Scores:        score,
+			StaleClusters: scoreResult.StaleClusters,
+			StalePolicy:   scoreResult.StalePolicy,
+		})
+		for name, reason := range scoreResult.Excluded {
+			if _, ok := scoreExcluded[name]; !ok {
+				scoreExcluded[name] = ExcludedCluster{ClusterName: name, Plugin: p.Name(), Reason: reason}
+			}
+		}
 
 		// The final score is a sum of each prioritizer score * weight.
 		// A higher weight indicates that the prioritizer weights more in the cluster selection,
@@ -252,7 +369,21 @@ func (s *pluginScheduler) Schedule(
 
 	}
 
-	// 4. Sort clusters by score, if score is equal, sort by name
+	// 4. Remove the clusters excluded by the prioritizers.
+	if len(scoreExcluded) > 0 {
+		remaining := []*clusterapiv1.ManagedCluster{}
+		for _, cluster := range filtered {
+			if e, ok := scoreExcluded[cluster.Name]; ok {
+				results.excluded = append(results.excluded, e)
+				delete(scoreSum, cluster.Name)
+				continue
+			}
+			remaining = append(remaining, cluster)
+		}
+		filtered = remaining
+	}
+
+	// 5. Sort clusters by score, if score is equal, sort by name
 	sort.SliceStable(filtered, func(i, j int) bool {
 		if scoreSum[filtered[i].Name] == scoreSum[filtered[j].Name] {
 			return filtered[i].Name < filtered[j].Name
@@ -264,10 +395,46 @@ func (s *pluginScheduler) Schedule(
 	results.feasibleClusters = filtered
 	results.scoreSum = scoreSum
 
-	// select clusters and generate cluster decisions
-	decisions := selectClusters(placement, filtered)
+	// select clusters and generate cluster decisions, the spread constraints are
+	// applied during the selection if defined in placement.
+	status = validateSpreadPolicy(placement)
+	if status.IsError() {
+		return results, status
+	}
+	decisions, spreadResults, blockedClusters := selectClustersWithSpread(withoutPinnedClusters(placement, len(pinned)), filtered)
+	results.spreadRecords = spreadResults
+
+	// the pinned clusters are always selected in front of the others
+	pinnedDecisions := []clusterapiv1beta1.ClusterDecision{}
+	for _, cluster := range pinned {
+		pinnedDecisions = append(pinnedDecisions, clusterapiv1beta1.ClusterDecision{ClusterName: cluster.Name})
+	}
+	decisions = append(pinnedDecisions, decisions...)
+
+	// record the feasible clusters not selected
+	decided := sets.NewString()
+	for _, d := range decisions {
+		decided.Insert(d.ClusterName)
+	}
+	for _, cluster := range filtered {
+		switch {
+		case decided.Has(cluster.Name):
+		case len(blockedClusters[cluster.Name]) > 0:
+			results.excluded = append(results.excluded, ExcludedCluster{
+				ClusterName: cluster.Name,
+				Plugin:      spreadPolicyName,
+				Reason:      blockedClusters[cluster.Name],
+			})
+		default:
+			results.excluded = append(results.excluded, ExcludedCluster{
+				ClusterName: cluster.Name,
+				Plugin:      "Prioritizer",
+				Reason:      fmt.Sprintf("outscored with total score %d", scoreSum[cluster.Name]),
+			})
+		}
+	}
 	scheduled, unscheduled := len(decisions), 0
-	if placement.Spec.NumberOfClusters != nil {
+	if placement.Spec.NumberOfClusters != nil && int(*placement.Spec.NumberOfClusters) > scheduled {
 		unscheduled = int(*placement.Spec.NumberOfClusters) - scheduled
 	}
 	results.scheduledDecisions = decisions
@@ -290,6 +457,49 @@ func (s *pluginScheduler) Schedule(
 	return results, finalStatus
 }
 
+// getExcludedClusters returns the clusters filtered out by a filter plugin with the reasons.
+func getExcludedClusters(pluginName string, clusters []*clusterapiv1.ManagedCluster, result plugins.PluginFilterResult) []ExcludedCluster {
+	excluded := []ExcludedCluster{}
+	// nothing is filtered out, which is the common case of most filters
+	if len(result.Filtered) == len(clusters) {
+		return excluded
+	}
+
+	kept := make(map[string]bool, len(result.Filtered))
+	for _, c := range result.Filtered {
+		kept[c.Name] = true
+	}
+
+	defaultReason := fmt.Sprintf("filtered out by %s", pluginName)
+	for _, c := range clusters {
+		if kept[c.Name] {
+			continue
+		}
+		reason := result.Reasons[c.Name]
+		if len(reason) == 0 {
+			reason = defaultReason
+		}
+		excluded = append(excluded, ExcludedCluster{ClusterName: c.Name, Plugin: pluginName, Reason: reason})
+	}
+	return excluded
+}
+
+// withoutPinnedClusters returns the placement with the numberOfClusters reduced by the number of the
+// pinned clusters, which is used to select the other clusters.
+func withoutPinnedClusters(placement *clusterapiv1beta1.Placement, numOfPinned int) *clusterapiv1beta1.Placement {
+	if numOfPinned == 0 || placement.Spec.NumberOfClusters == nil {
+		return placement
+	}
+
+	numOfClusters := *placement.Spec.NumberOfClusters - int32(numOfPinned)
+	if numOfClusters < 0 {
+		numOfClusters = 0
+	}
+	placement = placement.DeepCopy()
+	placement.Spec.NumberOfClusters = &numOfClusters
+	return placement
+}
+
 // makeClusterDecisions selects clusters based on given cluster slice and then creates
 // cluster decisions.
 func selectClusters(placement *clusterapiv1beta1.Placement, clusters []*clusterapiv1.ManagedCluster) []clusterapiv1beta1.ClusterDecision {
@@ -328,14 +538,30 @@ func setRequeueAfter(requeueAfter, newRequeueAfter *time.Duration) *time.Duratio
 
 // Get prioritizer weight for the placement.
 // In Additive and "" mode, will override defaultWeight with what placement has defined and return.
-// In Exact mode, will return the name and weight defined in placement.
+// The PlacementAffinity prioritizer has a default weight 1 in these modes if the placement has
+// preferred placement affinity terms.
+// In Exact mode, will return the name and weight defined in placement, with a Warning status if the
+// preferred placement affinity terms are ignored.
 func getWeights(defaultWeight map[clusterapiv1beta1.ScoreCoordinate]int32,
 	placement *clusterapiv1beta1.Placement) (map[clusterapiv1beta1.ScoreCoordinate]int32, *framework.Status) {
 	mode := placement.Spec.PrioritizerPolicy.Mode
 	switch {
 	case mode == clusterapiv1beta1.PrioritizerPolicyModeExact:
-		return mergeWeights(nil, placement.Spec.PrioritizerPolicy.Configurations)
+		weights, status := mergeWeights(nil, placement.Spec.PrioritizerPolicy.Configurations)
+		if status.IsError() || !hasPreferredPlacementAffinity(placement) || weights[placementAffinityCoordinate] != 0 {
+			return weights, status
+		}
+		return weights, framework.NewStatus("", framework.Warning,
+			fmt.Sprintf("preferred placement affinity of placement %s/%s is ignored since prioritizer %s is not configured",
+				placement.Namespace, placement.Name, PrioritizerPlacementAffinity))
 	case mode == clusterapiv1beta1.PrioritizerPolicyModeAdditive || mode == "":
+		if hasPreferredPlacementAffinity(placement) {
+			weights := map[clusterapiv1beta1.ScoreCoordinate]int32{placementAffinityCoordinate: 1}
+			for sc, w := range defaultWeight {
+				weights[sc] = w
+			}
+			defaultWeight = weights
+		}
 		return mergeWeights(defaultWeight, placement.Spec.PrioritizerPolicy.Configurations)
 	default:
 		msg := fmt.Sprintf("incorrect prioritizer policy mode: %s", mode)
@@ -343,6 +569,21 @@ func getWeights(defaultWeight map[clusterapiv1beta1.ScoreCoordinate]int32,
 	}
 }
 
+var placementAffinityCoordinate = clusterapiv1beta1.ScoreCoordinate{
+	Type:    clusterapiv1beta1.ScoreCoordinateTypeBuiltIn,
+	BuiltIn: PrioritizerPlacementAffinity,
+}
+
+// hasPreferredPlacementAffinity returns true if the placement has any preferred placement affinity
+// or anti-affinity term.
+func hasPreferredPlacementAffinity(placement *clusterapiv1beta1.Placement) bool {
+	terms, err := placementaffinity.GetPlacementAffinityTerms(placement)
+	if err != nil {
+		return false
+	}
+	return len(terms.PreferredAffinity) > 0 || len(terms.PreferredAntiAffinity) > 0
+}
+
 func mergeWeights(defaultWeight map[clusterapiv1beta1.ScoreCoordinate]int32,
 	customizedWeight []clusterapiv1beta1.PrioritizerConfig,
 ) (map[clusterapiv1beta1.ScoreCoordinate]int32, *framework.Status) {
@@ -365,7 +606,8 @@ func mergeWeights(defaultWeight map[clusterapiv1beta1.ScoreCoordinate]int32,
 }
 
 // Generate prioritizers for the placement.
-func getPrioritizers(weights map[clusterapiv1beta1.ScoreCoordinate]int32, handle plugins.Handle,
+func getPrioritizers(weights map[clusterapiv1beta1.ScoreCoordinate]int32,
+	extenders map[string]plugins.Prioritizer, handle plugins.Handle,
 ) (map[clusterapiv1beta1.ScoreCoordinate]plugins.Prioritizer, *framework.Status) {
 	result := make(map[clusterapiv1beta1.ScoreCoordinate]plugins.Prioritizer)
 	status := framework.NewStatus("", framework.Success, "")
@@ -379,8 +621,14 @@ func getPrioritizers(weights map[clusterapiv1beta1.ScoreCoordinate]int32, handle
 				result[k] = balance.New(handle)
 			case k.BuiltIn == PrioritizerSteady:
 				result[k] = steady.New(handle)
-			case k.BuiltIn == PrioritizerResourceAllocatableCPU || k.BuiltIn == PrioritizerResourceAllocatableMemory:
+			case k.BuiltIn == PrioritizerPlacementAffinity:
+				result[k] = placementaffinity.New(handle)
+			case resource.IsValidPrioritizerName(k.BuiltIn):
 				result[k] = resource.NewResourcePrioritizerBuilder(handle).WithPrioritizerName(k.BuiltIn).Build()
+			case claim.IsValidPrioritizerName(k.BuiltIn):
+				result[k] = claim.NewClaimValuePrioritizerBuilder(handle).WithPrioritizerName(k.BuiltIn).Build()
+			case extenders[k.BuiltIn] != nil:
+				result[k] = extenders[k.BuiltIn]
 			default:
 				msg := fmt.Sprintf("incorrect builtin prioritizer: %s", k.BuiltIn)
 				return nil, framework.NewStatus("", framework.Misconfigured, msg)
@@ -431,6 +679,18 @@ func (r *scheduleResult) Decisions() []clusterapiv1beta1.ClusterDecision {
 	return r.scheduledDecisions
 }
 
+func (r *scheduleResult) SpreadResults() []SpreadResult {
+	return r.spreadRecords
+}
+
+func (r *scheduleResult) ExcludedClusters() []ExcludedCluster {
+	return r.excluded
+}
+
+func (r *scheduleResult) PinnedClusters() []string {
+	return r.pinned
+}
+
 func (r *scheduleResult) NumOfUnscheduled() int {
 	return r.unscheduledDecisions
 }
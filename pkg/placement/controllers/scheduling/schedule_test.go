@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	clusterapiv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 	"reflect"
 	"sort"
@@ -19,6 +21,9 @@ import (
 
 	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
 	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/addon"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/extender"
+	"open-cluster-management.io/ocm/pkg/placement/plugins/placementaffinity"
 )
 
 func TestSchedule(t *testing.T) {
@@ -58,6 +63,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster1"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster1"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -101,6 +118,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster1"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster1"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -180,6 +209,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -216,6 +257,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster1"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster1"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -281,6 +334,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster1", "cluster3"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster1", "cluster3"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster1", "cluster3"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster1", "cluster3"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -326,6 +391,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -378,6 +455,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster1", "cluster2", "cluster3"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -394,6 +483,53 @@ func TestSchedule(t *testing.T) {
 			expectedUnScheduled: 0,
 			expectedStatus:      *framework.NewStatus("", framework.Success, ""),
 		},
+		{
+			name:      "placement with claim value prioritizer",
+			placement: testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(1).WithPrioritizerPolicy("Exact").WithPrioritizerConfig("ClaimValueDescending:cost.example.com", 1).Build(),
+			initObjs: []runtime.Object{
+				testinghelpers.NewClusterSet(clusterSetName).Build(),
+				testinghelpers.NewClusterSetBinding(placementNamespace, clusterSetName),
+			},
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithLabel(clusterapiv1beta2.ClusterSetLabel, clusterSetName).WithClaim("cost.example.com", "3").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithLabel(clusterapiv1beta2.ClusterSetLabel, clusterSetName).WithClaim("cost.example.com", "1").Build(),
+			},
+			decisions: []runtime.Object{},
+			expectedDecisions: []clusterapiv1beta1.ClusterDecision{
+				{ClusterName: "cluster2"},
+			},
+			expectedFilterResult: []FilterResult{
+				{
+					Name:             "Predicate",
+					FilteredClusters: []string{"cluster1", "cluster2"},
+				},
+				{
+					Name:             "Predicate,TaintToleration",
+					FilteredClusters: []string{"cluster2", "cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster2", "cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster2", "cluster1"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster2", "cluster1"},
+				},
+			},
+			expectedScoreResult: []PrioritizerResult{
+				{
+					Name:   "ClaimValueDescending:cost.example.com",
+					Weight: 1,
+					Scores: PrioritizerScore{"cluster1": -100, "cluster2": 100},
+				},
+			},
+			expectedUnScheduled: 0,
+			expectedStatus:      *framework.NewStatus("", framework.Success, ""),
+		},
 		{
 			name:      "placement with part of decisions scheduled",
 			placement: testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(4).Build(),
@@ -426,6 +562,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster1", "cluster2"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster1", "cluster2"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster1", "cluster2"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster1", "cluster2"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -472,6 +620,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster3", "cluster1", "cluster2"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster3", "cluster1", "cluster2"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster3", "cluster1", "cluster2"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster3", "cluster1", "cluster2"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -528,6 +688,18 @@ func TestSchedule(t *testing.T) {
 					Name:             "Predicate,TaintToleration",
 					FilteredClusters: []string{"cluster3", "cluster1", "cluster2"},
 				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource",
+					FilteredClusters: []string{"cluster3", "cluster1", "cluster2"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity",
+					FilteredClusters: []string{"cluster3", "cluster1", "cluster2"},
+				},
+				{
+					Name:             "Predicate,TaintToleration,MinimumResource,PlacementAffinity,AddOnHealth",
+					FilteredClusters: []string{"cluster3", "cluster1", "cluster2"},
+				},
 			},
 			expectedScoreResult: []PrioritizerResult{
 				{
@@ -591,10 +763,434 @@ func TestSchedule(t *testing.T) {
 	}
 }
 
+func TestExcludedClusters(t *testing.T) {
+	placementNamespace := "ns1"
+	placementName := "placement1"
+
+	placement := testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(1).AddPredicate(
+		&metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Amazon"}}, nil).Build()
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").WithLabel("cloud", "Amazon").Build(),
+		testinghelpers.NewManagedCluster("cluster2").WithLabel("cloud", "Amazon").Build(),
+		testinghelpers.NewManagedCluster("cluster3").WithLabel("cloud", "Google").Build(),
+		testinghelpers.NewManagedCluster("cluster4").WithLabel("cloud", "Amazon").WithTaint(&clusterapiv1.Taint{
+			Key:    "key1",
+			Value:  "value1",
+			Effect: clusterapiv1.TaintEffectNoSelect,
+		}).Build(),
+	}
+
+	initObjs := []runtime.Object{placement}
+	clusterClient := clusterfake.NewSimpleClientset(initObjs...)
+	s := NewPluginScheduler(testinghelpers.NewFakePluginHandle(t, clusterClient, initObjs...))
+	result, status := s.Schedule(context.TODO(), placement, clusters)
+	if status.IsError() {
+		t.Fatalf("unexpected err: %v", status.AsError())
+	}
+
+	expected := map[string]string{
+		"cluster2": "Prioritizer",
+		"cluster3": "Predicate",
+		"cluster4": "TaintToleration",
+	}
+	actual := map[string]string{}
+	for _, e := range result.ExcludedClusters() {
+		if len(e.Reason) == 0 {
+			t.Errorf("expected reason of excluded cluster %s", e.ClusterName)
+		}
+		actual[e.ClusterName] = e.Plugin
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected excluded clusters %v, but got %v", expected, actual)
+	}
+}
+
+func TestScheduleWithPinnedClusters(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").WithLabel("cloud", "Amazon").Build(),
+		testinghelpers.NewManagedCluster("cluster2").WithLabel("cloud", "Amazon").Build(),
+		testinghelpers.NewManagedCluster("cluster3").WithLabel("cloud", "Google").Build(),
+		testinghelpers.NewManagedCluster("cluster4").WithLabel("cloud", "Amazon").WithTaint(&clusterapiv1.Taint{
+			Key:    "key1",
+			Value:  "value1",
+			Effect: clusterapiv1.TaintEffectNoSelect,
+		}).Build(),
+	}
+	newPlacement := func(noc int32, pinned, excluded string) *clusterapiv1beta1.Placement {
+		return testinghelpers.NewPlacementWithAnnotations("ns1", "placement1", map[string]string{
+			PinnedClustersAnnotation:   pinned,
+			ExcludedClustersAnnotation: excluded,
+		}).WithNOC(noc).AddPredicate(
+			&metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Amazon"}}, nil).Build()
+	}
+
+	cases := []struct {
+		name                string
+		placement           *clusterapiv1beta1.Placement
+		expectedDecisions   []string
+		expectedPinned      []string
+		expectedExcluded    map[string]string
+		expectedUnScheduled int
+		expectedCode        framework.Code
+	}{
+		{
+			name:              "pin a cluster not matching the predicates",
+			placement:         newPlacement(2, "cluster3", ""),
+			expectedDecisions: []string{"cluster3", "cluster1"},
+			expectedPinned:    []string{"cluster3"},
+			expectedExcluded: map[string]string{
+				"cluster2": "Prioritizer",
+				"cluster4": "TaintToleration",
+			},
+		},
+		{
+			name:                "exclude a cluster",
+			placement:           newPlacement(2, "", "cluster1"),
+			expectedDecisions:   []string{"cluster2"},
+			expectedUnScheduled: 1,
+			expectedExcluded: map[string]string{
+				"cluster1": clusterPinningName,
+				"cluster3": "Predicate",
+				"cluster4": "TaintToleration",
+			},
+		},
+		{
+			name:              "pinned clusters exceed the number of clusters",
+			placement:         newPlacement(1, "cluster4, cluster3,cluster5", ""),
+			expectedDecisions: []string{"cluster4", "cluster3"},
+			expectedPinned:    []string{"cluster4", "cluster3"},
+			expectedExcluded: map[string]string{
+				"cluster1": "Prioritizer",
+				"cluster2": "Prioritizer",
+				"cluster5": clusterPinningName,
+			},
+		},
+		{
+			name:         "cluster both pinned and excluded",
+			placement:    newPlacement(2, "cluster1", "cluster1"),
+			expectedCode: framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			initObjs := []runtime.Object{c.placement}
+			clusterClient := clusterfake.NewSimpleClientset(initObjs...)
+			s := NewPluginScheduler(testinghelpers.NewFakePluginHandle(t, clusterClient, initObjs...))
+			result, status := s.Schedule(context.TODO(), c.placement, clusters)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+
+			actualDecisions := []string{}
+			for _, d := range result.Decisions() {
+				actualDecisions = append(actualDecisions, d.ClusterName)
+			}
+			if !reflect.DeepEqual(actualDecisions, c.expectedDecisions) {
+				t.Errorf("expected decisions %v, but got %v", c.expectedDecisions, actualDecisions)
+			}
+			if !reflect.DeepEqual(result.PinnedClusters(), c.expectedPinned) {
+				t.Errorf("expected pinned clusters %v, but got %v", c.expectedPinned, result.PinnedClusters())
+			}
+			if result.NumOfUnscheduled() != c.expectedUnScheduled {
+				t.Errorf("expected %d unscheduled, but got %d", c.expectedUnScheduled, result.NumOfUnscheduled())
+			}
+
+			actualExcluded := map[string]string{}
+			for _, e := range result.ExcludedClusters() {
+				actualExcluded[e.ClusterName] = e.Plugin
+			}
+			if !reflect.DeepEqual(actualExcluded, c.expectedExcluded) {
+				t.Errorf("expected excluded clusters %v, but got %v", c.expectedExcluded, actualExcluded)
+			}
+		})
+	}
+}
+
 func placementDecisionName(placementName string, index int) string {
 	return fmt.Sprintf("%s-decision-%d", placementName, index)
 }
 
+func TestScheduleWithExtenders(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+	}
+
+	// the extender filters out cluster3 and prefers cluster2
+	mux := http.NewServeMux()
+	mux.HandleFunc("/filter", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"clusterNames":["cluster1","cluster2"],"failedClusters":{"cluster3":"no quota"}}`))
+	})
+	mux.HandleFunc("/prioritize", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"scores":{"cluster1":0,"cluster2":100}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cases := []struct {
+		name              string
+		placement         *clusterapiv1beta1.Placement
+		expectedDecisions []string
+		expectedExcluded  map[string]string
+	}{
+		{
+			name:              "extender with default weight",
+			placement:         testinghelpers.NewPlacement("ns1", "placement1").WithNOC(1).Build(),
+			expectedDecisions: []string{"cluster2"},
+			expectedExcluded: map[string]string{
+				"cluster1": "Prioritizer",
+				"cluster3": "Extender/quota",
+			},
+		},
+		{
+			name: "extender prioritizer disabled by placement",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").WithNOC(1).
+				WithPrioritizerPolicy(clusterapiv1beta1.PrioritizerPolicyModeAdditive).
+				WithPrioritizerConfig("Extender/quota", 0).Build(),
+			expectedDecisions: []string{"cluster1"},
+			expectedExcluded: map[string]string{
+				"cluster2": "Prioritizer",
+				"cluster3": "Extender/quota",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			initObjs := []runtime.Object{c.placement}
+			clusterClient := clusterfake.NewSimpleClientset(initObjs...)
+			handle := testinghelpers.NewFakePluginHandle(t, clusterClient, initObjs...)
+			e, err := extender.New(handle, extender.ExtenderConfig{
+				Name:           "quota",
+				URLPrefix:      server.URL,
+				FilterVerb:     "filter",
+				PrioritizeVerb: "prioritize",
+				Weight:         1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := NewPluginScheduler(handle, e)
+			result, status := s.Schedule(context.TODO(), c.placement, clusters)
+			if status.IsError() {
+				t.Fatalf("unexpected status: %s", status.Message())
+			}
+
+			actualDecisions := []string{}
+			for _, d := range result.Decisions() {
+				actualDecisions = append(actualDecisions, d.ClusterName)
+			}
+			if !reflect.DeepEqual(actualDecisions, c.expectedDecisions) {
+				t.Errorf("expected decisions %v, but got %v", c.expectedDecisions, actualDecisions)
+			}
+			actualExcluded := map[string]string{}
+			for _, e := range result.ExcludedClusters() {
+				actualExcluded[e.ClusterName] = e.Plugin
+			}
+			if !reflect.DeepEqual(actualExcluded, c.expectedExcluded) {
+				t.Errorf("expected excluded clusters %v, but got %v", c.expectedExcluded, actualExcluded)
+			}
+		})
+	}
+}
+
+func TestScheduleWithStaleScores(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+	}
+	scores := []runtime.Object{
+		testinghelpers.NewAddOnPlacementScore("cluster1", "demo").WithScore("demo", 10).Build(),
+		testinghelpers.NewAddOnPlacementScore("cluster2", "demo").WithScore("demo", 20).Build(),
+	}
+	newPlacement := func(policy string) *clusterapiv1beta1.Placement {
+		return testinghelpers.NewPlacementWithAnnotations("ns1", "placement1", map[string]string{
+			addon.StaleScorePolicyAnnotation: policy,
+		}).WithNOC(3).WithPrioritizerPolicy(clusterapiv1beta1.PrioritizerPolicyModeExact).
+			WithScoreCoordinateAddOn("demo", "demo", 1).Build()
+	}
+
+	cases := []struct {
+		name              string
+		placement         *clusterapiv1beta1.Placement
+		expectedDecisions []string
+		expectedExcluded  map[string]string
+	}{
+		{
+			name:              "stale cluster scored max",
+			placement:         newPlacement("Max"),
+			expectedDecisions: []string{"cluster3", "cluster2", "cluster1"},
+			expectedExcluded:  map[string]string{},
+		},
+		{
+			name:              "stale cluster excluded",
+			placement:         newPlacement("Exclude"),
+			expectedDecisions: []string{"cluster2", "cluster1"},
+			expectedExcluded:  map[string]string{"cluster3": "AddOn/demo/demo"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			initObjs := append([]runtime.Object{c.placement}, scores...)
+			clusterClient := clusterfake.NewSimpleClientset(initObjs...)
+			s := NewPluginScheduler(testinghelpers.NewFakePluginHandle(t, clusterClient, initObjs...))
+			result, status := s.Schedule(context.TODO(), c.placement, clusters)
+			if status.IsError() {
+				t.Fatalf("unexpected status: %s", status.Message())
+			}
+
+			actualDecisions := []string{}
+			for _, d := range result.Decisions() {
+				actualDecisions = append(actualDecisions, d.ClusterName)
+			}
+			if !reflect.DeepEqual(actualDecisions, c.expectedDecisions) {
+				t.Errorf("expected decisions %v, but got %v", c.expectedDecisions, actualDecisions)
+			}
+			actualExcluded := map[string]string{}
+			for _, e := range result.ExcludedClusters() {
+				actualExcluded[e.ClusterName] = e.Plugin
+			}
+			if !reflect.DeepEqual(actualExcluded, c.expectedExcluded) {
+				t.Errorf("expected excluded clusters %v, but got %v", c.expectedExcluded, actualExcluded)
+			}
+			if _, ok := result.PrioritizerScores()["cluster3"]; ok && len(c.expectedExcluded) > 0 {
+				t.Errorf("expected no score of the excluded cluster")
+			}
+			stale := result.PrioritizerResults()[0].StaleClusters
+			if len(stale) != 1 || len(stale["cluster3"]) == 0 {
+				t.Errorf("expected stale cluster3, but got %v", stale)
+			}
+		})
+	}
+}
+
 func TestFilterResults(t *testing.T) {
 
 }
+
+func TestGetWeights(t *testing.T) {
+	balance := clusterapiv1beta1.ScoreCoordinate{Type: clusterapiv1beta1.ScoreCoordinateTypeBuiltIn, BuiltIn: PrioritizerBalance}
+	steady := clusterapiv1beta1.ScoreCoordinate{Type: clusterapiv1beta1.ScoreCoordinateTypeBuiltIn, BuiltIn: PrioritizerSteady}
+	preferred := map[string]string{
+		placementaffinity.PlacementAffinityAnnotation: `{"preferredAntiAffinity":["primary"]}`,
+	}
+
+	cases := []struct {
+		name            string
+		placement       *clusterapiv1beta1.Placement
+		annotations     map[string]string
+		expectedWeights map[clusterapiv1beta1.ScoreCoordinate]int32
+		expectedCode    framework.Code
+	}{
+		{
+			name:            "additive without preferred affinity",
+			placement:       testinghelpers.NewPlacement("ns1", "placement1").Build(),
+			expectedWeights: map[clusterapiv1beta1.ScoreCoordinate]int32{balance: 1, steady: 1},
+			expectedCode:    framework.Success,
+		},
+		{
+			name:            "additive with preferred affinity",
+			placement:       testinghelpers.NewPlacement("ns1", "placement1").Build(),
+			annotations:     preferred,
+			expectedWeights: map[clusterapiv1beta1.ScoreCoordinate]int32{balance: 1, steady: 1, placementAffinityCoordinate: 1},
+			expectedCode:    framework.Success,
+		},
+		{
+			name: "additive with preferred affinity disabled",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				WithPrioritizerConfig(PrioritizerPlacementAffinity, 0).Build(),
+			annotations:     preferred,
+			expectedWeights: map[clusterapiv1beta1.ScoreCoordinate]int32{balance: 1, steady: 1, placementAffinityCoordinate: 0},
+			expectedCode:    framework.Success,
+		},
+		{
+			name: "exact with preferred affinity configured",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				WithPrioritizerPolicy(clusterapiv1beta1.PrioritizerPolicyModeExact).
+				WithPrioritizerConfig(PrioritizerPlacementAffinity, 2).Build(),
+			annotations:     preferred,
+			expectedWeights: map[clusterapiv1beta1.ScoreCoordinate]int32{placementAffinityCoordinate: 2},
+			expectedCode:    framework.Success,
+		},
+		{
+			name: "exact with preferred affinity ignored",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				WithPrioritizerPolicy(clusterapiv1beta1.PrioritizerPolicyModeExact).
+				WithPrioritizerConfig(PrioritizerSteady, 1).Build(),
+			annotations:     preferred,
+			expectedWeights: map[clusterapiv1beta1.ScoreCoordinate]int32{steady: 1},
+			expectedCode:    framework.Warning,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.placement.Annotations = c.annotations
+			weights, status := getWeights(defaultPrioritizerConfig, c.placement)
+			if status.Code() != c.expectedCode {
+				t.Errorf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if !reflect.DeepEqual(weights, c.expectedWeights) {
+				t.Errorf("expected weights %v, but got %v", c.expectedWeights, weights)
+			}
+			if len(defaultPrioritizerConfig) != 2 {
+				t.Errorf("expected default weights unchanged, but got %v", defaultPrioritizerConfig)
+			}
+		})
+	}
+}
+
+func BenchmarkSchedule10000Clusters1000Placements(b *testing.B) {
+	benchmarkSchedule(b, 10000, 1000)
+}
+
+// benchmarkSchedule schedules pnum placements against cnum clusters in each iteration, with and
+// without the filter cache, and reports the number of placements scheduled per second.
+func benchmarkSchedule(b *testing.B, cnum, pnum int) {
+	clusters := []*clusterapiv1.ManagedCluster{}
+	objs := []runtime.Object{}
+	for i := 0; i < cnum; i++ {
+		cluster := testinghelpers.NewManagedCluster(fmt.Sprintf("cluster%d", i)).
+			WithLabel("cloud", fmt.Sprintf("cloud%d", i%10)).
+			WithClaim("region", fmt.Sprintf("region%d", i%5)).Build()
+		cluster.ResourceVersion = "1"
+		clusters = append(clusters, cluster)
+		objs = append(objs, cluster)
+	}
+
+	placements := []*clusterapiv1beta1.Placement{}
+	for i := 0; i < pnum; i++ {
+		placements = append(placements, testinghelpers.NewPlacement("ns1", fmt.Sprintf("placement%d", i)).WithNOC(10).
+			AddPredicate(&metav1.LabelSelector{MatchLabels: map[string]string{"cloud": fmt.Sprintf("cloud%d", i%10)}},
+				&clusterapiv1beta1.ClusterClaimSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "region", Operator: metav1.LabelSelectorOpNotIn, Values: []string{fmt.Sprintf("region%d", i%5)}},
+				}}).Build())
+	}
+
+	for _, cached := range []bool{true, false} {
+		b.Run(fmt.Sprintf("cached=%v", cached), func(b *testing.B) {
+			scheduler := NewPluginScheduler(testinghelpers.NewFakePluginHandle(b, clusterfake.NewSimpleClientset(), objs...))
+			if !cached {
+				scheduler.filterCache = nil
+			}
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				for _, placement := range placements {
+					if _, status := scheduler.Schedule(context.TODO(), placement, clusters); status.IsError() {
+						b.Fatalf("unexpected status: %v", status)
+					}
+				}
+			}
+			b.ReportMetric(float64(pnum*b.N)/b.Elapsed().Seconds(), "placements/s")
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,8 +24,10 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	cache "k8s.io/client-go/tools/cache"
 	kevents "k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterinformerv1alpha1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1alpha1"
@@ -39,16 +42,33 @@ import (
 
 	"open-cluster-management.io/ocm/pkg/common/queue"
 	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/controllers/metrics"
 )
 
 const (
 	schedulingControllerName = "SchedulingController"
 	maxNumOfClusterDecisions = 100
 	maxEventMessageLength    = 1000 //the event message can have at most 1024 characters, use 1000 as limitation here to keep some buffer
+
+	// PlacementConditionStaleScores means the scores of some feasible clusters reported by AddOnPlacementScores
+	// are missing or expired. It is only set on the placements prioritized by AddOnPlacementScores with a stale
+	// score policy.
+	PlacementConditionStaleScores = "StaleScores"
+	// maxStaleClustersInMessage is the max number of stale clusters listed in the condition message
+	maxStaleClustersInMessage = 10
 )
 
 var ResyncInterval = time.Minute * 5
 
+// ShardOwner decides which placement namespaces are scheduled by the controller when multiple replicas
+// run in sharded mode.
+type ShardOwner interface {
+	// Owns returns true if the controller owns the namespace and can write the PlacementDecisions in it.
+	Owns(namespace string) bool
+	// OnRebalance registers a handler called once the namespaces owned by the controller change.
+	OnRebalance(handler func())
+}
+
 // schedulingController schedules cluster decisions for Placements
 type schedulingController struct {
 	clusterClient           clusterclient.Interface
@@ -57,8 +77,13 @@ type schedulingController struct {
 	clusterSetBindingLister clusterlisterv1beta2.ManagedClusterSetBindingLister
 	placementLister         clusterlisterv1beta1.PlacementLister
 	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister
-	scheduler               Scheduler
-	recorder                kevents.EventRecorder
+	// placementDecisionIndexer indexes the placementdecisions by the decided clusters
+	placementDecisionIndexer cache.Indexer
+	scheduler                Scheduler
+	retainer                 *decisionRetainer
+	recorder                 kevents.EventRecorder
+	// shardOwner is nil if the controller schedules the placements in all namespaces
+	shardOwner ShardOwner
 }
 
 // NewSchedulingController return an instance of schedulingController
@@ -70,7 +95,9 @@ func NewSchedulingController(
 	placementInformer clusterinformerv1beta1.PlacementInformer,
 	placementDecisionInformer clusterinformerv1beta1.PlacementDecisionInformer,
 	placementScoreInformer clusterinformerv1alpha1.AddOnPlacementScoreInformer,
+	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
 	scheduler Scheduler,
+	shardOwner ShardOwner,
 	recorder events.Recorder, krecorder kevents.EventRecorder,
 ) factory.Controller {
 	syncCtx := factory.NewSyncContext(schedulingControllerName, recorder)
@@ -79,14 +106,23 @@ func NewSchedulingController(
 
 	// build controller
 	c := &schedulingController{
-		clusterClient:           clusterClient,
-		clusterLister:           clusterInformer.Lister(),
-		clusterSetLister:        clusterSetInformer.Lister(),
-		clusterSetBindingLister: clusterSetBindingInformer.Lister(),
-		placementLister:         placementInformer.Lister(),
-		placementDecisionLister: placementDecisionInformer.Lister(),
-		recorder:                krecorder,
-		scheduler:               scheduler,
+		clusterClient:            clusterClient,
+		clusterLister:            clusterInformer.Lister(),
+		clusterSetLister:         clusterSetInformer.Lister(),
+		clusterSetBindingLister:  clusterSetBindingInformer.Lister(),
+		placementLister:          placementInformer.Lister(),
+		placementDecisionLister:  placementDecisionInformer.Lister(),
+		placementDecisionIndexer: placementDecisionInformer.Informer().GetIndexer(),
+		recorder:                 krecorder,
+		scheduler:                scheduler,
+		retainer:                 newDecisionRetainer(),
+		shardOwner:               shardOwner,
+	}
+
+	// index placementdecisions by the decided clusters to count the slots used on each cluster
+	err := placementDecisionInformer.Informer().AddIndexers(DecisionsByClusterIndexers())
+	if err != nil {
+		utilruntime.HandleError(err)
 	}
 
 	// setup event handler for cluster informer.
@@ -95,7 +131,7 @@ func NewSchedulingController(
 	// informers/listers of clusterset/clustersetbinding/placement are synced during
 	// controller booting. But that should not cause any problem because all existing
 	// placements will be enqueued by the controller anyway when booting.
-	_, err := clusterInformer.Informer().AddEventHandler(&clusterEventHandler{
+	_, err = clusterInformer.Informer().AddEventHandler(&clusterEventHandler{
 		enqueuer: enQueuer,
 	})
 	if err != nil {
@@ -148,6 +184,50 @@ func NewSchedulingController(
 		utilruntime.HandleError(err)
 	}
 
+	// setup event handler for addon informer
+	// Once the conditions of an addon change, enqueue the placements requiring the addon.
+	_, err = addOnInformer.Informer().AddEventHandler(&cache.ResourceEventHandlerFuncs{
+		AddFunc: enQueuer.enqueueAddOn,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if addOnConditionsChanged(oldObj, newObj) {
+				enQueuer.enqueueAddOn(newObj)
+			}
+		},
+		DeleteFunc: enQueuer.enqueueAddOn,
+	})
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+
+	// setup event handler for placementdecision informer
+	// Once a placementdecision changes, enqueue the placements which have affinity or
+	// anti-affinity to the placement of the placementdecision.
+	_, err = placementDecisionInformer.Informer().AddEventHandler(&cache.ResourceEventHandlerFuncs{
+		AddFunc: enQueuer.enqueuePlacementDecision,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			enQueuer.enqueuePlacementDecision(newObj)
+		},
+		DeleteFunc: enQueuer.enqueuePlacementDecision,
+	})
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+
+	// enqueue all placements once the namespaces owned by the controller change, the placements in the
+	// namespaces no longer owned are skipped by the sync.
+	if shardOwner != nil {
+		shardOwner.OnRebalance(func() {
+			placements, err := c.placementLister.List(labels.Everything())
+			if err != nil {
+				utilruntime.HandleError(err)
+				return
+			}
+			for _, placement := range placements {
+				enQueuer.enqueuePlacementFunc(placement, syncCtx.Queue())
+			}
+		})
+	}
+
 	return factory.New().
 		WithSyncContext(syncCtx).
 		WithInformersQueueKeysFunc(
@@ -161,7 +241,7 @@ func NewSchedulingController(
 		},
 			queue.FileterByLabel(clusterapiv1beta1.PlacementLabel),
 			placementDecisionInformer.Informer()).
-		WithBareInformers(clusterInformer.Informer(), clusterSetInformer.Informer(), clusterSetBindingInformer.Informer(), placementScoreInformer.Informer()).
+		WithBareInformers(clusterInformer.Informer(), clusterSetInformer.Informer(), clusterSetBindingInformer.Informer(), placementScoreInformer.Informer(), addOnInformer.Informer()).
 		WithSync(c.sync).
 		ToController(schedulingControllerName, recorder)
 }
@@ -173,13 +253,34 @@ func (c *schedulingController) sync(ctx context.Context, syncCtx factory.SyncCon
 	placement, err := c.getPlacement(queueKey)
 	if errors.IsNotFound(err) {
 		// no work if placement is deleted
+		c.retainer.forget(queueKey)
+		if namespace, name, err := cache.SplitMetaNamespaceKey(queueKey); err == nil {
+			metrics.DeletePlacementMetrics(namespace, name)
+		}
 		return nil
 	}
 	if err != nil {
 		return err
 	}
 
-	return c.syncPlacement(ctx, syncCtx, placement)
+	// no work if the placement namespace is owned by another replica. The retained clusters are forgotten,
+	// the grace period restarts if the namespace is owned by the controller again.
+	if !c.owns(placement.Namespace) {
+		klog.V(4).Infof("Skip placement %q owned by another shard", queueKey)
+		c.retainer.forget(queueKey)
+		return nil
+	}
+
+	start := time.Now()
+	err = c.syncPlacement(ctx, syncCtx, placement)
+	metrics.ObserveSchedulingDuration(start, err)
+	return err
+}
+
+// owns returns true if the controller schedules the placements in the namespace. The namespaces may move to
+// another replica during the scheduling, so it is checked again before the decisions and status are written.
+func (c *schedulingController) owns(namespace string) bool {
+	return c.shardOwner == nil || c.shardOwner.Owns(namespace)
 }
 
 func (c *schedulingController) getPlacement(queueKey string) (*clusterapiv1beta1.Placement, error) {
@@ -224,39 +325,290 @@ func (c *schedulingController) syncPlacement(ctx context.Context, syncCtx factor
 		return err
 	}
 
+	// narrow down the available clusters to the candidates of a composite placement
+	clusters, compositeStatus := GetCompositeClusters(placement, clusters, c.placementLister, c.placementDecisionLister)
+
+	// narrow down the candidates to the clusters with enough slots for the placement. The slots are not
+	// counted in sharded mode, since the replicas schedule the placements sharing the clusters concurrently.
+	candidates := clusters
+	var capacityExcluded []ExcludedCluster
+	var capacityStatus *framework.Status
+	if c.shardOwner == nil {
+		clusters, capacityExcluded, capacityStatus = GetClustersWithCapacity(
+			placement, candidates, c.placementLister, c.placementDecisionIndexer)
+	} else {
+		capacityStatus = validateShardedCapacity(placement)
+	}
+
 	// schedule placement with scheduler
 	scheduleResult, status := c.scheduler.Schedule(ctx, placement, clusters)
+	switch {
+	case compositeStatus.IsError():
+		status = compositeStatus
+	case capacityStatus.IsError():
+		status = capacityStatus
+	}
+
+	// retain the previously decided clusters in the grace period
+	previousClusters := getDecisionClusterNames(c.placementDecisionLister, placement)
+	gracePeriod, graceStatus := getDecisionGracePeriod(placement)
+	if graceStatus.IsError() && !status.IsError() {
+		status = graceStatus
+	}
+	retained := c.retainer.retain(placement, gracePeriod, previousClusters, clusters, scheduleResult)
+	decisions := retained.decisions
+	numOfUnscheduled := scheduleResult.NumOfUnscheduled() - (len(decisions) - len(scheduleResult.Decisions()))
+	if numOfUnscheduled < 0 {
+		numOfUnscheduled = 0
+	}
+
+	// divide the decisions into decision groups
+	decisionGroups, groupStatus := c.generateDecisionGroups(placement, decisions)
+	if groupStatus.IsError() {
+		if !status.IsError() {
+			status = groupStatus
+		}
+		decisionGroups = []clusterDecisionGroup{{clusterDecisions: decisions}}
+	}
+
 	misconfiguredCondition := newMisconfiguredCondition(status)
 	satisfiedCondition := newSatisfiedCondition(
 		placement.Spec.ClusterSets,
 		clusterSetNames,
 		len(bindings),
 		len(clusters),
-		len(scheduleResult.Decisions()),
-		scheduleResult.NumOfUnscheduled(),
+		len(decisions),
+		numOfUnscheduled,
+		unsatisfiedSpreadConstraints(scheduleResult.SpreadResults()),
 		status,
 	)
 
-	// requeue placement if requeueAfter is defined in scheduleResult
-	if syncCtx != nil && scheduleResult.RequeueAfter() != nil {
+	// requeue placement if requeueAfter is defined in scheduleResult or clusters are retained
+	requeueAfter := setRequeueAfter(scheduleResult.RequeueAfter(), retained.requeueAfter)
+	if syncCtx != nil && requeueAfter != nil {
 		key, _ := cache.MetaNamespaceKeyFunc(placement)
-		t := scheduleResult.RequeueAfter()
-		klog.V(4).Infof("Requeue placement %s after %t", key, t)
-		syncCtx.Queue().AddAfter(key, *t)
+		klog.V(4).Infof("Requeue placement %s after %v", key, *requeueAfter)
+		syncCtx.Queue().AddAfter(key, *requeueAfter)
 	}
 
-	if err := c.bind(ctx, placement, scheduleResult.Decisions(), scheduleResult.PrioritizerScores(), status); err != nil {
+	// explain why the clusters in the existing decisions are retained or not selected anymore
+	c.recordRetainedClusters(placement, retained.retained)
+	c.recordPinnedClusters(placement, previousClusters, scheduleResult.PinnedClusters())
+	c.recordExcludedClusters(placement, previousClusters, candidates, decisions,
+		append(capacityExcluded, scheduleResult.ExcludedClusters()...))
+
+	if !c.owns(placement.Namespace) {
+		klog.V(4).Infof("Skip binding placement %s/%s owned by another shard", placement.Namespace, placement.Name)
+		return nil
+	}
+	if err := c.bind(ctx, placement, decisionGroups, scheduleResult.PrioritizerScores(), status); err != nil {
 		return err
 	}
 
+	// preempt the placements with lower priority on the decided clusters without enough slots
+	if c.shardOwner == nil {
+		var preemptQueue workqueue.RateLimitingInterface
+		if syncCtx != nil {
+			preemptQueue = syncCtx.Queue()
+		}
+		c.preempt(placement, clusters, decisions, preemptQueue)
+	}
+	recordPlacementMetrics(placement, previousClusters, scheduleResult, decisions, numOfUnscheduled)
+
+	// report the clusters with stale scores, keep the condition unchanged if the placement is not prioritized
+	conditions := []metav1.Condition{misconfiguredCondition, satisfiedCondition}
+	var removedConditionTypes []string
+	if !status.IsError() {
+		if staleCondition, ok := newStaleScoresCondition(scheduleResult.PrioritizerResults()); ok {
+			conditions = append(conditions, staleCondition)
+		} else {
+			removedConditionTypes = append(removedConditionTypes, PlacementConditionStaleScores)
+		}
+	}
+
 	// update placement status if necessary to signal no bindings
-	if err := c.updateStatus(ctx, placement, int32(len(scheduleResult.Decisions())), misconfiguredCondition, satisfiedCondition); err != nil {
+	if !c.owns(placement.Namespace) {
+		klog.V(4).Infof("Skip updating placement %s/%s owned by another shard", placement.Namespace, placement.Name)
+		return nil
+	}
+	if err := c.updateStatus(ctx, placement, int32(len(decisions)), removedConditionTypes, conditions...); err != nil {
 		return err
 	}
 
 	return status.AsError()
 }
 
+// recordPlacementMetrics records the number of feasible, selected and unscheduled clusters of the
+// placement, and the number of clusters added to and removed from the existing decisions.
+func recordPlacementMetrics(
+	placement *clusterapiv1beta1.Placement,
+	previous sets.String,
+	scheduleResult ScheduleResult,
+	decisions []clusterapiv1beta1.ClusterDecision,
+	numOfUnscheduled int,
+) {
+	current := sets.NewString()
+	for _, d := range decisions {
+		current.Insert(d.ClusterName)
+	}
+
+	metrics.RecordPlacementClusters(placement.Namespace, placement.Name,
+		len(scheduleResult.PrioritizerScores()), len(decisions), numOfUnscheduled)
+	metrics.RecordDecisionChanges(placement.Namespace, placement.Name,
+		current.Difference(previous).Len(), previous.Difference(current).Len())
+}
+
+// getDecisionClusterNames returns the names of the clusters in the existing placementdecisions.
+func getDecisionClusterNames(placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister,
+	placement *clusterapiv1beta1.Placement) sets.String {
+	clusterNames := sets.NewString()
+	placementDecisions, err := placementDecisionLister.PlacementDecisions(placement.Namespace).List(
+		labels.SelectorFromSet(labels.Set{clusterapiv1beta1.PlacementLabel: placement.Name}))
+	if err != nil {
+		return clusterNames
+	}
+
+	for _, placementDecision := range placementDecisions {
+		for _, d := range placementDecision.Status.Decisions {
+			clusterNames.Insert(d.ClusterName)
+		}
+	}
+	return clusterNames
+}
+
+// recordRetainedClusters emits an event on the placement with the clusters which are not selected
+// by the scheduler but retained in the decisions during the grace period.
+func (c *schedulingController) recordRetainedClusters(placement *clusterapiv1beta1.Placement, retained map[string]time.Duration) {
+	if len(retained) == 0 {
+		return
+	}
+
+	message := ""
+	for _, clusterName := range sets.StringKeySet(retained).List() {
+		tmpMessage := fmt.Sprintf("%s(%s) ", clusterName, retained[clusterName].Round(time.Second))
+		if len(message)+len(tmpMessage) > maxEventMessageLength {
+			message += "......"
+			break
+		}
+		message += tmpMessage
+	}
+
+	c.recorder.Eventf(
+		placement, nil, corev1.EventTypeNormal,
+		"ClustersRetain", "ClustersRetained",
+		strings.TrimSpace(message))
+}
+
+// recordPinnedClusters emits an event on the placement with the pinned clusters which are newly added
+// to the decisions regardless of the filters and scores.
+func (c *schedulingController) recordPinnedClusters(placement *clusterapiv1beta1.Placement, previous sets.String, pinned []string) {
+	message := ""
+	for _, clusterName := range pinned {
+		if previous.Has(clusterName) {
+			continue
+		}
+		tmpMessage := fmt.Sprintf("%s ", clusterName)
+		if len(message)+len(tmpMessage) > maxEventMessageLength {
+			message += "......"
+			break
+		}
+		message += tmpMessage
+	}
+	if len(message) == 0 {
+		return
+	}
+
+	c.recorder.Eventf(
+		placement, nil, corev1.EventTypeNormal,
+		"ClustersPin", "ClustersPinned",
+		strings.TrimSpace(message))
+}
+
+// recordExcludedClusters emits an event on the placement with the clusters which are removed from
+// the existing placementdecisions and the reasons.
+func (c *schedulingController) recordExcludedClusters(
+	placement *clusterapiv1beta1.Placement,
+	previous sets.String,
+	clusters []*clusterapiv1.ManagedCluster,
+	decisions []clusterapiv1beta1.ClusterDecision,
+	excluded []ExcludedCluster,
+) {
+	if previous.Len() == 0 {
+		return
+	}
+
+	message := excludedClustersMessage(previous, clusters, decisions, excluded)
+	if len(message) == 0 {
+		return
+	}
+	c.recorder.Eventf(
+		placement, nil, corev1.EventTypeNormal,
+		"ClustersExclude", "ClustersExcluded",
+		message)
+}
+
+// excludedClustersMessage returns a message with the clusters previously selected but not in the
+// decisions, and the reasons. The message is capped to maxEventMessageLength.
+func excludedClustersMessage(
+	previous sets.String,
+	clusters []*clusterapiv1.ManagedCluster,
+	decisions []clusterapiv1beta1.ClusterDecision,
+	excluded []ExcludedCluster,
+) string {
+	decided := sets.NewString()
+	for _, d := range decisions {
+		decided.Insert(d.ClusterName)
+	}
+	available := sets.NewString()
+	for _, cluster := range clusters {
+		available.Insert(cluster.Name)
+	}
+	reasons := map[string]string{}
+	for _, e := range excluded {
+		reasons[e.ClusterName] = fmt.Sprintf("%s: %s", e.Plugin, e.Reason)
+	}
+
+	message := ""
+	for _, clusterName := range previous.Difference(decided).List() {
+		reason, ok := reasons[clusterName]
+		switch {
+		case !available.Has(clusterName):
+			reason = "not in the bound clustersets"
+		case !ok:
+			reason = "not selected"
+		}
+		tmpMessage := fmt.Sprintf("%s(%s) ", clusterName, reason)
+		if len(message)+len(tmpMessage) > maxEventMessageLength {
+			message += "......"
+			break
+		}
+		message += tmpMessage
+	}
+	return strings.TrimSpace(message)
+}
+
+// GetAvailableClusters returns the clusters available for the placement in the same way as the
+// scheduling controller, which belong to the clustersets bound to the placement namespace and
+// selected by the placement.
+func GetAvailableClusters(
+	placement *clusterapiv1beta1.Placement,
+	clusterLister clusterlisterv1.ManagedClusterLister,
+	clusterSetLister clusterlisterv1beta2.ManagedClusterSetLister,
+	clusterSetBindingLister clusterlisterv1beta2.ManagedClusterSetBindingLister,
+) ([]*clusterapiv1.ManagedCluster, error) {
+	c := &schedulingController{
+		clusterLister:           clusterLister,
+		clusterSetLister:        clusterSetLister,
+		clusterSetBindingLister: clusterSetBindingLister,
+	}
+
+	bindings, err := c.getValidManagedClusterSetBindings(placement.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return c.getAvailableClusters(c.getEligibleClusterSets(placement, bindings))
+}
+
 // getManagedClusterSetBindings returns all bindings found in the placement namespace.
 func (c *schedulingController) getValidManagedClusterSetBindings(placementNamespace string) ([]*clusterapiv1beta2.ManagedClusterSetBinding, error) {
 	// get all clusterset bindings under the placement namespace
@@ -346,11 +698,15 @@ func (c *schedulingController) updateStatus(
 	ctx context.Context,
 	placement *clusterapiv1beta1.Placement,
 	numberOfSelectedClusters int32,
+	removedConditionTypes []string,
 	conditions ...metav1.Condition,
 ) error {
 	newPlacement := placement.DeepCopy()
 	newPlacement.Status.NumberOfSelectedClusters = numberOfSelectedClusters
 
+	for _, t := range removedConditionTypes {
+		meta.RemoveStatusCondition(&newPlacement.Status.Conditions, t)
+	}
 	for _, c := range conditions {
 		meta.SetStatusCondition(&newPlacement.Status.Conditions, c)
 	}
@@ -372,6 +728,7 @@ func newSatisfiedCondition(
 	numOfAvailableClusters,
 	numOfFeasibleClusters,
 	numOfUnscheduledDecisions int,
+	unsatisfiedSpreadConstraints []string,
 	status *framework.Status,
 ) metav1.Condition {
 	condition := metav1.Condition{
@@ -398,10 +755,20 @@ func newSatisfiedCondition(
 		condition.Status = metav1.ConditionFalse
 		condition.Reason = "NoManagedClusterMatched"
 		condition.Message = "No ManagedCluster matches any of the cluster predicate"
+	case numOfUnscheduledDecisions == 0 && len(unsatisfiedSpreadConstraints) != 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "AllDecisionsScheduled"
+		condition.Message = fmt.Sprintf("All cluster decisions scheduled, spread constraints [%s] are not satisfied",
+			strings.Join(unsatisfiedSpreadConstraints, ","))
 	case numOfUnscheduledDecisions == 0:
 		condition.Status = metav1.ConditionTrue
 		condition.Reason = "AllDecisionsScheduled"
 		condition.Message = "All cluster decisions scheduled"
+	case len(unsatisfiedSpreadConstraints) != 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "SpreadConstraintsUnsatisfiable"
+		condition.Message = fmt.Sprintf("%d cluster decisions unscheduled, spread constraints [%s] are not satisfied",
+			numOfUnscheduledDecisions, strings.Join(unsatisfiedSpreadConstraints, ","))
 	default:
 		condition.Status = metav1.ConditionFalse
 		condition.Reason = "NotAllDecisionsScheduled"
@@ -410,6 +777,46 @@ func newSatisfiedCondition(
 	return condition
 }
 
+// newStaleScoresCondition returns a new condition with type PlacementConditionStaleScores listing the
+// clusters with missing or expired scores, and false if no prioritizer reports stale scores.
+func newStaleScoresCondition(results []PrioritizerResult) (metav1.Condition, bool) {
+	stale := sets.NewString()
+	policies := sets.NewString()
+	for _, r := range results {
+		if len(r.StalePolicy) == 0 {
+			continue
+		}
+		policies.Insert(r.StalePolicy)
+		for name := range r.StaleClusters {
+			stale.Insert(name)
+		}
+	}
+	if policies.Len() == 0 {
+		return metav1.Condition{}, false
+	}
+
+	if stale.Len() == 0 {
+		return metav1.Condition{
+			Type:    PlacementConditionStaleScores,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ScoresUpToDate",
+			Message: "The scores of all the feasible clusters are up to date",
+		}, true
+	}
+
+	names := stale.List()
+	if len(names) > maxStaleClustersInMessage {
+		names = append(names[:maxStaleClustersInMessage], fmt.Sprintf("and %d more", stale.Len()-maxStaleClustersInMessage))
+	}
+	return metav1.Condition{
+		Type:   PlacementConditionStaleScores,
+		Status: metav1.ConditionTrue,
+		Reason: "ScoresStale",
+		Message: fmt.Sprintf("The scores of clusters [%s] are missing or expired, scored with stale score policy %s",
+			strings.Join(names, ","), strings.Join(policies.List(), ",")),
+	}, true
+}
+
 func newMisconfiguredCondition(status *framework.Status) metav1.Condition {
 	if status.Code() == framework.Misconfigured {
 		return metav1.Condition{
@@ -429,39 +836,47 @@ func newMisconfiguredCondition(status *framework.Status) metav1.Condition {
 }
 
 // bind updates the cluster decisions in the status of the placementdecisions with the given
-// cluster decision slice. New placementdecisions will be created if no one exists.
+// decision groups. The cluster decisions of each group are split into slices, and each slice
+// is bound to a placementdecision labeled with the group index and name. New placementdecisions
+// will be created if no one exists.
 func (c *schedulingController) bind(
 	ctx context.Context,
 	placement *clusterapiv1beta1.Placement,
-	clusterDecisions []clusterapiv1beta1.ClusterDecision,
+	decisionGroups []clusterDecisionGroup,
 	clusterScores PrioritizerScore,
 	status *framework.Status,
 ) error {
-	// sort clusterdecisions by cluster name
-	sort.SliceStable(clusterDecisions, func(i, j int) bool {
-		return clusterDecisions[i].ClusterName < clusterDecisions[j].ClusterName
-	})
-
-	// split the cluster decisions into slices, the size of each slice cannot exceed
-	// maxNumOfClusterDecisions.
+	// split the cluster decisions of each group into slices, the size of each slice cannot
+	// exceed maxNumOfClusterDecisions.
 	decisionSlices := [][]clusterapiv1beta1.ClusterDecision{}
-	remainingDecisions := clusterDecisions
-	for index := 0; len(remainingDecisions) > 0; index++ {
-		var decisionSlice []clusterapiv1beta1.ClusterDecision
-		switch {
-		case len(remainingDecisions) > maxNumOfClusterDecisions:
-			decisionSlice = remainingDecisions[0:maxNumOfClusterDecisions]
-			remainingDecisions = remainingDecisions[maxNumOfClusterDecisions:]
-		default:
-			decisionSlice = remainingDecisions
-			remainingDecisions = nil
+	decisionSliceGroups := []clusterDecisionGroup{}
+	for _, group := range decisionGroups {
+		// sort clusterdecisions by cluster name
+		clusterDecisions := group.clusterDecisions
+		sort.SliceStable(clusterDecisions, func(i, j int) bool {
+			return clusterDecisions[i].ClusterName < clusterDecisions[j].ClusterName
+		})
+
+		remainingDecisions := clusterDecisions
+		for index := 0; len(remainingDecisions) > 0; index++ {
+			var decisionSlice []clusterapiv1beta1.ClusterDecision
+			switch {
+			case len(remainingDecisions) > maxNumOfClusterDecisions:
+				decisionSlice = remainingDecisions[0:maxNumOfClusterDecisions]
+				remainingDecisions = remainingDecisions[maxNumOfClusterDecisions:]
+			default:
+				decisionSlice = remainingDecisions
+				remainingDecisions = nil
+			}
+			decisionSlices = append(decisionSlices, decisionSlice)
+			decisionSliceGroups = append(decisionSliceGroups, group)
 		}
-		decisionSlices = append(decisionSlices, decisionSlice)
 	}
 	// if decisionSlices is empty, append one empty slice.
 	// so that can create a PlacementDecision with empty decisions in status.
 	if len(decisionSlices) == 0 {
 		decisionSlices = append(decisionSlices, []clusterapiv1beta1.ClusterDecision{})
+		decisionSliceGroups = append(decisionSliceGroups, clusterDecisionGroup{})
 	}
 
 	// bind cluster decision slices to placementdecisions.
@@ -472,7 +887,7 @@ func (c *schedulingController) bind(
 		placementDecisionName := fmt.Sprintf("%s-decision-%d", placement.Name, index+1)
 		placementDecisionNames.Insert(placementDecisionName)
 		err := c.createOrUpdatePlacementDecision(
-			ctx, placement, placementDecisionName, decisionSlice, clusterScores, status)
+			ctx, placement, placementDecisionName, decisionSliceGroups[index], decisionSlice, clusterScores, status)
 		if err != nil {
 			errs = append(errs, err)
 		}
@@ -514,12 +929,14 @@ func (c *schedulingController) bind(
 	return errorhelpers.NewMultiLineAggregate(errs)
 }
 
-// createOrUpdatePlacementDecision creates a new PlacementDecision if it does not exist and
-// then updates the status with the given ClusterDecision slice if necessary
+// createOrUpdatePlacementDecision creates a new PlacementDecision if it does not exist, updates
+// the decision group labels and then updates the status with the given ClusterDecision slice
+// if necessary
 func (c *schedulingController) createOrUpdatePlacementDecision(
 	ctx context.Context,
 	placement *clusterapiv1beta1.Placement,
 	placementDecisionName string,
+	decisionGroup clusterDecisionGroup,
 	clusterDecisions []clusterapiv1beta1.ClusterDecision,
 	clusterScores PrioritizerScore,
 	status *framework.Status,
@@ -539,6 +956,8 @@ func (c *schedulingController) createOrUpdatePlacementDecision(
 				Namespace: placement.Namespace,
 				Labels: map[string]string{
 					clusterapiv1beta1.PlacementLabel: placement.Name,
+					DecisionGroupIndexLabel:          strconv.Itoa(decisionGroup.groupIndex),
+					DecisionGroupNameLabel:           decisionGroup.groupName,
 				},
 				OwnerReferences: []metav1.OwnerReference{*owner},
 			},
@@ -557,6 +976,23 @@ func (c *schedulingController) createOrUpdatePlacementDecision(
 		return err
 	}
 
+	// update the decision group labels of the placementdecision if the group changes
+	groupIndex := strconv.Itoa(decisionGroup.groupIndex)
+	if value, ok := placementDecision.Labels[DecisionGroupIndexLabel]; !ok || value != groupIndex ||
+		placementDecision.Labels[DecisionGroupNameLabel] != decisionGroup.groupName {
+		newPlacementDecision := placementDecision.DeepCopy()
+		if newPlacementDecision.Labels == nil {
+			newPlacementDecision.Labels = map[string]string{}
+		}
+		newPlacementDecision.Labels[DecisionGroupIndexLabel] = groupIndex
+		newPlacementDecision.Labels[DecisionGroupNameLabel] = decisionGroup.groupName
+		placementDecision, err = c.clusterClient.ClusterV1beta1().PlacementDecisions(newPlacementDecision.Namespace).
+			Update(ctx, newPlacementDecision, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
 	// update the status of the placementdecision if decisions change
 	if apiequality.Semantic.DeepEqual(placementDecision.Status.Decisions, clusterDecisions) {
 		return nil
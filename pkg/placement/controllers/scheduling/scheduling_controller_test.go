@@ -3,16 +3,23 @@ package scheduling
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 	kevents "k8s.io/client-go/tools/events"
+	"k8s.io/component-base/metrics/testutil"
 
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
@@ -21,6 +28,8 @@ import (
 
 	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
 	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/controllers/metrics"
+	"open-cluster-management.io/ocm/pkg/placement/controllers/sharding"
 	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
 	"open-cluster-management.io/ocm/test/integration/util"
 )
@@ -265,6 +274,8 @@ func TestSchedulingController_sync(t *testing.T) {
 				testinghelpers.NewManagedCluster("cluster1").WithLabel(clusterapiv1beta2.ClusterSetLabel, "clusterset1").Build(),
 				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 1)).
 					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
 					WithDecisions("cluster1", "cluster2", "cluster3").Build(),
 			},
 			scheduleResult: &scheduleResult{
@@ -307,6 +318,83 @@ func TestSchedulingController_sync(t *testing.T) {
 			clusterInformerFactory := newClusterInformerFactory(clusterClient, c.initObjs...)
 			s := &testScheduler{result: c.scheduleResult}
 
+			ctrl := schedulingController{
+				clusterClient:            clusterClient,
+				clusterLister:            clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				clusterSetLister:         clusterInformerFactory.Cluster().V1beta2().ManagedClusterSets().Lister(),
+				clusterSetBindingLister:  clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings().Lister(),
+				placementLister:          clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+				placementDecisionLister:  clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+				placementDecisionIndexer: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetIndexer(),
+				scheduler:                s,
+				retainer:                 newDecisionRetainer(),
+				recorder:                 kevents.NewFakeRecorder(100),
+			}
+
+			sysCtx := testingcommon.NewFakeSyncContext(t, c.placement.Namespace+"/"+c.placement.Name)
+			syncErr := ctrl.sync(context.TODO(), sysCtx)
+			if syncErr != nil {
+				t.Errorf("unexpected err: %v", syncErr)
+			}
+
+			c.validateActions(t, clusterClient.Actions())
+		})
+	}
+}
+
+type fakeShardOwner struct {
+	namespaces sets.String
+	// checksBeforeMoved is the number of ownership checks after which the namespaces move to another shard,
+	// the namespaces never move if it is 0.
+	checksBeforeMoved int
+	checks            int
+}
+
+func (o *fakeShardOwner) Owns(namespace string) bool {
+	o.checks++
+	if o.checksBeforeMoved > 0 && o.checks > o.checksBeforeMoved {
+		return false
+	}
+	return o.namespaces.Has(namespace)
+}
+
+func (o *fakeShardOwner) OnRebalance(handler func()) {}
+
+func TestSchedulingControllerWithShardOwner(t *testing.T) {
+	cases := []struct {
+		name              string
+		placement         *clusterapiv1beta1.Placement
+		checksBeforeMoved int
+		expectedActions   []string
+	}{
+		{
+			name:            "placement in an owned namespace",
+			placement:       testinghelpers.NewPlacement("ns1", "placement1").Build(),
+			expectedActions: []string{"create", "update"},
+		},
+		{
+			name:      "placement in a namespace owned by another shard",
+			placement: testinghelpers.NewPlacement("ns2", "placement1").Build(),
+		},
+		{
+			name:              "namespace moved to another shard before binding",
+			placement:         testinghelpers.NewPlacement("ns1", "placement1").Build(),
+			checksBeforeMoved: 1,
+		},
+		{
+			name:              "namespace moved to another shard before updating status",
+			placement:         testinghelpers.NewPlacement("ns1", "placement1").Build(),
+			checksBeforeMoved: 2,
+			expectedActions:   []string{"create"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			initObjs := []runtime.Object{c.placement}
+			clusterClient := clusterfake.NewSimpleClientset(initObjs...)
+			clusterInformerFactory := newClusterInformerFactory(clusterClient, initObjs...)
+
 			ctrl := schedulingController{
 				clusterClient:           clusterClient,
 				clusterLister:           clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
@@ -314,19 +402,159 @@ func TestSchedulingController_sync(t *testing.T) {
 				clusterSetBindingLister: clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings().Lister(),
 				placementLister:         clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
 				placementDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
-				scheduler:               s,
+				scheduler:               &testScheduler{result: &scheduleResult{}},
+				retainer:                newDecisionRetainer(),
 				recorder:                kevents.NewFakeRecorder(100),
+				shardOwner:              &fakeShardOwner{namespaces: sets.NewString("ns1"), checksBeforeMoved: c.checksBeforeMoved},
 			}
 
-			sysCtx := testingcommon.NewFakeSyncContext(t, c.placement.Namespace+"/"+c.placement.Name)
-			syncErr := ctrl.sync(context.TODO(), sysCtx)
-			if syncErr != nil {
-				t.Errorf("unexpected err: %v", syncErr)
+			syncCtx := testingcommon.NewFakeSyncContext(t, c.placement.Namespace+"/"+c.placement.Name)
+			if err := ctrl.sync(context.TODO(), syncCtx); err != nil {
+				t.Errorf("unexpected err: %v", err)
 			}
+			testingcommon.AssertActions(t, clusterClient.Actions(), c.expectedActions...)
+		})
+	}
+}
 
-			c.validateActions(t, clusterClient.Actions())
+// recordScheduler selects all the given clusters and records them by the placement key.
+type recordScheduler struct {
+	clusters map[string][]string
+}
+
+func (s *recordScheduler) Schedule(ctx context.Context,
+	placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster,
+) (ScheduleResult, *framework.Status) {
+	result := &scheduleResult{feasibleClusters: clusters}
+	names := []string{}
+	for _, cluster := range clusters {
+		names = append(names, cluster.Name)
+		result.scheduledDecisions = append(result.scheduledDecisions, clusterapiv1beta1.ClusterDecision{ClusterName: cluster.Name})
+	}
+	s.clusters[placement.Namespace+"/"+placement.Name] = names
+	return result, nil
+}
+
+func TestSchedulingControllerWithShardManagers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	leaseLister := kubeInformerFactory.Coordination().V1().Leases().Lister()
+	managers := []*sharding.ShardManager{
+		sharding.NewShardManager("replica1", "hub", time.Second, kubeClient.CoordinationV1(), leaseLister),
+		sharding.NewShardManager("replica2", "hub", time.Second, kubeClient.CoordinationV1(), leaseLister),
+	}
+	kubeInformerFactory.Start(ctx.Done())
+	kubeInformerFactory.WaitForCacheSync(ctx.Done())
+	for _, m := range managers {
+		go m.Run(ctx)
+	}
+
+	// wait until each replica owns a namespace
+	owned := make([]string, len(managers))
+	err := wait.PollImmediate(100*time.Millisecond, 30*time.Second, func() (bool, error) {
+		for i, m := range managers {
+			owned[i] = ""
+			for j := 0; j < 100 && len(owned[i]) == 0; j++ {
+				if ns := fmt.Sprintf("ns%d", j); m.Owns(ns) {
+					owned[i] = ns
+				}
+			}
+			if len(owned[i]) == 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("expected each replica owns a namespace: %v", err)
+	}
+	nsA, nsB := owned[0], owned[1]
+
+	// cluster1 has only 1 slot, which is taken by placement low in nsB
+	initObjs := []runtime.Object{
+		testinghelpers.NewClusterSet("global").Build(),
+		testinghelpers.NewClusterSetBinding(nsA, "global"),
+		testinghelpers.NewClusterSetBinding(nsB, "global"),
+		testinghelpers.NewManagedCluster("cluster1").WithLabel(clusterapiv1beta2.ClusterSetLabel, "global").
+			WithLabel(PlacementSlotsLabel, "1").Build(),
+		testinghelpers.NewPlacement(nsA, "plain").Build(),
+		testinghelpers.NewPlacement(nsB, "low").Build(),
+		testinghelpers.NewPlacementWithAnnotations(nsB, "high", map[string]string{PlacementPriorityAnnotation: "10"}).Build(),
+		testinghelpers.NewPlacementDecision(nsB, placementDecisionName("low", 1)).
+			WithLabel(clusterapiv1beta1.PlacementLabel, "low").WithDecisions("cluster1").Build(),
+	}
+
+	clusterClients := []*clusterfake.Clientset{}
+	schedulers := []*recordScheduler{}
+	ctrls := []*schedulingController{}
+	for _, m := range managers {
+		clusterClient := clusterfake.NewSimpleClientset(initObjs...)
+		clusterInformerFactory := newClusterInformerFactory(clusterClient, initObjs...)
+		s := &recordScheduler{clusters: map[string][]string{}}
+		clusterClients = append(clusterClients, clusterClient)
+		schedulers = append(schedulers, s)
+		ctrls = append(ctrls, &schedulingController{
+			clusterClient:            clusterClient,
+			clusterLister:            clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+			clusterSetLister:         clusterInformerFactory.Cluster().V1beta2().ManagedClusterSets().Lister(),
+			clusterSetBindingLister:  clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings().Lister(),
+			placementLister:          clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+			placementDecisionLister:  clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+			placementDecisionIndexer: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetIndexer(),
+			scheduler:                s,
+			retainer:                 newDecisionRetainer(),
+			recorder:                 kevents.NewFakeRecorder(100),
+			shardOwner:               m,
 		})
 	}
+
+	for i, ctrl := range ctrls {
+		for _, key := range []string{nsA + "/plain", nsB + "/high"} {
+			syncCtx := testingcommon.NewFakeSyncContext(t, key)
+			// the misconfigured placement is reported with an error by its owner
+			err := ctrl.sync(context.TODO(), syncCtx)
+			if expectErr := key == nsB+"/high" && i == 1; (err != nil) != expectErr {
+				t.Errorf("expected error %v of %s synced by replica%d, but got %v", expectErr, key, i+1, err)
+			}
+			// nothing is preempted by the high priority placement
+			if syncCtx.Queue().Len() != 0 {
+				t.Errorf("expected no placement queued by replica%d, but got %d", i+1, syncCtx.Queue().Len())
+			}
+		}
+	}
+
+	// each replica only writes the placements in its own namespace
+	for i, clusterClient := range clusterClients {
+		for _, action := range clusterClient.Actions() {
+			if action.GetNamespace() != owned[i] {
+				t.Errorf("expected replica%d only writes namespace %s, but got %v", i+1, owned[i], action)
+			}
+		}
+	}
+
+	// the slots are not counted, so the placement in nsA selects cluster1 with its slot taken
+	if actual := schedulers[0].clusters[nsA+"/plain"]; !reflect.DeepEqual(actual, []string{"cluster1"}) {
+		t.Errorf("expected placement %s/plain is scheduled with cluster1, but got %v", nsA, actual)
+	}
+
+	// the placement with priority is misconfigured in sharded mode
+	misconfigured := false
+	for _, action := range clusterClients[1].Actions() {
+		update, ok := action.(clienttesting.UpdateActionImpl)
+		if !ok || action.GetSubresource() != "status" {
+			continue
+		}
+		if placement, ok := update.Object.(*clusterapiv1beta1.Placement); ok && placement.Name == "high" {
+			misconfigured = meta.IsStatusConditionTrue(placement.Status.Conditions, clusterapiv1beta1.PlacementConditionMisconfigured)
+		}
+	}
+	if !misconfigured {
+		t.Errorf("expected placement %s/high is misconfigured, but got actions %v", nsB, clusterClients[1].Actions())
+	}
 }
 
 func TestGetValidManagedClusterSetBindings(t *testing.T) {
@@ -589,15 +817,16 @@ func TestGetAvailableClusters(t *testing.T) {
 
 func TestNewSatisfiedCondition(t *testing.T) {
 	cases := []struct {
-		name                      string
-		clusterSetsInSpec         []string
-		eligibleClusterSets       []string
-		numOfBindings             int
-		numOfAvailableClusters    int
-		numOfFeasibleClusters     int
-		numOfUnscheduledDecisions int
-		expectedStatus            metav1.ConditionStatus
-		expectedReason            string
+		name                         string
+		clusterSetsInSpec            []string
+		eligibleClusterSets          []string
+		numOfBindings                int
+		numOfAvailableClusters       int
+		numOfFeasibleClusters        int
+		numOfUnscheduledDecisions    int
+		unsatisfiedSpreadConstraints []string
+		expectedStatus               metav1.ConditionStatus
+		expectedReason               string
 	}{
 		{
 			name:                      "NoManagedClusterSetBindings",
@@ -656,6 +885,28 @@ func TestNewSatisfiedCondition(t *testing.T) {
 			expectedStatus:            metav1.ConditionFalse,
 			expectedReason:            "NotAllDecisionsScheduled",
 		},
+		{
+			name:                         "SpreadConstraintsUnsatisfiable",
+			eligibleClusterSets:          []string{"clusterset1"},
+			numOfBindings:                1,
+			numOfAvailableClusters:       3,
+			numOfFeasibleClusters:        2,
+			numOfUnscheduledDecisions:    1,
+			unsatisfiedSpreadConstraints: []string{"region"},
+			expectedStatus:               metav1.ConditionFalse,
+			expectedReason:               "SpreadConstraintsUnsatisfiable",
+		},
+		{
+			name:                         "AllDecisionsScheduled with spread constraints not satisfied",
+			eligibleClusterSets:          []string{"clusterset1"},
+			numOfBindings:                1,
+			numOfAvailableClusters:       3,
+			numOfFeasibleClusters:        3,
+			numOfUnscheduledDecisions:    0,
+			unsatisfiedSpreadConstraints: []string{"region"},
+			expectedStatus:               metav1.ConditionTrue,
+			expectedReason:               "AllDecisionsScheduled",
+		},
 	}
 
 	for _, c := range cases {
@@ -667,6 +918,7 @@ func TestNewSatisfiedCondition(t *testing.T) {
 				c.numOfAvailableClusters,
 				c.numOfFeasibleClusters,
 				c.numOfUnscheduledDecisions,
+				c.unsatisfiedSpreadConstraints,
 				nil,
 			)
 
@@ -727,6 +979,133 @@ func TestNewMisconfiguredCondition(t *testing.T) {
 	}
 }
 
+func TestNewStaleScoresCondition(t *testing.T) {
+	manyStale := map[string]string{}
+	for i := 0; i < 12; i++ {
+		manyStale[fmt.Sprintf("cluster%02d", i)] = "expired"
+	}
+
+	cases := []struct {
+		name            string
+		results         []PrioritizerResult
+		expectedOK      bool
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:    "no addon prioritizer",
+			results: []PrioritizerResult{{Name: "Balance"}, {Name: "Steady"}},
+		},
+		{
+			name:            "scores up to date",
+			results:         []PrioritizerResult{{Name: "AddOn/demo/demo", StalePolicy: "Zero"}},
+			expectedOK:      true,
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "ScoresUpToDate",
+			expectedMessage: "The scores of all the feasible clusters are up to date",
+		},
+		{
+			name: "stale scores of multiple prioritizers",
+			results: []PrioritizerResult{
+				{Name: "AddOn/demo/demo", StalePolicy: "Min", StaleClusters: map[string]string{"cluster2": "expired"}},
+				{Name: "AddOn/demo/other", StalePolicy: "Min", StaleClusters: map[string]string{"cluster1": "missing"}},
+			},
+			expectedOK:     true,
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "ScoresStale",
+			expectedMessage: "The scores of clusters [cluster1,cluster2] are missing or expired, " +
+				"scored with stale score policy Min",
+		},
+		{
+			name: "too many stale clusters",
+			results: []PrioritizerResult{
+				{Name: "AddOn/demo/demo", StalePolicy: "Exclude", StaleClusters: manyStale},
+			},
+			expectedOK:     true,
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "ScoresStale",
+			expectedMessage: "The scores of clusters [cluster00,cluster01,cluster02,cluster03,cluster04,cluster05," +
+				"cluster06,cluster07,cluster08,cluster09,and 2 more] are missing or expired, scored with stale score policy Exclude",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			condition, ok := newStaleScoresCondition(c.results)
+			if ok != c.expectedOK {
+				t.Fatalf("expected %v but got %v", c.expectedOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if condition.Status != c.expectedStatus {
+				t.Errorf("expected status %q but got %q", c.expectedStatus, condition.Status)
+			}
+			if condition.Reason != c.expectedReason {
+				t.Errorf("expected reason %q but got %q", c.expectedReason, condition.Reason)
+			}
+			if condition.Message != c.expectedMessage {
+				t.Errorf("expected message %q but got %q", c.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
+
+func TestExcludedClustersMessage(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+	}
+
+	cases := []struct {
+		name            string
+		previous        []string
+		result          *scheduleResult
+		expectedMessage string
+	}{
+		{
+			name:     "no cluster excluded",
+			previous: []string{"cluster1"},
+			result: &scheduleResult{
+				scheduledDecisions: newClusterDecisionsFromNames("cluster1"),
+			},
+		},
+		{
+			name:     "clusters excluded",
+			previous: []string{"cluster1", "cluster2", "cluster3", "cluster4"},
+			result: &scheduleResult{
+				scheduledDecisions: newClusterDecisionsFromNames("cluster1"),
+				excluded: []ExcludedCluster{
+					{ClusterName: "cluster2", Plugin: "Predicate", Reason: `label selector "cloud=Amazon" not matched`},
+				},
+			},
+			expectedMessage: `cluster2(Predicate: label selector "cloud=Amazon" not matched) cluster3(not selected) ` +
+				`cluster4(not in the bound clustersets)`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			message := excludedClustersMessage(sets.NewString(c.previous...), clusters, c.result.Decisions(), c.result.ExcludedClusters())
+			if message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, message)
+			}
+		})
+	}
+
+	// the message is capped
+	previous := sets.NewString()
+	for i := 0; i < 200; i++ {
+		previous.Insert(fmt.Sprintf("cluster-%d", i))
+	}
+	message := excludedClustersMessage(previous, clusters, nil, nil)
+	if len(message) > maxEventMessageLength+len("......") || !strings.HasSuffix(message, "......") {
+		t.Errorf("expected message capped, but got %d characters", len(message))
+	}
+}
+
 func TestBind(t *testing.T) {
 	placementNamespace := "ns1"
 	placementName := "placement1"
@@ -792,9 +1171,13 @@ func TestBind(t *testing.T) {
 			initObjs: []runtime.Object{
 				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 1)).
 					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
 					WithDecisions(newSelectedClusters(128)[:100]...).Build(),
 				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 2)).
 					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
 					WithDecisions(newSelectedClusters(128)[100:]...).Build(),
 			},
 			validateActions: testingcommon.AssertNoActions,
@@ -805,6 +1188,8 @@ func TestBind(t *testing.T) {
 			initObjs: []runtime.Object{
 				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 1)).
 					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
 					WithDecisions(newSelectedClusters(128)[:100]...).Build(),
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
@@ -824,9 +1209,13 @@ func TestBind(t *testing.T) {
 			initObjs: []runtime.Object{
 				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 1)).
 					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
 					WithDecisions(newSelectedClusters(128)[:100]...).Build(),
 				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 2)).
 					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
 					WithDecisions(newSelectedClusters(128)[100:]...).Build(),
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
@@ -845,9 +1234,13 @@ func TestBind(t *testing.T) {
 			initObjs: []runtime.Object{
 				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 1)).
 					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
 					WithDecisions(newSelectedClusters(128)[:100]...).Build(),
 				testinghelpers.NewPlacementDecision(placementNamespace, placementDecisionName(placementName, 2)).
 					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithLabel(DecisionGroupIndexLabel, "0").
+					WithLabel(DecisionGroupNameLabel, "").
 					WithDecisions(newSelectedClusters(128)[100:]...).Build(),
 			},
 			validateActions: func(t *testing.T, actions []clienttesting.Action) {
@@ -898,7 +1291,7 @@ func TestBind(t *testing.T) {
 			err := ctrl.bind(
 				context.TODO(),
 				testinghelpers.NewPlacement(placementNamespace, placementName).Build(),
-				c.clusterDecisions,
+				[]clusterDecisionGroup{{clusterDecisions: c.clusterDecisions}},
 				nil,
 				nil,
 			)
@@ -910,6 +1303,71 @@ func TestBind(t *testing.T) {
 	}
 }
 
+func TestRecordPlacementMetrics(t *testing.T) {
+	placement := testinghelpers.NewPlacement("ns1", "metrics").WithNOC(2).Build()
+	defer metrics.DeletePlacementMetrics(placement.Namespace, placement.Name)
+
+	result := &scheduleResult{
+		scoreSum: PrioritizerScore{"cluster1": 100, "cluster2": 50, "cluster3": 0},
+	}
+	decisions := newClusterDecisionsFromNames("cluster1", "cluster2")
+	recordPlacementMetrics(placement, sets.NewString("cluster2", "cluster4", "cluster5"), result, decisions, 1)
+
+	cases := []struct {
+		name     string
+		metric   func() (float64, error)
+		expected float64
+	}{
+		{
+			name: "feasible",
+			metric: func() (float64, error) {
+				return testutil.GetGaugeMetricValue(metrics.PlacementClusters.WithLabelValues("ns1", "metrics", metrics.ClustersFeasible))
+			},
+			expected: 3,
+		},
+		{
+			name: "selected",
+			metric: func() (float64, error) {
+				return testutil.GetGaugeMetricValue(metrics.PlacementClusters.WithLabelValues("ns1", "metrics", metrics.ClustersSelected))
+			},
+			expected: 2,
+		},
+		{
+			name: "unscheduled",
+			metric: func() (float64, error) {
+				return testutil.GetGaugeMetricValue(metrics.PlacementClusters.WithLabelValues("ns1", "metrics", metrics.ClustersUnscheduled))
+			},
+			expected: 1,
+		},
+		{
+			name: "added",
+			metric: func() (float64, error) {
+				return testutil.GetCounterMetricValue(metrics.DecisionsAdded.WithLabelValues("ns1", "metrics"))
+			},
+			expected: 1,
+		},
+		{
+			name: "removed",
+			metric: func() (float64, error) {
+				return testutil.GetCounterMetricValue(metrics.DecisionsRemoved.WithLabelValues("ns1", "metrics"))
+			},
+			expected: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := c.metric()
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
 func assertClustersSelected(t *testing.T, decisons []clusterapiv1beta1.ClusterDecision, clusterNames ...string) {
 	names := sets.NewString(clusterNames...)
 	for _, decision := range decisons {
@@ -0,0 +1,239 @@
+package scheduling
+
+import (
+	"fmt"
+	"sort"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+)
+
+const spreadPolicyName = "SpreadPolicy"
+
+// SpreadResult defines the result of one spread constraint, include the topology domains
+// of the selected clusters and whether the constraint is satisfied.
+type SpreadResult struct {
+	TopologyKey       string                                       `json:"topologyKey"`
+	TopologyKeyType   clusterapiv1beta1.TopologyKeyType            `json:"topologyKeyType"`
+	MaxSkew           int32                                        `json:"maxSkew"`
+	WhenUnsatisfiable clusterapiv1beta1.UnsatisfiableMaxSkewAction `json:"whenUnsatisfiable"`
+	// Domains contains the number of selected clusters in each topology domain.
+	Domains   map[string]int `json:"domains"`
+	Skew      int            `json:"skew"`
+	Satisfied bool           `json:"satisfied"`
+}
+
+// spreadConstraint tracks the selected clusters in each topology domain of a spread
+// constraint during the cluster selection.
+type spreadConstraint struct {
+	term clusterapiv1beta1.SpreadConstraintsTerm
+	// counts contains the number of selected clusters in each domain. All domains of the
+	// candidate clusters are initialized with 0.
+	counts map[string]int
+	// blocked is true if the constraint prevents a cluster from being selected while
+	// the decisions are not fulfilled yet.
+	blocked bool
+}
+
+// validateSpreadPolicy returns a Misconfigured status if any spread constraint of the
+// placement is invalid.
+func validateSpreadPolicy(placement *clusterapiv1beta1.Placement) *framework.Status {
+	for _, term := range placement.Spec.SpreadPolicy.SpreadConstraints {
+		if len(term.TopologyKey) == 0 {
+			return framework.NewStatus(spreadPolicyName, framework.Misconfigured, "topologyKey of spread constraint is required")
+		}
+		if term.TopologyKeyType != clusterapiv1beta1.TopologyKeyTypeLabel && term.TopologyKeyType != clusterapiv1beta1.TopologyKeyTypeClaim {
+			return framework.NewStatus(spreadPolicyName, framework.Misconfigured,
+				fmt.Sprintf("incorrect topologyKeyType %q of spread constraint %s", term.TopologyKeyType, term.TopologyKey))
+		}
+		if term.MaxSkew < 0 {
+			return framework.NewStatus(spreadPolicyName, framework.Misconfigured,
+				fmt.Sprintf("maxSkew of spread constraint %s should not be negative", term.TopologyKey))
+		}
+		switch term.WhenUnsatisfiable {
+		case "", clusterapiv1beta1.DoNotSchedule, clusterapiv1beta1.ScheduleAnyway:
+		default:
+			return framework.NewStatus(spreadPolicyName, framework.Misconfigured,
+				fmt.Sprintf("incorrect whenUnsatisfiable %q of spread constraint %s", term.WhenUnsatisfiable, term.TopologyKey))
+		}
+	}
+	return framework.NewStatus(spreadPolicyName, framework.Success, "")
+}
+
+// withSpreadDefaults returns the spread constraint term with the API defaults applied, an
+// unset maxSkew is 1 and an unset whenUnsatisfiable is ScheduleAnyway.
+func withSpreadDefaults(term clusterapiv1beta1.SpreadConstraintsTerm) clusterapiv1beta1.SpreadConstraintsTerm {
+	if term.MaxSkew == 0 {
+		term.MaxSkew = 1
+	}
+	if len(term.WhenUnsatisfiable) == 0 {
+		term.WhenUnsatisfiable = clusterapiv1beta1.ScheduleAnyway
+	}
+	return term
+}
+
+// selectClustersWithSpread selects clusters from the given sorted cluster slice without breaking
+// the spread constraints of the placement. Clusters are visited in order, a cluster which would make
+// the skew of a constraint exceed its maxSkew is skipped and revisited once more clusters are
+// selected. DoNotSchedule constraints are never broken, while ScheduleAnyway constraints are only
+// broken if the desired number of decisions cannot be fulfilled otherwise. It also returns the
+// reasons of the clusters blocked by the constraints, keyed by cluster name.
+func selectClustersWithSpread(placement *clusterapiv1beta1.Placement, clusters []*clusterapiv1.ManagedCluster) (
+	[]clusterapiv1beta1.ClusterDecision, []SpreadResult, map[string]string) {
+	blockedClusters := map[string]string{}
+	if len(placement.Spec.SpreadPolicy.SpreadConstraints) == 0 {
+		return selectClusters(placement, clusters), nil, blockedClusters
+	}
+
+	numOfDecisions := len(clusters)
+	if placement.Spec.NumberOfClusters != nil {
+		numOfDecisions = int(*placement.Spec.NumberOfClusters)
+	}
+
+	constraints := []*spreadConstraint{}
+	for _, term := range placement.Spec.SpreadPolicy.SpreadConstraints {
+		constraint := &spreadConstraint{term: withSpreadDefaults(term), counts: map[string]int{}}
+		for _, cluster := range clusters {
+			if value, ok := getTopologyValue(cluster, term); ok {
+				constraint.counts[value] = 0
+			}
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	selected := make([]bool, len(clusters))
+	decisions := []clusterapiv1beta1.ClusterDecision{}
+	// select clusters without breaking any constraint first, and then allow to break the
+	// ScheduleAnyway constraints if the decisions are still not fulfilled.
+	for _, strict := range []bool{true, false} {
+		for picked := true; picked && len(decisions) < numOfDecisions; {
+			picked = false
+			for i, cluster := range clusters {
+				if len(decisions) >= numOfDecisions {
+					break
+				}
+				if selected[i] || !canSelectCluster(cluster, constraints, strict) {
+					continue
+				}
+				selected[i], picked = true, true
+				for _, constraint := range constraints {
+					if value, ok := getTopologyValue(cluster, constraint.term); ok {
+						constraint.counts[value]++
+					}
+				}
+				decisions = append(decisions, clusterapiv1beta1.ClusterDecision{
+					ClusterName: cluster.Name,
+				})
+			}
+		}
+	}
+
+	// record the constraints which block the remaining clusters from being selected
+	if len(decisions) < numOfDecisions {
+		for i, cluster := range clusters {
+			if selected[i] {
+				continue
+			}
+			for _, constraint := range constraints {
+				if !constraint.allows(cluster, false) {
+					constraint.blocked = true
+					if _, ok := blockedClusters[cluster.Name]; !ok {
+						blockedClusters[cluster.Name] = fmt.Sprintf("spread constraint %s (%s) with maxSkew %d is not satisfied",
+							constraint.term.TopologyKey, constraint.term.TopologyKeyType, constraint.term.MaxSkew)
+					}
+				}
+			}
+		}
+	}
+
+	results := []SpreadResult{}
+	for _, constraint := range constraints {
+		skew := constraint.skew()
+		results = append(results, SpreadResult{
+			TopologyKey:       constraint.term.TopologyKey,
+			TopologyKeyType:   constraint.term.TopologyKeyType,
+			MaxSkew:           constraint.term.MaxSkew,
+			WhenUnsatisfiable: constraint.term.WhenUnsatisfiable,
+			Domains:           constraint.counts,
+			Skew:              skew,
+			Satisfied:         skew <= int(constraint.term.MaxSkew) && !constraint.blocked,
+		})
+	}
+
+	return decisions, results, blockedClusters
+}
+
+// canSelectCluster returns true if selecting the cluster does not break any of the constraints.
+// If strict is false, the ScheduleAnyway constraints are ignored.
+func canSelectCluster(cluster *clusterapiv1.ManagedCluster, constraints []*spreadConstraint, strict bool) bool {
+	for _, constraint := range constraints {
+		if !constraint.allows(cluster, strict) {
+			return false
+		}
+	}
+	return true
+}
+
+// allows returns true if the skew of the constraint does not exceed maxSkew after the cluster is selected.
+// A cluster without the topology key is not allowed by DoNotSchedule constraints and is ignored by
+// ScheduleAnyway constraints.
+func (c *spreadConstraint) allows(cluster *clusterapiv1.ManagedCluster, strict bool) bool {
+	doNotSchedule := c.term.WhenUnsatisfiable == clusterapiv1beta1.DoNotSchedule
+	value, ok := getTopologyValue(cluster, c.term)
+	if !ok {
+		return !doNotSchedule
+	}
+	if !doNotSchedule && !strict {
+		return true
+	}
+
+	minCount := c.counts[value] + 1
+	for domain, count := range c.counts {
+		if domain != value && count < minCount {
+			minCount = count
+		}
+	}
+	return c.counts[value]+1-minCount <= int(c.term.MaxSkew)
+}
+
+// skew returns the difference between the max and min number of selected clusters among all domains.
+func (c *spreadConstraint) skew() int {
+	if len(c.counts) == 0 {
+		return 0
+	}
+	counts := []int{}
+	for _, count := range c.counts {
+		counts = append(counts, count)
+	}
+	sort.Ints(counts)
+	return counts[len(counts)-1] - counts[0]
+}
+
+// getTopologyValue returns the value of the topology key of the spread constraint on the cluster.
+func getTopologyValue(cluster *clusterapiv1.ManagedCluster, term clusterapiv1beta1.SpreadConstraintsTerm) (string, bool) {
+	switch term.TopologyKeyType {
+	case clusterapiv1beta1.TopologyKeyTypeLabel:
+		value, ok := cluster.Labels[term.TopologyKey]
+		return value, ok
+	case clusterapiv1beta1.TopologyKeyTypeClaim:
+		for _, claim := range cluster.Status.ClusterClaims {
+			if claim.Name == term.TopologyKey {
+				return claim.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// unsatisfiedSpreadConstraints returns the topology keys of the spread constraints not satisfied.
+func unsatisfiedSpreadConstraints(results []SpreadResult) []string {
+	keys := []string{}
+	for _, r := range results {
+		if !r.Satisfied {
+			keys = append(keys, r.TopologyKey)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,160 @@
+package scheduling
+
+import (
+	"reflect"
+	"testing"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+func TestSelectClustersWithSpread(t *testing.T) {
+	placementNamespace := "ns1"
+	placementName := "placement1"
+
+	// clusters are sorted by score
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").WithLabel("region", "east").WithClaim("zone", "a").Build(),
+		testinghelpers.NewManagedCluster("cluster2").WithLabel("region", "east").WithClaim("zone", "b").Build(),
+		testinghelpers.NewManagedCluster("cluster3").WithLabel("region", "east").WithClaim("zone", "a").Build(),
+		testinghelpers.NewManagedCluster("cluster4").WithLabel("region", "west").WithClaim("zone", "b").Build(),
+		testinghelpers.NewManagedCluster("cluster5").Build(),
+	}
+
+	cases := []struct {
+		name                  string
+		placement             *clusterapiv1beta1.Placement
+		expectedDecisions     []string
+		expectedUnsatisfied   []string
+		expectedSpreadResults int
+	}{
+		{
+			name:              "no spread constraints",
+			placement:         testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).Build(),
+			expectedDecisions: []string{"cluster1", "cluster2"},
+		},
+		{
+			name: "spread across regions",
+			placement: testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 1, clusterapiv1beta1.DoNotSchedule).Build(),
+			expectedDecisions:     []string{"cluster1", "cluster4"},
+			expectedUnsatisfied:   []string{},
+			expectedSpreadResults: 1,
+		},
+		{
+			name: "do not schedule when max skew is exceeded",
+			placement: testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(4).
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 1, clusterapiv1beta1.DoNotSchedule).Build(),
+			expectedDecisions:     []string{"cluster1", "cluster4", "cluster2"},
+			expectedUnsatisfied:   []string{"region"},
+			expectedSpreadResults: 1,
+		},
+		{
+			name: "schedule anyway when max skew is exceeded",
+			placement: testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(5).
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 1, clusterapiv1beta1.ScheduleAnyway).Build(),
+			expectedDecisions:     []string{"cluster1", "cluster4", "cluster5", "cluster2", "cluster3"},
+			expectedUnsatisfied:   []string{"region"},
+			expectedSpreadResults: 1,
+		},
+		{
+			name: "spread across regions and zones",
+			placement: testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(3).
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 2, clusterapiv1beta1.DoNotSchedule).
+				AddSpreadConstraint("zone", clusterapiv1beta1.TopologyKeyTypeClaim, 1, clusterapiv1beta1.DoNotSchedule).Build(),
+			expectedDecisions:     []string{"cluster1", "cluster2", "cluster4"},
+			expectedUnsatisfied:   []string{},
+			expectedSpreadResults: 2,
+		},
+		{
+			name: "default max skew and whenUnsatisfiable",
+			placement: testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(5).
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 0, "").Build(),
+			expectedDecisions:     []string{"cluster1", "cluster4", "cluster5", "cluster2", "cluster3"},
+			expectedUnsatisfied:   []string{"region"},
+			expectedSpreadResults: 1,
+		},
+		{
+			name: "all clusters without number of clusters",
+			placement: testinghelpers.NewPlacement(placementNamespace, placementName).
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 2, clusterapiv1beta1.DoNotSchedule).Build(),
+			expectedDecisions:     []string{"cluster1", "cluster2", "cluster4", "cluster3"},
+			expectedUnsatisfied:   []string{"region"},
+			expectedSpreadResults: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			decisions, spreadResults, _ := selectClustersWithSpread(c.placement, clusters)
+
+			actual := []string{}
+			for _, d := range decisions {
+				actual = append(actual, d.ClusterName)
+			}
+			if !reflect.DeepEqual(actual, c.expectedDecisions) {
+				t.Errorf("expected decisions %v, but got %v", c.expectedDecisions, actual)
+			}
+			if len(spreadResults) != c.expectedSpreadResults {
+				t.Errorf("expected %d spread results, but got %d", c.expectedSpreadResults, len(spreadResults))
+			}
+			if c.expectedSpreadResults == 0 {
+				return
+			}
+			if unsatisfied := unsatisfiedSpreadConstraints(spreadResults); !reflect.DeepEqual(unsatisfied, c.expectedUnsatisfied) {
+				t.Errorf("expected unsatisfied constraints %v, but got %v", c.expectedUnsatisfied, unsatisfied)
+			}
+		})
+	}
+}
+
+func TestValidateSpreadPolicy(t *testing.T) {
+	cases := []struct {
+		name         string
+		placement    *clusterapiv1beta1.Placement
+		expectedCode framework.Code
+	}{
+		{
+			name: "valid spread policy",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 1, clusterapiv1beta1.DoNotSchedule).Build(),
+			expectedCode: framework.Success,
+		},
+		{
+			name: "invalid topology key type",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				AddSpreadConstraint("region", "Annotation", 1, clusterapiv1beta1.DoNotSchedule).Build(),
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name: "unset max skew and whenUnsatisfiable",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 0, "").Build(),
+			expectedCode: framework.Success,
+		},
+		{
+			name: "invalid max skew",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, -1, clusterapiv1beta1.DoNotSchedule).Build(),
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name: "invalid whenUnsatisfiable",
+			placement: testinghelpers.NewPlacement("ns1", "placement1").
+				AddSpreadConstraint("region", clusterapiv1beta1.TopologyKeyTypeLabel, 1, "Ignore").Build(),
+			expectedCode: framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			status := validateSpreadPolicy(c.placement)
+			if status.Code() != c.expectedCode {
+				t.Errorf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+		})
+	}
+}
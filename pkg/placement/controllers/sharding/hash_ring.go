@@ -0,0 +1,59 @@
+package sharding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// numOfVirtualNodes is the number of points each member has on the hash ring, which keeps the
+// namespaces evenly distributed among a small number of members.
+const numOfVirtualNodes = 100
+
+// hashRing is a consistent hash ring of the shard members. Only the namespaces on the arcs of a
+// joining or leaving member move to a different member.
+type hashRing struct {
+	hashes  []uint32
+	members map[uint32]string
+}
+
+func newHashRing(members []string) *hashRing {
+	r := &hashRing{members: map[uint32]string{}}
+	for _, member := range members {
+		for i := 0; i < numOfVirtualNodes; i++ {
+			h := hashKey(fmt.Sprintf("%s#%d", member, i))
+			// keep the smaller member name on a hash collision to have a stable ring
+			if existing, ok := r.members[h]; ok && existing < member {
+				continue
+			}
+			if _, ok := r.members[h]; !ok {
+				r.hashes = append(r.hashes, h)
+			}
+			r.members[h] = member
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// owner returns the member owning the key, or an empty string if the ring has no member.
+func (r *hashRing) owner(key string) string {
+	if r == nil || len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := hashKey(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.members[r.hashes[i]]
+}
+
+// hashKey hashes the key with sha256, which spreads the similar keys like namespace names evenly on
+// the ring.
+func hashKey(key string) uint32 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
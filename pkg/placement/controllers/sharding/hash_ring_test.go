@@ -0,0 +1,46 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashRing(t *testing.T) {
+	namespaces := []string{}
+	for i := 0; i < 1000; i++ {
+		namespaces = append(namespaces, fmt.Sprintf("ns%d", i))
+	}
+
+	if owner := newHashRing(nil).owner("ns1"); owner != "" {
+		t.Errorf("expected no owner on an empty ring, but got %q", owner)
+	}
+
+	ring := newHashRing([]string{"replica1", "replica2", "replica3"})
+	counts := map[string]int{}
+	for _, ns := range namespaces {
+		counts[ring.owner(ns)]++
+	}
+	for _, member := range []string{"replica1", "replica2", "replica3"} {
+		// each member should own a reasonable part of the namespaces
+		if counts[member] < 200 {
+			t.Errorf("expected member %s to own at least 200 namespaces, but got %d", member, counts[member])
+		}
+	}
+
+	// the namespaces only move to the joining member
+	joined := newHashRing([]string{"replica1", "replica2", "replica3", "replica4"})
+	for _, ns := range namespaces {
+		before, after := ring.owner(ns), joined.owner(ns)
+		if before != after && after != "replica4" {
+			t.Errorf("expected namespace %s to move to replica4, but moved from %s to %s", ns, before, after)
+		}
+	}
+
+	// the ring does not depend on the order of the members
+	reordered := newHashRing([]string{"replica3", "replica1", "replica2"})
+	for _, ns := range namespaces {
+		if ring.owner(ns) != reordered.owner(ns) {
+			t.Errorf("expected the same owner of namespace %s", ns)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package sharding
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationclientv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	coordinationlisterv1 "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// ShardLeaseLabel is the label on the Leases of the placement controller replicas in sharded mode,
+	// the value is always "true". The replica identity is the holder identity of the Lease.
+	ShardLeaseLabel = "placement.open-cluster-management.io/shard-member"
+
+	// shardLeasePrefix is the prefix of the Lease name of each replica, followed by the replica identity.
+	shardLeasePrefix = "placement-shard-"
+)
+
+// ShardManager coordinates the placement controller replicas running in sharded mode. Each replica
+// holds a Lease with the ShardLeaseLabel, and the replicas with live Leases are the members of a
+// consistent hash ring which assigns each placement namespace to exactly one member.
+//
+// To never have two replicas writing the PlacementDecisions of the same namespace, a replica drops the
+// namespaces it loses as soon as it observes a membership change, while it takes the namespaces it gains
+// only after the lease duration, by when the previous owner has observed the change or its Lease has
+// expired. A replica owns nothing once it fails to renew its own Lease within the lease duration.
+type ShardManager struct {
+	identity      string
+	namespace     string
+	leaseDuration time.Duration
+	leaseClient   coordinationclientv1.LeasesGetter
+	leaseLister   coordinationlisterv1.LeaseLister
+	clock         clock.Clock
+
+	lock        sync.RWMutex
+	members     sets.String
+	current     *hashRing
+	previous    *hashRing
+	changedAt   time.Time
+	settled     bool
+	lastRenew   time.Time
+	onRebalance []func()
+}
+
+// NewShardManager returns a ShardManager of the replica with the identity. The leaseLister should list
+// the Leases with the ShardLeaseLabel in the namespace.
+func NewShardManager(
+	identity, namespace string,
+	leaseDuration time.Duration,
+	leaseClient coordinationclientv1.LeasesGetter,
+	leaseLister coordinationlisterv1.LeaseLister,
+) *ShardManager {
+	return &ShardManager{
+		identity:      identity,
+		namespace:     namespace,
+		leaseDuration: leaseDuration,
+		leaseClient:   leaseClient,
+		leaseLister:   leaseLister,
+		clock:         clock.RealClock{},
+		members:       sets.NewString(),
+	}
+}
+
+// OnRebalance registers a handler called once the namespaces owned by the replica change.
+func (m *ShardManager) OnRebalance(handler func()) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.onRebalance = append(m.onRebalance, handler)
+}
+
+// Owns returns true if the replica owns the namespace and can write the PlacementDecisions in it.
+func (m *ShardManager) Owns(namespace string) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if m.lastRenew.IsZero() || m.clock.Since(m.lastRenew) >= m.leaseDuration {
+		return false
+	}
+	if m.current.owner(namespace) != m.identity {
+		return false
+	}
+	return m.settled || m.previous.owner(namespace) == m.identity
+}
+
+// Run renews the Lease of the replica and refreshes the members periodically until the context is done.
+// The Lease is deleted at last, so the other replicas take over the namespaces without waiting for the
+// Lease to expire.
+func (m *ShardManager) Run(ctx context.Context) {
+	wait.JitterUntilWithContext(ctx, func(ctx context.Context) {
+		if err := m.renew(ctx); err != nil {
+			utilruntime.HandleError(err)
+		}
+		m.refresh()
+	}, m.leaseDuration/3, 0.1, true)
+
+	// the context is done, use a new context to release the lease
+	releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m.release(releaseCtx)
+}
+
+// renew creates or updates the Lease of the replica.
+func (m *ShardManager) renew(ctx context.Context) error {
+	now := m.clock.Now()
+	leaseName := shardLeasePrefix + m.identity
+	lease, err := m.leaseClient.Leases(m.namespace).Get(ctx, leaseName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      leaseName,
+				Namespace: m.namespace,
+				Labels:    map[string]string{ShardLeaseLabel: "true"},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       pointer.String(m.identity),
+				LeaseDurationSeconds: pointer.Int32(int32(m.leaseDuration.Seconds())),
+				AcquireTime:          &metav1.MicroTime{Time: now},
+				RenewTime:            &metav1.MicroTime{Time: now},
+			},
+		}
+		if _, err := m.leaseClient.Leases(m.namespace).Create(ctx, lease, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		lease = lease.DeepCopy()
+		lease.Spec.HolderIdentity = pointer.String(m.identity)
+		lease.Spec.LeaseDurationSeconds = pointer.Int32(int32(m.leaseDuration.Seconds()))
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		if _, err := m.leaseClient.Leases(m.namespace).Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.lastRenew = now
+	return nil
+}
+
+// release deletes the Lease of the replica and gives up all the namespaces.
+func (m *ShardManager) release(ctx context.Context) {
+	m.lock.Lock()
+	m.lastRenew = time.Time{}
+	m.lock.Unlock()
+
+	err := m.leaseClient.Leases(m.namespace).Delete(ctx, shardLeasePrefix+m.identity, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		utilruntime.HandleError(err)
+	}
+}
+
+// refresh rebuilds the hash ring with the replicas having live Leases, and calls the rebalance handlers
+// once the members change or the namespaces gained by the replica are settled.
+func (m *ShardManager) refresh() {
+	members, err := m.liveMembers()
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	m.lock.Lock()
+	rebalance := false
+	switch {
+	case !members.Equal(m.members):
+		klog.Infof("Placement shard members change from %v to %v", m.members.List(), members.List())
+		m.members = members
+		m.previous, m.current = m.current, newHashRing(members.List())
+		m.changedAt = m.clock.Now()
+		m.settled = false
+		rebalance = true
+	case !m.settled && m.clock.Since(m.changedAt) >= m.leaseDuration:
+		m.settled = true
+		rebalance = true
+	}
+	handlers := m.onRebalance
+	m.lock.Unlock()
+
+	if !rebalance {
+		return
+	}
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+// liveMembers returns the identities of the replicas whose Leases are not expired, including the replica
+// itself if its Lease is renewed.
+func (m *ShardManager) liveMembers() (sets.String, error) {
+	leases, err := m.leaseLister.Leases(m.namespace).List(labels.SelectorFromSet(labels.Set{ShardLeaseLabel: "true"}))
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.clock.Now()
+	members := sets.NewString()
+	for _, lease := range leases {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expireTime := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if now.Before(expireTime) {
+			members.Insert(*lease.Spec.HolderIdentity)
+		}
+	}
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if !m.lastRenew.IsZero() && now.Sub(m.lastRenew) < m.leaseDuration {
+		members.Insert(m.identity)
+	}
+	return members, nil
+}
@@ -0,0 +1,151 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	testNamespace     = "open-cluster-management-hub"
+	testLeaseDuration = 30 * time.Second
+)
+
+func newShardLease(identity string, renewTime time.Time) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      shardLeasePrefix + identity,
+			Namespace: testNamespace,
+			Labels:    map[string]string{ShardLeaseLabel: "true"},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       pointer.String(identity),
+			LeaseDurationSeconds: pointer.Int32(int32(testLeaseDuration.Seconds())),
+			RenewTime:            &metav1.MicroTime{Time: renewTime},
+		},
+	}
+}
+
+// ownedNamespaces returns the namespaces owned by the manager in the first 100 namespaces.
+func ownedNamespaces(m *ShardManager) sets.String {
+	owned := sets.NewString()
+	for i := 0; i < 100; i++ {
+		ns := fmt.Sprintf("ns%d", i)
+		if m.Owns(ns) {
+			owned.Insert(ns)
+		}
+	}
+	return owned
+}
+
+func TestShardManager(t *testing.T) {
+	now := time.Now()
+	fakeClock := testingclock.NewFakeClock(now)
+	kubeClient := kubefake.NewSimpleClientset()
+	informers := kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	leaseStore := informers.Coordination().V1().Leases().Informer().GetStore()
+
+	m := NewShardManager("replica1", testNamespace, testLeaseDuration,
+		kubeClient.CoordinationV1(), informers.Coordination().V1().Leases().Lister())
+	m.clock = fakeClock
+	rebalanced := 0
+	m.OnRebalance(func() { rebalanced++ })
+
+	// nothing is owned before the lease is renewed
+	if m.Owns("ns1") {
+		t.Errorf("expected no namespace owned before the lease is renewed")
+	}
+
+	// the lease is created
+	if err := m.renew(context.TODO()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	lease, err := kubeClient.CoordinationV1().Leases(testNamespace).Get(context.TODO(), "placement-shard-replica1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if *lease.Spec.HolderIdentity != "replica1" || lease.Labels[ShardLeaseLabel] != "true" {
+		t.Errorf("unexpected lease %v", lease)
+	}
+
+	// the namespaces are owned after the lease duration
+	m.refresh()
+	if rebalanced != 1 || ownedNamespaces(m).Len() != 0 {
+		t.Errorf("expected no namespace owned before settled, but got %d rebalanced and %v", rebalanced, ownedNamespaces(m).List())
+	}
+	fakeClock.Step(testLeaseDuration)
+	if err := m.renew(context.TODO()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	m.refresh()
+	if rebalanced != 2 || ownedNamespaces(m).Len() != 100 {
+		t.Errorf("expected all namespaces owned, but got %d rebalanced and %d owned", rebalanced, ownedNamespaces(m).Len())
+	}
+
+	// another replica joins, the lost namespaces are dropped immediately and nothing is gained
+	if err := leaseStore.Add(newShardLease("replica2", fakeClock.Now())); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	// an expired lease and a lease without the label are ignored
+	if err := leaseStore.Add(newShardLease("replica3", fakeClock.Now().Add(-testLeaseDuration))); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	unlabeled := newShardLease("leader", fakeClock.Now())
+	unlabeled.Name, unlabeled.Labels = "placement-controller-lock", nil
+	if err := leaseStore.Add(unlabeled); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	m.refresh()
+	if !m.members.Equal(sets.NewString("replica1", "replica2")) {
+		t.Errorf("expected members replica1 and replica2, but got %v", m.members.List())
+	}
+	owned := ownedNamespaces(m)
+	if rebalanced != 3 || owned.Len() == 0 || owned.Len() == 100 {
+		t.Errorf("expected part of the namespaces owned, but got %d rebalanced and %d owned", rebalanced, owned.Len())
+	}
+	for _, ns := range owned.List() {
+		if m.current.owner(ns) != "replica1" {
+			t.Errorf("expected namespace %s owned by replica1 on the ring", ns)
+		}
+	}
+
+	// replica2 leaves, its namespaces are taken over after the lease duration
+	if err := leaseStore.Delete(newShardLease("replica2", fakeClock.Now())); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	m.refresh()
+	if rebalanced != 4 || !ownedNamespaces(m).Equal(owned) {
+		t.Errorf("expected the owned namespaces unchanged before settled, but got %d rebalanced and %d owned",
+			rebalanced, ownedNamespaces(m).Len())
+	}
+	fakeClock.Step(testLeaseDuration)
+	if err := m.renew(context.TODO()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	m.refresh()
+	if rebalanced != 5 || ownedNamespaces(m).Len() != 100 {
+		t.Errorf("expected all namespaces owned, but got %d rebalanced and %d owned", rebalanced, ownedNamespaces(m).Len())
+	}
+
+	// nothing is owned if the lease is not renewed in the lease duration
+	fakeClock.Step(testLeaseDuration)
+	if m.Owns("ns0") {
+		t.Errorf("expected no namespace owned without renewing the lease")
+	}
+
+	// the lease is deleted once released
+	m.release(context.TODO())
+	if _, err := kubeClient.CoordinationV1().Leases(testNamespace).Get(
+		context.TODO(), "placement-shard-replica1", metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the lease deleted")
+	}
+}
@@ -2,17 +2,24 @@ package debugger
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
 
 	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterinformerv1beta1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta1"
+	clusterinformerv1beta2 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta2"
 	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterlisterv1beta1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
+	clusterlisterv1beta2 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta2"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
 	scheduling "open-cluster-management.io/ocm/pkg/placement/controllers/scheduling"
 )
 
@@ -20,30 +27,94 @@ const DebugPath = "/debug/placements/"
 
 // Debugger provides a debug http endpoint for scheduler
 type Debugger struct {
-	scheduler       scheduling.Scheduler
-	clusterLister   clusterlisterv1.ManagedClusterLister
-	placementLister clusterlisterv1beta1.PlacementLister
+	scheduler               scheduling.Scheduler
+	clusterLister           clusterlisterv1.ManagedClusterLister
+	placementLister         clusterlisterv1beta1.PlacementLister
+	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister
+	clusterSetLister        clusterlisterv1beta2.ManagedClusterSetLister
+	clusterSetBindingLister clusterlisterv1beta2.ManagedClusterSetBindingLister
+	// placementDecisionIndexer indexes the placementdecisions by the decided clusters, the index is added
+	// by the scheduling controller.
+	placementDecisionIndexer cache.Indexer
 }
 
 // DebugResult is the result returned by debugger
 type DebugResult struct {
 	FilterResults     []scheduling.FilterResult      `json:"filteredPiplieResults,omitempty"`
 	PrioritizeResults []scheduling.PrioritizerResult `json:"prioritizeResults,omitempty"`
+	SpreadResults     []scheduling.SpreadResult      `json:"spreadResults,omitempty"`
+	ExcludedClusters  []scheduling.ExcludedCluster   `json:"excludedClusters,omitempty"`
+	PinnedClusters    []string                       `json:"pinnedClusters,omitempty"`
 	Error             string                         `json:"error,omitempty"`
 }
 
+// SimulateRequest is the request body posted to debugger to simulate the scheduling of a placement.
+type SimulateRequest struct {
+	// Placement is the placement to schedule. The namespace and name are taken from the
+	// request path, and the placement in the hub is used if the spec is not specified.
+	Placement *clusterapiv1beta1.Placement `json:"placement,omitempty"`
+
+	// Clusters is a hypothetical cluster list which replaces the clusters in the hub.
+	Clusters []clusterapiv1.ManagedCluster `json:"clusters,omitempty"`
+
+	// ClusterOverrides overrides the labels and taints of the clusters before scheduling.
+	ClusterOverrides []ClusterOverride `json:"clusterOverrides,omitempty"`
+}
+
+// ClusterOverride overrides the labels and taints of a cluster.
+type ClusterOverride struct {
+	ClusterName string `json:"clusterName"`
+
+	// Labels are added to the cluster, or replace the existing labels with the same keys.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// RemoveLabels are the keys of the labels removed from the cluster.
+	RemoveLabels []string `json:"removeLabels,omitempty"`
+
+	// Taints replaces the taints of the cluster if specified.
+	Taints *[]clusterapiv1.Taint `json:"taints,omitempty"`
+}
+
+// SimulateResult is the result returned by debugger for a simulated scheduling.
+type SimulateResult struct {
+	DebugResult      `json:",inline"`
+	Decisions        []clusterapiv1beta1.ClusterDecision `json:"decisions,omitempty"`
+	Scores           scheduling.PrioritizerScore         `json:"scores,omitempty"`
+	NumOfUnscheduled int                                 `json:"numOfUnscheduled"`
+	Diff             DecisionDiff                        `json:"diff"`
+}
+
+// DecisionDiff is the difference between the simulated decisions and the current decisions.
+type DecisionDiff struct {
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+	Unchanged []string `json:"unchanged,omitempty"`
+}
+
 func NewDebugger(
 	scheduler scheduling.Scheduler,
 	placementInformer clusterinformerv1beta1.PlacementInformer,
-	clusterInformer clusterinformerv1.ManagedClusterInformer) *Debugger {
+	placementDecisionInformer clusterinformerv1beta1.PlacementDecisionInformer,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	clusterSetInformer clusterinformerv1beta2.ManagedClusterSetInformer,
+	clusterSetBindingInformer clusterinformerv1beta2.ManagedClusterSetBindingInformer) *Debugger {
 	return &Debugger{
-		scheduler:       scheduler,
-		clusterLister:   clusterInformer.Lister(),
-		placementLister: placementInformer.Lister(),
+		scheduler:                scheduler,
+		clusterLister:            clusterInformer.Lister(),
+		placementLister:          placementInformer.Lister(),
+		placementDecisionLister:  placementDecisionInformer.Lister(),
+		clusterSetLister:         clusterSetInformer.Lister(),
+		clusterSetBindingLister:  clusterSetBindingInformer.Lister(),
+		placementDecisionIndexer: placementDecisionInformer.Informer().GetIndexer(),
 	}
 }
 
 func (d *Debugger) Handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		d.simulate(w, r)
+		return
+	}
+
 	namespace, name, err := d.parsePath(r.URL.Path)
 	if err != nil {
 		d.reportErr(w, err)
@@ -56,7 +127,7 @@ func (d *Debugger) Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	clusters, err := d.clusterLister.List(labels.Everything())
+	clusters, capacityExcluded, candidateStatus, err := d.getCandidateClusters(placement, d.clusterLister)
 	if err != nil {
 		d.reportErr(w, err)
 		return
@@ -64,13 +135,198 @@ func (d *Debugger) Handler(w http.ResponseWriter, r *http.Request) {
 
 	scheduleResults, _ := d.scheduler.Schedule(r.Context(), placement, clusters)
 
-	result := DebugResult{FilterResults: scheduleResults.FilterResults(), PrioritizeResults: scheduleResults.PrioritizerResults()}
+	result := DebugResult{
+		FilterResults:     scheduleResults.FilterResults(),
+		PrioritizeResults: scheduleResults.PrioritizerResults(),
+		SpreadResults:     scheduleResults.SpreadResults(),
+		ExcludedClusters:  append(capacityExcluded, scheduleResults.ExcludedClusters()...),
+		PinnedClusters:    scheduleResults.PinnedClusters(),
+	}
+	if candidateStatus.IsError() {
+		result.Error = candidateStatus.AsError().Error()
+	}
+
+	resultByte, _ := json.Marshal(result)
+
+	_, _ = w.Write(resultByte)
+}
+
+// simulate schedules the placement in the request body with the real scheduler and returns the
+// decisions and the difference with the current decisions, nothing is changed in the hub.
+func (d *Debugger) simulate(w http.ResponseWriter, r *http.Request) {
+	namespace, name, err := d.parsePath(r.URL.Path)
+	if err != nil {
+		d.reportErr(w, err)
+		return
+	}
+
+	request := &SimulateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		d.reportErr(w, fmt.Errorf("failed to decode request: %v", err))
+		return
+	}
+
+	placement := request.Placement
+	if placement == nil {
+		placement, err = d.placementLister.Placements(namespace).Get(name)
+		if err != nil {
+			d.reportErr(w, err)
+			return
+		}
+	}
+	placement = placement.DeepCopy()
+	placement.Namespace = namespace
+	placement.Name = name
+
+	// the hypothetical clusters have no resourceVersion, so that the filter results of them are not
+	// cached by the scheduler.
+	clusters := []*clusterapiv1.ManagedCluster{}
+	if len(request.Clusters) > 0 {
+		for i := range request.Clusters {
+			cluster := request.Clusters[i].DeepCopy()
+			cluster.ResourceVersion = ""
+			clusters = append(clusters, cluster)
+		}
+	} else {
+		hubClusters, err := d.clusterLister.List(labels.Everything())
+		if err != nil {
+			d.reportErr(w, err)
+			return
+		}
+		for _, cluster := range hubClusters {
+			clusters = append(clusters, cluster.DeepCopy())
+		}
+	}
+	clusters = overrideClusters(clusters, request.ClusterOverrides)
+
+	// the clusters are selected by the clustersets after the overrides, since the overridden labels may
+	// change the clusterset membership of the clusters.
+	clusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, cluster := range clusters {
+		if err := clusterIndexer.Add(cluster); err != nil {
+			d.reportErr(w, err)
+			return
+		}
+	}
+	clusters, capacityExcluded, candidateStatus, err := d.getCandidateClusters(
+		placement, clusterlisterv1.NewManagedClusterLister(clusterIndexer))
+	if err != nil {
+		d.reportErr(w, err)
+		return
+	}
+
+	currentDecisions, err := d.getCurrentDecisions(namespace, name)
+	if err != nil {
+		d.reportErr(w, err)
+		return
+	}
+
+	scheduleResults, status := d.scheduler.Schedule(r.Context(), placement, clusters)
+	if candidateStatus.IsError() {
+		status = candidateStatus
+	}
+
+	result := SimulateResult{
+		DebugResult: DebugResult{
+			FilterResults:     scheduleResults.FilterResults(),
+			PrioritizeResults: scheduleResults.PrioritizerResults(),
+			SpreadResults:     scheduleResults.SpreadResults(),
+			ExcludedClusters:  append(capacityExcluded, scheduleResults.ExcludedClusters()...),
+			PinnedClusters:    scheduleResults.PinnedClusters(),
+		},
+		Decisions:        scheduleResults.Decisions(),
+		Scores:           scheduleResults.PrioritizerScores(),
+		NumOfUnscheduled: scheduleResults.NumOfUnscheduled(),
+		Diff:             diffDecisions(currentDecisions, scheduleResults.Decisions()),
+	}
+	if status.IsError() {
+		result.Error = status.AsError().Error()
+	}
 
 	resultByte, _ := json.Marshal(result)
 
 	_, _ = w.Write(resultByte)
 }
 
+// getCandidateClusters returns the clusters scheduled for the placement in the same way as the scheduling
+// controller: the clusters of the clustersets bound to the placement namespace, narrowed down to the candidates
+// of a composite placement and the clusters with enough slots for the placement.
+func (d *Debugger) getCandidateClusters(
+	placement *clusterapiv1beta1.Placement,
+	clusterLister clusterlisterv1.ManagedClusterLister,
+) ([]*clusterapiv1.ManagedCluster, []scheduling.ExcludedCluster, *framework.Status, error) {
+	clusters, err := scheduling.GetAvailableClusters(placement, clusterLister, d.clusterSetLister, d.clusterSetBindingLister)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	clusters, compositeStatus := scheduling.GetCompositeClusters(placement, clusters, d.placementLister, d.placementDecisionLister)
+	clusters, excluded, capacityStatus := scheduling.GetClustersWithCapacity(
+		placement, clusters, d.placementLister, d.placementDecisionIndexer)
+	if compositeStatus.IsError() {
+		return clusters, excluded, compositeStatus, nil
+	}
+	return clusters, excluded, capacityStatus, nil
+}
+
+// getCurrentDecisions returns the names of clusters in the current decisions of the placement.
+func (d *Debugger) getCurrentDecisions(namespace, name string) (sets.String, error) {
+	current := sets.NewString()
+	placementDecisions, err := d.placementDecisionLister.PlacementDecisions(namespace).List(
+		labels.SelectorFromSet(labels.Set{clusterapiv1beta1.PlacementLabel: name}))
+	if err != nil {
+		return current, err
+	}
+	for _, placementDecision := range placementDecisions {
+		for _, d := range placementDecision.Status.Decisions {
+			current.Insert(d.ClusterName)
+		}
+	}
+	return current, nil
+}
+
+// overrideClusters applies the overrides on the labels and taints of the clusters. The resourceVersion of the
+// overridden clusters is cleared, since they are no longer the same as the clusters in the hub.
+func overrideClusters(clusters []*clusterapiv1.ManagedCluster, overrides []ClusterOverride) []*clusterapiv1.ManagedCluster {
+	overridesByName := map[string]ClusterOverride{}
+	for _, o := range overrides {
+		overridesByName[o.ClusterName] = o
+	}
+
+	for _, cluster := range clusters {
+		o, ok := overridesByName[cluster.Name]
+		if !ok {
+			continue
+		}
+		cluster.ResourceVersion = ""
+		for _, k := range o.RemoveLabels {
+			delete(cluster.Labels, k)
+		}
+		for k, v := range o.Labels {
+			if cluster.Labels == nil {
+				cluster.Labels = map[string]string{}
+			}
+			cluster.Labels[k] = v
+		}
+		if o.Taints != nil {
+			cluster.Spec.Taints = *o.Taints
+		}
+	}
+	return clusters
+}
+
+// diffDecisions compares the simulated decisions with the current decisions.
+func diffDecisions(current sets.String, decisions []clusterapiv1beta1.ClusterDecision) DecisionDiff {
+	diff := DecisionDiff{}
+	simulated := sets.NewString()
+	for _, d := range decisions {
+		simulated.Insert(d.ClusterName)
+	}
+	diff.Added = simulated.Difference(current).List()
+	diff.Removed = current.Difference(simulated).List()
+	diff.Unchanged = simulated.Intersection(current).List()
+	return diff
+}
+
 func (d *Debugger) parsePath(path string) (string, string, error) {
 	metaNamespaceKey := strings.TrimPrefix(path, DebugPath)
 	return cache.SplitMetaNamespaceKey(metaNamespaceKey)
@@ -1,6 +1,7 @@
 package debugger
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,14 +9,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterapiv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 
 	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
 	scheduling "open-cluster-management.io/ocm/pkg/placement/controllers/scheduling"
@@ -29,7 +34,11 @@ type testScheduler struct {
 type testResult struct {
 	filterResults     []scheduling.FilterResult
 	prioritizeResults []scheduling.PrioritizerResult
+	spreadResults     []scheduling.SpreadResult
+	excludedClusters  []scheduling.ExcludedCluster
 	scoreSum          scheduling.PrioritizerScore
+	decisions         []clusterapiv1beta1.ClusterDecision
+	numOfUnscheduled  int
 }
 
 func (r *testResult) FilterResults() []scheduling.FilterResult {
@@ -44,12 +53,24 @@ func (r *testResult) PrioritizerScores() scheduling.PrioritizerScore {
 	return r.scoreSum
 }
 
+func (r *testResult) SpreadResults() []scheduling.SpreadResult {
+	return r.spreadResults
+}
+
+func (r *testResult) ExcludedClusters() []scheduling.ExcludedCluster {
+	return r.excludedClusters
+}
+
+func (r *testResult) PinnedClusters() []string {
+	return nil
+}
+
 func (r *testResult) Decisions() []clusterapiv1beta1.ClusterDecision {
-	return []clusterapiv1beta1.ClusterDecision{}
+	return r.decisions
 }
 
 func (r *testResult) NumOfUnscheduled() int {
-	return 0
+	return r.numOfUnscheduled
 }
 
 func (s *testScheduler) Schedule(ctx context.Context,
@@ -73,18 +94,89 @@ func TestDebugger(t *testing.T) {
 		initObjs          []runtime.Object
 		filterResults     []scheduling.FilterResult
 		prioritizeResults []scheduling.PrioritizerResult
+		spreadResults     []scheduling.SpreadResult
+		excludedClusters  []scheduling.ExcludedCluster
 		key               string
+		expectedClusters  []string
+		expectedExcluded  []scheduling.ExcludedCluster
 	}{
 		{
 			name: "A valid placement",
 			initObjs: []runtime.Object{
 				testinghelpers.NewPlacement(placementNamespace, placementName).Build(),
+				newClusterSet("global", nil),
+				testinghelpers.NewClusterSetBinding(placementNamespace, "global"),
 				testinghelpers.NewManagedCluster("cluster1").Build(),
 				testinghelpers.NewManagedCluster("cluster2").Build(),
 			},
 			filterResults:     []scheduling.FilterResult{{Name: "filter1", FilteredClusters: []string{"cluster1", "cluster2"}}},
 			prioritizeResults: []scheduling.PrioritizerResult{{Name: "prioritize1", Scores: map[string]int64{"cluster1": 100, "cluster2": 0}}},
 			key:               placementNamespace + "/" + placementName,
+			expectedClusters:  []string{"cluster1", "cluster2"},
+		},
+		{
+			name: "A placement with spread policy",
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacement(placementNamespace, placementName).Build(),
+				newClusterSet("global", nil),
+				testinghelpers.NewClusterSetBinding(placementNamespace, "global"),
+				testinghelpers.NewManagedCluster("cluster1").Build(),
+				testinghelpers.NewManagedCluster("cluster2").Build(),
+			},
+			filterResults:     []scheduling.FilterResult{{Name: "filter1", FilteredClusters: []string{"cluster1", "cluster2"}}},
+			prioritizeResults: []scheduling.PrioritizerResult{{Name: "prioritize1", Scores: map[string]int64{"cluster1": 100, "cluster2": 0}}},
+			spreadResults: []scheduling.SpreadResult{{
+				TopologyKey:       "region",
+				TopologyKeyType:   clusterapiv1beta1.TopologyKeyTypeLabel,
+				MaxSkew:           1,
+				WhenUnsatisfiable: clusterapiv1beta1.DoNotSchedule,
+				Domains:           map[string]int{"east": 1, "west": 1},
+				Satisfied:         true,
+			}},
+			key:              placementNamespace + "/" + placementName,
+			expectedClusters: []string{"cluster1", "cluster2"},
+		},
+		{
+			name: "A placement with excluded clusters",
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacement(placementNamespace, placementName).Build(),
+				newClusterSet("global", nil),
+				testinghelpers.NewClusterSetBinding(placementNamespace, "global"),
+				testinghelpers.NewManagedCluster("cluster1").Build(),
+				testinghelpers.NewManagedCluster("cluster2").Build(),
+			},
+			filterResults: []scheduling.FilterResult{{Name: "filter1", FilteredClusters: []string{"cluster1"}}},
+			excludedClusters: []scheduling.ExcludedCluster{{
+				ClusterName: "cluster2",
+				Plugin:      "filter1",
+				Reason:      "label selector not matched",
+			}},
+			key:              placementNamespace + "/" + placementName,
+			expectedClusters: []string{"cluster1", "cluster2"},
+			expectedExcluded: []scheduling.ExcludedCluster{{
+				ClusterName: "cluster2",
+				Plugin:      "filter1",
+				Reason:      "label selector not matched",
+			}},
+		},
+		{
+			name: "A placement with clusters not in the bound clustersets or without slots",
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacement(placementNamespace, placementName).Build(),
+				newClusterSet("prod", map[string]string{"env": "prod"}),
+				testinghelpers.NewClusterSetBinding(placementNamespace, "prod"),
+				testinghelpers.NewManagedCluster("cluster1").WithLabel("env", "prod").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithLabel("env", "prod").
+					WithLabel(scheduling.PlacementSlotsLabel, "0").Build(),
+				testinghelpers.NewManagedCluster("cluster3").Build(),
+			},
+			key:              placementNamespace + "/" + placementName,
+			expectedClusters: []string{"cluster1"},
+			expectedExcluded: []scheduling.ExcludedCluster{{
+				ClusterName: "cluster2",
+				Plugin:      "PlacementCapacity",
+				Reason:      "0 of 0 slots are taken by placements which cannot be preempted, 1 required",
+			}},
 		},
 	}
 
@@ -92,9 +184,13 @@ func TestDebugger(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			clusterClient := clusterfake.NewSimpleClientset(c.initObjs...)
 			clusterInformerFactory := testinghelpers.NewClusterInformerFactory(clusterClient, c.initObjs...)
-			s := &testScheduler{result: &testResult{filterResults: c.filterResults, prioritizeResults: c.prioritizeResults}}
-			debugger := NewDebugger(
-				s, clusterInformerFactory.Cluster().V1beta1().Placements(), clusterInformerFactory.Cluster().V1().ManagedClusters())
+			s := &recordScheduler{testScheduler: testScheduler{result: &testResult{
+				filterResults:     c.filterResults,
+				prioritizeResults: c.prioritizeResults,
+				spreadResults:     c.spreadResults,
+				excludedClusters:  c.excludedClusters,
+			}}}
+			debugger := newTestDebugger(s, clusterInformerFactory)
 			server := httptest.NewServer(http.HandlerFunc(debugger.Handler))
 			res, err := http.Get(fmt.Sprintf("%s%s%s", server.URL, DebugPath, c.key))
 
@@ -121,7 +217,230 @@ func TestDebugger(t *testing.T) {
 				t.Errorf("Expect prioritize result to be: %v. but got: %v", c.prioritizeResults, result.PrioritizeResults)
 			}
 
+			if !reflect.DeepEqual(result.SpreadResults, c.spreadResults) {
+				t.Errorf("Expect spread result to be: %v. but got: %v", c.spreadResults, result.SpreadResults)
+			}
+
+			if !reflect.DeepEqual(result.ExcludedClusters, c.expectedExcluded) {
+				t.Errorf("Expect excluded clusters to be: %v. but got: %v", c.expectedExcluded, result.ExcludedClusters)
+			}
+
+			actualClusters := []string{}
+			for _, cluster := range s.clusters {
+				actualClusters = append(actualClusters, cluster.Name)
+			}
+			sort.Strings(actualClusters)
+			if !reflect.DeepEqual(actualClusters, c.expectedClusters) {
+				t.Errorf("Expect clusters to be: %v. but got: %v", c.expectedClusters, actualClusters)
+			}
+
 			server.Close()
 		})
 	}
 }
+
+type recordScheduler struct {
+	testScheduler
+	placement *clusterapiv1beta1.Placement
+	clusters  []*clusterapiv1.ManagedCluster
+}
+
+func (s *recordScheduler) Schedule(ctx context.Context,
+	placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster,
+) (scheduling.ScheduleResult, *framework.Status) {
+	s.placement = placement
+	s.clusters = clusters
+	return s.result, nil
+}
+
+func TestSimulate(t *testing.T) {
+	placementNamespace := "test"
+	placementName := "test"
+
+	cases := []struct {
+		name             string
+		initObjs         []runtime.Object
+		request          SimulateRequest
+		decisions        []clusterapiv1beta1.ClusterDecision
+		expectedClusters map[string]map[string]string
+		// expectedResourceVersions are the resourceVersions of the scheduled clusters, which are cleared
+		// for the overridden and hypothetical clusters
+		expectedResourceVersions map[string]string
+		expectedNOC              *int32
+		expectedDiff             DecisionDiff
+	}{
+		{
+			name: "simulate existing placement with cluster overrides",
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacement(placementNamespace, placementName).WithNOC(2).Build(),
+				newClusterSet("global", nil),
+				testinghelpers.NewClusterSetBinding(placementNamespace, "global"),
+				testinghelpers.NewPlacementDecision(placementNamespace, placementName+"-decision-1").
+					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithDecisions("cluster1", "cluster2").Build(),
+				withResourceVersion(testinghelpers.NewManagedCluster("cluster1").WithLabel("env", "prod").Build(), "1"),
+				withResourceVersion(testinghelpers.NewManagedCluster("cluster2").Build(), "2"),
+				withResourceVersion(testinghelpers.NewManagedCluster("cluster3").Build(), "3"),
+				withResourceVersion(testinghelpers.NewManagedCluster("cluster4").WithLabel("env", "prod").Build(), "4"),
+			},
+			request: SimulateRequest{
+				ClusterOverrides: []ClusterOverride{
+					{ClusterName: "cluster1", RemoveLabels: []string{"env"}},
+					{ClusterName: "cluster2", Labels: map[string]string{"env": ""}},
+					{ClusterName: "cluster3", Labels: map[string]string{"env": "dev"}},
+				},
+			},
+			decisions: []clusterapiv1beta1.ClusterDecision{{ClusterName: "cluster2"}, {ClusterName: "cluster3"}},
+			expectedClusters: map[string]map[string]string{
+				"cluster1": {},
+				"cluster2": {"env": ""},
+				"cluster3": {"env": "dev"},
+				"cluster4": {"env": "prod"},
+			},
+			expectedResourceVersions: map[string]string{"cluster1": "", "cluster2": "", "cluster3": "", "cluster4": "4"},
+			expectedNOC:              int32Ptr(2),
+			expectedDiff: DecisionDiff{
+				Added:     []string{"cluster3"},
+				Removed:   []string{"cluster1"},
+				Unchanged: []string{"cluster2"},
+			},
+		},
+		{
+			name: "simulate new placement with hypothetical clusters",
+			initObjs: []runtime.Object{
+				newClusterSet("global", nil),
+				testinghelpers.NewClusterSetBinding(placementNamespace, "global"),
+				testinghelpers.NewManagedCluster("cluster1").Build(),
+			},
+			request: SimulateRequest{
+				Placement: testinghelpers.NewPlacement("", "").WithNOC(1).Build(),
+				Clusters: []clusterapiv1.ManagedCluster{
+					*withResourceVersion(testinghelpers.NewManagedCluster("cluster4").Build(), "4"),
+				},
+			},
+			decisions: []clusterapiv1beta1.ClusterDecision{{ClusterName: "cluster4"}},
+			expectedClusters: map[string]map[string]string{
+				"cluster4": nil,
+			},
+			expectedResourceVersions: map[string]string{"cluster4": ""},
+			expectedNOC:              int32Ptr(1),
+			expectedDiff: DecisionDiff{
+				Added: []string{"cluster4"},
+			},
+		},
+		{
+			name: "simulate clusterset membership changed by cluster overrides",
+			initObjs: []runtime.Object{
+				testinghelpers.NewPlacement(placementNamespace, placementName).Build(),
+				newClusterSet("prod", map[string]string{"env": "prod"}),
+				testinghelpers.NewClusterSetBinding(placementNamespace, "prod"),
+				testinghelpers.NewPlacementDecision(placementNamespace, placementName+"-decision-1").
+					WithLabel(clusterapiv1beta1.PlacementLabel, placementName).
+					WithDecisions("cluster1").Build(),
+				withResourceVersion(testinghelpers.NewManagedCluster("cluster1").WithLabel("env", "prod").Build(), "1"),
+				withResourceVersion(testinghelpers.NewManagedCluster("cluster2").Build(), "2"),
+			},
+			request: SimulateRequest{
+				ClusterOverrides: []ClusterOverride{
+					{ClusterName: "cluster1", RemoveLabels: []string{"env"}},
+					{ClusterName: "cluster2", Labels: map[string]string{"env": "prod"}},
+				},
+			},
+			decisions: []clusterapiv1beta1.ClusterDecision{{ClusterName: "cluster2"}},
+			expectedClusters: map[string]map[string]string{
+				"cluster2": {"env": "prod"},
+			},
+			expectedResourceVersions: map[string]string{"cluster2": ""},
+			expectedDiff: DecisionDiff{
+				Added:   []string{"cluster2"},
+				Removed: []string{"cluster1"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterClient := clusterfake.NewSimpleClientset(c.initObjs...)
+			clusterInformerFactory := testinghelpers.NewClusterInformerFactory(clusterClient, c.initObjs...)
+			s := &recordScheduler{testScheduler: testScheduler{result: &testResult{decisions: c.decisions}}}
+			debugger := newTestDebugger(s, clusterInformerFactory)
+			server := httptest.NewServer(http.HandlerFunc(debugger.Handler))
+			defer server.Close()
+
+			body, _ := json.Marshal(c.request)
+			res, err := http.Post(fmt.Sprintf("%s%s%s/%s", server.URL, DebugPath, placementNamespace, placementName),
+				"application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("Expect no error but get %v", err)
+			}
+
+			responseBody, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("Unexpected error reading response body: %v", err)
+			}
+
+			result := &SimulateResult{}
+			if err := json.Unmarshal(responseBody, result); err != nil {
+				t.Errorf("Unexpected error unmarshaling reulst: %v", err)
+			}
+			if len(result.Error) != 0 {
+				t.Fatalf("Unexpected error: %s", result.Error)
+			}
+
+			if s.placement.Namespace != placementNamespace || s.placement.Name != placementName {
+				t.Errorf("Expect placement %s/%s scheduled, but got %s/%s", placementNamespace, placementName, s.placement.Namespace, s.placement.Name)
+			}
+			if !reflect.DeepEqual(s.placement.Spec.NumberOfClusters, c.expectedNOC) {
+				t.Errorf("Expect numberOfClusters %v, but got %v", c.expectedNOC, s.placement.Spec.NumberOfClusters)
+			}
+
+			actualClusters := map[string]map[string]string{}
+			actualResourceVersions := map[string]string{}
+			for _, cluster := range s.clusters {
+				actualClusters[cluster.Name] = cluster.Labels
+				actualResourceVersions[cluster.Name] = cluster.ResourceVersion
+			}
+			if !reflect.DeepEqual(actualClusters, c.expectedClusters) {
+				t.Errorf("Expect clusters %v, but got %v", c.expectedClusters, actualClusters)
+			}
+			if !reflect.DeepEqual(actualResourceVersions, c.expectedResourceVersions) {
+				t.Errorf("Expect resourceVersions %v, but got %v", c.expectedResourceVersions, actualResourceVersions)
+			}
+
+			if !reflect.DeepEqual(result.Decisions, c.decisions) {
+				t.Errorf("Expect decisions %v, but got %v", c.decisions, result.Decisions)
+			}
+			if !reflect.DeepEqual(result.Diff, c.expectedDiff) {
+				t.Errorf("Expect diff %v, but got %v", c.expectedDiff, result.Diff)
+			}
+		})
+	}
+}
+
+func newTestDebugger(s scheduling.Scheduler, clusterInformerFactory clusterinformers.SharedInformerFactory) *Debugger {
+	return NewDebugger(
+		s,
+		clusterInformerFactory.Cluster().V1beta1().Placements(),
+		clusterInformerFactory.Cluster().V1beta1().PlacementDecisions(),
+		clusterInformerFactory.Cluster().V1().ManagedClusters(),
+		clusterInformerFactory.Cluster().V1beta2().ManagedClusterSets(),
+		clusterInformerFactory.Cluster().V1beta2().ManagedClusterSetBindings())
+}
+
+// newClusterSet returns a clusterset selecting the clusters with the labels.
+func newClusterSet(name string, clusterLabels map[string]string) *clusterapiv1beta2.ManagedClusterSet {
+	return testinghelpers.NewClusterSet(name).WithClusterSelector(clusterapiv1beta2.ManagedClusterSelector{
+		SelectorType:  clusterapiv1beta2.LabelSelector,
+		LabelSelector: &metav1.LabelSelector{MatchLabels: clusterLabels},
+	}).Build()
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func withResourceVersion(cluster *clusterapiv1.ManagedCluster, resourceVersion string) *clusterapiv1.ManagedCluster {
+	cluster.ResourceVersion = resourceVersion
+	return cluster
+}
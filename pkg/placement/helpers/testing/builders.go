@@ -9,6 +9,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
+	addonapiv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	clusterapiv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
 	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
@@ -118,6 +119,17 @@ func (b *placementBuilder) AddToleration(toleration *clusterapiv1beta1.Toleratio
 	return b
 }
 
+func (b *placementBuilder) AddSpreadConstraint(topologyKey string, topologyKeyType clusterapiv1beta1.TopologyKeyType,
+	maxSkew int32, whenUnsatisfiable clusterapiv1beta1.UnsatisfiableMaxSkewAction) *placementBuilder {
+	b.placement.Spec.SpreadPolicy.SpreadConstraints = append(b.placement.Spec.SpreadPolicy.SpreadConstraints, clusterapiv1beta1.SpreadConstraintsTerm{
+		TopologyKey:       topologyKey,
+		TopologyKeyType:   topologyKeyType,
+		MaxSkew:           maxSkew,
+		WhenUnsatisfiable: whenUnsatisfiable,
+	})
+	return b
+}
+
 func (b *placementBuilder) WithNumOfSelectedClusters(nosc int) *placementBuilder {
 	b.placement.Status.NumberOfSelectedClusters = int32(nosc)
 	return b
@@ -362,3 +374,31 @@ func (a *addOnPlacementScoreBuilder) WithValidUntil(validUntil time.Time) *addOn
 func (a *addOnPlacementScoreBuilder) Build() *clusterapiv1alpha1.AddOnPlacementScore {
 	return a.addOnPlacementScore
 }
+
+type managedClusterAddOnBuilder struct {
+	addOn *addonapiv1alpha1.ManagedClusterAddOn
+}
+
+func NewManagedClusterAddOn(clusterName, name string) *managedClusterAddOnBuilder {
+	return &managedClusterAddOnBuilder{
+		addOn: &addonapiv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterName,
+				Name:      name,
+			},
+		},
+	}
+}
+
+func (b *managedClusterAddOnBuilder) WithCondition(conditionType string, status metav1.ConditionStatus) *managedClusterAddOnBuilder {
+	meta.SetStatusCondition(&b.addOn.Status.Conditions, metav1.Condition{
+		Type:   conditionType,
+		Status: status,
+		Reason: "Test",
+	})
+	return b
+}
+
+func (b *managedClusterAddOnBuilder) Build() *addonapiv1alpha1.ManagedClusterAddOn {
+	return b.addOn
+}
@@ -6,6 +6,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	kevents "k8s.io/client-go/tools/events"
 
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
@@ -18,6 +19,7 @@ type FakePluginHandle struct {
 	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister
 	scoreLister             clusterlisterv1alpha1.AddOnPlacementScoreLister
 	clusterLister           clusterlisterv1.ManagedClusterLister
+	addOnLister             addonlisterv1alpha1.ManagedClusterAddOnLister
 	client                  clusterclient.Interface
 }
 
@@ -31,18 +33,23 @@ func (f *FakePluginHandle) ScoreLister() clusterlisterv1alpha1.AddOnPlacementSco
 func (f *FakePluginHandle) ClusterLister() clusterlisterv1.ManagedClusterLister {
 	return f.clusterLister
 }
+func (f *FakePluginHandle) AddOnLister() addonlisterv1alpha1.ManagedClusterAddOnLister {
+	return f.addOnLister
+}
 func (f *FakePluginHandle) ClusterClient() clusterclient.Interface {
 	return f.client
 }
 
 func NewFakePluginHandle(
-	t *testing.T, client *clusterfake.Clientset, objects ...runtime.Object) *FakePluginHandle {
+	t testing.TB, client *clusterfake.Clientset, objects ...runtime.Object) *FakePluginHandle {
 	informers := NewClusterInformerFactory(client, objects...)
+	addOnInformers := NewAddOnInformerFactory(objects...)
 	return &FakePluginHandle{
 		recorder:                kevents.NewFakeRecorder(100),
 		client:                  client,
 		placementDecisionLister: informers.Cluster().V1beta1().PlacementDecisions().Lister(),
 		scoreLister:             informers.Cluster().V1alpha1().AddOnPlacementScores().Lister(),
 		clusterLister:           informers.Cluster().V1().ManagedClusters().Lister(),
+		addOnLister:             addOnInformers.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
 	}
 }
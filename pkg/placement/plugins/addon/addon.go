@@ -3,9 +3,12 @@ package addon
 import (
 	"context"
 	"fmt"
+	"math"
+	"time"
 
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
+	"k8s.io/utils/pointer"
 
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
@@ -19,13 +22,86 @@ const (
 	description    = `
 	Customize prioritizer get cluster scores from AddOnPlacementScores with sepcific
 	resource name and score name. The clusters which doesn't have corresponding
-	AddOnPlacementScores resource or has expired score is given score 0.
+	AddOnPlacementScores resource or has expired score are given the score by the
+	stale score policy of the placement, which is 0 by default.
 	`
+
+	// StaleScorePolicyAnnotation is the annotation on Placement which defines how the clusters are scored if
+	// their AddOnPlacementScores are missing or expired. The value is one of Zero, Min, Max, LastKnown and
+	// Exclude. Defaults to Zero. The StaleScores condition is only reported on the placements with the annotation.
+	StaleScorePolicyAnnotation = "cluster.open-cluster-management.io/experimental-stale-score-policy"
+
+	// StaleScoreHalfLifeAnnotation is the annotation on Placement which defines the duration in which an
+	// expired score decays to half of its value with the LastKnown policy. Defaults to 1h.
+	StaleScoreHalfLifeAnnotation = "cluster.open-cluster-management.io/experimental-stale-score-half-life"
+
+	defaultStaleScoreHalfLife = time.Hour
+)
+
+// StaleScorePolicy defines the score of a cluster whose AddOnPlacementScore is missing or expired.
+type StaleScorePolicy string
+
+const (
+	// StaleScorePolicyZero gives the cluster score 0.
+	StaleScorePolicyZero StaleScorePolicy = "Zero"
+	// StaleScorePolicyMin gives the cluster the minimum score, so it is selected last.
+	StaleScorePolicyMin StaleScorePolicy = "Min"
+	// StaleScorePolicyMax gives the cluster the maximum score, so it is selected first.
+	StaleScorePolicyMax StaleScorePolicy = "Max"
+	// StaleScorePolicyLastKnown gives the cluster the last known score decaying over time since it
+	// expired. The cluster without any known score is given score 0.
+	StaleScorePolicyLastKnown StaleScorePolicy = "LastKnown"
+	// StaleScorePolicyExclude never selects the cluster.
+	StaleScorePolicyExclude StaleScorePolicy = "Exclude"
 )
 
 var _ plugins.Prioritizer = &AddOn{}
 var AddOnClock = clock.Clock(clock.RealClock{})
 
+// GetStaleScorePolicy returns the stale score policy of the placement and the half life of the LastKnown
+// policy.
+func GetStaleScorePolicy(placement *clusterapiv1beta1.Placement) (StaleScorePolicy, time.Duration, error) {
+	policy := StaleScorePolicy(placement.GetAnnotations()[StaleScorePolicyAnnotation])
+	switch policy {
+	case "":
+		policy = StaleScorePolicyZero
+	case StaleScorePolicyZero, StaleScorePolicyMin, StaleScorePolicyMax, StaleScorePolicyLastKnown, StaleScorePolicyExclude:
+	default:
+		return "", 0, fmt.Errorf("incorrect annotation %s: %q", StaleScorePolicyAnnotation, policy)
+	}
+
+	halfLife := defaultStaleScoreHalfLife
+	if value, ok := placement.GetAnnotations()[StaleScoreHalfLifeAnnotation]; ok && len(value) > 0 {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to parse annotation %s: %v", StaleScoreHalfLifeAnnotation, err)
+		}
+		if d <= 0 {
+			return "", 0, fmt.Errorf("annotation %s should be greater than 0", StaleScoreHalfLifeAnnotation)
+		}
+		halfLife = d
+	}
+	return policy, halfLife, nil
+}
+
+// score returns the score of a stale cluster with the last known value, which expired the given duration
+// ago. The value is nil if the score is never reported.
+func (p StaleScorePolicy) score(value *int64, expired, halfLife time.Duration) int64 {
+	switch p {
+	case StaleScorePolicyMin:
+		return plugins.MinClusterScore
+	case StaleScorePolicyMax:
+		return plugins.MaxClusterScore
+	case StaleScorePolicyLastKnown:
+		if value == nil {
+			return 0
+		}
+		return int64(math.Round(float64(*value) * math.Pow(0.5, float64(expired)/float64(halfLife))))
+	default:
+		return 0
+	}
+}
+
 type AddOn struct {
 	handle          plugins.Handle
 	prioritizerName string
@@ -70,7 +146,14 @@ func (c *AddOn) Description() string {
 
 func (c *AddOn) Score(ctx context.Context, placement *clusterapiv1beta1.Placement,
 	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginScoreResult, *framework.Status) {
+	policy, halfLife, err := GetStaleScorePolicy(placement)
+	if err != nil {
+		return plugins.PluginScoreResult{}, framework.NewStatus(c.Name(), framework.Misconfigured, err.Error())
+	}
+
 	scores := map[string]int64{}
+	staleClusters := map[string]string{}
+	excluded := map[string]string{}
 	expiredScores := ""
 	status := framework.NewStatus(c.Name(), framework.Success, "")
 
@@ -82,21 +165,39 @@ func (c *AddOn) Score(ctx context.Context, placement *clusterapiv1beta1.Placemen
 		// get AddOnPlacementScores CR with resourceName
 		addOnScores, err := c.handle.ScoreLister().AddOnPlacementScores(namespace).Get(c.resourceName)
 		if err != nil {
-			klog.Warningf("Getting AddOnPlacementScores failed: %s", err)
-			continue
+			klog.V(4).Infof("Getting AddOnPlacementScores failed: %s", err)
+		}
+
+		// get AddOnPlacementScores score with scoreName
+		var value *int64
+		if addOnScores != nil {
+			for _, v := range addOnScores.Status.Scores {
+				if v.Name == c.scoreName {
+					value = pointer.Int64(int64(v.Value))
+				}
+			}
 		}
 
-		// check score valid time
-		if (addOnScores.Status.ValidUntil != nil) && AddOnClock.Now().After(addOnScores.Status.ValidUntil.Time) {
+		// check score valid time, the policy gives the score if it is missing or expired
+		switch {
+		case addOnScores == nil:
+			staleClusters[cluster.Name] = fmt.Sprintf("AddOnPlacementScore %s is not found", c.resourceName)
+			scores[cluster.Name] = policy.score(nil, 0, halfLife)
+		case addOnScores.Status.ValidUntil != nil && AddOnClock.Now().After(addOnScores.Status.ValidUntil.Time):
 			expiredScores = fmt.Sprintf("%s %s/%s", expiredScores, namespace, c.resourceName)
-			continue
+			staleClusters[cluster.Name] = fmt.Sprintf("AddOnPlacementScore %s expired at %s",
+				c.resourceName, addOnScores.Status.ValidUntil.UTC().Format(time.RFC3339))
+			scores[cluster.Name] = policy.score(value, AddOnClock.Since(addOnScores.Status.ValidUntil.Time), halfLife)
+		case value == nil:
+			staleClusters[cluster.Name] = fmt.Sprintf("score %s is not found in AddOnPlacementScore %s",
+				c.scoreName, c.resourceName)
+			scores[cluster.Name] = policy.score(nil, 0, halfLife)
+		default:
+			scores[cluster.Name] = *value
 		}
 
-		// get AddOnPlacementScores score with scoreName
-		for _, v := range addOnScores.Status.Scores {
-			if v.Name == c.scoreName {
-				scores[cluster.Name] = int64(v.Value)
-			}
+		if _, ok := staleClusters[cluster.Name]; ok && policy == StaleScorePolicyExclude {
+			excluded[cluster.Name] = staleClusters[cluster.Name]
 		}
 	}
 
@@ -108,9 +209,17 @@ func (c *AddOn) Score(ctx context.Context, placement *clusterapiv1beta1.Placemen
 		)
 	}
 
-	return plugins.PluginScoreResult{
-		Scores: scores,
-	}, status
+	result := plugins.PluginScoreResult{
+		Scores:        scores,
+		StaleClusters: staleClusters,
+		Excluded:      excluded,
+	}
+	// the stale policy is only reported if the placement opts in, so that the placements prioritized by
+	// AddOnPlacementScores before the annotation is introduced are not updated with a new condition.
+	if len(placement.GetAnnotations()[StaleScorePolicyAnnotation]) > 0 {
+		result.StalePolicy = string(policy)
+	}
+	return result, status
 }
 
 func (c *AddOn) RequeueAfter(ctx context.Context, placement *clusterapiv1beta1.Placement) (plugins.PluginRequeueResult, *framework.Status) {
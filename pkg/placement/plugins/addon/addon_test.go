@@ -3,6 +3,7 @@ package addon
 import (
 	"context"
 	"errors"
+	"sort"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	clusterapivbeta1 "open-cluster-management.io/api/cluster/v1beta1"
 
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
 	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
 )
 
@@ -108,3 +110,133 @@ func TestScoreClusterWithAddOn(t *testing.T) {
 		})
 	}
 }
+
+func TestScoreWithStaleScorePolicy(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+		testinghelpers.NewManagedCluster("cluster4").Build(),
+	}
+	addOnScores := []runtime.Object{
+		testinghelpers.NewAddOnPlacementScore("cluster1", "test").WithScore("score1", 30).Build(),
+		// expired one hour ago
+		testinghelpers.NewAddOnPlacementScore("cluster2", "test").WithScore("score1", 80).
+			WithValidUntil(fakeTime.Add(-time.Hour)).Build(),
+		testinghelpers.NewAddOnPlacementScore("cluster3", "test").WithScore("score2", 50).Build(),
+	}
+	staleClusters := map[string]string{
+		"cluster2": "AddOnPlacementScore test expired at 2021-12-31T23:00:00Z",
+		"cluster3": "score score1 is not found in AddOnPlacementScore test",
+		"cluster4": "AddOnPlacementScore test is not found",
+	}
+	newPlacement := func(annotations map[string]string) *clusterapivbeta1.Placement {
+		return testinghelpers.NewPlacementWithAnnotations("test", "test", annotations).
+			WithScoreCoordinateAddOn("test", "score1", 1).Build()
+	}
+
+	cases := []struct {
+		name             string
+		placement        *clusterapivbeta1.Placement
+		expectedScores   map[string]int64
+		expectedPolicy   string
+		expectedExcluded []string
+		expectedErr      bool
+	}{
+		{
+			name:           "zero by default",
+			placement:      newPlacement(nil),
+			expectedScores: map[string]int64{"cluster1": 30, "cluster2": 0, "cluster3": 0, "cluster4": 0},
+		},
+		{
+			name:           "zero",
+			placement:      newPlacement(map[string]string{StaleScorePolicyAnnotation: "Zero"}),
+			expectedScores: map[string]int64{"cluster1": 30, "cluster2": 0, "cluster3": 0, "cluster4": 0},
+			expectedPolicy: "Zero",
+		},
+		{
+			name:           "min",
+			placement:      newPlacement(map[string]string{StaleScorePolicyAnnotation: "Min"}),
+			expectedScores: map[string]int64{"cluster1": 30, "cluster2": -100, "cluster3": -100, "cluster4": -100},
+			expectedPolicy: "Min",
+		},
+		{
+			name:           "max",
+			placement:      newPlacement(map[string]string{StaleScorePolicyAnnotation: "Max"}),
+			expectedScores: map[string]int64{"cluster1": 30, "cluster2": 100, "cluster3": 100, "cluster4": 100},
+			expectedPolicy: "Max",
+		},
+		{
+			name:           "last known with default half life",
+			placement:      newPlacement(map[string]string{StaleScorePolicyAnnotation: "LastKnown"}),
+			expectedScores: map[string]int64{"cluster1": 30, "cluster2": 40, "cluster3": 0, "cluster4": 0},
+			expectedPolicy: "LastKnown",
+		},
+		{
+			name: "last known with half life",
+			placement: newPlacement(map[string]string{
+				StaleScorePolicyAnnotation:   "LastKnown",
+				StaleScoreHalfLifeAnnotation: "30m",
+			}),
+			expectedScores: map[string]int64{"cluster1": 30, "cluster2": 20, "cluster3": 0, "cluster4": 0},
+			expectedPolicy: "LastKnown",
+		},
+		{
+			name:             "exclude",
+			placement:        newPlacement(map[string]string{StaleScorePolicyAnnotation: "Exclude"}),
+			expectedScores:   map[string]int64{"cluster1": 30, "cluster2": 0, "cluster3": 0, "cluster4": 0},
+			expectedPolicy:   "Exclude",
+			expectedExcluded: []string{"cluster2", "cluster3", "cluster4"},
+		},
+		{
+			name:        "invalid policy",
+			placement:   newPlacement(map[string]string{StaleScorePolicyAnnotation: "Average"}),
+			expectedErr: true,
+		},
+		{
+			name: "invalid half life",
+			placement: newPlacement(map[string]string{
+				StaleScorePolicyAnnotation:   "LastKnown",
+				StaleScoreHalfLifeAnnotation: "-1h",
+			}),
+			expectedErr: true,
+		},
+	}
+
+	AddOnClock = testingclock.NewFakeClock(fakeTime)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			addon := NewAddOnPrioritizerBuilder(testinghelpers.NewFakePluginHandle(t, nil, addOnScores...)).
+				WithResourceName("test").WithScoreName("score1").Build()
+
+			result, status := addon.Score(context.TODO(), c.placement, clusters)
+			if c.expectedErr {
+				if status.Code() != framework.Misconfigured {
+					t.Errorf("expected misconfigured, but got %v", status.Code())
+				}
+				return
+			}
+
+			if !apiequality.Semantic.DeepEqual(result.Scores, c.expectedScores) {
+				t.Errorf("expected scores %v, but got %v", c.expectedScores, result.Scores)
+			}
+			if result.StalePolicy != c.expectedPolicy {
+				t.Errorf("expected policy %s, but got %s", c.expectedPolicy, result.StalePolicy)
+			}
+			if !apiequality.Semantic.DeepEqual(result.StaleClusters, staleClusters) {
+				t.Errorf("expected stale clusters %v, but got %v", staleClusters, result.StaleClusters)
+			}
+			excluded := []string{}
+			for name := range result.Excluded {
+				excluded = append(excluded, name)
+			}
+			sort.Strings(excluded)
+			if len(c.expectedExcluded) == 0 {
+				c.expectedExcluded = []string{}
+			}
+			if !apiequality.Semantic.DeepEqual(excluded, c.expectedExcluded) {
+				t.Errorf("expected excluded clusters %v, but got %v", c.expectedExcluded, excluded)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package addonhealth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	addonapiv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+)
+
+var _ plugins.Filter = &AddOnHealth{}
+
+const (
+	// RequiredAddOnsAnnotation is the annotation on Placement which defines the addons required to be
+	// healthy on the selected clusters. The value is a json encoded list of RequiredAddOn, for example
+	// [{"name":"governance-policy-framework"},{"name":"observability-controller","conditions":[{"type":"Degraded","status":"False"}]}]
+	RequiredAddOnsAnnotation = "cluster.open-cluster-management.io/experimental-required-addons"
+
+	description = `
+	AddOnHealth is a plugin that filters out the managed clusters on which the addons required by
+	the placement are not installed or do not have the required conditions. An addon is required to
+	be Available if no condition is specified.
+	`
+)
+
+// RequiredAddOn defines an addon required by the placement and the conditions the ManagedClusterAddOn
+// should have.
+type RequiredAddOn struct {
+	// Name is the name of the ManagedClusterAddOn.
+	Name string `json:"name"`
+	// Conditions are the conditions the ManagedClusterAddOn should have. Defaults to Available is True.
+	Conditions []RequiredCondition `json:"conditions,omitempty"`
+}
+
+// RequiredCondition defines the required status of a condition.
+type RequiredCondition struct {
+	Type   string                 `json:"type"`
+	Status metav1.ConditionStatus `json:"status"`
+}
+
+type AddOnHealth struct {
+	handle plugins.Handle
+}
+
+func New(handle plugins.Handle) *AddOnHealth {
+	return &AddOnHealth{
+		handle: handle,
+	}
+}
+
+func (p *AddOnHealth) Name() string {
+	return reflect.TypeOf(*p).Name()
+}
+
+func (p *AddOnHealth) Description() string {
+	return description
+}
+
+func (p *AddOnHealth) Filter(ctx context.Context, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginFilterResult, *framework.Status) {
+	status := framework.NewStatus(p.Name(), framework.Success, "")
+
+	requiredAddOns, err := GetRequiredAddOns(placement)
+	if err != nil {
+		return plugins.PluginFilterResult{}, framework.NewStatus(p.Name(), framework.Misconfigured, err.Error())
+	}
+	if len(requiredAddOns) == 0 {
+		return plugins.PluginFilterResult{
+			Filtered: clusters,
+		}, status
+	}
+
+	matched := []*clusterapiv1.ManagedCluster{}
+	reasons := map[string]string{}
+	for _, cluster := range clusters {
+		unhealthy := []string{}
+		for _, required := range requiredAddOns {
+			addOn, err := p.handle.AddOnLister().ManagedClusterAddOns(cluster.Name).Get(required.Name)
+			switch {
+			case errors.IsNotFound(err):
+				unhealthy = append(unhealthy, fmt.Sprintf("addon %s is not installed", required.Name))
+				continue
+			case err != nil:
+				return plugins.PluginFilterResult{}, framework.NewStatus(p.Name(), framework.Error, err.Error())
+			}
+			unhealthy = append(unhealthy, unmatchedConditions(addOn, required)...)
+		}
+		if len(unhealthy) > 0 {
+			reasons[cluster.Name] = strings.Join(unhealthy, "; ")
+			continue
+		}
+		matched = append(matched, cluster)
+	}
+
+	return plugins.PluginFilterResult{
+		Filtered: matched,
+		Reasons:  reasons,
+	}, status
+}
+
+// RequeueAfter returns an empty result since the placement is enqueued by the scheduling controller
+// once the conditions of a required addon change.
+func (p *AddOnHealth) RequeueAfter(ctx context.Context, placement *clusterapiv1beta1.Placement) (plugins.PluginRequeueResult, *framework.Status) {
+	return plugins.PluginRequeueResult{}, framework.NewStatus(p.Name(), framework.Success, "")
+}
+
+// unmatchedConditions returns the messages of the required conditions the addon does not have.
+func unmatchedConditions(addOn *addonapiv1alpha1.ManagedClusterAddOn, required RequiredAddOn) []string {
+	unmatched := []string{}
+	for _, c := range required.Conditions {
+		condition := meta.FindStatusCondition(addOn.Status.Conditions, c.Type)
+		switch {
+		case condition == nil:
+			unmatched = append(unmatched, fmt.Sprintf("addon %s has no condition %s", required.Name, c.Type))
+		case condition.Status != c.Status:
+			unmatched = append(unmatched, fmt.Sprintf("addon %s condition %s is %s, expected %s",
+				required.Name, c.Type, condition.Status, c.Status))
+		}
+	}
+	return unmatched
+}
+
+// GetRequiredAddOns returns the addons defined in the annotation of the placement. The conditions of an
+// addon default to Available is True.
+func GetRequiredAddOns(placement *clusterapiv1beta1.Placement) ([]RequiredAddOn, error) {
+	value, ok := placement.GetAnnotations()[RequiredAddOnsAnnotation]
+	if !ok || len(value) == 0 {
+		return nil, nil
+	}
+
+	requiredAddOns := []RequiredAddOn{}
+	if err := json.Unmarshal([]byte(value), &requiredAddOns); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", RequiredAddOnsAnnotation, err)
+	}
+
+	for i, required := range requiredAddOns {
+		if len(required.Name) == 0 {
+			return nil, fmt.Errorf("addon name in annotation %s should not be empty", RequiredAddOnsAnnotation)
+		}
+		if len(required.Conditions) == 0 {
+			requiredAddOns[i].Conditions = []RequiredCondition{
+				{
+					Type:   addonapiv1alpha1.ManagedClusterAddOnConditionAvailable,
+					Status: metav1.ConditionTrue,
+				},
+			}
+			continue
+		}
+		for _, c := range required.Conditions {
+			if len(c.Type) == 0 {
+				return nil, fmt.Errorf("condition type of addon %s should not be empty", required.Name)
+			}
+			switch c.Status {
+			case metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionUnknown:
+			default:
+				return nil, fmt.Errorf("incorrect status %q of condition %s of addon %s", c.Status, c.Type, required.Name)
+			}
+		}
+	}
+	return requiredAddOns, nil
+}
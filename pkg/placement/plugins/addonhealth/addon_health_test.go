@@ -0,0 +1,120 @@
+package addonhealth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+func TestMatchWithRequiredAddOns(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+	}
+	addOns := []runtime.Object{
+		testinghelpers.NewManagedClusterAddOn("cluster1", "policy").
+			WithCondition("Available", metav1.ConditionTrue).
+			WithCondition("Degraded", metav1.ConditionFalse).Build(),
+		testinghelpers.NewManagedClusterAddOn("cluster1", "observability").
+			WithCondition("Available", metav1.ConditionTrue).
+			WithCondition("Degraded", metav1.ConditionFalse).Build(),
+		testinghelpers.NewManagedClusterAddOn("cluster2", "policy").
+			WithCondition("Available", metav1.ConditionUnknown).Build(),
+		testinghelpers.NewManagedClusterAddOn("cluster2", "observability").
+			WithCondition("Available", metav1.ConditionTrue).
+			WithCondition("Degraded", metav1.ConditionTrue).Build(),
+		testinghelpers.NewManagedClusterAddOn("cluster3", "policy").Build(),
+	}
+
+	cases := []struct {
+		name                 string
+		placement            *clusterapiv1beta1.Placement
+		expectedClusterNames []string
+		expectedReasons      map[string]string
+		expectedCode         framework.Code
+	}{
+		{
+			name:                 "no required addons",
+			placement:            testinghelpers.NewPlacement("test", "test").Build(),
+			expectedClusterNames: []string{"cluster1", "cluster2", "cluster3"},
+		},
+		{
+			name: "addon available by default",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				RequiredAddOnsAnnotation: `[{"name":"policy"}]`,
+			}).Build(),
+			expectedClusterNames: []string{"cluster1"},
+			expectedReasons: map[string]string{
+				"cluster2": "addon policy condition Available is Unknown, expected True",
+				"cluster3": "addon policy has no condition Available",
+			},
+		},
+		{
+			name: "multiple addons with conditions",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				RequiredAddOnsAnnotation: `[{"name":"observability","conditions":[{"type":"Degraded","status":"False"}]},{"name":"policy"}]`,
+			}).Build(),
+			expectedClusterNames: []string{"cluster1"},
+			expectedReasons: map[string]string{
+				"cluster2": "addon observability condition Degraded is True, expected False; " +
+					"addon policy condition Available is Unknown, expected True",
+				"cluster3": "addon observability is not installed; addon policy has no condition Available",
+			},
+		},
+		{
+			name: "invalid annotation",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				RequiredAddOnsAnnotation: `[{"name":`,
+			}).Build(),
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name: "empty addon name",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				RequiredAddOnsAnnotation: `[{"name":""}]`,
+			}).Build(),
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name: "invalid condition status",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				RequiredAddOnsAnnotation: `[{"name":"policy","conditions":[{"type":"Available","status":"Yes"}]}]`,
+			}).Build(),
+			expectedCode: framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := New(testinghelpers.NewFakePluginHandle(t, nil, addOns...))
+			result, status := p.Filter(context.TODO(), c.placement, clusters)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+
+			actual := []string{}
+			for _, cluster := range result.Filtered {
+				actual = append(actual, cluster.Name)
+			}
+			if !reflect.DeepEqual(actual, c.expectedClusterNames) {
+				t.Errorf("expected clusters %v, but got %v", c.expectedClusterNames, actual)
+			}
+			if len(c.expectedReasons) > 0 && !reflect.DeepEqual(result.Reasons, c.expectedReasons) {
+				t.Errorf("expected reasons %v, but got %v", c.expectedReasons, result.Reasons)
+			}
+		})
+	}
+}
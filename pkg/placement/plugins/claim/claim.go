@@ -0,0 +1,167 @@
+package claim
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+)
+
+const (
+	description = `
+	ClaimValue prioritizer makes the scheduling decisions based on the numeric value of a
+	cluster claim. With ClaimValueAscending:<claim name>, the clusters that has the highest
+	value are given the highest score, while the lowest is given the lowest score. With
+	ClaimValueDescending:<claim name>, the clusters that has the lowest value are given the
+	highest score. The clusters without the claim or with a non-numeric value are given score 0.
+	`
+
+	// AscendingPrefix is the prefix of the prioritizer name which gives the highest score to
+	// the cluster with the highest claim value.
+	AscendingPrefix = "ClaimValueAscending:"
+	// DescendingPrefix is the prefix of the prioritizer name which gives the highest score to
+	// the cluster with the lowest claim value.
+	DescendingPrefix = "ClaimValueDescending:"
+)
+
+var _ plugins.Prioritizer = &ClaimValue{}
+
+type ClaimValue struct {
+	handle          plugins.Handle
+	prioritizerName string
+	claimName       string
+	descending      bool
+}
+
+type ClaimValueBuilder struct {
+	claimValue *ClaimValue
+}
+
+func NewClaimValuePrioritizerBuilder(handle plugins.Handle) *ClaimValueBuilder {
+	return &ClaimValueBuilder{
+		claimValue: &ClaimValue{
+			handle: handle,
+		},
+	}
+}
+
+func (c *ClaimValueBuilder) WithPrioritizerName(name string) *ClaimValueBuilder {
+	c.claimValue.prioritizerName = name
+	return c
+}
+
+func (c *ClaimValueBuilder) Build() *ClaimValue {
+	c.claimValue.claimName, c.claimValue.descending = parsePrioritizerName(c.claimValue.prioritizerName)
+	return c.claimValue
+}
+
+// IsValidPrioritizerName returns true if the prioritizerName is a valid claim value prioritizer.
+func IsValidPrioritizerName(prioritizerName string) bool {
+	claimName, _ := parsePrioritizerName(prioritizerName)
+	return len(claimName) > 0
+}
+
+// ReferencedClaim returns the name of the claim scored by the claim value prioritizer.
+func ReferencedClaim(prioritizerName string) (string, bool) {
+	claimName, _ := parsePrioritizerName(prioritizerName)
+	return claimName, len(claimName) > 0
+}
+
+// parse prioritizerName to claim name and order.
+// For example, prioritizerName ClaimValueDescending:cost.example.com will return cost.example.com, true.
+func parsePrioritizerName(prioritizerName string) (claimName string, descending bool) {
+	switch {
+	case strings.HasPrefix(prioritizerName, AscendingPrefix):
+		return strings.TrimPrefix(prioritizerName, AscendingPrefix), false
+	case strings.HasPrefix(prioritizerName, DescendingPrefix):
+		return strings.TrimPrefix(prioritizerName, DescendingPrefix), true
+	}
+	return "", false
+}
+
+func (c *ClaimValue) Name() string {
+	return c.prioritizerName
+}
+
+func (c *ClaimValue) Description() string {
+	return description
+}
+
+// Score normalizes the claim values of the clusters into the range from -100 to 100.
+func (c *ClaimValue) Score(ctx context.Context, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginScoreResult, *framework.Status) {
+	scores := map[string]int64{}
+	status := framework.NewStatus(c.Name(), framework.Success, "")
+
+	values := map[string]float64{}
+	for _, cluster := range clusters {
+		// default score is 0
+		scores[cluster.Name] = 0
+		if value, ok := getClaimValue(cluster, c.claimName); ok {
+			values[cluster.Name] = value
+		}
+	}
+	if len(values) == 0 {
+		return plugins.PluginScoreResult{Scores: scores}, status
+	}
+
+	// get min and max value among all the clusters
+	first := true
+	var minValue, maxValue float64
+	for _, value := range values {
+		if first || value < minValue {
+			minValue = value
+		}
+		if first || value > maxValue {
+			maxValue = value
+		}
+		first = false
+	}
+
+	// the range overflows to +Inf if the values are close to the float limits, they are scored equally
+	// in this case as well as when they are the same.
+	valueRange := maxValue - minValue
+	for name, value := range values {
+		// score = ((value - min(value)) / (max(value) - min(value)) - 0.5) * 2 * 100
+		if valueRange == 0 || math.IsInf(valueRange, 0) {
+			scores[name] = 100
+			continue
+		}
+		ratio := (value - minValue) / valueRange
+		if c.descending {
+			ratio = 1 - ratio
+		}
+		scores[name] = int64((ratio - 0.5) * 2.0 * 100.0)
+	}
+
+	return plugins.PluginScoreResult{Scores: scores}, status
+}
+
+func (c *ClaimValue) RequeueAfter(ctx context.Context, placement *clusterapiv1beta1.Placement) (plugins.PluginRequeueResult, *framework.Status) {
+	return plugins.PluginRequeueResult{}, framework.NewStatus(c.Name(), framework.Success, "")
+}
+
+// getClaimValue returns the numeric value of the claim in the status of cluster.
+func getClaimValue(cluster *clusterapiv1.ManagedCluster, claimName string) (float64, bool) {
+	for _, claim := range cluster.Status.ClusterClaims {
+		if claim.Name != claimName {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(claim.Value), 64)
+		if err != nil {
+			return 0, false
+		}
+		// NaN and Inf cannot be normalized into a score
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return 0, false
+		}
+		return value, true
+	}
+	return 0, false
+}
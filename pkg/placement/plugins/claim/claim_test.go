@@ -0,0 +1,112 @@
+package claim
+
+import (
+	"context"
+	"testing"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+func TestScoreClusterWithClaimValue(t *testing.T) {
+	cases := []struct {
+		name            string
+		prioritizerName string
+		clusters        []*clusterapiv1.ManagedCluster
+		expectedScores  map[string]int64
+	}{
+		{
+			name:            "ascending",
+			prioritizerName: "ClaimValueAscending:tier.example.com",
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithClaim("tier.example.com", "1").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithClaim("tier.example.com", "2").Build(),
+				testinghelpers.NewManagedCluster("cluster3").WithClaim("tier.example.com", "3").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": -100, "cluster2": 0, "cluster3": 100},
+		},
+		{
+			name:            "descending",
+			prioritizerName: "ClaimValueDescending:cost.example.com",
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithClaim("cost.example.com", "0.5").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithClaim("cost.example.com", "1.5").Build(),
+				testinghelpers.NewManagedCluster("cluster3").WithClaim("cost.example.com", "2.5").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": 0, "cluster3": -100},
+		},
+		{
+			name:            "same value",
+			prioritizerName: "ClaimValueDescending:cost.example.com",
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithClaim("cost.example.com", "1").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithClaim("cost.example.com", "1").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": 100},
+		},
+		{
+			name:            "missing or invalid claims",
+			prioritizerName: "ClaimValueAscending:tier.example.com",
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithClaim("tier.example.com", "1").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithClaim("tier.example.com", "gold").Build(),
+				testinghelpers.NewManagedCluster("cluster3").Build(),
+				testinghelpers.NewManagedCluster("cluster4").WithClaim("tier.example.com", "5").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": -100, "cluster2": 0, "cluster3": 0, "cluster4": 100},
+		},
+		{
+			name:            "non-finite claims",
+			prioritizerName: "ClaimValueAscending:tier.example.com",
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithClaim("tier.example.com", "1").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithClaim("tier.example.com", "NaN").Build(),
+				testinghelpers.NewManagedCluster("cluster3").WithClaim("tier.example.com", "+Inf").Build(),
+				testinghelpers.NewManagedCluster("cluster4").WithClaim("tier.example.com", "-Inf").Build(),
+				testinghelpers.NewManagedCluster("cluster5").WithClaim("tier.example.com", "5").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": -100, "cluster2": 0, "cluster3": 0, "cluster4": 0, "cluster5": 100},
+		},
+		{
+			name:            "overflowed range",
+			prioritizerName: "ClaimValueAscending:tier.example.com",
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithClaim("tier.example.com", "-1.7e308").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithClaim("tier.example.com", "1.7e308").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": 100},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := NewClaimValuePrioritizerBuilder(testinghelpers.NewFakePluginHandle(t, nil)).
+				WithPrioritizerName(c.prioritizerName).Build()
+			scoreResult, status := p.Score(context.TODO(), testinghelpers.NewPlacement("test", "test").Build(), c.clusters)
+			if err := status.AsError(); err != nil {
+				t.Errorf("Expect no error, but got %v", err)
+			}
+			if !apiequality.Semantic.DeepEqual(scoreResult.Scores, c.expectedScores) {
+				t.Errorf("Expect score %v, but got %v", c.expectedScores, scoreResult.Scores)
+			}
+		})
+	}
+}
+
+func TestIsValidPrioritizerName(t *testing.T) {
+	cases := map[string]bool{
+		"ClaimValueAscending:tier.example.com":  true,
+		"ClaimValueDescending:cost.example.com": true,
+		"ClaimValueAscending:":                  false,
+		"ClaimValue:cost.example.com":           false,
+		"Balance":                               false,
+	}
+	for name, expected := range cases {
+		if actual := IsValidPrioritizerName(name); actual != expected {
+			t.Errorf("expected %v of %q, but got %v", expected, name, actual)
+		}
+	}
+}
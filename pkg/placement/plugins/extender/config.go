@@ -0,0 +1,158 @@
+package extender
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+// FailurePolicy defines how the scheduler handles the failure of calling an extender.
+type FailurePolicy string
+
+const (
+	// FailurePolicyIgnore ignores the extender if it fails, the clusters are not filtered and get score 0.
+	FailurePolicyIgnore FailurePolicy = "Ignore"
+	// FailurePolicyFail fails the scheduling of the placement if the extender fails.
+	FailurePolicyFail FailurePolicy = "Fail"
+
+	defaultTimeout = 5 * time.Second
+)
+
+// Config is the configuration of the scheduling extenders, which is loaded from a yaml or json file.
+type Config struct {
+	Extenders []ExtenderConfig `json:"extenders"`
+}
+
+// ExtenderConfig is the configuration of a scheduling extender.
+type ExtenderConfig struct {
+	// Name is the unique name of the extender. The plugin name is Extender/<name>, which is also the
+	// builtIn name to configure the weight of the extender in the prioritizerPolicy of a placement.
+	Name string `json:"name"`
+	// URLPrefix is the prefix of the extender urls, the verbs are appended to it.
+	URLPrefix string `json:"urlPrefix"`
+	// FilterVerb is the verb of the filter call, the extender is not a filter if it is empty.
+	FilterVerb string `json:"filterVerb,omitempty"`
+	// PrioritizeVerb is the verb of the prioritize call, the extender is not a prioritizer if it is empty.
+	PrioritizeVerb string `json:"prioritizeVerb,omitempty"`
+	// Weight is the default weight of the extender prioritizer. A placement can override it in the
+	// prioritizerPolicy.
+	Weight int32 `json:"weight,omitempty"`
+	// Timeout is the timeout of each call, defaults to 5s.
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// FailurePolicy is Ignore or Fail, defaults to Ignore.
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+	// CacheTTL is the duration the results of the same placement and clusters are reused. The results
+	// are not cached if it is zero.
+	CacheTTL metav1.Duration `json:"cacheTTL,omitempty"`
+	// TLS is the tls configuration of the https calls.
+	TLS *TLSConfig `json:"tls,omitempty"`
+}
+
+// TLSConfig is the tls configuration of an extender.
+type TLSConfig struct {
+	// CAFile is the file of the CA bundle to verify the extender, the system CAs are used if empty.
+	CAFile string `json:"caFile,omitempty"`
+	// CertFile and KeyFile are the files of the client certificate and key.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// InsecureSkipVerify skips the verification of the extender certificate.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// LoadConfig loads and validates the extender configuration in the file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse extender config %s: %v", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("incorrect extender config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	names := sets.NewString()
+	for _, e := range c.Extenders {
+		if len(e.Name) == 0 {
+			return fmt.Errorf("extender name should not be empty")
+		}
+		if names.Has(e.Name) {
+			return fmt.Errorf("duplicated extender %s", e.Name)
+		}
+		names.Insert(e.Name)
+
+		if _, err := url.ParseRequestURI(e.URLPrefix); err != nil {
+			return fmt.Errorf("incorrect urlPrefix of extender %s: %v", e.Name, err)
+		}
+		if len(e.FilterVerb) == 0 && len(e.PrioritizeVerb) == 0 {
+			return fmt.Errorf("extender %s should have filterVerb or prioritizeVerb", e.Name)
+		}
+		if e.Weight < 0 {
+			return fmt.Errorf("weight of extender %s should not be negative", e.Name)
+		}
+		if e.Timeout.Duration < 0 || e.CacheTTL.Duration < 0 {
+			return fmt.Errorf("timeout and cacheTTL of extender %s should not be negative", e.Name)
+		}
+		switch e.FailurePolicy {
+		case "", FailurePolicyIgnore, FailurePolicyFail:
+		default:
+			return fmt.Errorf("incorrect failurePolicy %q of extender %s", e.FailurePolicy, e.Name)
+		}
+	}
+	return nil
+}
+
+// newHTTPClient returns the http client with the timeout and tls configuration of the extender.
+func newHTTPClient(config ExtenderConfig) (*http.Client, error) {
+	timeout := config.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	if config.TLS == nil {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// #nosec G402 -- skipping the verification is explicitly configured by the user
+		InsecureSkipVerify: config.TLS.InsecureSkipVerify,
+	}
+	if len(config.TLS.CAFile) > 0 {
+		caData, err := os.ReadFile(config.TLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificate found in %s", config.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(config.TLS.CertFile) > 0 || len(config.TLS.KeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(config.TLS.CertFile, config.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	client.Transport = transport
+	return client, nil
+}
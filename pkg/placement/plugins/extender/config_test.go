@@ -0,0 +1,119 @@
+package extender
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cases := []struct {
+		name           string
+		content        string
+		expectedConfig *Config
+		expectedErr    bool
+	}{
+		{
+			name: "valid config",
+			content: `
+extenders:
+- name: cost
+  urlPrefix: https://cost.example.com/scheduler
+  filterVerb: filter
+  prioritizeVerb: prioritize
+  weight: 2
+  timeout: 3s
+  failurePolicy: Fail
+  cacheTTL: 1m
+  tls:
+    caFile: /etc/extender/ca.crt
+- name: quota
+  urlPrefix: http://quota.example.com
+  filterVerb: filter
+`,
+			expectedConfig: &Config{
+				Extenders: []ExtenderConfig{
+					{
+						Name:           "cost",
+						URLPrefix:      "https://cost.example.com/scheduler",
+						FilterVerb:     "filter",
+						PrioritizeVerb: "prioritize",
+						Weight:         2,
+						Timeout:        metav1.Duration{Duration: 3 * time.Second},
+						FailurePolicy:  FailurePolicyFail,
+						CacheTTL:       metav1.Duration{Duration: time.Minute},
+						TLS:            &TLSConfig{CAFile: "/etc/extender/ca.crt"},
+					},
+					{
+						Name:       "quota",
+						URLPrefix:  "http://quota.example.com",
+						FilterVerb: "filter",
+					},
+				},
+			},
+		},
+		{
+			name:        "unknown field",
+			content:     "extenders:\n- name: cost\n  url: http://cost\n  filterVerb: filter\n",
+			expectedErr: true,
+		},
+		{
+			name:        "empty name",
+			content:     "extenders:\n- urlPrefix: http://cost\n  filterVerb: filter\n",
+			expectedErr: true,
+		},
+		{
+			name: "duplicated name",
+			content: "extenders:\n- name: cost\n  urlPrefix: http://cost\n  filterVerb: filter\n" +
+				"- name: cost\n  urlPrefix: http://cost2\n  filterVerb: filter\n",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid url",
+			content:     "extenders:\n- name: cost\n  urlPrefix: cost\n  filterVerb: filter\n",
+			expectedErr: true,
+		},
+		{
+			name:        "no verb",
+			content:     "extenders:\n- name: cost\n  urlPrefix: http://cost\n",
+			expectedErr: true,
+		},
+		{
+			name:        "negative weight",
+			content:     "extenders:\n- name: cost\n  urlPrefix: http://cost\n  prioritizeVerb: prioritize\n  weight: -1\n",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid failure policy",
+			content:     "extenders:\n- name: cost\n  urlPrefix: http://cost\n  filterVerb: filter\n  failurePolicy: Retry\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(c.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadConfig(path)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config, c.expectedConfig) {
+				t.Errorf("expected config %+v, but got %+v", c.expectedConfig, config)
+			}
+		})
+	}
+}
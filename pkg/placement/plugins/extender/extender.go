@@ -0,0 +1,341 @@
+package extender
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+)
+
+var _ plugins.Filter = &Extender{}
+var _ plugins.Prioritizer = &Extender{}
+
+const (
+	// namePrefix is the prefix of the extender plugin names. The name of the extender prioritizer is also
+	// the builtIn name to configure its weight in the prioritizerPolicy of a placement.
+	namePrefix = "Extender/"
+
+	// maxResponseSize is the max size of the response body read from an extender.
+	maxResponseSize = 10 << 20
+
+	description = `
+	Extender is a plugin that calls an out-of-process scheduling extender over http. The filter call
+	returns the clusters kept by the extender, and the prioritize call returns the score of each cluster.
+	`
+)
+
+// ExtenderArgs is the request body posted to the extender.
+type ExtenderArgs struct {
+	// Placement is the placement being scheduled, only the metadata and spec are set.
+	Placement *clusterapiv1beta1.Placement `json:"placement"`
+	// ClusterNames are the names of the candidate clusters.
+	ClusterNames []string `json:"clusterNames"`
+}
+
+// ExtenderFilterResult is the response of the filter call.
+type ExtenderFilterResult struct {
+	// ClusterNames are the names of the clusters kept by the extender.
+	ClusterNames []string `json:"clusterNames"`
+	// FailedClusters are the reasons why the clusters are filtered out, keyed by the cluster name.
+	FailedClusters map[string]string `json:"failedClusters,omitempty"`
+	// Error is set if the extender fails to filter the clusters.
+	Error string `json:"error,omitempty"`
+}
+
+// ExtenderPrioritizeResult is the response of the prioritize call.
+type ExtenderPrioritizeResult struct {
+	// Scores are the scores of the clusters keyed by the cluster name, in the range of -100 to 100.
+	// The clusters without a score get 0.
+	Scores map[string]int64 `json:"scores"`
+	// Error is set if the extender fails to prioritize the clusters.
+	Error string `json:"error,omitempty"`
+}
+
+type Extender struct {
+	handle plugins.Handle
+	config ExtenderConfig
+	client *http.Client
+	cache  *resultCache
+}
+
+// New returns the extender plugin with the config.
+func New(handle plugins.Handle, config ExtenderConfig) (*Extender, error) {
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client of extender %s: %v", config.Name, err)
+	}
+	return &Extender{
+		handle: handle,
+		config: config,
+		client: client,
+		cache:  newResultCache(config.CacheTTL.Duration, clock.RealClock{}),
+	}, nil
+}
+
+// NewExtenders returns the extender plugins in the config.
+func NewExtenders(handle plugins.Handle, config *Config) ([]*Extender, error) {
+	extenders := []*Extender{}
+	if config == nil {
+		return extenders, nil
+	}
+	for _, c := range config.Extenders {
+		e, err := New(handle, c)
+		if err != nil {
+			return nil, err
+		}
+		extenders = append(extenders, e)
+	}
+	return extenders, nil
+}
+
+func (e *Extender) Name() string {
+	return namePrefix + e.config.Name
+}
+
+func (e *Extender) Description() string {
+	return description
+}
+
+// IsFilter returns true if the extender has a filter verb.
+func (e *Extender) IsFilter() bool {
+	return len(e.config.FilterVerb) > 0
+}
+
+// IsPrioritizer returns true if the extender has a prioritize verb.
+func (e *Extender) IsPrioritizer() bool {
+	return len(e.config.PrioritizeVerb) > 0
+}
+
+// Weight returns the default weight of the extender prioritizer.
+func (e *Extender) Weight() int32 {
+	return e.config.Weight
+}
+
+func (e *Extender) Filter(ctx context.Context, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginFilterResult, *framework.Status) {
+	if !e.IsFilter() {
+		return plugins.PluginFilterResult{Filtered: clusters}, framework.NewStatus(e.Name(), framework.Success, "")
+	}
+
+	result := &ExtenderFilterResult{}
+	if err := e.call(ctx, e.config.FilterVerb, placement, clusters, result); err != nil {
+		return plugins.PluginFilterResult{Filtered: clusters}, e.failure(err)
+	}
+	if len(result.Error) > 0 {
+		return plugins.PluginFilterResult{Filtered: clusters}, e.failure(fmt.Errorf("%s", result.Error))
+	}
+
+	kept := make(map[string]bool, len(result.ClusterNames))
+	for _, name := range result.ClusterNames {
+		kept[name] = true
+	}
+	// keep the order of the input clusters and ignore the unknown clusters in the response
+	filtered := []*clusterapiv1.ManagedCluster{}
+	for _, cluster := range clusters {
+		if kept[cluster.Name] {
+			filtered = append(filtered, cluster)
+		}
+	}
+
+	return plugins.PluginFilterResult{
+		Filtered: filtered,
+		Reasons:  result.FailedClusters,
+	}, framework.NewStatus(e.Name(), framework.Success, "")
+}
+
+func (e *Extender) Score(ctx context.Context, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginScoreResult, *framework.Status) {
+	scores := map[string]int64{}
+	for _, cluster := range clusters {
+		scores[cluster.Name] = 0
+	}
+	if !e.IsPrioritizer() {
+		return plugins.PluginScoreResult{Scores: scores}, framework.NewStatus(e.Name(), framework.Success, "")
+	}
+
+	result := &ExtenderPrioritizeResult{}
+	if err := e.call(ctx, e.config.PrioritizeVerb, placement, clusters, result); err != nil {
+		return plugins.PluginScoreResult{Scores: scores}, e.failure(err)
+	}
+	if len(result.Error) > 0 {
+		return plugins.PluginScoreResult{Scores: scores}, e.failure(fmt.Errorf("%s", result.Error))
+	}
+
+	for name, score := range result.Scores {
+		if _, ok := scores[name]; !ok {
+			continue
+		}
+		switch {
+		case score > plugins.MaxClusterScore:
+			score = plugins.MaxClusterScore
+		case score < plugins.MinClusterScore:
+			score = plugins.MinClusterScore
+		}
+		scores[name] = score
+	}
+	return plugins.PluginScoreResult{Scores: scores}, framework.NewStatus(e.Name(), framework.Success, "")
+}
+
+func (e *Extender) RequeueAfter(ctx context.Context, placement *clusterapiv1beta1.Placement) (plugins.PluginRequeueResult, *framework.Status) {
+	return plugins.PluginRequeueResult{}, framework.NewStatus(e.Name(), framework.Success, "")
+}
+
+// failure returns the status of a failed call according to the failure policy. The clusters are not
+// filtered and get score 0 if the failure is ignored.
+func (e *Extender) failure(err error) *framework.Status {
+	if e.config.FailurePolicy == FailurePolicyFail {
+		return framework.NewStatus(e.Name(), framework.Error, fmt.Sprintf("extender %s failed: %v", e.config.Name, err))
+	}
+	return framework.NewStatus(e.Name(), framework.Warning, fmt.Sprintf("extender %s failed and is ignored: %v", e.config.Name, err))
+}
+
+// call posts the placement and the cluster names to the verb of the extender, and decodes the response
+// into the result. The response of the same request is served from the cache before it expires.
+func (e *Extender) call(ctx context.Context, verb string, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster, result interface{}) error {
+	args := &ExtenderArgs{
+		Placement: &clusterapiv1beta1.Placement{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: clusterapiv1beta1.GroupVersion.String(),
+				Kind:       "Placement",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        placement.Name,
+				Namespace:   placement.Namespace,
+				UID:         placement.UID,
+				Generation:  placement.Generation,
+				Labels:      placement.Labels,
+				Annotations: placement.Annotations,
+			},
+			Spec: placement.Spec,
+		},
+		ClusterNames: make([]string, 0, len(clusters)),
+	}
+	for _, cluster := range clusters {
+		args.ClusterNames = append(args.ClusterNames, cluster.Name)
+	}
+	body, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	key := verb + "/" + hex.EncodeToString(sum[:])
+	data, ok := e.cache.get(key)
+	if !ok {
+		data, err = e.post(ctx, verb, body)
+		if err != nil {
+			return err
+		}
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %v", verb, err)
+	}
+	// the response with an error is not cached, so it is retried in the next scheduling
+	if !ok && !hasError(result) {
+		e.cache.set(key, data)
+	}
+	return nil
+}
+
+func (e *Extender) post(ctx context.Context, verb string, body []byte) ([]byte, error) {
+	url := strings.TrimSuffix(e.config.URLPrefix, "/") + "/" + verb
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returns status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+func hasError(result interface{}) bool {
+	switch r := result.(type) {
+	case *ExtenderFilterResult:
+		return len(r.Error) > 0
+	case *ExtenderPrioritizeResult:
+		return len(r.Error) > 0
+	}
+	return false
+}
+
+// resultCache caches the responses of the extender for a ttl. Nothing is cached if the ttl is zero.
+type resultCache struct {
+	lock    sync.Mutex
+	ttl     time.Duration
+	clock   clock.Clock
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	data     []byte
+	expireAt time.Time
+}
+
+func newResultCache(ttl time.Duration, clock clock.Clock) *resultCache {
+	return &resultCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+func (c *resultCache) get(key string) ([]byte, bool) {
+	if c.ttl == 0 {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.clock.Now().Before(entry.expireAt) {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (c *resultCache) set(key string, data []byte) {
+	if c.ttl == 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// drop the expired entries, so the cache does not grow with the placements and clusters changing
+	now := c.clock.Now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expireAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{data: data, expireAt: now.Add(c.ttl)}
+}
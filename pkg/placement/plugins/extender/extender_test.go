@@ -0,0 +1,326 @@
+package extender
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+// newTestServer returns an extender keeping the clusters except cluster2, and scoring the clusters by
+// the length of their names times 50.
+func newTestServer(t *testing.T, calls *int32) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/filter", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		args := &ExtenderArgs{}
+		if err := json.NewDecoder(r.Body).Decode(args); err != nil {
+			t.Errorf("failed to decode args: %v", err)
+		}
+		result := &ExtenderFilterResult{FailedClusters: map[string]string{}}
+		for _, name := range args.ClusterNames {
+			if name == "cluster2" {
+				result.FailedClusters[name] = "rejected by " + args.Placement.Name
+				continue
+			}
+			result.ClusterNames = append(result.ClusterNames, name)
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	})
+	mux.HandleFunc("/prioritize", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		args := &ExtenderArgs{}
+		if err := json.NewDecoder(r.Body).Decode(args); err != nil {
+			t.Errorf("failed to decode args: %v", err)
+		}
+		result := &ExtenderPrioritizeResult{Scores: map[string]int64{"unknown": 10}}
+		for _, name := range args.ClusterNames {
+			result.Scores[name] = int64(len(name)) * 50
+		}
+		result.Scores["cluster1"] = -200
+		_ = json.NewEncoder(w).Encode(result)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		_, _ = w.Write([]byte(`{"error":"internal error"}`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFilter(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+	}
+
+	cases := []struct {
+		name                 string
+		verb                 string
+		failurePolicy        FailurePolicy
+		expectedClusterNames []string
+		expectedReasons      map[string]string
+		expectedCode         framework.Code
+	}{
+		{
+			name:                 "filter clusters",
+			verb:                 "filter",
+			expectedClusterNames: []string{"cluster1", "cluster3"},
+			expectedReasons:      map[string]string{"cluster2": "rejected by test"},
+		},
+		{
+			name:                 "not found with ignore policy",
+			verb:                 "notfound",
+			expectedClusterNames: []string{"cluster1", "cluster2", "cluster3"},
+			expectedCode:         framework.Warning,
+		},
+		{
+			name:          "not found with fail policy",
+			verb:          "notfound",
+			failurePolicy: FailurePolicyFail,
+			expectedCode:  framework.Error,
+		},
+		{
+			name:                 "error in response with ignore policy",
+			verb:                 "error",
+			failurePolicy:        FailurePolicyIgnore,
+			expectedClusterNames: []string{"cluster1", "cluster2", "cluster3"},
+			expectedCode:         framework.Warning,
+		},
+		{
+			name:          "error in response with fail policy",
+			verb:          "error",
+			failurePolicy: FailurePolicyFail,
+			expectedCode:  framework.Error,
+		},
+		{
+			name:          "timeout with fail policy",
+			verb:          "slow",
+			failurePolicy: FailurePolicyFail,
+			expectedCode:  framework.Error,
+		},
+	}
+
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := New(testinghelpers.NewFakePluginHandle(t, nil), ExtenderConfig{
+				Name:          "test",
+				URLPrefix:     server.URL,
+				FilterVerb:    c.verb,
+				Timeout:       metav1.Duration{Duration: 50 * time.Millisecond},
+				FailurePolicy: c.failurePolicy,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, status := e.Filter(context.TODO(), testinghelpers.NewPlacement("default", "test").Build(), clusters)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+
+			actual := []string{}
+			for _, cluster := range result.Filtered {
+				actual = append(actual, cluster.Name)
+			}
+			if !reflect.DeepEqual(actual, c.expectedClusterNames) {
+				t.Errorf("expected clusters %v, but got %v", c.expectedClusterNames, actual)
+			}
+			if len(c.expectedReasons) > 0 && !reflect.DeepEqual(result.Reasons, c.expectedReasons) {
+				t.Errorf("expected reasons %v, but got %v", c.expectedReasons, result.Reasons)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("c2").Build(),
+		testinghelpers.NewManagedCluster("c3").Build(),
+	}
+
+	cases := []struct {
+		name           string
+		verb           string
+		failurePolicy  FailurePolicy
+		expectedScores map[string]int64
+		expectedCode   framework.Code
+	}{
+		{
+			name:           "prioritize clusters",
+			verb:           "prioritize",
+			expectedScores: map[string]int64{"cluster1": -100, "c2": 100, "c3": 100},
+		},
+		{
+			name:           "error with ignore policy",
+			verb:           "error",
+			expectedScores: map[string]int64{"cluster1": 0, "c2": 0, "c3": 0},
+			expectedCode:   framework.Warning,
+		},
+		{
+			name:          "error with fail policy",
+			verb:          "error",
+			failurePolicy: FailurePolicyFail,
+			expectedCode:  framework.Error,
+		},
+	}
+
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := New(testinghelpers.NewFakePluginHandle(t, nil), ExtenderConfig{
+				Name:           "test",
+				URLPrefix:      server.URL + "/",
+				PrioritizeVerb: c.verb,
+				FailurePolicy:  c.failurePolicy,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, status := e.Score(context.TODO(), testinghelpers.NewPlacement("default", "test").Build(), clusters)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+			if !reflect.DeepEqual(result.Scores, c.expectedScores) {
+				t.Errorf("expected scores %v, but got %v", c.expectedScores, result.Scores)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+		testinghelpers.NewManagedCluster("cluster2").Build(),
+	}
+
+	var calls int32
+	server := newTestServer(t, &calls)
+	defer server.Close()
+
+	e, err := New(testinghelpers.NewFakePluginHandle(t, nil), ExtenderConfig{
+		Name:       "test",
+		URLPrefix:  server.URL,
+		FilterVerb: "filter",
+		CacheTTL:   metav1.Duration{Duration: time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	e.cache.clock = fakeClock
+
+	placement := testinghelpers.NewPlacement("default", "test").Build()
+	filter := func(clusters []*clusterapiv1.ManagedCluster, expectedCalls int32) {
+		if _, status := e.Filter(context.TODO(), placement, clusters); status.Code() != framework.Success {
+			t.Fatalf("unexpected status: %s", status.Message())
+		}
+		if actual := atomic.LoadInt32(&calls); actual != expectedCalls {
+			t.Errorf("expected %d calls, but got %d", expectedCalls, actual)
+		}
+	}
+
+	filter(clusters, 1)
+	// the same request is served from the cache
+	filter(clusters, 1)
+	// the clusters change
+	filter(clusters[:1], 2)
+	// the placement changes
+	placement.Generation = 2
+	filter(clusters, 3)
+	// the cache expires
+	fakeClock.Step(2 * time.Minute)
+	filter(clusters, 4)
+}
+
+func TestTLS(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, &calls)
+	server.Close()
+	server = httptest.NewUnstartedServer(server.Config.Handler)
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caData, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name         string
+		tls          *TLSConfig
+		expectedCode framework.Code
+	}{
+		{
+			name:         "unknown authority",
+			expectedCode: framework.Error,
+		},
+		{
+			name:         "ca file",
+			tls:          &TLSConfig{CAFile: caFile},
+			expectedCode: framework.Success,
+		},
+		{
+			name:         "insecure skip verify",
+			tls:          &TLSConfig{InsecureSkipVerify: true},
+			expectedCode: framework.Success,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := New(testinghelpers.NewFakePluginHandle(t, nil), ExtenderConfig{
+				Name:          "test",
+				URLPrefix:     server.URL,
+				FilterVerb:    "filter",
+				FailurePolicy: FailurePolicyFail,
+				TLS:           c.tls,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clusters := []*clusterapiv1.ManagedCluster{testinghelpers.NewManagedCluster("cluster1").Build()}
+			_, status := e.Filter(context.TODO(), testinghelpers.NewPlacement("default", "test").Build(), clusters)
+			if status.Code() != c.expectedCode {
+				t.Errorf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+		})
+	}
+}
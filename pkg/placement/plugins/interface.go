@@ -7,6 +7,7 @@ import (
 
 	"k8s.io/client-go/tools/events"
 
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterlisterv1alpha1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1alpha1"
@@ -45,6 +46,17 @@ type Filter interface {
 	Filter(ctx context.Context, placement *clusterapiv1beta1.Placement, clusters []*clusterapiv1.ManagedCluster) (PluginFilterResult, *framework.Status)
 }
 
+// CacheableFilter defines a filter plugin whose result of a cluster only depends on the placement
+// and the cluster itself. The scheduler caches the result of each cluster and reuses it until the
+// cluster or the cache key of the placement changes.
+type CacheableFilter interface {
+	Filter
+
+	// CacheKey returns a key identifying the inputs of the placement the filter depends on. Placements
+	// with the same key share the cached results.
+	CacheKey(placement *clusterapiv1beta1.Placement) string
+}
+
 // Prioritizer defines a prioritizer plugin that score each cluster. The score is normalized
 // as a floating between 0 and 1.
 type Prioritizer interface {
@@ -67,6 +79,9 @@ type Handle interface {
 	// ClusterLister lists all ManagedClusters
 	ClusterLister() clusterlisterv1.ManagedClusterLister
 
+	// AddOnLister lists all ManagedClusterAddOns
+	AddOnLister() addonlisterv1alpha1.ManagedClusterAddOnLister
+
 	// ClusterClient returns the cluster client
 	ClusterClient() clusterclient.Interface
 
@@ -78,12 +93,29 @@ type Handle interface {
 type PluginFilterResult struct {
 	// Filtered contains the filtered ManagedCluster.
 	Filtered []*clusterapiv1.ManagedCluster
+
+	// Reasons contains the reason why a ManagedCluster is filtered out, keyed by the
+	// cluster name. It is optional and may not cover all the filtered out clusters.
+	Reasons map[string]string
 }
 
 // PluginScoreResult contains the details of a score plugin result.
 type PluginScoreResult struct {
 	// Scores contains the ManagedCluster scores.
 	Scores map[string]int64
+
+	// StaleClusters contains the reason why the score of a ManagedCluster is missing or expired,
+	// keyed by the cluster name. It is optional and only set by the prioritizers depending on
+	// scores reported by the managed clusters.
+	StaleClusters map[string]string
+
+	// StalePolicy is the policy giving the scores of the StaleClusters. It is only set if the placement
+	// defines a stale score policy.
+	StalePolicy string
+
+	// Excluded contains the reason why a ManagedCluster should not be selected, keyed by the
+	// cluster name. The excluded clusters are removed from the feasible clusters after scoring.
+	Excluded map[string]string
 }
 
 // PluginRequeueResult contains the requeue result of a placement.
@@ -91,3 +123,12 @@ type PluginRequeueResult struct {
 	// RequeueTime contains the expect requeue time.
 	RequeueTime *time.Time
 }
+
+// GetClusterClaims returns a map containing cluster claims from the status of cluster
+func GetClusterClaims(cluster *clusterapiv1.ManagedCluster) map[string]string {
+	claims := map[string]string{}
+	for _, claim := range cluster.Status.ClusterClaims {
+		claims[claim.Name] = claim.Value
+	}
+	return claims
+}
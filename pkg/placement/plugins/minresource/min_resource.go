@@ -0,0 +1,144 @@
+package minresource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+)
+
+var _ plugins.CacheableFilter = &MinimumResource{}
+
+const (
+	// MinimumResourcesAnnotation is the annotation on Placement which defines the minimum allocatable
+	// resources a cluster should have to be selected. The value is a json encoded map from resource
+	// name to quantity, for example {"cpu":"4","memory":"16Gi","nvidia.com/gpu":"1"}.
+	MinimumResourcesAnnotation = "cluster.open-cluster-management.io/experimental-minimum-resources"
+
+	description = `
+	MinimumResource is a plugin that filters out the managed clusters whose allocatable
+	resources are less than the minimum resources required by the placement.
+	`
+)
+
+type MinimumResource struct {
+	handle plugins.Handle
+}
+
+func New(handle plugins.Handle) *MinimumResource {
+	return &MinimumResource{
+		handle: handle,
+	}
+}
+
+func (p *MinimumResource) Name() string {
+	return reflect.TypeOf(*p).Name()
+}
+
+func (p *MinimumResource) Description() string {
+	return description
+}
+
+func (p *MinimumResource) Filter(ctx context.Context, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginFilterResult, *framework.Status) {
+	status := framework.NewStatus(p.Name(), framework.Success, "")
+
+	minResources, err := getMinimumResources(placement)
+	if err != nil {
+		return plugins.PluginFilterResult{}, framework.NewStatus(
+			p.Name(),
+			framework.Misconfigured,
+			err.Error(),
+		)
+	}
+	if len(minResources) == 0 || len(clusters) == 0 {
+		return plugins.PluginFilterResult{
+			Filtered: clusters,
+		}, status
+	}
+
+	// check the resources in order to have a stable reason
+	resourceNames := []string{}
+	for name := range minResources {
+		resourceNames = append(resourceNames, string(name))
+	}
+	sort.Strings(resourceNames)
+
+	matched := []*clusterapiv1.ManagedCluster{}
+	reasons := map[string]string{}
+	for _, cluster := range clusters {
+		insufficient := []string{}
+		for _, name := range resourceNames {
+			resourceName := clusterapiv1.ResourceName(name)
+			required := minResources[resourceName]
+			allocatable, ok := cluster.Status.Allocatable[resourceName]
+			switch {
+			case !ok:
+				insufficient = append(insufficient, fmt.Sprintf("no allocatable %s", name))
+			case allocatable.Cmp(required) < 0:
+				insufficient = append(insufficient, fmt.Sprintf("allocatable %s %s is less than %s",
+					name, allocatable.String(), required.String()))
+			}
+		}
+		if len(insufficient) > 0 {
+			reasons[cluster.Name] = strings.Join(insufficient, "; ")
+			continue
+		}
+		matched = append(matched, cluster)
+	}
+
+	return plugins.PluginFilterResult{
+		Filtered: matched,
+		Reasons:  reasons,
+	}, status
+}
+
+// CacheKey returns the minimum resources annotation of the placement.
+func (p *MinimumResource) CacheKey(placement *clusterapiv1beta1.Placement) string {
+	return placement.GetAnnotations()[MinimumResourcesAnnotation]
+}
+
+// RequeueAfter returns an empty result since the placement is enqueued by the scheduling controller
+// once the status of a managed cluster changes.
+func (p *MinimumResource) RequeueAfter(ctx context.Context, placement *clusterapiv1beta1.Placement) (plugins.PluginRequeueResult, *framework.Status) {
+	return plugins.PluginRequeueResult{}, framework.NewStatus(p.Name(), framework.Success, "")
+}
+
+// getMinimumResources returns the minimum resources defined in the annotation of the placement.
+func getMinimumResources(placement *clusterapiv1beta1.Placement) (map[clusterapiv1.ResourceName]resource.Quantity, error) {
+	value, ok := placement.GetAnnotations()[MinimumResourcesAnnotation]
+	if !ok || len(value) == 0 {
+		return nil, nil
+	}
+
+	quantities := map[string]string{}
+	if err := json.Unmarshal([]byte(value), &quantities); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", MinimumResourcesAnnotation, err)
+	}
+
+	minResources := map[clusterapiv1.ResourceName]resource.Quantity{}
+	for name, quantity := range quantities {
+		if len(name) == 0 {
+			return nil, fmt.Errorf("resource name in annotation %s should not be empty", MinimumResourcesAnnotation)
+		}
+		q, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect quantity %q of resource %s: %v", quantity, name, err)
+		}
+		if q.Sign() < 0 {
+			return nil, fmt.Errorf("quantity of resource %s should not be negative", name)
+		}
+		minResources[clusterapiv1.ResourceName(name)] = q
+	}
+	return minResources, nil
+}
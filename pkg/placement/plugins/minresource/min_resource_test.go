@@ -0,0 +1,99 @@
+package minresource
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+func TestMatchWithMinimumResources(t *testing.T) {
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").
+			WithResource(clusterapiv1.ResourceCPU, "8", "8").
+			WithResource(clusterapiv1.ResourceMemory, "32Gi", "32Gi").
+			WithResource("nvidia.com/gpu", "2", "2").Build(),
+		testinghelpers.NewManagedCluster("cluster2").
+			WithResource(clusterapiv1.ResourceCPU, "2", "8").
+			WithResource(clusterapiv1.ResourceMemory, "16Gi", "32Gi").Build(),
+		testinghelpers.NewManagedCluster("cluster3").Build(),
+	}
+
+	cases := []struct {
+		name                 string
+		placement            *clusterapiv1beta1.Placement
+		expectedClusterNames []string
+		expectedReasons      map[string]string
+		expectedCode         framework.Code
+	}{
+		{
+			name:                 "no minimum resources",
+			placement:            testinghelpers.NewPlacement("test", "test").Build(),
+			expectedClusterNames: []string{"cluster1", "cluster2", "cluster3"},
+		},
+		{
+			name: "minimum cpu and memory",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				MinimumResourcesAnnotation: `{"cpu":"2","memory":"16Gi"}`,
+			}).Build(),
+			expectedClusterNames: []string{"cluster1", "cluster2"},
+			expectedReasons: map[string]string{
+				"cluster3": "no allocatable cpu; no allocatable memory",
+			},
+		},
+		{
+			name: "minimum cpu and gpu",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				MinimumResourcesAnnotation: `{"cpu":"4","nvidia.com/gpu":"1"}`,
+			}).Build(),
+			expectedClusterNames: []string{"cluster1"},
+			expectedReasons: map[string]string{
+				"cluster2": "allocatable cpu 2 is less than 4; no allocatable nvidia.com/gpu",
+				"cluster3": "no allocatable cpu; no allocatable nvidia.com/gpu",
+			},
+		},
+		{
+			name: "invalid annotation",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				MinimumResourcesAnnotation: `{"cpu":`,
+			}).Build(),
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name: "invalid quantity",
+			placement: testinghelpers.NewPlacementWithAnnotations("test", "test", map[string]string{
+				MinimumResourcesAnnotation: `{"cpu":"four"}`,
+			}).Build(),
+			expectedCode: framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := New(testinghelpers.NewFakePluginHandle(t, nil))
+			result, status := p.Filter(context.TODO(), c.placement, clusters)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+
+			actual := []string{}
+			for _, cluster := range result.Filtered {
+				actual = append(actual, cluster.Name)
+			}
+			if !reflect.DeepEqual(actual, c.expectedClusterNames) {
+				t.Errorf("expected clusters %v, but got %v", c.expectedClusterNames, actual)
+			}
+			if len(c.expectedReasons) > 0 && !reflect.DeepEqual(result.Reasons, c.expectedReasons) {
+				t.Errorf("expected reasons %v, but got %v", c.expectedReasons, result.Reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package placementaffinity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	"open-cluster-management.io/ocm/pkg/placement/plugins"
+)
+
+var _ plugins.Filter = &PlacementAffinity{}
+var _ plugins.Prioritizer = &PlacementAffinity{}
+
+const (
+	// PlacementAffinityAnnotation is the annotation on Placement which defines the affinity and
+	// anti-affinity to the other placements in the same namespace. The value is a json encoded
+	// PlacementAffinityTerms.
+	PlacementAffinityAnnotation = "cluster.open-cluster-management.io/experimental-placement-affinity"
+
+	description = `
+	PlacementAffinity makes the scheduling decisions based on the decisions of the other placements
+	in the same namespace. As a filter, it keeps the clusters selected by all the placements in
+	requiredAffinity and none of the placements in requiredAntiAffinity. As a prioritizer, the
+	clusters selected by more placements in preferredAffinity and less placements in
+	preferredAntiAffinity are given the higher score. The prioritizer is enabled with weight 1 if
+	the placement has preferred terms, unless the prioritizer policy configures it otherwise.
+	`
+)
+
+// PlacementAffinityTerms defines the names of the placements in the same namespace a placement
+// has affinity or anti-affinity to.
+type PlacementAffinityTerms struct {
+	// RequiredAffinity requires the clusters to be selected by all the placements.
+	RequiredAffinity []string `json:"requiredAffinity,omitempty"`
+	// RequiredAntiAffinity requires the clusters not to be selected by any of the placements.
+	RequiredAntiAffinity []string `json:"requiredAntiAffinity,omitempty"`
+	// PreferredAffinity prefers the clusters selected by the placements.
+	PreferredAffinity []string `json:"preferredAffinity,omitempty"`
+	// PreferredAntiAffinity prefers the clusters not selected by the placements.
+	PreferredAntiAffinity []string `json:"preferredAntiAffinity,omitempty"`
+}
+
+type PlacementAffinity struct {
+	handle plugins.Handle
+}
+
+func New(handle plugins.Handle) *PlacementAffinity {
+	return &PlacementAffinity{
+		handle: handle,
+	}
+}
+
+func (p *PlacementAffinity) Name() string {
+	return reflect.TypeOf(*p).Name()
+}
+
+func (p *PlacementAffinity) Description() string {
+	return description
+}
+
+func (p *PlacementAffinity) Filter(ctx context.Context, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginFilterResult, *framework.Status) {
+	status := framework.NewStatus(p.Name(), framework.Success, "")
+
+	terms, err := GetPlacementAffinityTerms(placement)
+	if err != nil {
+		return plugins.PluginFilterResult{}, framework.NewStatus(p.Name(), framework.Misconfigured, err.Error())
+	}
+	if len(terms.RequiredAffinity) == 0 && len(terms.RequiredAntiAffinity) == 0 {
+		return plugins.PluginFilterResult{
+			Filtered: clusters,
+		}, status
+	}
+
+	affinity := p.getDecisionClusterNames(placement.Namespace, terms.RequiredAffinity)
+	antiAffinity := p.getDecisionClusterNames(placement.Namespace, terms.RequiredAntiAffinity)
+
+	matched := []*clusterapiv1.ManagedCluster{}
+	reasons := map[string]string{}
+	for _, cluster := range clusters {
+		unmatched := []string{}
+		for _, name := range terms.RequiredAffinity {
+			if !affinity[name].Has(cluster.Name) {
+				unmatched = append(unmatched, fmt.Sprintf("not selected by placement %s", name))
+			}
+		}
+		for _, name := range terms.RequiredAntiAffinity {
+			if antiAffinity[name].Has(cluster.Name) {
+				unmatched = append(unmatched, fmt.Sprintf("selected by placement %s", name))
+			}
+		}
+		if len(unmatched) > 0 {
+			reasons[cluster.Name] = strings.Join(unmatched, "; ")
+			continue
+		}
+		matched = append(matched, cluster)
+	}
+
+	return plugins.PluginFilterResult{
+		Filtered: matched,
+		Reasons:  reasons,
+	}, status
+}
+
+// Score gives each cluster 100 for every preferred placement selecting it and -100 for every
+// preferred anti-affinity placement selecting it, and averages the result by the number of placements.
+// The score range is from -100 to 100.
+func (p *PlacementAffinity) Score(ctx context.Context, placement *clusterapiv1beta1.Placement,
+	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginScoreResult, *framework.Status) {
+	scores := map[string]int64{}
+	status := framework.NewStatus(p.Name(), framework.Success, "")
+
+	terms, err := GetPlacementAffinityTerms(placement)
+	if err != nil {
+		return plugins.PluginScoreResult{}, framework.NewStatus(p.Name(), framework.Misconfigured, err.Error())
+	}
+
+	affinity := p.getDecisionClusterNames(placement.Namespace, terms.PreferredAffinity)
+	antiAffinity := p.getDecisionClusterNames(placement.Namespace, terms.PreferredAntiAffinity)
+	numOfTerms := int64(len(terms.PreferredAffinity) + len(terms.PreferredAntiAffinity))
+
+	for _, cluster := range clusters {
+		scores[cluster.Name] = 0
+		if numOfTerms == 0 {
+			continue
+		}
+		var score int64
+		for _, name := range terms.PreferredAffinity {
+			if affinity[name].Has(cluster.Name) {
+				score += plugins.MaxClusterScore
+			}
+		}
+		for _, name := range terms.PreferredAntiAffinity {
+			if antiAffinity[name].Has(cluster.Name) {
+				score += plugins.MinClusterScore
+			}
+		}
+		scores[cluster.Name] = score / numOfTerms
+	}
+
+	return plugins.PluginScoreResult{
+		Scores: scores,
+	}, status
+}
+
+func (p *PlacementAffinity) RequeueAfter(ctx context.Context, placement *clusterapiv1beta1.Placement) (plugins.PluginRequeueResult, *framework.Status) {
+	return plugins.PluginRequeueResult{}, framework.NewStatus(p.Name(), framework.Success, "")
+}
+
+// getDecisionClusterNames returns the names of the clusters in the decisions of each placement.
+func (p *PlacementAffinity) getDecisionClusterNames(namespace string, placementNames []string) map[string]sets.String {
+	clusterNames := map[string]sets.String{}
+	for _, name := range placementNames {
+		clusterNames[name] = sets.NewString()
+		decisions, err := p.handle.DecisionLister().PlacementDecisions(namespace).List(
+			labels.SelectorFromSet(labels.Set{clusterapiv1beta1.PlacementLabel: name}))
+		if err != nil {
+			continue
+		}
+		for _, decision := range decisions {
+			for _, d := range decision.Status.Decisions {
+				clusterNames[name].Insert(d.ClusterName)
+			}
+		}
+	}
+	return clusterNames
+}
+
+// GetPlacementAffinityTerms returns the PlacementAffinityTerms defined in the annotation of the placement.
+func GetPlacementAffinityTerms(placement *clusterapiv1beta1.Placement) (*PlacementAffinityTerms, error) {
+	terms := &PlacementAffinityTerms{}
+	value, ok := placement.GetAnnotations()[PlacementAffinityAnnotation]
+	if !ok || len(value) == 0 {
+		return terms, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), terms); err != nil {
+		return terms, fmt.Errorf("failed to parse annotation %s: %v", PlacementAffinityAnnotation, err)
+	}
+
+	for _, name := range ReferencedPlacements(terms) {
+		if len(name) == 0 {
+			return terms, fmt.Errorf("placement name in annotation %s should not be empty", PlacementAffinityAnnotation)
+		}
+		if name == placement.Name {
+			return terms, fmt.Errorf("placement %s should not have affinity to itself", placement.Name)
+		}
+	}
+	return terms, nil
+}
+
+// ReferencedPlacements returns the names of all the placements in the terms.
+func ReferencedPlacements(terms *PlacementAffinityTerms) []string {
+	names := sets.NewString()
+	names.Insert(terms.RequiredAffinity...)
+	names.Insert(terms.RequiredAntiAffinity...)
+	names.Insert(terms.PreferredAffinity...)
+	names.Insert(terms.PreferredAntiAffinity...)
+	return names.List()
+}
@@ -0,0 +1,154 @@
+package placementaffinity
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
+	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
+)
+
+var clusters = []*clusterapiv1.ManagedCluster{
+	testinghelpers.NewManagedCluster("cluster1").Build(),
+	testinghelpers.NewManagedCluster("cluster2").Build(),
+	testinghelpers.NewManagedCluster("cluster3").Build(),
+}
+
+var decisions = []runtime.Object{
+	testinghelpers.NewPlacementDecision("test", "primary-decision-1").
+		WithLabel(clusterapiv1beta1.PlacementLabel, "primary").WithDecisions("cluster1").Build(),
+	testinghelpers.NewPlacementDecision("test", "db-decision-1").
+		WithLabel(clusterapiv1beta1.PlacementLabel, "db").WithDecisions("cluster1", "cluster2").Build(),
+	testinghelpers.NewPlacementDecision("other", "db-decision-1").
+		WithLabel(clusterapiv1beta1.PlacementLabel, "db").WithDecisions("cluster3").Build(),
+}
+
+func TestFilterWithPlacementAffinity(t *testing.T) {
+	cases := []struct {
+		name                 string
+		annotation           string
+		expectedClusterNames []string
+		expectedReasons      map[string]string
+		expectedCode         framework.Code
+	}{
+		{
+			name:                 "no placement affinity",
+			expectedClusterNames: []string{"cluster1", "cluster2", "cluster3"},
+		},
+		{
+			name:                 "required affinity",
+			annotation:           `{"requiredAffinity":["db"]}`,
+			expectedClusterNames: []string{"cluster1", "cluster2"},
+			expectedReasons:      map[string]string{"cluster3": "not selected by placement db"},
+		},
+		{
+			name:                 "required anti-affinity",
+			annotation:           `{"requiredAntiAffinity":["primary"]}`,
+			expectedClusterNames: []string{"cluster2", "cluster3"},
+			expectedReasons:      map[string]string{"cluster1": "selected by placement primary"},
+		},
+		{
+			name:                 "required affinity and anti-affinity",
+			annotation:           `{"requiredAffinity":["db"],"requiredAntiAffinity":["primary"]}`,
+			expectedClusterNames: []string{"cluster2"},
+			expectedReasons: map[string]string{
+				"cluster1": "selected by placement primary",
+				"cluster3": "not selected by placement db",
+			},
+		},
+		{
+			name:                 "required affinity to placement without decisions",
+			annotation:           `{"requiredAffinity":["unknown"]}`,
+			expectedClusterNames: []string{},
+		},
+		{
+			name:         "invalid annotation",
+			annotation:   `{"requiredAffinity":`,
+			expectedCode: framework.Misconfigured,
+		},
+		{
+			name:         "affinity to itself",
+			annotation:   `{"requiredAffinity":["dr"]}`,
+			expectedCode: framework.Misconfigured,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			placement := testinghelpers.NewPlacementWithAnnotations("test", "dr", map[string]string{
+				PlacementAffinityAnnotation: c.annotation,
+			}).Build()
+			p := New(testinghelpers.NewFakePluginHandle(t, nil, decisions...))
+
+			result, status := p.Filter(context.TODO(), placement, clusters)
+			if status.Code() != c.expectedCode {
+				t.Fatalf("expected code %v, but got %v: %s", c.expectedCode, status.Code(), status.Message())
+			}
+			if status.IsError() {
+				return
+			}
+
+			actual := []string{}
+			for _, cluster := range result.Filtered {
+				actual = append(actual, cluster.Name)
+			}
+			if !reflect.DeepEqual(actual, c.expectedClusterNames) {
+				t.Errorf("expected clusters %v, but got %v", c.expectedClusterNames, actual)
+			}
+			if len(c.expectedReasons) > 0 && !reflect.DeepEqual(result.Reasons, c.expectedReasons) {
+				t.Errorf("expected reasons %v, but got %v", c.expectedReasons, result.Reasons)
+			}
+		})
+	}
+}
+
+func TestScoreWithPlacementAffinity(t *testing.T) {
+	cases := []struct {
+		name           string
+		annotation     string
+		expectedScores map[string]int64
+	}{
+		{
+			name:           "no placement affinity",
+			expectedScores: map[string]int64{"cluster1": 0, "cluster2": 0, "cluster3": 0},
+		},
+		{
+			name:           "preferred affinity",
+			annotation:     `{"preferredAffinity":["db"]}`,
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": 100, "cluster3": 0},
+		},
+		{
+			name:           "preferred affinity and anti-affinity",
+			annotation:     `{"preferredAffinity":["db"],"preferredAntiAffinity":["primary"]}`,
+			expectedScores: map[string]int64{"cluster1": 0, "cluster2": 50, "cluster3": 0},
+		},
+		{
+			name:           "preferred anti-affinity",
+			annotation:     `{"preferredAntiAffinity":["primary"]}`,
+			expectedScores: map[string]int64{"cluster1": -100, "cluster2": 0, "cluster3": 0},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			placement := testinghelpers.NewPlacementWithAnnotations("test", "dr", map[string]string{
+				PlacementAffinityAnnotation: c.annotation,
+			}).Build()
+			p := New(testinghelpers.NewFakePluginHandle(t, nil, decisions...))
+
+			result, status := p.Score(context.TODO(), placement, clusters)
+			if err := status.AsError(); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(result.Scores, c.expectedScores) {
+				t.Errorf("expected scores %v, but got %v", c.expectedScores, result.Scores)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package predicate
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/blang/semver/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Extended operators of the label/claim selector requirements. The numeric operators compare the
+// value of a label/claim with the value of the requirement as numbers, and the semver operators
+// compare them as semantic versions, for example "v1.26.3" or "1.26". A requirement with an extended
+// operator must have exactly one value. A cluster without the label/claim, or whose value cannot be
+// parsed, does not match the requirement.
+const (
+	OpGt metav1.LabelSelectorOperator = "Gt"
+	OpLt metav1.LabelSelectorOperator = "Lt"
+	OpGe metav1.LabelSelectorOperator = "Ge"
+	OpLe metav1.LabelSelectorOperator = "Le"
+
+	OpSemverGt metav1.LabelSelectorOperator = "SemverGt"
+	OpSemverLt metav1.LabelSelectorOperator = "SemverLt"
+	OpSemverGe metav1.LabelSelectorOperator = "SemverGe"
+	OpSemverLe metav1.LabelSelectorOperator = "SemverLe"
+)
+
+var numericOperators = map[metav1.LabelSelectorOperator]bool{
+	OpGt: true, OpLt: true, OpGe: true, OpLe: true,
+}
+
+var semverOperators = map[metav1.LabelSelectorOperator]bool{
+	OpSemverGt: true, OpSemverLt: true, OpSemverGe: true, OpSemverLe: true,
+}
+
+// extendedRequirement is a requirement with a numeric or semver operator.
+type extendedRequirement struct {
+	key      string
+	operator metav1.LabelSelectorOperator
+	value    string
+	number   float64
+	version  semver.Version
+}
+
+// isExtendedOperator returns true if the operator is a numeric or semver operator.
+func isExtendedOperator(operator metav1.LabelSelectorOperator) bool {
+	return numericOperators[operator] || semverOperators[operator]
+}
+
+// newExtendedRequirement validates the requirement and returns an extendedRequirement.
+func newExtendedRequirement(requirement metav1.LabelSelectorRequirement) (extendedRequirement, error) {
+	r := extendedRequirement{key: requirement.Key, operator: requirement.Operator}
+	if len(requirement.Values) != 1 {
+		return r, fmt.Errorf("values of requirement %q with operator %s should have exactly one element",
+			requirement.Key, requirement.Operator)
+	}
+	r.value = requirement.Values[0]
+
+	var err error
+	switch {
+	case numericOperators[requirement.Operator]:
+		if r.number, err = strconv.ParseFloat(r.value, 64); err != nil {
+			return r, fmt.Errorf("value %q of requirement %q with operator %s is not a number",
+				r.value, requirement.Key, requirement.Operator)
+		}
+	case semverOperators[requirement.Operator]:
+		if r.version, err = semver.ParseTolerant(r.value); err != nil {
+			return r, fmt.Errorf("value %q of requirement %q with operator %s is not a semantic version",
+				r.value, requirement.Key, requirement.Operator)
+		}
+	default:
+		return r, fmt.Errorf("%q is not a valid operator", requirement.Operator)
+	}
+	return r, nil
+}
+
+// Matches returns true if the value of the key in the set satisfies the requirement.
+func (r extendedRequirement) Matches(set labels.Set) bool {
+	if !set.Has(r.key) {
+		return false
+	}
+	value := set.Get(r.key)
+
+	var result int
+	if numericOperators[r.operator] {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		switch {
+		case number < r.number:
+			result = -1
+		case number > r.number:
+			result = 1
+		}
+	} else {
+		version, err := semver.ParseTolerant(value)
+		if err != nil {
+			return false
+		}
+		result = version.Compare(r.version)
+	}
+
+	switch r.operator {
+	case OpGt, OpSemverGt:
+		return result > 0
+	case OpLt, OpSemverLt:
+		return result < 0
+	case OpGe, OpSemverGe:
+		return result >= 0
+	case OpLe, OpSemverLe:
+		return result <= 0
+	}
+	return false
+}
+
+func (r extendedRequirement) String() string {
+	return fmt.Sprintf("%s %s %s", r.key, r.operator, r.value)
+}
+
+// extendedSelector is a selector combining a set-based labels.Selector with the extended requirements.
+type extendedSelector struct {
+	selector     labels.Selector
+	requirements []extendedRequirement
+}
+
+// newExtendedSelector builds an extendedSelector with the label selector. The requirements with the
+// extended operators are split from the label selector, and the others are converted to labels.Selector.
+func newExtendedSelector(labelSelector metav1.LabelSelector) (*extendedSelector, error) {
+	s := &extendedSelector{}
+	setBased := metav1.LabelSelector{MatchLabels: labelSelector.MatchLabels}
+	for _, requirement := range labelSelector.MatchExpressions {
+		if !isExtendedOperator(requirement.Operator) {
+			setBased.MatchExpressions = append(setBased.MatchExpressions, requirement)
+			continue
+		}
+		r, err := newExtendedRequirement(requirement)
+		if err != nil {
+			return nil, err
+		}
+		s.requirements = append(s.requirements, r)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&setBased)
+	if err != nil {
+		return nil, err
+	}
+	s.selector = selector
+	return s, nil
+}
+
+// Matches returns true if the set matches the label selector and all extended requirements.
+func (s *extendedSelector) Matches(set labels.Set) bool {
+	if !s.selector.Matches(set) {
+		return false
+	}
+	for _, r := range s.requirements {
+		if !r.Matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *extendedSelector) String() string {
+	str := s.selector.String()
+	for _, r := range s.requirements {
+		if len(str) > 0 {
+			str += ","
+		}
+		str += r.String()
+	}
+	return str
+}
@@ -0,0 +1,158 @@
+package predicate
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestExtendedSelector(t *testing.T) {
+	cases := []struct {
+		name          string
+		labelSelector metav1.LabelSelector
+		set           labels.Set
+		expectedErr   bool
+		expectedMatch bool
+	}{
+		{
+			name: "numeric greater than",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: OpGt, Values: []string{"3"}},
+			}},
+			set:           labels.Set{"nodes": "5"},
+			expectedMatch: true,
+		},
+		{
+			name: "numeric greater than with equal value",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: OpGt, Values: []string{"3"}},
+			}},
+			set: labels.Set{"nodes": "3"},
+		},
+		{
+			name: "numeric greater than or equal with equal value",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: OpGe, Values: []string{"3"}},
+			}},
+			set:           labels.Set{"nodes": "3"},
+			expectedMatch: true,
+		},
+		{
+			name: "numeric less than with float",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "load", Operator: OpLt, Values: []string{"0.75"}},
+			}},
+			set:           labels.Set{"load": "0.5"},
+			expectedMatch: true,
+		},
+		{
+			name: "numeric less than or equal",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: OpLe, Values: []string{"3"}},
+			}},
+			set: labels.Set{"nodes": "10"},
+		},
+		{
+			name: "numeric with value not a number",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: OpGt, Values: []string{"3"}},
+			}},
+			set: labels.Set{"nodes": "many"},
+		},
+		{
+			name: "numeric without the key",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: OpLt, Values: []string{"3"}},
+			}},
+			set: labels.Set{},
+		},
+		{
+			name: "semver greater than or equal",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "kubeversion.open-cluster-management.io", Operator: OpSemverGe, Values: []string{"1.26"}},
+			}},
+			set:           labels.Set{"kubeversion.open-cluster-management.io": "v1.26.3"},
+			expectedMatch: true,
+		},
+		{
+			name: "semver compares versions instead of numbers",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "kubeversion.open-cluster-management.io", Operator: OpSemverGt, Values: []string{"v1.9"}},
+			}},
+			set:           labels.Set{"kubeversion.open-cluster-management.io": "v1.26.3"},
+			expectedMatch: true,
+		},
+		{
+			name: "semver less than",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "kubeversion.open-cluster-management.io", Operator: OpSemverLt, Values: []string{"v1.26.0"}},
+			}},
+			set: labels.Set{"kubeversion.open-cluster-management.io": "v1.26.3"},
+		},
+		{
+			name: "semver less than or equal",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "kubeversion.open-cluster-management.io", Operator: OpSemverLe, Values: []string{"v1.26.3"}},
+			}},
+			set:           labels.Set{"kubeversion.open-cluster-management.io": "v1.26.3"},
+			expectedMatch: true,
+		},
+		{
+			name: "extended and set based requirements",
+			labelSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"cloud": "Amazon"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "nodes", Operator: OpGe, Values: []string{"3"}},
+				},
+			},
+			set: labels.Set{"cloud": "Google", "nodes": "5"},
+		},
+		{
+			name: "multiple values",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: OpGt, Values: []string{"3", "4"}},
+			}},
+			expectedErr: true,
+		},
+		{
+			name: "invalid number",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: OpGt, Values: []string{"three"}},
+			}},
+			expectedErr: true,
+		},
+		{
+			name: "invalid semver",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "kubeversion.open-cluster-management.io", Operator: OpSemverGt, Values: []string{"latest"}},
+			}},
+			expectedErr: true,
+		},
+		{
+			name: "invalid operator",
+			labelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "nodes", Operator: "Between", Values: []string{"3"}},
+			}},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			selector, err := newExtendedSelector(c.labelSelector)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if selector.Matches(c.set) != c.expectedMatch {
+				t.Errorf("expected match %v with %v", c.expectedMatch, c.set)
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ package predicate
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -14,15 +17,16 @@ import (
 	"open-cluster-management.io/ocm/pkg/placement/plugins"
 )
 
-var _ plugins.Filter = &Predicate{}
+var _ plugins.CacheableFilter = &Predicate{}
 
-const description = "Predicate filter filters the clusters based on predicate defined in placement"
+const description = "Predicate filter filters the clusters based on predicate defined in placement, " +
+	"supports the numeric operators Gt/Lt/Ge/Le and the semver operators SemverGt/SemverLt/SemverGe/SemverLe"
 
 type Predicate struct{}
 
 type predicateSelector struct {
-	labelSelector labels.Selector
-	claimSelector labels.Selector
+	labelSelector *extendedSelector
+	claimSelector *extendedSelector
 }
 
 func New(handle plugins.Handle) *Predicate {
@@ -81,58 +85,58 @@ func (p *Predicate) Filter(
 
 	// match cluster with selectors one by one
 	matched := []*clusterapiv1.ManagedCluster{}
+	reasons := map[string]string{}
 	for _, cluster := range clusters {
-		claims := getClusterClaims(cluster)
+		claims := plugins.GetClusterClaims(cluster)
+		unmatched := []string{}
 		for _, ps := range predicateSelectors {
 			// match with label selector
 			if ok := ps.labelSelector.Matches(labels.Set(cluster.Labels)); !ok {
+				unmatched = append(unmatched, fmt.Sprintf("label selector %q not matched", ps.labelSelector.String()))
 				continue
 			}
 			// match with claim selector
 			if ok := ps.claimSelector.Matches(labels.Set(claims)); !ok {
+				unmatched = append(unmatched, fmt.Sprintf("claim selector %q not matched", ps.claimSelector.String()))
 				continue
 			}
 			matched = append(matched, cluster)
+			unmatched = nil
 			break
 		}
+		if len(unmatched) > 0 {
+			reasons[cluster.Name] = strings.Join(unmatched, "; ")
+		}
 	}
 
 	return plugins.PluginFilterResult{
 		Filtered: matched,
+		Reasons:  reasons,
 	}, status
 }
 
-func (p *Predicate) RequeueAfter(ctx context.Context, placement *clusterapiv1beta1.Placement) (plugins.PluginRequeueResult, *framework.Status) {
-	return plugins.PluginRequeueResult{}, framework.NewStatus(p.Name(), framework.Success, "")
-}
-
-// getClusterClaims returns a map containing cluster claims from the status of cluster
-func getClusterClaims(cluster *clusterapiv1.ManagedCluster) map[string]string {
-	claims := map[string]string{}
-	for _, claim := range cluster.Status.ClusterClaims {
-		claims[claim.Name] = claim.Value
+// CacheKey returns the json encoded predicates of the placement, placements with the same predicates
+// filter clusters in the same way.
+func (p *Predicate) CacheKey(placement *clusterapiv1beta1.Placement) string {
+	data, err := json.Marshal(placement.Spec.Predicates)
+	if err != nil {
+		return ""
 	}
-	return claims
+	return string(data)
 }
 
-// convertLabelSelector converts metav1.LabelSelector to labels.Selector
-func convertLabelSelector(labelSelector metav1.LabelSelector) (labels.Selector, error) {
-	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
-	if err != nil {
-		return labels.Nothing(), err
-	}
+func (p *Predicate) RequeueAfter(ctx context.Context, placement *clusterapiv1beta1.Placement) (plugins.PluginRequeueResult, *framework.Status) {
+	return plugins.PluginRequeueResult{}, framework.NewStatus(p.Name(), framework.Success, "")
+}
 
-	return selector, nil
+// convertLabelSelector converts metav1.LabelSelector to extendedSelector
+func convertLabelSelector(labelSelector metav1.LabelSelector) (*extendedSelector, error) {
+	return newExtendedSelector(labelSelector)
 }
 
-// convertClaimSelector converts ClusterClaimSelector to labels.Selector
-func convertClaimSelector(clusterClaimSelector clusterapiv1beta1.ClusterClaimSelector) (labels.Selector, error) {
-	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+// convertClaimSelector converts ClusterClaimSelector to extendedSelector
+func convertClaimSelector(clusterClaimSelector clusterapiv1beta1.ClusterClaimSelector) (*extendedSelector, error) {
+	return newExtendedSelector(metav1.LabelSelector{
 		MatchExpressions: clusterClaimSelector.MatchExpressions,
 	})
-	if err != nil {
-		return labels.Nothing(), err
-	}
-
-	return selector, nil
 }
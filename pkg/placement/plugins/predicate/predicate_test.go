@@ -11,6 +11,7 @@ import (
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 
+	"open-cluster-management.io/ocm/pkg/placement/controllers/framework"
 	testinghelpers "open-cluster-management.io/ocm/pkg/placement/helpers/testing"
 )
 
@@ -20,6 +21,7 @@ func TestMatchWithClusterPredicates(t *testing.T) {
 		placement            *clusterapiv1beta1.Placement
 		clusters             []*clusterapiv1.ManagedCluster
 		expectedClusterNames []string
+		expectedReasons      map[string]string
 	}{
 		{
 			name: "match with label",
@@ -33,6 +35,7 @@ func TestMatchWithClusterPredicates(t *testing.T) {
 				testinghelpers.NewManagedCluster("cluster2").WithLabel("cloud", "Google").Build(),
 			},
 			expectedClusterNames: []string{"cluster1"},
+			expectedReasons:      map[string]string{"cluster2": `label selector "cloud=Amazon" not matched`},
 		},
 		{
 			name: "match with claim",
@@ -105,6 +108,34 @@ func TestMatchWithClusterPredicates(t *testing.T) {
 			},
 			expectedClusterNames: []string{"cluster1", "cluster2"},
 		},
+		{
+			name: "match with semver and numeric operators",
+			placement: testinghelpers.NewPlacement("test", "test").AddPredicate(
+				&metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "nodes", Operator: OpGt, Values: []string{"3"}},
+					},
+				},
+				&clusterapiv1beta1.ClusterClaimSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "kubeversion.open-cluster-management.io", Operator: OpSemverGe, Values: []string{"1.26"}},
+					},
+				},
+			).Build(),
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithLabel("nodes", "5").
+					WithClaim("kubeversion.open-cluster-management.io", "v1.26.3").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithLabel("nodes", "5").
+					WithClaim("kubeversion.open-cluster-management.io", "v1.25.9").Build(),
+				testinghelpers.NewManagedCluster("cluster3").WithLabel("nodes", "3").
+					WithClaim("kubeversion.open-cluster-management.io", "v1.27.0").Build(),
+			},
+			expectedClusterNames: []string{"cluster1"},
+			expectedReasons: map[string]string{
+				"cluster2": `claim selector "kubeversion.open-cluster-management.io SemverGe 1.26" not matched`,
+				"cluster3": `label selector "nodes Gt 3" not matched`,
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -127,7 +158,51 @@ func TestMatchWithClusterPredicates(t *testing.T) {
 			if expectedClusterNames.Len() > 0 {
 				t.Errorf("expected clusters not selected: %s", strings.Join(expectedClusterNames.List(), ","))
 			}
+			if len(result.Reasons) != len(c.clusters)-len(clusters) {
+				t.Errorf("expected reasons for %d clusters but got %v", len(c.clusters)-len(clusters), result.Reasons)
+			}
+			for name, reason := range c.expectedReasons {
+				if result.Reasons[name] != reason {
+					t.Errorf("expected reason %q of cluster %s but got %q", reason, name, result.Reasons[name])
+				}
+			}
 		})
 	}
 
 }
+
+func TestMisconfiguredPredicate(t *testing.T) {
+	placement := testinghelpers.NewPlacement("test", "test").AddPredicate(
+		nil,
+		&clusterapiv1beta1.ClusterClaimSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "kubeversion.open-cluster-management.io", Operator: OpSemverGe, Values: []string{"latest"}},
+			},
+		}).Build()
+	clusters := []*clusterapiv1.ManagedCluster{
+		testinghelpers.NewManagedCluster("cluster1").Build(),
+	}
+
+	p := &Predicate{}
+	_, status := p.Filter(context.TODO(), placement, clusters)
+	if status.Code() != framework.Misconfigured {
+		t.Errorf("expected code %v, but got %v", framework.Misconfigured, status.Code())
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	amazon := &metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Amazon"}}
+	google := &metav1.LabelSelector{MatchLabels: map[string]string{"cloud": "Google"}}
+
+	p := &Predicate{}
+	key1 := p.CacheKey(testinghelpers.NewPlacement("ns1", "placement1").AddPredicate(amazon, nil).Build())
+	key2 := p.CacheKey(testinghelpers.NewPlacement("ns2", "placement2").AddPredicate(amazon, nil).Build())
+	key3 := p.CacheKey(testinghelpers.NewPlacement("ns1", "placement1").AddPredicate(google, nil).Build())
+
+	if key1 != key2 {
+		t.Errorf("expected placements with the same predicates to have the same key, but got %q and %q", key1, key2)
+	}
+	if key1 == key3 {
+		t.Errorf("expected placements with different predicates to have different keys, but got %q", key1)
+	}
+}
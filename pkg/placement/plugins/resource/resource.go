@@ -3,8 +3,9 @@ package resource
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"math"
 	"sort"
+	"strings"
 
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
@@ -16,18 +17,41 @@ import (
 const (
 	placementLabel = clusterapiv1beta1.PlacementLabel
 	description    = `
-	ResourceAllocatableCPU and ResourceAllocatableMemory prioritizer makes the scheduling
-	decisions based on the resource allocatable of managed clusters.
-	The clusters that has the most allocatable are given the highest score,
-	while the least is given the lowest score.
+	Resource prioritizers make the scheduling decisions based on the resources of managed clusters.
+	ResourceAllocatable<Resource>: the clusters that has the most allocatable are given the highest
+	score, while the least is given the lowest score.
+	ResourceLeastAllocatable<Resource>: the clusters that has the least allocatable are given the
+	highest score, which packs the workloads into as few clusters as possible.
+	ResourceAllocatableRatio<Resource>: the clusters that has the highest allocatable-to-capacity
+	ratio, which is the least utilized, are given the highest score.
+	ResourceBalancedAllocation: the clusters that has the most balanced cpu and memory utilization
+	are given the highest score.
+	<Resource> is CPU, Memory, GPU, EphemeralStorage or any resource name in the allocatable of
+	managed clusters following a colon, for example ResourceAllocatable:nvidia.com/gpu.
 	`
+
+	prioritizerNamePrefix = "Resource"
+
+	algorithmAllocatable        = "Allocatable"
+	algorithmLeastAllocatable   = "LeastAllocatable"
+	algorithmAllocatableRatio   = "AllocatableRatio"
+	algorithmBalancedAllocation = "BalancedAllocation"
 )
 
 var _ plugins.Prioritizer = &ResourcePrioritizer{}
 
 var resourceMap = map[string]clusterapiv1.ResourceName{
-	"CPU":    clusterapiv1.ResourceCPU,
-	"Memory": clusterapiv1.ResourceMemory,
+	"CPU":              clusterapiv1.ResourceCPU,
+	"Memory":           clusterapiv1.ResourceMemory,
+	"GPU":              "nvidia.com/gpu",
+	"EphemeralStorage": "ephemeral-storage",
+}
+
+// algorithms with a resource, longer names go first so a name is not matched by its prefix
+var resourceAlgorithms = []string{
+	algorithmLeastAllocatable,
+	algorithmAllocatableRatio,
+	algorithmAllocatable,
 }
 
 type ResourcePrioritizer struct {
@@ -61,12 +85,36 @@ func (r *ResourcePrioritizerBuilder) Build() *ResourcePrioritizer {
 	return r.resourcePrioritizer
 }
 
+// IsValidPrioritizerName returns true if the prioritizerName is a valid resource prioritizer.
+func IsValidPrioritizerName(prioritizerName string) bool {
+	algorithm, _ := parsePrioritizerName(prioritizerName)
+	return len(algorithm) > 0
+}
+
 // parese prioritizerName to algorithm and resource.
-// For example, prioritizerName ResourceAllocatableCPU will return Allocatable, CPU.
+// For example, prioritizerName ResourceAllocatableCPU will return Allocatable, CPU,
+// ResourceLeastAllocatable:nvidia.com/gpu will return LeastAllocatable, nvidia.com/gpu,
+// and ResourceBalancedAllocation will return BalancedAllocation and an empty resource.
 func parsePrioritizerName(prioritizerName string) (algorithm string, resource clusterapiv1.ResourceName) {
-	s := regexp.MustCompile("[A-Z]+[a-z]*").FindAllString(prioritizerName, -1)
-	if len(s) == 3 {
-		return s[1], resourceMap[s[2]]
+	if !strings.HasPrefix(prioritizerName, prioritizerNamePrefix) {
+		return "", ""
+	}
+	name := strings.TrimPrefix(prioritizerName, prioritizerNamePrefix)
+	if name == algorithmBalancedAllocation {
+		return algorithmBalancedAllocation, ""
+	}
+
+	for _, a := range resourceAlgorithms {
+		if !strings.HasPrefix(name, a) {
+			continue
+		}
+		suffix := strings.TrimPrefix(name, a)
+		if r, ok := resourceMap[suffix]; ok {
+			return a, r
+		}
+		if strings.HasPrefix(suffix, ":") && len(suffix) > 1 {
+			return a, clusterapiv1.ResourceName(suffix[1:])
+		}
 	}
 	return "", ""
 }
@@ -82,8 +130,15 @@ func (r *ResourcePrioritizer) Description() string {
 func (r *ResourcePrioritizer) Score(ctx context.Context, placement *clusterapiv1beta1.Placement,
 	clusters []*clusterapiv1.ManagedCluster) (plugins.PluginScoreResult, *framework.Status) {
 	status := framework.NewStatus(r.Name(), framework.Success, "")
-	if r.algorithm == "Allocatable" {
-		return mostResourceAllocatableScores(r.resource, clusters), status
+	switch r.algorithm {
+	case algorithmAllocatable:
+		return resourceAllocatableScores(r.resource, clusters, true), status
+	case algorithmLeastAllocatable:
+		return resourceAllocatableScores(r.resource, clusters, false), status
+	case algorithmAllocatableRatio:
+		return resourceAllocatableRatioScores(r.resource, clusters), status
+	case algorithmBalancedAllocation:
+		return balancedResourceAllocationScores(clusters), status
 	}
 	return plugins.PluginScoreResult{}, status
 }
@@ -93,9 +148,10 @@ func (r *ResourcePrioritizer) RequeueAfter(ctx context.Context, placement *clust
 }
 
 // Calculate clusters scores based on the resource allocatable.
-// The clusters that has the most allocatable are given the highest score, while the least is given the lowest score.
+// If most is true, the clusters that has the most allocatable are given the highest score, while the least
+// is given the lowest score. Otherwise, the clusters that has the least allocatable are given the highest score.
 // The score range is from -100 to 100.
-func mostResourceAllocatableScores(resourceName clusterapiv1.ResourceName, clusters []*clusterapiv1.ManagedCluster) plugins.PluginScoreResult {
+func resourceAllocatableScores(resourceName clusterapiv1.ResourceName, clusters []*clusterapiv1.ManagedCluster, most bool) plugins.PluginScoreResult {
 	scores := map[string]int64{}
 
 	// get resourceName's min and max allocatable among all the clusters
@@ -116,6 +172,9 @@ func mostResourceAllocatableScores(resourceName clusterapiv1.ResourceName, clust
 		// score = ((resource_x_allocatable - min(resource_x_allocatable)) / (max(resource_x_allocatable) - min(resource_x_allocatable)) - 0.5) * 2 * 100
 		if (maxAllocatable - minAllocatable) != 0 {
 			ratio := (allocatable - minAllocatable) / (maxAllocatable - minAllocatable)
+			if !most {
+				ratio = 1 - ratio
+			}
 			scores[cluster.Name] = int64((ratio - 0.5) * 2.0 * 100.0)
 		} else {
 			scores[cluster.Name] = 100.0
@@ -127,6 +186,64 @@ func mostResourceAllocatableScores(resourceName clusterapiv1.ResourceName, clust
 	}
 }
 
+// Calculate clusters scores based on the ratio of resource allocatable to capacity.
+// The clusters that has the highest ratio, which is the least utilized, are given the highest score.
+// The score range is from -100 to 100.
+func resourceAllocatableRatioScores(resourceName clusterapiv1.ResourceName, clusters []*clusterapiv1.ManagedCluster) plugins.PluginScoreResult {
+	scores := map[string]int64{}
+
+	for _, cluster := range clusters {
+		ratio, err := getClusterAllocatableRatio(cluster, resourceName)
+		if err != nil {
+			continue
+		}
+
+		// score = (resource_x_allocatable / resource_x_capacity - 0.5) * 2 * 100
+		scores[cluster.Name] = int64((ratio - 0.5) * 2.0 * 100.0)
+	}
+
+	return plugins.PluginScoreResult{
+		Scores: scores,
+	}
+}
+
+// Calculate clusters scores based on the difference between cpu and memory utilization.
+// The clusters that has the most balanced utilization are given the highest score.
+// The score range is from -100 to 100.
+func balancedResourceAllocationScores(clusters []*clusterapiv1.ManagedCluster) plugins.PluginScoreResult {
+	scores := map[string]int64{}
+
+	for _, cluster := range clusters {
+		cpuRatio, err := getClusterAllocatableRatio(cluster, clusterapiv1.ResourceCPU)
+		if err != nil {
+			continue
+		}
+		memoryRatio, err := getClusterAllocatableRatio(cluster, clusterapiv1.ResourceMemory)
+		if err != nil {
+			continue
+		}
+
+		// score = (1 - |cpu_utilization - memory_utilization| - 0.5) * 2 * 100
+		scores[cluster.Name] = int64((0.5 - math.Abs(cpuRatio-memoryRatio)) * 2.0 * 100.0)
+	}
+
+	return plugins.PluginScoreResult{
+		Scores: scores,
+	}
+}
+
+// Return the ratio of allocatable to capacity of the resourceName in one cluster, the ratio is capped to [0, 1].
+func getClusterAllocatableRatio(cluster *clusterapiv1.ManagedCluster, resourceName clusterapiv1.ResourceName) (float64, error) {
+	allocatable, capacity, err := getClusterResource(cluster, resourceName)
+	if err != nil {
+		return 0, err
+	}
+	if capacity <= 0 {
+		return 0, fmt.Errorf("capacity %s of cluster %s is zero", resourceName, cluster.Name)
+	}
+	return math.Max(0, math.Min(1, allocatable/capacity)), nil
+}
+
 // Go through one cluster resources and return the allocatable and capacity of the resourceName.
 func getClusterResource(cluster *clusterapiv1.ManagedCluster, resourceName clusterapiv1.ResourceName) (allocatable, capacity float64, err error) {
 	if v, exist := cluster.Status.Allocatable[resourceName]; exist {
@@ -119,6 +119,70 @@ func TestScoreClusterWithResource(t *testing.T) {
 			},
 			expectedScores: map[string]int64{},
 		},
+		{
+			name:      "scores of ResourceLeastAllocatableMemory",
+			resource:  clusterapiv1.ResourceMemory,
+			algorithm: "LeastAllocatable",
+			placement: testinghelpers.NewPlacement("test", "test").Build(),
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithResource(clusterapiv1.ResourceMemory, "20", "100").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithResource(clusterapiv1.ResourceMemory, "60", "100").Build(),
+				testinghelpers.NewManagedCluster("cluster3").WithResource(clusterapiv1.ResourceMemory, "100", "100").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": 0, "cluster3": -100},
+		},
+		{
+			name:      "scores of ResourceLeastAllocatableMemory with same resource value",
+			resource:  clusterapiv1.ResourceMemory,
+			algorithm: "LeastAllocatable",
+			placement: testinghelpers.NewPlacement("test", "test").Build(),
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithResource(clusterapiv1.ResourceMemory, "50", "100").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithResource(clusterapiv1.ResourceMemory, "50", "100").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": 100},
+		},
+		{
+			name:      "scores of ResourceAllocatableRatio with extended resource",
+			resource:  "ephemeral-storage",
+			algorithm: "AllocatableRatio",
+			placement: testinghelpers.NewPlacement("test", "test").Build(),
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithResource("ephemeral-storage", "10Gi", "10Gi").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithResource("ephemeral-storage", "50Gi", "100Gi").Build(),
+				testinghelpers.NewManagedCluster("cluster3").WithResource("ephemeral-storage", "0", "1Ti").Build(),
+				testinghelpers.NewManagedCluster("cluster4").WithResource("ephemeral-storage", "0", "0").Build(),
+				testinghelpers.NewManagedCluster("cluster5").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": 0, "cluster3": -100},
+		},
+		{
+			name:      "scores of ResourceAllocatable with extended resource",
+			resource:  "nvidia.com/gpu",
+			algorithm: "Allocatable",
+			placement: testinghelpers.NewPlacement("test", "test").Build(),
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").WithResource("nvidia.com/gpu", "8", "8").Build(),
+				testinghelpers.NewManagedCluster("cluster2").WithResource("nvidia.com/gpu", "0", "8").Build(),
+				testinghelpers.NewManagedCluster("cluster3").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": -100},
+		},
+		{
+			name:      "scores of ResourceBalancedAllocation",
+			algorithm: "BalancedAllocation",
+			placement: testinghelpers.NewPlacement("test", "test").Build(),
+			clusters: []*clusterapiv1.ManagedCluster{
+				testinghelpers.NewManagedCluster("cluster1").
+					WithResource(clusterapiv1.ResourceCPU, "5", "10").WithResource(clusterapiv1.ResourceMemory, "50", "100").Build(),
+				testinghelpers.NewManagedCluster("cluster2").
+					WithResource(clusterapiv1.ResourceCPU, "10", "10").WithResource(clusterapiv1.ResourceMemory, "0", "100").Build(),
+				testinghelpers.NewManagedCluster("cluster3").
+					WithResource(clusterapiv1.ResourceCPU, "5", "10").WithResource(clusterapiv1.ResourceMemory, "100", "100").Build(),
+				testinghelpers.NewManagedCluster("cluster4").WithResource(clusterapiv1.ResourceCPU, "5", "10").Build(),
+			},
+			expectedScores: map[string]int64{"cluster1": 100, "cluster2": -100, "cluster3": 0},
+		},
 	}
 
 	for _, c := range cases {
@@ -143,3 +207,33 @@ func TestScoreClusterWithResource(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePrioritizerName(t *testing.T) {
+	cases := []struct {
+		prioritizerName   string
+		expectedAlgorithm string
+		expectedResource  clusterapiv1.ResourceName
+	}{
+		{"ResourceAllocatableCPU", "Allocatable", clusterapiv1.ResourceCPU},
+		{"ResourceAllocatableMemory", "Allocatable", clusterapiv1.ResourceMemory},
+		{"ResourceLeastAllocatableCPU", "LeastAllocatable", clusterapiv1.ResourceCPU},
+		{"ResourceAllocatableRatioMemory", "AllocatableRatio", clusterapiv1.ResourceMemory},
+		{"ResourceAllocatableGPU", "Allocatable", "nvidia.com/gpu"},
+		{"ResourceLeastAllocatableEphemeralStorage", "LeastAllocatable", "ephemeral-storage"},
+		{"ResourceAllocatableRatio:example.com/foo", "AllocatableRatio", "example.com/foo"},
+		{"ResourceBalancedAllocation", "BalancedAllocation", ""},
+		{"ResourceAllocatableDisk", "", ""},
+		{"ResourceAllocatable:", "", ""},
+		{"ResourceUnknownCPU", "", ""},
+		{"Balance", "", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.prioritizerName, func(t *testing.T) {
+			algorithm, resource := parsePrioritizerName(c.prioritizerName)
+			if algorithm != c.expectedAlgorithm || resource != c.expectedResource {
+				t.Errorf("expected %q, %q, but got %q, %q", c.expectedAlgorithm, c.expectedResource, algorithm, resource)
+			}
+		})
+	}
+}
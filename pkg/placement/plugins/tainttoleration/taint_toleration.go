@@ -77,14 +77,18 @@ func (pl *TaintToleration) Filter(ctx context.Context, placement *clusterapiv1be
 
 	// filter the clusters
 	matched := []*clusterapiv1.ManagedCluster{}
+	reasons := map[string]string{}
 	for _, cluster := range clusters {
-		if tolerated, _, _ := isClusterTolerated(cluster, placement.Spec.Tolerations, decisionClusterNames.Has(cluster.Name)); tolerated {
+		if tolerated, _, msg := isClusterTolerated(cluster, placement.Spec.Tolerations, decisionClusterNames.Has(cluster.Name)); tolerated {
 			matched = append(matched, cluster)
+		} else {
+			reasons[cluster.Name] = msg
 		}
 	}
 
 	return plugins.PluginFilterResult{
 		Filtered: matched,
+		Reasons:  reasons,
 	}, status
 }
 
@@ -120,6 +124,9 @@ func isClusterTolerated(cluster *clusterapiv1.ManagedCluster, tolerations []clus
 	for _, taint := range cluster.Spec.Taints {
 		tolerated, requeue, message := isTaintTolerated(taint, tolerations, inDecision)
 		if !tolerated {
+			if len(message) == 0 {
+				message = fmt.Sprintf("taint %s=%s:%s is not tolerated", taint.Key, taint.Value, taint.Effect)
+			}
 			return false, nil, message
 		}
 		minRequeue = minRequeueTime(minRequeue, requeue)
@@ -770,6 +770,9 @@ func TestMatchWithClusterTaintToleration(t *testing.T) {
 					strings.Join(expectedClusterNames.List(), ","),
 				)
 			}
+			if err == nil && len(result.Reasons) != len(c.clusters)-len(clusters) {
+				t.Errorf("expected reasons for %d clusters but got %v", len(c.clusters)-len(clusters), result.Reasons)
+			}
 
 			requeueResult, _ := p.RequeueAfter(context.TODO(), c.placement)
 			expectedRequeueTime := c.expectedRequeueResult.RequeueTime
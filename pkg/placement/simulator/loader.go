@@ -0,0 +1,243 @@
+package simulator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	addonapiv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonscheme "open-cluster-management.io/api/client/addon/clientset/versioned/scheme"
+	clusterscheme "open-cluster-management.io/api/client/cluster/clientset/versioned/scheme"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	clusterapiv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+)
+
+const defaultNamespace = "default"
+
+var (
+	scheme  = runtime.NewScheme()
+	decoder runtime.Decoder
+)
+
+func init() {
+	utilruntime.Must(clusterscheme.AddToScheme(scheme))
+	utilruntime.Must(addonscheme.AddToScheme(scheme))
+	decoder = serializer.NewCodecFactory(scheme).UniversalDeserializer()
+}
+
+// Objects contains the objects loaded from the manifest files.
+type Objects struct {
+	Placements         []*clusterapiv1beta1.Placement
+	PlacementDecisions []*clusterapiv1beta1.PlacementDecision
+	Clusters           []*clusterapiv1.ManagedCluster
+	ClusterSets        []*clusterapiv1beta2.ManagedClusterSet
+	ClusterSetBindings []*clusterapiv1beta2.ManagedClusterSetBinding
+	Scores             []*clusterapiv1alpha1.AddOnPlacementScore
+	AddOns             []*addonapiv1alpha1.ManagedClusterAddOn
+}
+
+// LoadObjects loads the objects from the given files or directories. The yaml or json files in a
+// directory are loaded recursively. A file may contain multiple documents or a List, objects of
+// kinds not related to the scheduling are ignored.
+func LoadObjects(paths ...string) (*Objects, error) {
+	objs := &Objects{}
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// files in a directory are filtered by extension, while a file specified explicitly is always loaded
+			if file != path && !isManifestFile(file) {
+				return nil
+			}
+
+			data, err := os.ReadFile(filepath.Clean(file))
+			if err != nil {
+				return err
+			}
+			if err := objs.decode(data); err != nil {
+				return fmt.Errorf("failed to load %s: %v", file, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+func isManifestFile(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// decode decodes all the documents in the data.
+func (o *Objects) decode(data []byte) error {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := o.decodeDocument(doc); err != nil {
+			return err
+		}
+	}
+}
+
+func (o *Objects) decodeDocument(doc []byte) error {
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(doc, typeMeta); err != nil {
+		return err
+	}
+	// ignore empty documents
+	if len(typeMeta.Kind) == 0 && len(typeMeta.APIVersion) == 0 {
+		return nil
+	}
+
+	if typeMeta.Kind == "List" {
+		list := &metav1.List{}
+		if err := yaml.Unmarshal(doc, list); err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			if err := o.decodeDocument(item.Raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if typeMeta.GroupVersionKind() == clusterapiv1beta1.SchemeGroupVersion.WithKind("Placement") {
+		defaulted, err := setPlacementDefaults(doc)
+		if err != nil {
+			return err
+		}
+		doc = defaulted
+	}
+
+	obj, _, err := decoder.Decode(doc, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	o.add(obj)
+	return nil
+}
+
+// setPlacementDefaults applies the defaults of the Placement CRD to the unset fields, as the
+// apiserver does when the placement is created.
+func setPlacementDefaults(doc []byte) ([]byte, error) {
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal(doc, &obj); err != nil {
+		return nil, err
+	}
+
+	for _, config := range nestedMaps(obj, "spec", "prioritizerPolicy", "configurations") {
+		setDefault(config, "weight", int64(1))
+		if coordinate, ok := config["scoreCoordinate"].(map[string]interface{}); ok {
+			setDefault(coordinate, "type", clusterapiv1beta1.ScoreCoordinateTypeBuiltIn)
+		}
+	}
+	if policy, ok := nestedMap(obj, "spec", "prioritizerPolicy"); ok {
+		setDefault(policy, "mode", string(clusterapiv1beta1.PrioritizerPolicyModeAdditive))
+	}
+	for _, term := range nestedMaps(obj, "spec", "spreadPolicy", "spreadConstraints") {
+		setDefault(term, "maxSkew", int64(1))
+		setDefault(term, "whenUnsatisfiable", string(clusterapiv1beta1.ScheduleAnyway))
+	}
+	for _, toleration := range nestedMaps(obj, "spec", "tolerations") {
+		setDefault(toleration, "operator", string(clusterapiv1beta1.TolerationOpEqual))
+	}
+
+	return json.Marshal(obj)
+}
+
+// nestedMap returns the map in the object with the given path of fields.
+func nestedMap(obj map[string]interface{}, fields ...string) (map[string]interface{}, bool) {
+	for _, field := range fields {
+		m, ok := obj[field].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		obj = m
+	}
+	return obj, true
+}
+
+// nestedMaps returns the maps in the list of the object with the given path of fields.
+func nestedMaps(obj map[string]interface{}, fields ...string) []map[string]interface{} {
+	parent, ok := nestedMap(obj, fields[:len(fields)-1]...)
+	if !ok {
+		return nil
+	}
+	items, _ := parent[fields[len(fields)-1]].([]interface{})
+	maps := []map[string]interface{}{}
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			maps = append(maps, m)
+		}
+	}
+	return maps
+}
+
+func setDefault(obj map[string]interface{}, field string, value interface{}) {
+	if _, ok := obj[field]; !ok {
+		obj[field] = value
+	}
+}
+
+func (o *Objects) add(obj runtime.Object) {
+	switch t := obj.(type) {
+	case *clusterapiv1beta1.Placement:
+		if len(t.Namespace) == 0 {
+			t.Namespace = defaultNamespace
+		}
+		o.Placements = append(o.Placements, t)
+	case *clusterapiv1beta1.PlacementDecision:
+		if len(t.Namespace) == 0 {
+			t.Namespace = defaultNamespace
+		}
+		o.PlacementDecisions = append(o.PlacementDecisions, t)
+	case *clusterapiv1.ManagedCluster:
+		o.Clusters = append(o.Clusters, t)
+	case *clusterapiv1beta2.ManagedClusterSet:
+		o.ClusterSets = append(o.ClusterSets, t)
+	case *clusterapiv1beta2.ManagedClusterSetBinding:
+		if len(t.Namespace) == 0 {
+			t.Namespace = defaultNamespace
+		}
+		o.ClusterSetBindings = append(o.ClusterSetBindings, t)
+	case *clusterapiv1alpha1.AddOnPlacementScore:
+		o.Scores = append(o.Scores, t)
+	case *addonapiv1alpha1.ManagedClusterAddOn:
+		o.AddOns = append(o.AddOns, t)
+	}
+}
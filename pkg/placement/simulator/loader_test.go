@@ -0,0 +1,255 @@
+package simulator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+)
+
+const testClusters = `
+apiVersion: cluster.open-cluster-management.io/v1
+kind: ManagedCluster
+metadata:
+  name: cluster1
+  labels:
+    cluster.open-cluster-management.io/clusterset: prod
+    cloud: Amazon
+---
+apiVersion: cluster.open-cluster-management.io/v1
+kind: ManagedCluster
+metadata:
+  name: cluster2
+  labels:
+    cluster.open-cluster-management.io/clusterset: prod
+    cloud: Google
+---
+apiVersion: cluster.open-cluster-management.io/v1
+kind: ManagedCluster
+metadata:
+  name: cluster3
+  labels:
+    cluster.open-cluster-management.io/clusterset: prod
+    cloud: Amazon
+---
+apiVersion: cluster.open-cluster-management.io/v1beta2
+kind: ManagedClusterSet
+metadata:
+  name: prod
+---
+apiVersion: cluster.open-cluster-management.io/v1beta2
+kind: ManagedClusterSetBinding
+metadata:
+  name: prod
+  namespace: apps
+spec:
+  clusterSet: prod
+`
+
+const testPlacements = `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: cluster.open-cluster-management.io/v1beta1
+  kind: Placement
+  metadata:
+    name: amazon
+    namespace: apps
+  spec:
+    numberOfClusters: 1
+    predicates:
+    - requiredClusterSelector:
+        labelSelector:
+          matchLabels:
+            cloud: Amazon
+- apiVersion: cluster.open-cluster-management.io/v1beta1
+  kind: Placement
+  metadata:
+    name: all
+  spec: {}
+- apiVersion: cluster.open-cluster-management.io/v1beta1
+  kind: Placement
+  metadata:
+    name: misconfigured
+    namespace: apps
+  spec:
+    prioritizerPolicy:
+      mode: Unknown
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: ignored
+`
+
+const testScores = `{
+  "apiVersion": "cluster.open-cluster-management.io/v1alpha1",
+  "kind": "AddOnPlacementScore",
+  "metadata": {"name": "demo", "namespace": "cluster1"}
+}`
+
+// writeTestFiles writes the files into a temp directory and returns the directory.
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadObjects(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"clusters.yaml":            testClusters,
+		"apps/placements.yml":      testPlacements,
+		"scores/score.json":        testScores,
+		"README.md":                "not a manifest",
+		"apps/kustomization.txt":   "kind: Placement",
+		"empty/empty.yaml":         "---\n# nothing\n---\n",
+		"apps/invalid/.keep":       "",
+		"apps/invalid/extra.other": "",
+	})
+
+	cases := []struct {
+		name                       string
+		paths                      []string
+		expectedPlacements         []string
+		expectedClusters           int
+		expectedClusterSets        int
+		expectedClusterSetBindings int
+		expectedScores             int
+		expectedErr                bool
+	}{
+		{
+			name:                       "load directory",
+			paths:                      []string{dir},
+			expectedPlacements:         []string{"apps/amazon", "default/all", "apps/misconfigured"},
+			expectedClusters:           3,
+			expectedClusterSets:        1,
+			expectedClusterSetBindings: 1,
+			expectedScores:             1,
+		},
+		{
+			name:               "load files",
+			paths:              []string{filepath.Join(dir, "apps/placements.yml"), filepath.Join(dir, "scores/score.json")},
+			expectedPlacements: []string{"apps/amazon", "default/all", "apps/misconfigured"},
+			expectedScores:     1,
+		},
+		{
+			name:        "file not found",
+			paths:       []string{filepath.Join(dir, "notfound.yaml")},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid manifest",
+			paths:       []string{filepath.Join(dir, "apps/kustomization.txt")},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objs, err := LoadObjects(c.paths...)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			placements := []string{}
+			for _, p := range objs.Placements {
+				placements = append(placements, p.Namespace+"/"+p.Name)
+			}
+			if len(placements) != len(c.expectedPlacements) {
+				t.Fatalf("expected placements %v, but got %v", c.expectedPlacements, placements)
+			}
+			for i := range placements {
+				if placements[i] != c.expectedPlacements[i] {
+					t.Errorf("expected placements %v, but got %v", c.expectedPlacements, placements)
+				}
+			}
+			if len(objs.Clusters) != c.expectedClusters {
+				t.Errorf("expected %d clusters, but got %d", c.expectedClusters, len(objs.Clusters))
+			}
+			if len(objs.ClusterSets) != c.expectedClusterSets {
+				t.Errorf("expected %d clustersets, but got %d", c.expectedClusterSets, len(objs.ClusterSets))
+			}
+			if len(objs.ClusterSetBindings) != c.expectedClusterSetBindings {
+				t.Errorf("expected %d clustersetbindings, but got %d", c.expectedClusterSetBindings, len(objs.ClusterSetBindings))
+			}
+			if len(objs.Scores) != c.expectedScores {
+				t.Errorf("expected %d scores, but got %d", c.expectedScores, len(objs.Scores))
+			}
+		})
+	}
+}
+
+func TestLoadObjectsWithDefaults(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"placement.yaml": `
+apiVersion: cluster.open-cluster-management.io/v1beta1
+kind: Placement
+metadata:
+  name: defaults
+spec:
+  prioritizerPolicy:
+    configurations:
+    - scoreCoordinate:
+        builtIn: Steady
+    - scoreCoordinate:
+        builtIn: Balance
+      weight: 0
+  spreadPolicy:
+    spreadConstraints:
+    - topologyKey: region
+      topologyKeyType: Label
+  tolerations:
+  - key: gpu
+`,
+	})
+
+	objs, err := LoadObjects(dir)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(objs.Placements) != 1 {
+		t.Fatalf("expected 1 placement, but got %d", len(objs.Placements))
+	}
+
+	spec := objs.Placements[0].Spec
+	expectedPolicy := clusterapiv1beta1.PrioritizerPolicy{
+		Mode: clusterapiv1beta1.PrioritizerPolicyModeAdditive,
+		Configurations: []clusterapiv1beta1.PrioritizerConfig{
+			{
+				ScoreCoordinate: &clusterapiv1beta1.ScoreCoordinate{
+					Type: clusterapiv1beta1.ScoreCoordinateTypeBuiltIn, BuiltIn: "Steady"},
+				Weight: 1,
+			},
+			{
+				ScoreCoordinate: &clusterapiv1beta1.ScoreCoordinate{
+					Type: clusterapiv1beta1.ScoreCoordinateTypeBuiltIn, BuiltIn: "Balance"},
+				Weight: 0,
+			},
+		},
+	}
+	if !reflect.DeepEqual(spec.PrioritizerPolicy, expectedPolicy) {
+		t.Errorf("expected prioritizer policy %v, but got %v", expectedPolicy, spec.PrioritizerPolicy)
+	}
+	term := spec.SpreadPolicy.SpreadConstraints[0]
+	if term.MaxSkew != 1 || term.WhenUnsatisfiable != clusterapiv1beta1.ScheduleAnyway {
+		t.Errorf("expected spread constraint defaults, but got %v", term)
+	}
+	if spec.Tolerations[0].Operator != clusterapiv1beta1.TolerationOpEqual {
+		t.Errorf("expected toleration operator Equal, but got %q", spec.Tolerations[0].Operator)
+	}
+}
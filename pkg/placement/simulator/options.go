@@ -0,0 +1,76 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Options defines the flags of the placement simulate command.
+type Options struct {
+	Files      []string
+	Placements []string
+	Output     string
+}
+
+// NewOptions returns the default options of the placement simulate command.
+func NewOptions() *Options {
+	return &Options{
+		Output: OutputTable,
+	}
+}
+
+func (o *Options) AddFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVarP(&o.Files, "filename", "f", o.Files,
+		"Files or directories containing the Placement, ManagedCluster, ManagedClusterSet, "+
+			"ManagedClusterSetBinding, PlacementDecision, AddOnPlacementScore and ManagedClusterAddOn manifests.")
+	flags.StringSliceVar(&o.Placements, "placement", o.Placements,
+		"Namespace/name of the placements to schedule. All the placements in the files are scheduled if not specified.")
+	flags.StringVarP(&o.Output, "output", "o", o.Output, "Output format, one of table or json.")
+}
+
+func (o *Options) Validate() error {
+	if len(o.Files) == 0 {
+		return fmt.Errorf("at least one file or directory is required")
+	}
+	if o.Output != OutputTable && o.Output != OutputJSON {
+		return fmt.Errorf("unsupported output format %q", o.Output)
+	}
+	return nil
+}
+
+// Run schedules the placements in the files and prints the results. It returns an error if any
+// placement fails to be scheduled, for example the placement is misconfigured.
+func (o *Options) Run(ctx context.Context, out io.Writer) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	objs, err := LoadObjects(o.Files...)
+	if err != nil {
+		return err
+	}
+
+	results, err := Simulate(ctx, objs, o.Placements...)
+	if err != nil {
+		return err
+	}
+
+	if err := PrintResults(out, results, o.Output); err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, result := range results {
+		if len(result.Error) > 0 {
+			failed = append(failed, result.Placement)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to schedule placements: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
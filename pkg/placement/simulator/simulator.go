@@ -0,0 +1,283 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	kevents "k8s.io/client-go/tools/events"
+
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+
+	"open-cluster-management.io/ocm/pkg/placement/controllers/scheduling"
+	"open-cluster-management.io/ocm/pkg/placement/debugger"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// Result is the simulated scheduling result of a placement.
+type Result struct {
+	Placement              string `json:"placement"`
+	debugger.DebugResult   `json:",inline"`
+	Decisions              []clusterapiv1beta1.ClusterDecision `json:"decisions"`
+	Scores                 scheduling.PrioritizerScore         `json:"scores,omitempty"`
+	NumOfUnscheduled       int                                 `json:"numOfUnscheduled"`
+	NumOfAvailableClusters int                                 `json:"numOfAvailableClusters"`
+}
+
+// Simulate schedules the placements with the real scheduler against the loaded objects, nothing is read
+// from or written to a hub. If placementKeys is not empty, only the placements with the namespace/name
+// keys are scheduled.
+func Simulate(ctx context.Context, objs *Objects, placementKeys ...string) ([]Result, error) {
+	clusterClient := clusterfake.NewSimpleClientset()
+	informers := clusterinformers.NewSharedInformerFactory(clusterClient, 0)
+	clusterInformer := informers.Cluster().V1().ManagedClusters()
+	clusterSetInformer := informers.Cluster().V1beta2().ManagedClusterSets()
+	clusterSetBindingInformer := informers.Cluster().V1beta2().ManagedClusterSetBindings()
+	placementInformer := informers.Cluster().V1beta1().Placements()
+	placementDecisionInformer := informers.Cluster().V1beta1().PlacementDecisions()
+	scoreInformer := informers.Cluster().V1alpha1().AddOnPlacementScores()
+	addOnInformer := addoninformers.NewSharedInformerFactory(addonfake.NewSimpleClientset(), 0).
+		Addon().V1alpha1().ManagedClusterAddOns()
+
+	for _, o := range objs.Clusters {
+		if err := clusterInformer.Informer().GetStore().Add(o); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range objs.ClusterSets {
+		if err := clusterSetInformer.Informer().GetStore().Add(o); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range objs.ClusterSetBindings {
+		if err := clusterSetBindingInformer.Informer().GetStore().Add(o); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range objs.Placements {
+		if err := placementInformer.Informer().GetStore().Add(o); err != nil {
+			return nil, err
+		}
+	}
+	if err := placementDecisionInformer.Informer().AddIndexers(scheduling.DecisionsByClusterIndexers()); err != nil {
+		return nil, err
+	}
+	for _, o := range objs.PlacementDecisions {
+		if err := placementDecisionInformer.Informer().GetStore().Add(o); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range objs.Scores {
+		if err := scoreInformer.Informer().GetStore().Add(o); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range objs.AddOns {
+		if err := addOnInformer.Informer().GetStore().Add(o); err != nil {
+			return nil, err
+		}
+	}
+
+	handle := scheduling.NewSchedulerHandler(
+		clusterClient,
+		placementDecisionInformer.Lister(),
+		scoreInformer.Lister(),
+		clusterInformer.Lister(),
+		addOnInformer.Lister(),
+		&kevents.FakeRecorder{},
+	)
+	scheduler := scheduling.NewPluginScheduler(handle)
+
+	placements, err := selectPlacements(objs.Placements, placementKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, placement := range placements {
+		result := Result{Placement: placement.Namespace + "/" + placement.Name}
+
+		clusters, err := scheduling.GetAvailableClusters(
+			placement, clusterInformer.Lister(), clusterSetInformer.Lister(), clusterSetBindingInformer.Lister())
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		clusters, compositeStatus := scheduling.GetCompositeClusters(
+			placement, clusters, placementInformer.Lister(), placementDecisionInformer.Lister())
+		result.NumOfAvailableClusters = len(clusters)
+		clusters, capacityExcluded, capacityStatus := scheduling.GetClustersWithCapacity(
+			placement, clusters, placementInformer.Lister(), placementDecisionInformer.Informer().GetIndexer())
+
+		scheduleResult, status := scheduler.Schedule(ctx, placement, clusters)
+		switch {
+		case compositeStatus.IsError():
+			status = compositeStatus
+		case capacityStatus.IsError():
+			status = capacityStatus
+		}
+		result.DebugResult = debugger.DebugResult{
+			FilterResults:     scheduleResult.FilterResults(),
+			PrioritizeResults: scheduleResult.PrioritizerResults(),
+			SpreadResults:     scheduleResult.SpreadResults(),
+			ExcludedClusters:  append(capacityExcluded, scheduleResult.ExcludedClusters()...),
+			PinnedClusters:    scheduleResult.PinnedClusters(),
+		}
+		result.Decisions = scheduleResult.Decisions()
+		result.Scores = scheduleResult.PrioritizerScores()
+		result.NumOfUnscheduled = scheduleResult.NumOfUnscheduled()
+		if status.IsError() {
+			result.Error = status.AsError().Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// selectPlacements returns the placements with the given keys in order, or all the placements sorted by
+// key if no key is given.
+func selectPlacements(placements []*clusterapiv1beta1.Placement, keys []string) ([]*clusterapiv1beta1.Placement, error) {
+	byKey := map[string]*clusterapiv1beta1.Placement{}
+	for _, placement := range placements {
+		byKey[placement.Namespace+"/"+placement.Name] = placement
+	}
+
+	if len(keys) == 0 {
+		keys = sets.StringKeySet(byKey).List()
+	}
+
+	selected := []*clusterapiv1beta1.Placement{}
+	for _, key := range keys {
+		placement, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("placement %q is not found", key)
+		}
+		selected = append(selected, placement)
+	}
+	return selected, nil
+}
+
+// PrintResults prints the results in the given output format.
+func PrintResults(out io.Writer, results []Result, output string) error {
+	switch output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case OutputTable, "":
+		for i, result := range results {
+			if i > 0 {
+				if _, err := fmt.Fprintln(out); err != nil {
+					return err
+				}
+			}
+			if err := printTable(out, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q", output)
+}
+
+// printTable prints the result of a placement as a table. The selected clusters are listed first in
+// the decision order, followed by the other feasible clusters sorted by score, and then the clusters
+// filtered out.
+func printTable(out io.Writer, result Result) error {
+	if _, err := fmt.Fprintf(out, "Placement: %s\n", result.Placement); err != nil {
+		return err
+	}
+	if len(result.Error) > 0 {
+		if _, err := fmt.Fprintf(out, "Error: %s\n", result.Error); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(out, "Available clusters: %d, Decisions: %d, Unscheduled: %d\n",
+		result.NumOfAvailableClusters, len(result.Decisions), result.NumOfUnscheduled); err != nil {
+		return err
+	}
+
+	reasons := map[string]string{}
+	for _, excluded := range result.ExcludedClusters {
+		if _, ok := reasons[excluded.ClusterName]; !ok {
+			reasons[excluded.ClusterName] = fmt.Sprintf("%s: %s", excluded.Plugin, excluded.Reason)
+		}
+	}
+
+	selected := sets.NewString()
+	clusterNames := []string{}
+	for _, d := range result.Decisions {
+		selected.Insert(d.ClusterName)
+		clusterNames = append(clusterNames, d.ClusterName)
+	}
+	feasible := []string{}
+	for name := range result.Scores {
+		if !selected.Has(name) {
+			feasible = append(feasible, name)
+		}
+	}
+	sort.SliceStable(feasible, func(i, j int) bool {
+		if result.Scores[feasible[i]] == result.Scores[feasible[j]] {
+			return feasible[i] < feasible[j]
+		}
+		return result.Scores[feasible[i]] > result.Scores[feasible[j]]
+	})
+	clusterNames = append(clusterNames, feasible...)
+	filtered := []string{}
+	for name := range reasons {
+		if _, ok := result.Scores[name]; !ok {
+			filtered = append(filtered, name)
+		}
+	}
+	sort.Strings(filtered)
+	clusterNames = append(clusterNames, filtered...)
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	header := []string{"CLUSTER", "SELECTED", "SCORE"}
+	for _, p := range result.PrioritizeResults {
+		header = append(header, fmt.Sprintf("%s(x%d)", p.Name, p.Weight))
+	}
+	header = append(header, "REASON")
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+
+	for _, name := range clusterNames {
+		row := []string{name, fmt.Sprintf("%v", selected.Has(name))}
+		score, ok := result.Scores[name]
+		if ok {
+			row = append(row, fmt.Sprintf("%d", score))
+		} else {
+			row = append(row, "-")
+		}
+		for _, p := range result.PrioritizeResults {
+			if s, ok := p.Scores[name]; ok {
+				row = append(row, fmt.Sprintf("%d", s))
+			} else {
+				row = append(row, "-")
+			}
+		}
+		row = append(row, reasons[name])
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,236 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func loadTestObjects(t *testing.T) *Objects {
+	dir := writeTestFiles(t, map[string]string{
+		"clusters.yaml":   testClusters,
+		"placements.yaml": testPlacements,
+	})
+	objs, err := LoadObjects(dir)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	return objs
+}
+
+func TestSimulate(t *testing.T) {
+	cases := []struct {
+		name                 string
+		placementKeys        []string
+		expectedPlacements   []string
+		expectedDecisions    map[string][]string
+		expectedUnscheduled  map[string]int
+		expectedAvailable    map[string]int
+		expectedExcluded     map[string][]string
+		expectedErrPlacement string
+		expectedErr          bool
+	}{
+		{
+			name:               "schedule all placements",
+			expectedPlacements: []string{"apps/amazon", "apps/misconfigured", "default/all"},
+			expectedDecisions: map[string][]string{
+				"apps/amazon": {"cluster1"},
+			},
+			expectedUnscheduled: map[string]int{
+				"apps/amazon": 0,
+			},
+			expectedAvailable: map[string]int{
+				"apps/amazon": 3,
+				// no clusterset is bound to the default namespace
+				"default/all": 0,
+			},
+			expectedExcluded: map[string][]string{
+				"apps/amazon": {"cluster2", "cluster3"},
+			},
+			expectedErrPlacement: "apps/misconfigured",
+		},
+		{
+			name:               "schedule specified placements",
+			placementKeys:      []string{"apps/amazon"},
+			expectedPlacements: []string{"apps/amazon"},
+			expectedDecisions: map[string][]string{
+				"apps/amazon": {"cluster1"},
+			},
+		},
+		{
+			name:          "placement not found",
+			placementKeys: []string{"apps/notfound"},
+			expectedErr:   true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			results, err := Simulate(context.TODO(), loadTestObjects(t), c.placementKeys...)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			if len(results) != len(c.expectedPlacements) {
+				t.Fatalf("expected %d results, but got %d", len(c.expectedPlacements), len(results))
+			}
+			for i, result := range results {
+				if result.Placement != c.expectedPlacements[i] {
+					t.Errorf("expected placement %s, but got %s", c.expectedPlacements[i], result.Placement)
+				}
+				if result.Placement == c.expectedErrPlacement {
+					if len(result.Error) == 0 {
+						t.Errorf("expected error of placement %s, but got none", result.Placement)
+					}
+					continue
+				}
+				if len(result.Error) > 0 {
+					t.Errorf("unexpected error of placement %s: %s", result.Placement, result.Error)
+				}
+
+				decisions := []string{}
+				for _, d := range result.Decisions {
+					decisions = append(decisions, d.ClusterName)
+				}
+				if strings.Join(decisions, ",") != strings.Join(c.expectedDecisions[result.Placement], ",") {
+					t.Errorf("expected decisions %v of placement %s, but got %v",
+						c.expectedDecisions[result.Placement], result.Placement, decisions)
+				}
+				if expected, ok := c.expectedUnscheduled[result.Placement]; ok && result.NumOfUnscheduled != expected {
+					t.Errorf("expected %d unscheduled of placement %s, but got %d",
+						expected, result.Placement, result.NumOfUnscheduled)
+				}
+				if expected, ok := c.expectedAvailable[result.Placement]; ok && result.NumOfAvailableClusters != expected {
+					t.Errorf("expected %d available clusters of placement %s, but got %d",
+						expected, result.Placement, result.NumOfAvailableClusters)
+				}
+
+				excluded := []string{}
+				for _, e := range result.ExcludedClusters {
+					excluded = append(excluded, e.ClusterName)
+				}
+				if expected, ok := c.expectedExcluded[result.Placement]; ok && strings.Join(excluded, ",") != strings.Join(expected, ",") {
+					t.Errorf("expected excluded clusters %v of placement %s, but got %v", expected, result.Placement, excluded)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintResults(t *testing.T) {
+	results, err := Simulate(context.TODO(), loadTestObjects(t), "apps/amazon")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	t.Run("table", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		if err := PrintResults(out, results, OutputTable); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if lines[0] != "Placement: apps/amazon" {
+			t.Errorf("unexpected title %q", lines[0])
+		}
+		// title, summary, header and a row for each of the 3 clusters
+		if len(lines) != 6 {
+			t.Fatalf("expected 6 lines, but got %d:\n%s", len(lines), out.String())
+		}
+		for i, prefix := range []string{"CLUSTER", "cluster1", "cluster3", "cluster2"} {
+			if !strings.HasPrefix(lines[i+2], prefix) {
+				t.Errorf("expected line %d starts with %s, but got %q", i+2, prefix, lines[i+2])
+			}
+		}
+		if !strings.Contains(lines[5], "Predicate") {
+			t.Errorf("expected filter reason in %q", lines[5])
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		if err := PrintResults(out, results, OutputJSON); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		decoded := []Result{}
+		if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if len(decoded) != 1 || decoded[0].Placement != "apps/amazon" || len(decoded[0].Decisions) != 1 {
+			t.Errorf("unexpected output %s", out.String())
+		}
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		for _, output := range []string{OutputTable, OutputJSON} {
+			if err := PrintResults(failingWriter{}, results, output); err == nil {
+				t.Errorf("expected error of output %s, but got nil", output)
+			}
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		if err := PrintResults(&bytes.Buffer{}, results, "wide"); err == nil {
+			t.Errorf("expected error, but got nil")
+		}
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
+
+func TestRun(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"clusters.yaml":   testClusters,
+		"placements.yaml": testPlacements,
+	})
+
+	cases := []struct {
+		name        string
+		options     *Options
+		expectedErr string
+	}{
+		{
+			name:        "no files",
+			options:     &Options{Output: OutputTable},
+			expectedErr: "at least one file or directory is required",
+		},
+		{
+			name:        "unsupported output",
+			options:     &Options{Files: []string{dir}, Output: "wide"},
+			expectedErr: `unsupported output format "wide"`,
+		},
+		{
+			name:    "scheduled",
+			options: &Options{Files: []string{dir}, Placements: []string{"apps/amazon"}, Output: OutputJSON},
+		},
+		{
+			name:        "failed to schedule",
+			options:     &Options{Files: []string{dir}, Output: OutputTable},
+			expectedErr: "failed to schedule placements: apps/misconfigured",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.options.Run(context.TODO(), &bytes.Buffer{})
+			switch {
+			case len(c.expectedErr) == 0 && err != nil:
+				t.Errorf("unexpected err: %v", err)
+			case len(c.expectedErr) > 0 && (err == nil || err.Error() != c.expectedErr):
+				t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	fakedynamic "k8s.io/client-go/dynamic/fake"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
 
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
@@ -666,3 +667,45 @@ func TestBuildResourceMeta(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatFieldValue(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    workapiv1.FieldValue
+		expected string
+	}{
+		{
+			name:     "integer",
+			value:    workapiv1.FieldValue{Type: workapiv1.Integer, Integer: pointer.Int64(3)},
+			expected: "3",
+		},
+		{
+			name:     "string",
+			value:    workapiv1.FieldValue{Type: workapiv1.String, String: pointer.String("Running")},
+			expected: "Running",
+		},
+		{
+			name:     "boolean",
+			value:    workapiv1.FieldValue{Type: workapiv1.Boolean, Boolean: pointer.Bool(true)},
+			expected: "true",
+		},
+		{
+			name:     "json raw",
+			value:    workapiv1.FieldValue{Type: workapiv1.JsonRaw, JsonRaw: pointer.String(`{"a":1}`)},
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "empty",
+			value:    workapiv1.FieldValue{},
+			expected: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := FormatFieldValue(c.value); actual != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
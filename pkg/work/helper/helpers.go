@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -472,6 +473,21 @@ func BuildResourceMeta(
 	return resourceMeta, mapping.Resource, err
 }
 
+// FormatFieldValue returns the string form of the status feedback field value.
+func FormatFieldValue(value workapiv1.FieldValue) string {
+	switch {
+	case value.Integer != nil:
+		return strconv.FormatInt(*value.Integer, 10)
+	case value.String != nil:
+		return *value.String
+	case value.Boolean != nil:
+		return strconv.FormatBool(*value.Boolean)
+	case value.JsonRaw != nil:
+		return *value.JsonRaw
+	}
+	return ""
+}
+
 type PlacementDecisionGetter struct {
 	Client clusterlister.PlacementDecisionLister
 }
@@ -2,6 +2,7 @@ package manifestworkreplicasetcontroller
 
 import (
 	"context"
+	errorhelpers "errors"
 	"fmt"
 	"strings"
 
@@ -14,9 +15,14 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	appsinformerv1 "k8s.io/client-go/informers/apps/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	addoninformerv1alpha1 "open-cluster-management.io/api/client/addon/informers/externalversions/addon/v1alpha1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterinformerv1beta1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta1"
 	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
 	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
@@ -46,6 +52,8 @@ type ManifestWorkReplicaSetController struct {
 	workClient                    workclientset.Interface
 	manifestWorkReplicaSetLister  worklisterv1alpha1.ManifestWorkReplicaSetLister
 	manifestWorkReplicaSetIndexer cache.Indexer
+	placeDecisionIndexer          cache.Indexer
+	addOnIndexer                  cache.Indexer
 
 	reconcilers []ManifestWorkReplicaSetReconcile
 }
@@ -66,13 +74,19 @@ const (
 func NewManifestWorkReplicaSetController(
 	recorder events.Recorder,
 	workClient workclientset.Interface,
+	kubeClient kubernetes.Interface,
 	manifestWorkReplicaSetInformer workinformerv1alpha1.ManifestWorkReplicaSetInformer,
 	manifestWorkInformer workinformerv1.ManifestWorkInformer,
 	placementInformer clusterinformerv1beta1.PlacementInformer,
-	placeDecisionInformer clusterinformerv1beta1.PlacementDecisionInformer) factory.Controller {
+	placeDecisionInformer clusterinformerv1beta1.PlacementDecisionInformer,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	addOnConfigInformer addoninformerv1alpha1.AddOnDeploymentConfigInformer,
+	revisionInformer appsinformerv1.ControllerRevisionInformer) factory.Controller {
 
 	controller := newController(
-		workClient, manifestWorkReplicaSetInformer, manifestWorkInformer, placementInformer, placeDecisionInformer)
+		workClient, kubeClient, manifestWorkReplicaSetInformer, manifestWorkInformer, placementInformer, placeDecisionInformer,
+		clusterInformer, addOnInformer, addOnConfigInformer, revisionInformer)
 
 	err := manifestWorkReplicaSetInformer.Informer().AddIndexers(
 		cache.Indexers{
@@ -81,8 +95,43 @@ func NewManifestWorkReplicaSetController(
 	if err != nil {
 		utilruntime.HandleError(err)
 	}
+	err = placeDecisionInformer.Informer().AddIndexers(
+		cache.Indexers{
+			placementDecisionByCluster: indexPlacementDecisionByCluster,
+		})
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+	err = addOnInformer.Informer().AddIndexers(
+		cache.Indexers{
+			addOnByConfig: indexAddOnByConfig,
+		})
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+
+	syncCtx := factory.NewSyncContext("ManifestWorkReplicaSetController", recorder)
+
+	// The ManifestWorkReplicaSets rendered per cluster are enqueued only if the template values of the clusters
+	// selected by them change.
+	_, err = clusterInformer.Informer().AddEventHandler(
+		newRenderEventHandler(syncCtx.Queue(), controller.clusterQueueKeysFunc, clusterTemplateValuesChanged))
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+	_, err = addOnInformer.Informer().AddEventHandler(
+		newRenderEventHandler(syncCtx.Queue(), controller.addOnQueueKeysFunc, addOnConfigReferencesChanged))
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+	_, err = addOnConfigInformer.Informer().AddEventHandler(
+		newRenderEventHandler(syncCtx.Queue(), controller.addOnConfigQueueKeysFunc, addOnConfigVariablesChanged))
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
 
 	return factory.New().
+		WithSyncContext(syncCtx).
 		WithInformersQueueKeysFunc(queue.QueueKeyByMetaNamespaceName, manifestWorkReplicaSetInformer.Informer()).
 		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
 			accessor, _ := meta.Accessor(obj)
@@ -97,28 +146,68 @@ func NewManifestWorkReplicaSetController(
 			return fmt.Sprintf("%s/%s", keys[0], keys[1])
 		},
 			queue.FileterByLabel(ManifestWorkReplicaSetControllerNameLabelKey),
-			manifestWorkInformer.Informer()).
+			manifestWorkInformer.Informer(), revisionInformer.Informer()).
 		WithInformersQueueKeysFunc(controller.placementDecisionQueueKeysFunc, placeDecisionInformer.Informer()).
 		WithInformersQueueKeysFunc(controller.placementQueueKeysFunc, placementInformer.Informer()).
+		WithBareInformers(clusterInformer.Informer(), addOnInformer.Informer(), addOnConfigInformer.Informer()).
 		WithSync(controller.sync).ToController("ManifestWorkReplicaSetController", recorder)
 }
 
+// newRenderEventHandler returns an event handler which enqueues the keys of the object when it is added or deleted,
+// or when it is updated and changed returns true.
+func newRenderEventHandler(queue workqueue.RateLimitingInterface, queueKeysFunc factory.ObjectQueueKeysFunc,
+	changed func(oldObj, newObj interface{}) bool) cache.ResourceEventHandler {
+	enqueue := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		runtimeObj, ok := obj.(runtime.Object)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("object %T is not runtime Object", obj))
+			return
+		}
+		for _, key := range queueKeysFunc(runtimeObj) {
+			queue.Add(key)
+		}
+	}
+
+	return &cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if changed(oldObj, newObj) {
+				enqueue(newObj)
+			}
+		},
+		DeleteFunc: enqueue,
+	}
+}
+
 func newController(workClient workclientset.Interface,
+	kubeClient kubernetes.Interface,
 	manifestWorkReplicaSetInformer workinformerv1alpha1.ManifestWorkReplicaSetInformer,
 	manifestWorkInformer workinformerv1.ManifestWorkInformer,
 	placementInformer clusterinformerv1beta1.PlacementInformer,
-	placeDecisionInformer clusterinformerv1beta1.PlacementDecisionInformer) *ManifestWorkReplicaSetController {
+	placeDecisionInformer clusterinformerv1beta1.PlacementDecisionInformer,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	addOnInformer addoninformerv1alpha1.ManagedClusterAddOnInformer,
+	addOnConfigInformer addoninformerv1alpha1.AddOnDeploymentConfigInformer,
+	revisionInformer appsinformerv1.ControllerRevisionInformer) *ManifestWorkReplicaSetController {
 	return &ManifestWorkReplicaSetController{
 		workClient:                    workClient,
 		manifestWorkReplicaSetLister:  manifestWorkReplicaSetInformer.Lister(),
 		manifestWorkReplicaSetIndexer: manifestWorkReplicaSetInformer.Informer().GetIndexer(),
+		placeDecisionIndexer:          placeDecisionInformer.Informer().GetIndexer(),
+		addOnIndexer:                  addOnInformer.Informer().GetIndexer(),
 
 		reconcilers: []ManifestWorkReplicaSetReconcile{
 			&finalizeReconciler{workApplier: workapplier.NewWorkApplierWithTypedClient(workClient, manifestWorkInformer.Lister()),
 				workClient: workClient, manifestWorkLister: manifestWorkInformer.Lister()},
 			&addFinalizerReconciler{workClient: workClient},
+			&revisionReconciler{workClient: workClient, kubeClient: kubeClient, revisionLister: revisionInformer.Lister(),
+				manifestWorkLister: manifestWorkInformer.Lister()},
 			&deployReconciler{workApplier: workapplier.NewWorkApplierWithTypedClient(workClient, manifestWorkInformer.Lister()),
-				manifestWorkLister: manifestWorkInformer.Lister(), placementLister: placementInformer.Lister(), placeDecisionLister: placeDecisionInformer.Lister()},
+				manifestWorkLister: manifestWorkInformer.Lister(), placementLister: placementInformer.Lister(), placeDecisionLister: placeDecisionInformer.Lister(),
+				clusterLister: clusterInformer.Lister(), addOnLister: addOnInformer.Lister(), addOnConfigLister: addOnConfigInformer.Lister()},
 			&statusReconciler{manifestWorkLister: manifestWorkInformer.Lister()},
 		},
 	}
@@ -149,8 +238,11 @@ func (m *ManifestWorkReplicaSetController) sync(ctx context.Context, controllerC
 	var state reconcileState
 	var errs []error
 	for _, reconciler := range m.reconcilers {
+		var rqe *requeueError
 		manifestWorkReplicaSet, state, err = reconciler.reconcile(ctx, manifestWorkReplicaSet)
-		if err != nil {
+		if errorhelpers.As(err, &rqe) {
+			controllerContext.Queue().AddAfter(key, rqe.requeueAfter)
+		} else if err != nil {
 			errs = append(errs, err)
 		}
 		if state == reconcileStop {
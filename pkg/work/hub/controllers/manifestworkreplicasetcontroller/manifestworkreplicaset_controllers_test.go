@@ -10,8 +10,12 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	fakekube "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 
+	fakeaddonclient "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
 	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
@@ -223,12 +227,22 @@ func TestManifestWorkReplicaSetControllerPatchStatus(t *testing.T) {
 			clusterInformers.Cluster().V1beta1().Placements().Informer().GetStore().Add(c.placement)
 			clusterInformers.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(c.decision)
 
+			addOnInformers := addoninformers.NewSharedInformerFactory(fakeaddonclient.NewSimpleClientset(), 10*time.Minute)
+
+			fakeKubeClient := fakekube.NewSimpleClientset()
+			kubeInformers := kubeinformers.NewSharedInformerFactory(fakeKubeClient, 10*time.Minute)
+
 			ctrl := newController(
 				fakeClient,
+				fakeKubeClient,
 				workInformers.Work().V1alpha1().ManifestWorkReplicaSets(),
 				workInformers.Work().V1().ManifestWorks(),
 				clusterInformers.Cluster().V1beta1().Placements(),
 				clusterInformers.Cluster().V1beta1().PlacementDecisions(),
+				clusterInformers.Cluster().V1().ManagedClusters(),
+				addOnInformers.Addon().V1alpha1().ManagedClusterAddOns(),
+				addOnInformers.Addon().V1alpha1().AddOnDeploymentConfigs(),
+				kubeInformers.Apps().V1().ControllerRevisions(),
 			)
 
 			controllerContext := testingcommon.NewFakeSyncContext(t, c.mwrSet.Namespace+"/"+c.mwrSet.Name)
@@ -3,6 +3,7 @@ package manifestworkreplicasetcontroller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
@@ -10,6 +11,8 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterlister "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
 	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
@@ -26,6 +29,9 @@ type deployReconciler struct {
 	manifestWorkLister  worklisterv1.ManifestWorkLister
 	placeDecisionLister clusterlister.PlacementDecisionLister
 	placementLister     clusterlister.PlacementLister
+	clusterLister       clusterlisterv1.ManagedClusterLister
+	addOnLister         addonlisterv1alpha1.ManagedClusterAddOnLister
+	addOnConfigLister   addonlisterv1alpha1.AddOnDeploymentConfigLister
 }
 
 func (d *deployReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
@@ -67,39 +73,33 @@ func (d *deployReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 		deletedClusters = deletedClusters.Union(deleted)
 	}
 
-	// Create manifestWork for added clusters
-	for cls := range addedClusters {
-		mw, err := CreateManifestWork(mwrSet, cls)
-		if err != nil {
-			errs = append(errs, err)
-			continue
-		}
+	// Render the manifestWorks of the clusters. The clusters failing to render are skipped, so their
+	// manifestWorks are not created or updated.
+	required, err := d.renderManifestWorks(mwrSet, existingClusters.Difference(deletedClusters).Union(addedClusters))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	omitRevisionOfUnrevisionedWorks(required, manifestWorks)
 
-		_, err = d.workApplier.Apply(ctx, mw)
+	// Create or update manifestWorks in case there are changes at ManifestWork or ManifestWorkReplicaSet.
+	// The clusters are updated in batches if the ManifestWorkReplicaSet has a rollout strategy.
+	var clustersToApply []*workv1.ManifestWork
+	var requeueAfter *time.Duration
+	if required != nil {
+		clustersToApply, requeueAfter, err = d.rollout(mwrSet, placements, manifestWorks, required)
 		if err != nil {
 			errs = append(errs, err)
 		}
 	}
-
-	// Update manifestWorks in case there are changes at ManifestWork or ManifestWorkReplicaSet
-	for cls := range existingClusters {
-		// Delete manifestWork for deleted clusters
-		if deletedClusters.Has(cls) {
-			err = d.workApplier.Delete(ctx, cls, mwrSet.Name)
-			if err != nil {
-				errs = append(errs, err)
-			}
-			continue
-		}
-
-		mw, err := CreateManifestWork(mwrSet, cls)
-		if err != nil {
+	for _, mw := range clustersToApply {
+		if _, err := d.workApplier.Apply(ctx, mw); err != nil {
 			errs = append(errs, err)
-			continue
 		}
+	}
 
-		_, err = d.workApplier.Apply(ctx, mw)
-		if err != nil {
+	// Delete manifestWorks of the deleted clusters
+	for cls := range existingClusters.Intersection(deletedClusters) {
+		if err := d.workApplier.Delete(ctx, cls, mwrSet.Name); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -124,9 +124,121 @@ func (d *deployReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetPlacementDecisionVerified(workapiv1alpha1.ReasonAsExpected, ""))
 	}
 
+	// check the rollout again once the progressing clusters time out
+	if len(errs) == 0 && requeueAfter != nil {
+		return mwrSet, reconcileContinue, &requeueError{
+			requeueAfter: roundUpSeconds(*requeueAfter),
+			message:      fmt.Sprintf("rollout of ManifestWorkReplicaSet %s/%s is in progress", mwrSet.Namespace, mwrSet.Name),
+		}
+	}
 	return mwrSet, reconcileContinue, utilerrors.NewAggregate(errs)
 }
 
+// rollout returns the manifestWorks to create or update on the clusters. All the manifestWorks are returned
+// if the ManifestWorkReplicaSet has no rollout strategy, otherwise only the ones of the current batch are
+// returned and the rollout progress is set in the status.
+func (d *deployReconciler) rollout(
+	mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	placements []*clusterv1beta1.Placement,
+	manifestWorks []*workv1.ManifestWork,
+	required map[string]*workv1.ManifestWork,
+) ([]*workv1.ManifestWork, *time.Duration, error) {
+	clusters := sets.KeySet(required)
+	strategy, err := getRolloutStrategy(mwrSet)
+	switch {
+	case err != nil:
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions,
+			getRolloutCondition(ReasonRolloutInvalidStrategy, metav1.ConditionFalse, err.Error()))
+		return nil, nil, nil
+	case strategy == nil:
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRolloutProgressing)
+		works := []*workv1.ManifestWork{}
+		for _, cls := range sets.List(clusters) {
+			works = append(works, required[cls])
+		}
+		return works, nil, nil
+	}
+
+	existing := map[string]*workv1.ManifestWork{}
+	for _, mw := range manifestWorks {
+		existing[mw.Namespace] = mw
+	}
+	batches, err := getRolloutBatches(strategy, clusters, placements, d.placeDecisionLister)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := rollout(mwrSet, strategy, batches, required, existing)
+	apimeta.SetStatusCondition(&mwrSet.Status.Conditions, result.condition)
+
+	works := []*workv1.ManifestWork{}
+	appliedTime := rolloutClock.Now().UTC().Format(time.RFC3339)
+	for _, cls := range result.clustersToApply {
+		mw := required[cls]
+		if mw.Annotations == nil {
+			mw.Annotations = map[string]string{}
+		}
+		mw.Annotations[RolloutAppliedTimeAnnotationKey] = appliedTime
+		works = append(works, mw)
+	}
+
+	return works, result.requeueAfter, nil
+}
+
+// omitRevisionOfUnrevisionedWorks removes the revision annotation from the required manifestWorks of the
+// clusters whose manifestWorks have no revision annotation but are otherwise up to date. These manifestWorks are
+// created before the revisions are introduced, and are not updated or rolled out again only to add the
+// annotation. They get the annotation once the template changes.
+func omitRevisionOfUnrevisionedWorks(required map[string]*workv1.ManifestWork, manifestWorks []*workv1.ManifestWork) {
+	for _, mw := range manifestWorks {
+		if _, ok := mw.Annotations[RevisionAnnotationKey]; ok {
+			continue
+		}
+		work, ok := required[mw.Namespace]
+		if !ok {
+			continue
+		}
+		unrevisioned := work.DeepCopy()
+		delete(unrevisioned.Annotations, RevisionAnnotationKey)
+		if workapplier.ManifestWorkEqual(unrevisioned, mw) {
+			required[mw.Namespace] = unrevisioned
+		}
+	}
+}
+
+// renderManifestWorks returns the manifestWorks of the clusters keyed by the cluster name, which are rendered
+// with the templates and overrides of the ManifestWorkReplicaSet. The render errors are set in the status, and
+// nil is returned if the overrides are invalid.
+func (d *deployReconciler) renderManifestWorks(
+	mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	clusters sets.Set[string],
+) (map[string]*workv1.ManifestWork, error) {
+	config, err := getRenderConfig(mwrSet)
+	if err != nil {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(
+			ManifestWorkReplicaSetConditionManifestworkRendered, ReasonInvalidOverrides, err.Error(), metav1.ConditionFalse))
+		return nil, nil
+	}
+
+	required := map[string]*workv1.ManifestWork{}
+	renderErrs := map[string]error{}
+	for cls := range clusters {
+		mw, err := CreateManifestWork(mwrSet, cls)
+		if err != nil {
+			return nil, err
+		}
+		if config != nil {
+			if err := d.renderManifestWork(config, mw); err != nil {
+				renderErrs[cls] = err
+				continue
+			}
+		}
+		required[cls] = mw
+	}
+	setRenderedCondition(mwrSet, config, renderErrs)
+	return required, nil
+}
+
 // Return only True status if there all clusters have manifests applied as expected
 func GetManifestworkApplied(reason string, message string) metav1.Condition {
 	if reason == workapiv1alpha1.ReasonAsExpected {
@@ -161,11 +273,17 @@ func CreateManifestWork(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, clusterN
 		return nil, fmt.Errorf("Invalid cluster namespace")
 	}
 
+	revision, _, err := getRevisionName(mwrSet)
+	if err != nil {
+		return nil, err
+	}
+
 	return &workv1.ManifestWork{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      mwrSet.Name,
-			Namespace: clusterNS,
-			Labels:    map[string]string{ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet)},
+			Name:        mwrSet.Name,
+			Namespace:   clusterNS,
+			Labels:      map[string]string{ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet)},
+			Annotations: map[string]string{RevisionAnnotationKey: revision},
 		},
 		Spec: mwrSet.Spec.ManifestWorkTemplate}, nil
 }
@@ -2,6 +2,7 @@ package manifestworkreplicasetcontroller
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -13,8 +14,10 @@ import (
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
 	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
 
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
 	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
 )
 
@@ -200,3 +203,67 @@ func TestDeployReconcileAsPlacementNotExist(t *testing.T) {
 		t.Fatal("Placement condition Reason not match PlacementDecisionEmpty ", placeCondition)
 	}
 }
+
+func TestDeployReconcileWithUnrevisionedManifestWorks(t *testing.T) {
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		templateChanged bool
+		expectedActions []string
+	}{
+		{
+			name: "up to date without rollout strategy",
+		},
+		{
+			name:        "up to date with rollout strategy",
+			annotations: map[string]string{RolloutStrategyAnnotationKey: `{"type":"Rolling","maxConcurrency":1}`},
+		},
+		{
+			name:            "template changed",
+			templateChanged: true,
+			expectedActions: []string{"patch", "patch"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", c.annotations)
+			if c.templateChanged {
+				mwrSet.Spec.ManifestWorkTemplate.DeleteOption = &workv1.DeleteOption{PropagationPolicy: workv1.DeletePropagationPolicyTypeOrphan}
+			}
+			works := helpertest.CreateTestManifestWorks("test", "default", "cls1", "cls2")
+			fWorkClient := fakeworkclient.NewSimpleClientset(append(works, mwrSet)...)
+			workInformerFactory := workinformers.NewSharedInformerFactory(fWorkClient, 0)
+			for _, mw := range works {
+				if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+					t.Fatal(err)
+				}
+			}
+			mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+			placement, placementDecision := helpertest.CreateTestPlacement("placement", "default", "cls1", "cls2")
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(fakeclusterclient.NewSimpleClientset(), 0)
+			if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+				t.Fatal(err)
+			}
+			if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+				t.Fatal(err)
+			}
+
+			reconciler := deployReconciler{
+				workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+				manifestWorkLister:  mwLister,
+				placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+				placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+			}
+			fWorkClient.ClearActions()
+			if _, _, err := reconciler.reconcile(context.TODO(), mwrSet); err != nil {
+				var rErr *requeueError
+				if !errors.As(err, &rErr) {
+					t.Fatal(err)
+				}
+			}
+			testingcommon.AssertActions(t, fWorkClient.Actions(), c.expectedActions...)
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+)
+
+const (
+	// FeedbackAggregationAnnotationKey is the annotation on ManifestWorkReplicaSet which defines how the status
+	// feedback values of the ManifestWorks are aggregated. The value is a json encoded list of
+	// FeedbackAggregationRule. The feedback values must be configured in the ManifestConfigs of the template.
+	FeedbackAggregationAnnotationKey = "work.open-cluster-management.io/experimental-feedback-aggregation"
+
+	// ManifestWorkReplicaSetConditionFeedbackAggregated is the condition with the aggregated feedback values in
+	// the message. It is only set if the feedback aggregation annotation is set.
+	ManifestWorkReplicaSetConditionFeedbackAggregated = "FeedbackAggregated"
+
+	// ManifestWorkReplicaSetConditionClusterStatuses is the condition with the compact status table of the
+	// clusters in the message, the clusters not available are listed first. It is true if the ManifestWorks
+	// of all the clusters are available, and it is only set if the feedback aggregation annotation is set.
+	ManifestWorkReplicaSetConditionClusterStatuses = "ClusterStatuses"
+
+	ReasonFeedbackAggregated      = "FeedbackAggregated"
+	ReasonInvalidAggregationRules = "InvalidAggregationRules"
+	ReasonAllClustersAvailable    = "AllClustersAvailable"
+	ReasonClustersNotAvailable    = "ClustersNotAvailable"
+
+	// maxClusterStatusesInMessage is the max number of clusters listed in the cluster statuses condition message
+	maxClusterStatusesInMessage = 50
+
+	// maxFeedbackValueLength is the max length in bytes of a feedback value in the cluster statuses condition
+	// message, the longer values are truncated.
+	maxFeedbackValueLength = 64
+
+	// maxFeedbackRuleNameLength is the max length in bytes of the name of a feedback aggregation rule.
+	maxFeedbackRuleNameLength = 63
+
+	// maxConditionMessageLength is the max length in bytes of a condition message allowed by the api.
+	maxConditionMessageLength = 32768
+)
+
+// the compact statuses of the clusters in the cluster statuses condition
+const (
+	clusterStatusDeleting    = "Deleting"
+	clusterStatusDegraded    = "Degraded"
+	clusterStatusProgressing = "Progressing"
+	clusterStatusPending     = "Pending"
+	clusterStatusApplied     = "Applied"
+	clusterStatusAvailable   = "Available"
+)
+
+// AggregationType is the type of a feedback aggregation.
+type AggregationType string
+
+const (
+	// AggregationSum is the sum of the integer feedback values.
+	AggregationSum AggregationType = "Sum"
+	// AggregationMin is the minimum of the integer feedback values.
+	AggregationMin AggregationType = "Min"
+	// AggregationMax is the maximum of the integer feedback values.
+	AggregationMax AggregationType = "Max"
+	// AggregationClusters is the list of the clusters whose feedback value equals the value of the rule, or
+	// the clusters reporting the feedback value if the value of the rule is empty.
+	AggregationClusters AggregationType = "Clusters"
+)
+
+// FeedbackAggregationRule defines how a status feedback value of a manifest is aggregated across the clusters.
+type FeedbackAggregationRule struct {
+	// Name is the name of the aggregated value in the condition messages.
+	Name string `json:"name"`
+	// ResourceIdentifier identifies the manifest reporting the feedback value.
+	ResourceIdentifier workv1.ResourceIdentifier `json:"resourceIdentifier"`
+	// FeedbackName is the name of the feedback value of the manifest.
+	FeedbackName string `json:"feedbackName"`
+	// Type is the type of the aggregation.
+	Type AggregationType `json:"type"`
+	// Value is the feedback value matched by the Clusters aggregation. Integer and boolean values are matched
+	// by their string form.
+	Value string `json:"value,omitempty"`
+}
+
+// getFeedbackAggregationRules returns the feedback aggregation rules of the ManifestWorkReplicaSet, or nil if
+// the feedback is not aggregated.
+func getFeedbackAggregationRules(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) ([]FeedbackAggregationRule, error) {
+	value, ok := mwrSet.Annotations[FeedbackAggregationAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	rules := []FeedbackAggregationRule{}
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode feedback aggregation rules: %w", err)
+	}
+
+	names := map[string]bool{}
+	for i, rule := range rules {
+		switch {
+		case len(rule.Name) == 0:
+			return nil, fmt.Errorf("name of feedback aggregation rule %d is empty", i)
+		case len(rule.Name) > maxFeedbackRuleNameLength:
+			return nil, fmt.Errorf("name of feedback aggregation rule %d is longer than %d bytes", i, maxFeedbackRuleNameLength)
+		case names[rule.Name]:
+			return nil, fmt.Errorf("feedback aggregation rule %s is duplicated", rule.Name)
+		case len(rule.ResourceIdentifier.Resource) == 0 || len(rule.ResourceIdentifier.Name) == 0:
+			return nil, fmt.Errorf("resource and name of feedback aggregation rule %s are required", rule.Name)
+		case len(rule.FeedbackName) == 0:
+			return nil, fmt.Errorf("feedback name of feedback aggregation rule %s is empty", rule.Name)
+		}
+		switch rule.Type {
+		case AggregationSum, AggregationMin, AggregationMax, AggregationClusters:
+		default:
+			return nil, fmt.Errorf("type %q of feedback aggregation rule %s is not supported", rule.Type, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return rules, nil
+}
+
+// getFeedbackValue returns the feedback value of the rule reported by the ManifestWork, or nil if it is not
+// reported.
+func getFeedbackValue(mw *workv1.ManifestWork, rule FeedbackAggregationRule) *workv1.FieldValue {
+	id := rule.ResourceIdentifier
+	for _, manifest := range mw.Status.ResourceStatus.Manifests {
+		meta := manifest.ResourceMeta
+		if meta.Group != id.Group || meta.Resource != id.Resource || meta.Namespace != id.Namespace || meta.Name != id.Name {
+			continue
+		}
+		for _, value := range manifest.StatusFeedbacks.Values {
+			if value.Name == rule.FeedbackName {
+				return &value.Value
+			}
+		}
+	}
+	return nil
+}
+
+// aggregateFeedback returns the message of the value aggregated by the rule from the ManifestWorks.
+func aggregateFeedback(rule FeedbackAggregationRule, manifestWorks []*workv1.ManifestWork) string {
+	reported := 0
+	var result *int64
+	clusters := []string{}
+	for _, mw := range manifestWorks {
+		value := getFeedbackValue(mw, rule)
+		if value == nil {
+			continue
+		}
+
+		if rule.Type == AggregationClusters {
+			reported++
+			if len(rule.Value) == 0 || helper.FormatFieldValue(*value) == rule.Value {
+				clusters = append(clusters, mw.Namespace)
+			}
+			continue
+		}
+
+		// only the integer values are aggregated by sum, min and max
+		if value.Integer == nil {
+			continue
+		}
+		reported++
+		switch {
+		case result == nil:
+			v := *value.Integer
+			result = &v
+		case rule.Type == AggregationSum:
+			*result += *value.Integer
+		case rule.Type == AggregationMin && *value.Integer < *result:
+			*result = *value.Integer
+		case rule.Type == AggregationMax && *value.Integer > *result:
+			*result = *value.Integer
+		}
+	}
+
+	aggregated := "none"
+	switch {
+	case rule.Type == AggregationClusters:
+		sort.Strings(clusters)
+		if len(clusters) > maxClustersInMessage {
+			clusters = append(clusters[:maxClustersInMessage], fmt.Sprintf("and %d more", len(clusters)-maxClustersInMessage))
+		}
+		aggregated = fmt.Sprintf("[%s]", strings.Join(clusters, ","))
+	case result != nil:
+		aggregated = strconv.FormatInt(*result, 10)
+	}
+	return fmt.Sprintf("%s: %s from %d/%d clusters", rule.Name, aggregated, reported, len(manifestWorks))
+}
+
+// getClusterStatus returns the compact status of the ManifestWork.
+func getClusterStatus(mw *workv1.ManifestWork) string {
+	switch {
+	case !mw.DeletionTimestamp.IsZero():
+		return clusterStatusDeleting
+	case apimeta.IsStatusConditionTrue(mw.Status.Conditions, workv1.WorkDegraded):
+		return clusterStatusDegraded
+	case apimeta.IsStatusConditionTrue(mw.Status.Conditions, workv1.WorkProgressing):
+		return clusterStatusProgressing
+	case apimeta.IsStatusConditionTrue(mw.Status.Conditions, workv1.WorkAvailable):
+		return clusterStatusAvailable
+	case apimeta.IsStatusConditionTrue(mw.Status.Conditions, workv1.WorkApplied):
+		return clusterStatusApplied
+	}
+	return clusterStatusPending
+}
+
+// clusterStatusOrder is the order of the clusters in the status table, the clusters need attention first.
+var clusterStatusOrder = map[string]int{
+	clusterStatusDegraded:    0,
+	clusterStatusPending:     1,
+	clusterStatusProgressing: 2,
+	clusterStatusApplied:     3,
+	clusterStatusDeleting:    4,
+	clusterStatusAvailable:   5,
+}
+
+// getClusterStatusesCondition returns the condition with the status table of the clusters, each row is the
+// compact status of the cluster with its feedback values of the rules.
+func getClusterStatusesCondition(rules []FeedbackAggregationRule, manifestWorks []*workv1.ManifestWork) metav1.Condition {
+	type clusterStatus struct {
+		cluster string
+		status  string
+	}
+	statuses := []clusterStatus{}
+	available := 0
+	for _, mw := range manifestWorks {
+		status := getClusterStatus(mw)
+		if status == clusterStatusAvailable {
+			available++
+		}
+		statuses = append(statuses, clusterStatus{cluster: mw.Namespace, status: status})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].status != statuses[j].status {
+			return clusterStatusOrder[statuses[i].status] < clusterStatusOrder[statuses[j].status]
+		}
+		return statuses[i].cluster < statuses[j].cluster
+	})
+
+	works := map[string]*workv1.ManifestWork{}
+	for _, mw := range manifestWorks {
+		works[mw.Namespace] = mw
+	}
+	message := fmt.Sprintf("%d/%d clusters available", available, len(manifestWorks))
+	rows := []string{}
+	for _, status := range statuses {
+		row := fmt.Sprintf("%s: %s", status.cluster, status.status)
+		if len(rules) > 0 {
+			values := []string{}
+			for _, rule := range rules {
+				value := "-"
+				if v := getFeedbackValue(works[status.cluster], rule); v != nil {
+					value = truncateFeedbackValue(helper.FormatFieldValue(*v))
+				}
+				values = append(values, fmt.Sprintf("%s=%s", rule.Name, value))
+			}
+			row = fmt.Sprintf("%s [%s]", row, strings.Join(values, ","))
+		}
+		rows = append(rows, row)
+	}
+
+	rows = limitMessageRows(len(message)+len(": "), rows, maxClusterStatusesInMessage)
+	if len(rows) > 0 {
+		message = fmt.Sprintf("%s: %s", message, strings.Join(rows, "; "))
+	}
+	if available == len(manifestWorks) {
+		return getCondition(ManifestWorkReplicaSetConditionClusterStatuses, ReasonAllClustersAvailable, message, metav1.ConditionTrue)
+	}
+	return getCondition(ManifestWorkReplicaSetConditionClusterStatuses, ReasonClustersNotAvailable, message, metav1.ConditionFalse)
+}
+
+// limitMessageRows returns the rows fitting in a condition message following the given length when joined
+// by "; ", and at most maxRows of them. The rows left out are counted in a trailing "and N more" row.
+func limitMessageRows(length int, rows []string, maxRows int) []string {
+	limited := []string{}
+	for i, row := range rows {
+		// keep the room for the number of the rows left out if the row is not the last one
+		reserved := 0
+		if i < len(rows)-1 {
+			reserved = len("; ") + len(fmt.Sprintf("and %d more", len(rows)-i-1))
+		}
+		if i == maxRows || length+len(row)+reserved > maxConditionMessageLength {
+			return append(limited, fmt.Sprintf("and %d more", len(rows)-i))
+		}
+		limited = append(limited, row)
+		length += len(row) + len("; ")
+	}
+	return limited
+}
+
+// truncateFeedbackValue truncates the feedback value to maxFeedbackValueLength bytes at a rune boundary.
+func truncateFeedbackValue(value string) string {
+	if len(value) <= maxFeedbackValueLength {
+		return value
+	}
+	end := maxFeedbackValueLength
+	for end > 0 && !utf8.RuneStart(value[end]) {
+		end--
+	}
+	return value[:end] + "..."
+}
+
+// setFeedbackConditions sets the conditions of the aggregated feedback values and the cluster statuses of the
+// ManifestWorkReplicaSet, or removes them if the feedback aggregation annotation is not set.
+func setFeedbackConditions(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, manifestWorks []*workv1.ManifestWork) {
+	if _, ok := mwrSet.Annotations[FeedbackAggregationAnnotationKey]; !ok {
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionFeedbackAggregated)
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionClusterStatuses)
+		return
+	}
+
+	rules, err := getFeedbackAggregationRules(mwrSet)
+	if err != nil {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionFeedbackAggregated,
+			ReasonInvalidAggregationRules, err.Error(), metav1.ConditionFalse))
+	} else {
+		// the feedback values of the deleting manifestworks are not aggregated
+		works := []*workv1.ManifestWork{}
+		for _, mw := range manifestWorks {
+			if mw.DeletionTimestamp.IsZero() {
+				works = append(works, mw)
+			}
+		}
+		messages := []string{}
+		for _, rule := range rules {
+			messages = append(messages, aggregateFeedback(rule, works))
+		}
+		messages = limitMessageRows(0, messages, len(messages))
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionFeedbackAggregated,
+			ReasonFeedbackAggregated, strings.Join(messages, "; "), metav1.ConditionTrue))
+	}
+
+	apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getClusterStatusesCondition(rules, manifestWorks))
+}
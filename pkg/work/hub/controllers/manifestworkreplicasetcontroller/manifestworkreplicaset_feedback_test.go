@@ -0,0 +1,476 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+var testDeploymentIdentifier = workv1.ResourceIdentifier{
+	Group:     "apps",
+	Resource:  "deployments",
+	Namespace: "default",
+	Name:      "test",
+}
+
+var testJobIdentifier = workv1.ResourceIdentifier{
+	Group:     "batch",
+	Resource:  "jobs",
+	Namespace: "default",
+	Name:      "test",
+}
+
+// newFeedbackManifestWork returns a ManifestWork on the cluster with the true conditions, and the feedback
+// values of the resource.
+func newFeedbackManifestWork(cluster string, conditions []string, id workv1.ResourceIdentifier,
+	values ...workv1.FeedbackValue) *workv1.ManifestWork {
+	mw := &workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: cluster,
+			Labels:    map[string]string{ManifestWorkReplicaSetControllerNameLabelKey: "default.test"},
+		},
+	}
+	for _, condition := range conditions {
+		apimeta.SetStatusCondition(&mw.Status.Conditions, getCondition(condition, "", "", metav1.ConditionTrue))
+	}
+	if len(values) > 0 {
+		mw.Status.ResourceStatus.Manifests = []workv1.ManifestCondition{
+			{
+				ResourceMeta: workv1.ManifestResourceMeta{
+					Group:     id.Group,
+					Resource:  id.Resource,
+					Namespace: id.Namespace,
+					Name:      id.Name,
+				},
+				StatusFeedbacks: workv1.StatusFeedbackResult{Values: values},
+			},
+		}
+	}
+	return mw
+}
+
+func integerFeedback(name string, value int64) workv1.FeedbackValue {
+	return workv1.FeedbackValue{Name: name, Value: workv1.FieldValue{Type: workv1.Integer, Integer: pointer.Int64(value)}}
+}
+
+func stringFeedback(name string, value string) workv1.FeedbackValue {
+	return workv1.FeedbackValue{Name: name, Value: workv1.FieldValue{Type: workv1.String, String: pointer.String(value)}}
+}
+
+func TestGetFeedbackAggregationRules(t *testing.T) {
+	cases := []struct {
+		name          string
+		annotations   map[string]string
+		expectedRules int
+		expectedErr   string
+	}{
+		{
+			name: "not aggregated",
+		},
+		{
+			name: "valid rules",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `[
+				{"name":"readyReplicas","resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"default","name":"test"},"feedbackName":"ReadyReplicas","type":"Sum"},
+				{"name":"failedJobs","resourceIdentifier":{"group":"batch","resource":"jobs","namespace":"default","name":"test"},"feedbackName":"JobComplete","type":"Clusters","value":"False"}
+			]`},
+			expectedRules: 2,
+		},
+		{
+			name:        "invalid json",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `{`},
+			expectedErr: "failed to decode feedback aggregation rules",
+		},
+		{
+			name: "empty name",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `[
+				{"resourceIdentifier":{"resource":"deployments","name":"test"},"feedbackName":"ReadyReplicas","type":"Sum"}
+			]`},
+			expectedErr: "name of feedback aggregation rule 0 is empty",
+		},
+		{
+			name: "too long name",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: fmt.Sprintf(`[
+				{"name":%q,"resourceIdentifier":{"resource":"deployments","name":"test"},"feedbackName":"ReadyReplicas","type":"Sum"}
+			]`, strings.Repeat("a", 64))},
+			expectedErr: "name of feedback aggregation rule 0 is longer than 63 bytes",
+		},
+		{
+			name: "duplicated name",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `[
+				{"name":"a","resourceIdentifier":{"resource":"deployments","name":"test"},"feedbackName":"ReadyReplicas","type":"Sum"},
+				{"name":"a","resourceIdentifier":{"resource":"deployments","name":"test"},"feedbackName":"ReadyReplicas","type":"Min"}
+			]`},
+			expectedErr: "feedback aggregation rule a is duplicated",
+		},
+		{
+			name: "no resource",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `[
+				{"name":"a","resourceIdentifier":{"name":"test"},"feedbackName":"ReadyReplicas","type":"Sum"}
+			]`},
+			expectedErr: "resource and name of feedback aggregation rule a are required",
+		},
+		{
+			name: "no feedback name",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `[
+				{"name":"a","resourceIdentifier":{"resource":"deployments","name":"test"},"type":"Sum"}
+			]`},
+			expectedErr: "feedback name of feedback aggregation rule a is empty",
+		},
+		{
+			name: "unsupported type",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `[
+				{"name":"a","resourceIdentifier":{"resource":"deployments","name":"test"},"feedbackName":"ReadyReplicas","type":"Avg"}
+			]`},
+			expectedErr: `type "Avg" of feedback aggregation rule a is not supported`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSet("test", "default", "placement")
+			mwrSet.Annotations = c.annotations
+			rules, err := getFeedbackAggregationRules(mwrSet)
+			switch {
+			case len(c.expectedErr) > 0 && (err == nil || !strings.Contains(err.Error(), c.expectedErr)):
+				t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+			case len(c.expectedErr) == 0 && err != nil:
+				t.Fatal(err)
+			}
+			if len(rules) != c.expectedRules {
+				t.Errorf("expected %d rules, but got %d", c.expectedRules, len(rules))
+			}
+		})
+	}
+}
+
+func TestAggregateFeedback(t *testing.T) {
+	works := []*workv1.ManifestWork{
+		newFeedbackManifestWork("cluster1", nil, testDeploymentIdentifier, integerFeedback("ReadyReplicas", 3)),
+		newFeedbackManifestWork("cluster2", nil, testDeploymentIdentifier, integerFeedback("ReadyReplicas", 1)),
+		newFeedbackManifestWork("cluster3", nil, testDeploymentIdentifier, integerFeedback("ReadyReplicas", 5)),
+		newFeedbackManifestWork("cluster4", nil, testDeploymentIdentifier, stringFeedback("ReadyReplicas", "unknown")),
+		newFeedbackManifestWork("cluster5", nil, testDeploymentIdentifier),
+	}
+	jobWorks := []*workv1.ManifestWork{
+		newFeedbackManifestWork("cluster1", nil, testJobIdentifier, stringFeedback("JobComplete", "True")),
+		newFeedbackManifestWork("cluster2", nil, testJobIdentifier, stringFeedback("JobComplete", "False")),
+		newFeedbackManifestWork("cluster3", nil, testJobIdentifier, stringFeedback("JobComplete", "False")),
+		newFeedbackManifestWork("cluster4", nil, testJobIdentifier),
+	}
+
+	manyWorks := []*workv1.ManifestWork{}
+	for i := 0; i < maxClustersInMessage+2; i++ {
+		manyWorks = append(manyWorks, newFeedbackManifestWork(
+			fmt.Sprintf("cluster%02d", i), nil, testJobIdentifier, stringFeedback("JobComplete", "False")))
+	}
+
+	cases := []struct {
+		name     string
+		rule     FeedbackAggregationRule
+		works    []*workv1.ManifestWork
+		expected string
+	}{
+		{
+			name: "sum",
+			rule: FeedbackAggregationRule{Name: "readyReplicas", ResourceIdentifier: testDeploymentIdentifier,
+				FeedbackName: "ReadyReplicas", Type: AggregationSum},
+			works:    works,
+			expected: "readyReplicas: 9 from 3/5 clusters",
+		},
+		{
+			name: "min",
+			rule: FeedbackAggregationRule{Name: "readyReplicas", ResourceIdentifier: testDeploymentIdentifier,
+				FeedbackName: "ReadyReplicas", Type: AggregationMin},
+			works:    works,
+			expected: "readyReplicas: 1 from 3/5 clusters",
+		},
+		{
+			name: "max",
+			rule: FeedbackAggregationRule{Name: "readyReplicas", ResourceIdentifier: testDeploymentIdentifier,
+				FeedbackName: "ReadyReplicas", Type: AggregationMax},
+			works:    works,
+			expected: "readyReplicas: 5 from 3/5 clusters",
+		},
+		{
+			name: "not reported",
+			rule: FeedbackAggregationRule{Name: "readyReplicas", ResourceIdentifier: testDeploymentIdentifier,
+				FeedbackName: "AvailableReplicas", Type: AggregationMax},
+			works:    works,
+			expected: "readyReplicas: none from 0/5 clusters",
+		},
+		{
+			name: "another resource",
+			rule: FeedbackAggregationRule{Name: "readyReplicas", ResourceIdentifier: testJobIdentifier,
+				FeedbackName: "ReadyReplicas", Type: AggregationSum},
+			works:    works,
+			expected: "readyReplicas: none from 0/5 clusters",
+		},
+		{
+			name: "clusters with value",
+			rule: FeedbackAggregationRule{Name: "failedJobs", ResourceIdentifier: testJobIdentifier,
+				FeedbackName: "JobComplete", Type: AggregationClusters, Value: "False"},
+			works:    jobWorks,
+			expected: "failedJobs: [cluster2,cluster3] from 3/4 clusters",
+		},
+		{
+			name: "clusters reporting",
+			rule: FeedbackAggregationRule{Name: "jobs", ResourceIdentifier: testJobIdentifier,
+				FeedbackName: "JobComplete", Type: AggregationClusters},
+			works:    jobWorks,
+			expected: "jobs: [cluster1,cluster2,cluster3] from 3/4 clusters",
+		},
+		{
+			name: "clusters capped",
+			rule: FeedbackAggregationRule{Name: "failedJobs", ResourceIdentifier: testJobIdentifier,
+				FeedbackName: "JobComplete", Type: AggregationClusters, Value: "False"},
+			works: manyWorks,
+			expected: "failedJobs: [cluster00,cluster01,cluster02,cluster03,cluster04,cluster05,cluster06,cluster07," +
+				"cluster08,cluster09,and 2 more] from 12/12 clusters",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := aggregateFeedback(c.rule, c.works)
+			if actual != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetClusterStatusesCondition(t *testing.T) {
+	deleting := newFeedbackManifestWork("cluster0", []string{workv1.WorkAvailable}, testDeploymentIdentifier)
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	rules := []FeedbackAggregationRule{
+		{Name: "readyReplicas", ResourceIdentifier: testDeploymentIdentifier, FeedbackName: "ReadyReplicas", Type: AggregationSum},
+	}
+
+	manyWorks := []*workv1.ManifestWork{}
+	for i := 0; i < maxClusterStatusesInMessage+1; i++ {
+		manyWorks = append(manyWorks, newFeedbackManifestWork(
+			fmt.Sprintf("cluster%02d", i), []string{workv1.WorkApplied, workv1.WorkAvailable}, testDeploymentIdentifier))
+	}
+
+	cases := []struct {
+		name            string
+		rules           []FeedbackAggregationRule
+		works           []*workv1.ManifestWork
+		expectedStatus  metav1.ConditionStatus
+		expectedMessage string
+	}{
+		{
+			name:            "no clusters",
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "0/0 clusters available",
+		},
+		{
+			name:  "clusters not available first",
+			rules: rules,
+			works: []*workv1.ManifestWork{
+				newFeedbackManifestWork("cluster1", []string{workv1.WorkApplied, workv1.WorkAvailable}, testDeploymentIdentifier,
+					integerFeedback("ReadyReplicas", 3)),
+				newFeedbackManifestWork("cluster2", []string{workv1.WorkApplied, workv1.WorkDegraded}, testDeploymentIdentifier,
+					integerFeedback("ReadyReplicas", 0)),
+				newFeedbackManifestWork("cluster3", []string{workv1.WorkApplied, workv1.WorkProgressing}, testDeploymentIdentifier,
+					integerFeedback("ReadyReplicas", 1)),
+				newFeedbackManifestWork("cluster4", nil, testDeploymentIdentifier),
+				newFeedbackManifestWork("cluster5", []string{workv1.WorkApplied}, testDeploymentIdentifier),
+				deleting,
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedMessage: "1/6 clusters available: cluster2: Degraded [readyReplicas=0]; cluster4: Pending [readyReplicas=-]; " +
+				"cluster3: Progressing [readyReplicas=1]; cluster5: Applied [readyReplicas=-]; " +
+				"cluster0: Deleting [readyReplicas=-]; cluster1: Available [readyReplicas=3]",
+		},
+		{
+			name:            "all available",
+			works:           manyWorks[:2],
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "2/2 clusters available: cluster00: Available; cluster01: Available",
+		},
+		{
+			name:           "clusters capped",
+			works:          manyWorks,
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name: "long values truncated",
+			rules: []FeedbackAggregationRule{
+				{Name: "image", ResourceIdentifier: testDeploymentIdentifier, FeedbackName: "Image", Type: AggregationClusters},
+			},
+			works: []*workv1.ManifestWork{
+				newFeedbackManifestWork("cluster1", []string{workv1.WorkAvailable}, testDeploymentIdentifier,
+					stringFeedback("Image", strings.Repeat("a", maxFeedbackValueLength-1)+"é")),
+			},
+			expectedStatus: metav1.ConditionTrue,
+			expectedMessage: fmt.Sprintf("1/1 clusters available: cluster1: Available [image=%s...]",
+				strings.Repeat("a", maxFeedbackValueLength-1)),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cond := getClusterStatusesCondition(c.rules, c.works)
+			if cond.Status != c.expectedStatus {
+				t.Errorf("expected status %s, but got %s", c.expectedStatus, cond.Status)
+			}
+			if len(c.expectedMessage) > 0 && cond.Message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, cond.Message)
+			}
+			if len(c.works) > maxClusterStatusesInMessage && !strings.HasSuffix(cond.Message,
+				fmt.Sprintf("and %d more", len(c.works)-maxClusterStatusesInMessage)) {
+				t.Errorf("expected message capped, but got %q", cond.Message)
+			}
+		})
+	}
+}
+
+func TestGetClusterStatusesConditionWithWideTable(t *testing.T) {
+	rules := []FeedbackAggregationRule{}
+	values := []workv1.FeedbackValue{}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("%s%d", strings.Repeat("value", 10), i)
+		rules = append(rules, FeedbackAggregationRule{
+			Name: name, ResourceIdentifier: testDeploymentIdentifier, FeedbackName: name, Type: AggregationClusters})
+		values = append(values, workv1.FeedbackValue{Name: name, Value: workv1.FieldValue{
+			Type: workv1.JsonRaw, JsonRaw: pointer.String(fmt.Sprintf("[%q]", strings.Repeat("x", 10000)))}})
+	}
+	works := []*workv1.ManifestWork{}
+	for i := 0; i < maxClusterStatusesInMessage; i++ {
+		works = append(works, newFeedbackManifestWork(fmt.Sprintf("cluster%02d", i), []string{workv1.WorkAvailable},
+			testDeploymentIdentifier, values...))
+	}
+
+	cond := getClusterStatusesCondition(rules, works)
+	if len(cond.Message) > maxConditionMessageLength {
+		t.Errorf("expected message no longer than %d bytes, but got %d", maxConditionMessageLength, len(cond.Message))
+	}
+	if !strings.HasPrefix(cond.Message, "50/50 clusters available: cluster00: Available") ||
+		!regexp.MustCompile(`; and \d+ more$`).MatchString(cond.Message) {
+		t.Errorf("expected message capped, but got %q", cond.Message)
+	}
+}
+
+func TestSetFeedbackConditionsWithManyRules(t *testing.T) {
+	rules := []FeedbackAggregationRule{}
+	for i := 0; i < 1000; i++ {
+		rules = append(rules, FeedbackAggregationRule{
+			Name:               fmt.Sprintf("%s%d", strings.Repeat("value", 10), i),
+			ResourceIdentifier: testDeploymentIdentifier,
+			FeedbackName:       "ReadyReplicas",
+			Type:               AggregationSum,
+		})
+	}
+	annotation, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("test", "default", "placement")
+	mwrSet.Annotations = map[string]string{FeedbackAggregationAnnotationKey: string(annotation)}
+	works := []*workv1.ManifestWork{
+		newFeedbackManifestWork("cluster1", []string{workv1.WorkAvailable}, testDeploymentIdentifier,
+			integerFeedback("ReadyReplicas", 3)),
+	}
+
+	setFeedbackConditions(mwrSet, works)
+	cond := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionFeedbackAggregated)
+	if cond == nil {
+		t.Fatalf("expected condition %s, but got none", ManifestWorkReplicaSetConditionFeedbackAggregated)
+	}
+	if len(cond.Message) > maxConditionMessageLength {
+		t.Errorf("expected message no longer than %d bytes, but got %d", maxConditionMessageLength, len(cond.Message))
+	}
+	if !strings.HasPrefix(cond.Message, rules[0].Name+": 3 from 1/1 clusters; ") ||
+		!regexp.MustCompile(`; and \d+ more$`).MatchString(cond.Message) {
+		t.Errorf("expected message capped, but got %q", cond.Message)
+	}
+}
+
+func TestStatusReconcileWithFeedbackAggregation(t *testing.T) {
+	cases := []struct {
+		name               string
+		annotations        map[string]string
+		expectedConditions map[string]metav1.ConditionStatus
+	}{
+		{
+			name:               "not aggregated",
+			expectedConditions: map[string]metav1.ConditionStatus{},
+		},
+		{
+			name: "aggregated",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `[
+				{"name":"readyReplicas","resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"default","name":"test"},"feedbackName":"ReadyReplicas","type":"Sum"}
+			]`},
+			expectedConditions: map[string]metav1.ConditionStatus{
+				ManifestWorkReplicaSetConditionFeedbackAggregated: metav1.ConditionTrue,
+				ManifestWorkReplicaSetConditionClusterStatuses:    metav1.ConditionFalse,
+			},
+		},
+		{
+			name:        "invalid rules",
+			annotations: map[string]string{FeedbackAggregationAnnotationKey: `[{}]`},
+			expectedConditions: map[string]metav1.ConditionStatus{
+				ManifestWorkReplicaSetConditionFeedbackAggregated: metav1.ConditionFalse,
+				ManifestWorkReplicaSetConditionClusterStatuses:    metav1.ConditionFalse,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSet("test", "default", "placement")
+			mwrSet.Annotations = c.annotations
+			mwrSet.Status.Summary.Total = 2
+			// the conditions are removed if the feedback is not aggregated
+			apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(
+				ManifestWorkReplicaSetConditionFeedbackAggregated, ReasonFeedbackAggregated, "", metav1.ConditionTrue))
+
+			workInformerFactory := workinformers.NewSharedInformerFactory(fakeworkclient.NewSimpleClientset(), 10*time.Minute)
+			works := []*workv1.ManifestWork{
+				newFeedbackManifestWork("cluster1", []string{workv1.WorkApplied, workv1.WorkAvailable}, testDeploymentIdentifier,
+					integerFeedback("ReadyReplicas", 3)),
+				newFeedbackManifestWork("cluster2", []string{workv1.WorkApplied}, testDeploymentIdentifier),
+			}
+			for _, mw := range works {
+				if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := &statusReconciler{manifestWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister()}
+			mwrSet, _, err := r.reconcile(context.TODO(), mwrSet)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, conditionType := range []string{
+				ManifestWorkReplicaSetConditionFeedbackAggregated, ManifestWorkReplicaSetConditionClusterStatuses} {
+				cond := apimeta.FindStatusCondition(mwrSet.Status.Conditions, conditionType)
+				expected, ok := c.expectedConditions[conditionType]
+				switch {
+				case !ok && cond != nil:
+					t.Errorf("expected condition %s removed, but got %v", conditionType, cond)
+				case ok && (cond == nil || cond.Status != expected):
+					t.Errorf("expected condition %s %s, but got %v", conditionType, expected, cond)
+				}
+			}
+		})
+	}
+}
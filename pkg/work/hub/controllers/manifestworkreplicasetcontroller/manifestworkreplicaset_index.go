@@ -4,18 +4,24 @@ import (
 	"fmt"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
 )
 
 const (
 	manifestWorkReplicaSetByPlacement = "manifestWorkReplicaSetByPlacement"
+	placementDecisionByCluster        = "placementDecisionByCluster"
+	addOnByConfig                     = "addOnByConfig"
 )
 
 func (m *ManifestWorkReplicaSetController) placementQueueKeysFunc(obj runtime.Object) []string {
@@ -41,6 +47,83 @@ func (m *ManifestWorkReplicaSetController) placementQueueKeysFunc(obj runtime.Ob
 	return keys
 }
 
+// clusterQueueKeysFunc returns the keys of the ManifestWorkReplicaSets rendered per cluster which select the
+// cluster, since the template values change with the labels and claims of the cluster.
+func (m *ManifestWorkReplicaSetController) clusterQueueKeysFunc(obj runtime.Object) []string {
+	accessor, _ := meta.Accessor(obj)
+	return m.renderQueueKeys(accessor.GetName(), fmt.Sprintf("managedCluster %s", accessor.GetName()))
+}
+
+// addOnQueueKeysFunc returns the keys of the ManifestWorkReplicaSets rendered per cluster which select the
+// cluster of the addon, since the template values change with the configs of the addon.
+func (m *ManifestWorkReplicaSetController) addOnQueueKeysFunc(obj runtime.Object) []string {
+	accessor, _ := meta.Accessor(obj)
+	return m.renderQueueKeys(accessor.GetNamespace(),
+		fmt.Sprintf("managedClusterAddOn %s/%s", accessor.GetNamespace(), accessor.GetName()))
+}
+
+// addOnConfigQueueKeysFunc returns the keys of the ManifestWorkReplicaSets rendered per cluster which select the
+// clusters of the addons referring to the AddOnDeploymentConfig.
+func (m *ManifestWorkReplicaSetController) addOnConfigQueueKeysFunc(obj runtime.Object) []string {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return []string{}
+	}
+
+	objs, err := m.addOnIndexer.ByIndex(addOnByConfig, key)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return []string{}
+	}
+
+	keys := sets.New[string]()
+	for _, o := range objs {
+		addOn := o.(*addonv1alpha1.ManagedClusterAddOn)
+		keys.Insert(m.renderQueueKeys(addOn.Namespace, fmt.Sprintf("addOnDeploymentConfig %s", key))...)
+	}
+
+	return sets.List(keys)
+}
+
+// renderQueueKeys returns the keys of the ManifestWorkReplicaSets rendered per cluster whose placements decide
+// the cluster.
+func (m *ManifestWorkReplicaSetController) renderQueueKeys(clusterName, reason string) []string {
+	decisions, err := m.placeDecisionIndexer.ByIndex(placementDecisionByCluster, clusterName)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return []string{}
+	}
+
+	keys := sets.New[string]()
+	for _, d := range decisions {
+		decision := d.(*clusterv1beta1.PlacementDecision)
+		placementName, ok := decision.Labels[clusterv1beta1.PlacementLabel]
+		if !ok {
+			continue
+		}
+
+		objs, err := m.manifestWorkReplicaSetIndexer.ByIndex(manifestWorkReplicaSetByPlacement,
+			fmt.Sprintf("%s/%s", decision.Namespace, placementName))
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+
+		for _, o := range objs {
+			manifestWorkReplicaSet := o.(*workapiv1alpha1.ManifestWorkReplicaSet)
+			if !renderEnabled(manifestWorkReplicaSet) {
+				continue
+			}
+			klog.V(4).Infof("enqueue manifestWorkReplicaSet %s/%s, because of %s", manifestWorkReplicaSet.Namespace,
+				manifestWorkReplicaSet.Name, reason)
+			keys.Insert(fmt.Sprintf("%s/%s", manifestWorkReplicaSet.Namespace, manifestWorkReplicaSet.Name))
+		}
+	}
+
+	return sets.List(keys)
+}
+
 func (m *ManifestWorkReplicaSetController) placementDecisionQueueKeysFunc(obj runtime.Object) []string {
 	accessor, _ := meta.Accessor(obj)
 	placementName, ok := accessor.GetLabels()[clusterv1beta1.PlacementLabel]
@@ -95,6 +178,80 @@ func indexManifestWorkReplicaSetByPlacement(obj interface{}) ([]string, error) {
 	return keys, nil
 }
 
+func indexPlacementDecisionByCluster(obj interface{}) ([]string, error) {
+	placementDecision, ok := obj.(*clusterv1beta1.PlacementDecision)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not a PlacementDecision", obj)
+	}
+
+	var keys []string
+	for _, decision := range placementDecision.Status.Decisions {
+		keys = append(keys, decision.ClusterName)
+	}
+
+	return keys, nil
+}
+
+func indexAddOnByConfig(obj interface{}) ([]string, error) {
+	addOn, ok := obj.(*addonv1alpha1.ManagedClusterAddOn)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not a ManagedClusterAddOn", obj)
+	}
+
+	var keys []string
+	for _, referent := range addOnDeploymentConfigReferents(addOn) {
+		keys = append(keys, fmt.Sprintf("%s/%s", referent.Namespace, referent.Name))
+	}
+
+	return keys, nil
+}
+
+// clusterTemplateValuesChanged returns true if the labels or claims of the cluster, which are used as the
+// template values, change.
+func clusterTemplateValuesChanged(oldObj, newObj interface{}) bool {
+	oldCluster, ok := oldObj.(*clusterv1.ManagedCluster)
+	if !ok {
+		return true
+	}
+	newCluster, ok := newObj.(*clusterv1.ManagedCluster)
+	if !ok {
+		return true
+	}
+
+	return !equality.Semantic.DeepEqual(oldCluster.Labels, newCluster.Labels) ||
+		!equality.Semantic.DeepEqual(oldCluster.Status.ClusterClaims, newCluster.Status.ClusterClaims)
+}
+
+// addOnConfigReferencesChanged returns true if the AddOnDeploymentConfigs referred by the addon change.
+func addOnConfigReferencesChanged(oldObj, newObj interface{}) bool {
+	oldAddOn, ok := oldObj.(*addonv1alpha1.ManagedClusterAddOn)
+	if !ok {
+		return true
+	}
+	newAddOn, ok := newObj.(*addonv1alpha1.ManagedClusterAddOn)
+	if !ok {
+		return true
+	}
+
+	return !equality.Semantic.DeepEqual(addOnDeploymentConfigReferents(oldAddOn), addOnDeploymentConfigReferents(newAddOn))
+}
+
+// addOnConfigVariablesChanged returns true if the customized variables of the AddOnDeploymentConfig, or whether
+// they are exposed to the templates, change.
+func addOnConfigVariablesChanged(oldObj, newObj interface{}) bool {
+	oldConfig, ok := oldObj.(*addonv1alpha1.AddOnDeploymentConfig)
+	if !ok {
+		return true
+	}
+	newConfig, ok := newObj.(*addonv1alpha1.AddOnDeploymentConfig)
+	if !ok {
+		return true
+	}
+
+	return oldConfig.Annotations[TemplateValuesAnnotationKey] != newConfig.Annotations[TemplateValuesAnnotationKey] ||
+		!equality.Semantic.DeepEqual(oldConfig.Spec.CustomizedVariables, newConfig.Spec.CustomizedVariables)
+}
+
 // manifestWorkReplicaSetKey return the value of the key of manifestworkreplicaset, and comply with
 // label value format.
 func manifestWorkReplicaSetKey(mwrs *workapiv1alpha1.ManifestWorkReplicaSet) string {
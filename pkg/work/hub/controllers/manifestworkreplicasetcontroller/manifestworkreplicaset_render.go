@@ -0,0 +1,483 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+const (
+	// TemplateAnnotationKey is the annotation on ManifestWorkReplicaSet which enables rendering the string values
+	// in the manifests as go templates per cluster if it is "true". The values of the templates are TemplateValues.
+	// Referring to a missing map key fails the rendering, use index and default for the optional values, e.g.
+	// {{ index .ClusterLabels "registry" | default "quay.io" }}. The templates cannot define or call other
+	// templates, range only over the fields of the values without nesting, and the rendered size is limited.
+	TemplateAnnotationKey = "work.open-cluster-management.io/experimental-template"
+
+	// TemplateValuesAnnotationKey is the annotation on AddOnDeploymentConfig which exposes its customized
+	// variables to the templates of the ManifestWorkReplicaSets if it is "true". The variables are readable by
+	// anyone who can create a ManifestWorkReplicaSet selecting the clusters of the addons using the config, so
+	// the configs are not exposed by default.
+	TemplateValuesAnnotationKey = "work.open-cluster-management.io/experimental-template-values"
+
+	// OverridesAnnotationKey is the annotation on ManifestWorkReplicaSet which defines the patches applied to the
+	// manifests of the selected clusters. The value is a json encoded list of ManifestOverride, and the overrides
+	// are applied in order before the templates are rendered.
+	OverridesAnnotationKey = "work.open-cluster-management.io/experimental-overrides"
+
+	// ManifestWorkReplicaSetConditionManifestworkRendered is the condition of rendering the manifestworks with the
+	// templates and overrides. It is false if the manifestworks of any cluster fail to render, and the
+	// manifestworks of these clusters are not created or updated.
+	ManifestWorkReplicaSetConditionManifestworkRendered = "ManifestworkRendered"
+
+	ReasonRenderFailed     = "RenderFailed"
+	ReasonInvalidOverrides = "InvalidOverrides"
+
+	addOnDeploymentConfigResource = "addondeploymentconfigs"
+
+	// maxRenderedValueLength is the max length in bytes of a rendered template value.
+	maxRenderedValueLength = 64 * 1024
+
+	// maxRenderedManifestLength is the max length in bytes of a rendered manifest, which is the default manifest
+	// limit of the ManifestWork webhook.
+	maxRenderedManifestLength = 500 * 1024
+)
+
+// ManifestOverride defines the patches applied to the manifests of the clusters selected by the cluster selector.
+type ManifestOverride struct {
+	// ClusterSelector selects the clusters by labels. All the clusters are selected if it is not set.
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// Patches are applied to the manifests in order.
+	Patches []ManifestPatch `json:"patches"`
+}
+
+// ManifestPatchType is the type of a manifest patch.
+type ManifestPatchType string
+
+const (
+	// ManifestPatchTypeMerge is a json merge patch as defined in RFC7386.
+	ManifestPatchTypeMerge ManifestPatchType = "Merge"
+	// ManifestPatchTypeJSON is a json patch as defined in RFC6902.
+	ManifestPatchTypeJSON ManifestPatchType = "JSON"
+)
+
+// ManifestPatch is a patch applied to the manifests matching the group, kind, namespace and name. An empty
+// field matches any manifest.
+type ManifestPatch struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Type is the type of the patch, defaults to Merge.
+	Type  ManifestPatchType `json:"type,omitempty"`
+	Patch json.RawMessage   `json:"patch"`
+}
+
+// TemplateValues are the values of the templates in the manifests rendered for a cluster.
+type TemplateValues struct {
+	// ClusterName is the name of the ManagedCluster.
+	ClusterName string
+	// ClusterLabels are the labels of the ManagedCluster.
+	ClusterLabels map[string]string
+	// ClusterClaims are the values of the cluster claims in the status of the ManagedCluster keyed by name.
+	ClusterClaims map[string]string
+	// AddOnConfigs are the customized variables of the AddOnDeploymentConfigs used by the ManagedClusterAddOns
+	// of the cluster, keyed by the addon name and the variable name. Only the configs with the template values
+	// annotation are included.
+	AddOnConfigs map[string]map[string]string
+}
+
+// templateFuncs are the functions available in the templates in addition to the builtin ones. They only
+// transform the values, so the templates cannot access anything out of the TemplateValues.
+var templateFuncs = template.FuncMap{
+	"default": func(defaultValue, value string) string {
+		if len(value) == 0 {
+			return defaultValue
+		}
+		return value
+	},
+	"required": func(message, value string) (string, error) {
+		if len(value) == 0 {
+			return "", fmt.Errorf("%s", message)
+		}
+		return value, nil
+	},
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+}
+
+// renderConfig is how the manifestworks of a ManifestWorkReplicaSet are rendered per cluster.
+type renderConfig struct {
+	template  bool
+	overrides []manifestOverride
+}
+
+type manifestOverride struct {
+	selector labels.Selector
+	patches  []ManifestPatch
+}
+
+// renderEnabled returns true if the manifestworks of the ManifestWorkReplicaSet are rendered per cluster.
+func renderEnabled(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) bool {
+	_, ok := mwrSet.Annotations[OverridesAnnotationKey]
+	return ok || mwrSet.Annotations[TemplateAnnotationKey] == "true"
+}
+
+// getRenderConfig returns how the manifestworks of the ManifestWorkReplicaSet are rendered, or nil if they are
+// not rendered per cluster.
+func getRenderConfig(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (*renderConfig, error) {
+	if !renderEnabled(mwrSet) {
+		return nil, nil
+	}
+
+	config := &renderConfig{template: mwrSet.Annotations[TemplateAnnotationKey] == "true"}
+	value := mwrSet.Annotations[OverridesAnnotationKey]
+	if len(value) == 0 {
+		return config, nil
+	}
+
+	overrides := []ManifestOverride{}
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", OverridesAnnotationKey, err)
+	}
+	for i, override := range overrides {
+		selector := labels.Everything()
+		if override.ClusterSelector != nil {
+			var err error
+			selector, err = metav1.LabelSelectorAsSelector(override.ClusterSelector)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect cluster selector of override %d: %v", i, err)
+			}
+		}
+		for j, patch := range override.Patches {
+			if len(patch.Patch) == 0 {
+				return nil, fmt.Errorf("patch %d of override %d is empty", j, i)
+			}
+			switch patch.Type {
+			case "", ManifestPatchTypeMerge:
+			case ManifestPatchTypeJSON:
+				if _, err := jsonpatch.DecodePatch(patch.Patch); err != nil {
+					return nil, fmt.Errorf("incorrect json patch %d of override %d: %v", j, i, err)
+				}
+			default:
+				return nil, fmt.Errorf("incorrect type %q of patch %d of override %d", patch.Type, j, i)
+			}
+		}
+		config.overrides = append(config.overrides, manifestOverride{selector: selector, patches: override.Patches})
+	}
+	return config, nil
+}
+
+// renderManifestWork renders the manifests of the ManifestWork with the overrides and templates of the cluster.
+// The manifests of the ManifestWork are replaced, so the template of the ManifestWorkReplicaSet is not changed.
+func (d *deployReconciler) renderManifestWork(config *renderConfig, mw *workv1.ManifestWork) error {
+	cluster, err := d.clusterLister.Get(mw.Namespace)
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("managed cluster %s is not found", mw.Namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	var values *TemplateValues
+	if config.template {
+		values, err = d.getTemplateValues(cluster)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifests := make([]workv1.Manifest, 0, len(mw.Spec.Workload.Manifests))
+	for i, manifest := range mw.Spec.Workload.Manifests {
+		raw := manifest.Raw
+		for _, override := range config.overrides {
+			if !override.selector.Matches(labels.Set(cluster.Labels)) {
+				continue
+			}
+			if raw, err = applyPatches(raw, override.patches); err != nil {
+				return fmt.Errorf("failed to override manifest %d: %v", i, err)
+			}
+		}
+		if values != nil {
+			if raw, err = renderTemplates(raw, values); err != nil {
+				return fmt.Errorf("failed to render manifest %d: %v", i, err)
+			}
+		}
+		manifests = append(manifests, workv1.Manifest{RawExtension: runtime.RawExtension{Raw: raw}})
+	}
+	mw.Spec.Workload.Manifests = manifests
+	return nil
+}
+
+// getTemplateValues returns the template values of the cluster.
+func (d *deployReconciler) getTemplateValues(cluster *clusterv1.ManagedCluster) (*TemplateValues, error) {
+	values := &TemplateValues{
+		ClusterName:   cluster.Name,
+		ClusterLabels: map[string]string{},
+		ClusterClaims: map[string]string{},
+		AddOnConfigs:  map[string]map[string]string{},
+	}
+	for key, value := range cluster.Labels {
+		values.ClusterLabels[key] = value
+	}
+	for _, claim := range cluster.Status.ClusterClaims {
+		values.ClusterClaims[claim.Name] = claim.Value
+	}
+
+	addOns, err := d.addOnLister.ManagedClusterAddOns(cluster.Name).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, addOn := range addOns {
+		variables := map[string]string{}
+		for _, referent := range addOnDeploymentConfigReferents(addOn) {
+			config, err := d.addOnConfigLister.AddOnDeploymentConfigs(referent.Namespace).Get(referent.Name)
+			if errors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if config.Annotations[TemplateValuesAnnotationKey] != "true" {
+				continue
+			}
+			for _, variable := range config.Spec.CustomizedVariables {
+				variables[variable.Name] = variable.Value
+			}
+		}
+		values.AddOnConfigs[addOn.Name] = variables
+	}
+	return values, nil
+}
+
+// addOnDeploymentConfigReferents returns the AddOnDeploymentConfigs referred by the addon, the desired config is
+// preferred over the last applied one.
+func addOnDeploymentConfigReferents(addOn *addonv1alpha1.ManagedClusterAddOn) []addonv1alpha1.ConfigReferent {
+	var referents []addonv1alpha1.ConfigReferent
+	for _, ref := range addOn.Status.ConfigReferences {
+		if ref.Group != addonv1alpha1.GroupName || ref.Resource != addOnDeploymentConfigResource {
+			continue
+		}
+		referent := ref.ConfigReferent
+		if ref.DesiredConfig != nil {
+			referent = ref.DesiredConfig.ConfigReferent
+		}
+		referents = append(referents, referent)
+	}
+	return referents
+}
+
+// applyPatches applies the patches matching the manifest.
+func applyPatches(raw []byte, patches []ManifestPatch) ([]byte, error) {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+	gvk := obj.GroupVersionKind()
+
+	for i, patch := range patches {
+		if !patchMatches(patch, gvk, obj.GetNamespace(), obj.GetName()) {
+			continue
+		}
+
+		var err error
+		switch patch.Type {
+		case ManifestPatchTypeJSON:
+			var p jsonpatch.Patch
+			if p, err = jsonpatch.DecodePatch(patch.Patch); err == nil {
+				raw, err = p.Apply(raw)
+			}
+		default:
+			raw, err = jsonpatch.MergePatch(raw, patch.Patch)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply patch %d: %v", i, err)
+		}
+	}
+	return raw, nil
+}
+
+func patchMatches(patch ManifestPatch, gvk schema.GroupVersionKind, namespace, name string) bool {
+	return (len(patch.Group) == 0 || patch.Group == gvk.Group) &&
+		(len(patch.Kind) == 0 || patch.Kind == gvk.Kind) &&
+		(len(patch.Namespace) == 0 || patch.Namespace == namespace) &&
+		(len(patch.Name) == 0 || patch.Name == name)
+}
+
+// renderTemplates renders the string values of the manifest as templates. Only the values are rendered, so the
+// rendered values cannot change the structure of the manifest.
+func renderTemplates(raw []byte, values *TemplateValues) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var obj interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	obj, err := renderValue("", obj, values)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(rendered) > maxRenderedManifestLength {
+		return nil, fmt.Errorf("the rendered manifest is larger than %d bytes", maxRenderedManifestLength)
+	}
+	return rendered, nil
+}
+
+func renderValue(path string, value interface{}, values *TemplateValues) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New(path).Option("missingkey=error").Funcs(templateFuncs).Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(tmpl.Templates()) > 1 {
+			return nil, fmt.Errorf("template: %s: define and block are not allowed", path)
+		}
+		if err := validateTemplateNode(path, tmpl.Tree.Root, false); err != nil {
+			return nil, err
+		}
+		buf := &limitedBuffer{limit: maxRenderedValueLength}
+		if err := tmpl.Execute(buf, values); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case map[string]interface{}:
+		for key, item := range v {
+			rendered, err := renderValue(strings.TrimPrefix(path+"."+key, "."), item, values)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = rendered
+		}
+	case []interface{}:
+		for i, item := range v {
+			rendered, err := renderValue(fmt.Sprintf("%s[%d]", path, i), item, values)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rendered
+		}
+	}
+	return value, nil
+}
+
+// validateTemplateNode returns an error if the template node calls other templates, or ranges over anything
+// other than a field of the values, or ranges in a range, so that the rendering cost is bounded by the values.
+func validateTemplateNode(path string, node parse.Node, inRange bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := validateTemplateNode(path, child, inRange); err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		return fmt.Errorf("template: %s: template is not allowed", path)
+	case *parse.IfNode:
+		return validateBranchNode(path, &n.BranchNode, inRange)
+	case *parse.WithNode:
+		return validateBranchNode(path, &n.BranchNode, inRange)
+	case *parse.RangeNode:
+		if inRange {
+			return fmt.Errorf("template: %s: nested range is not allowed", path)
+		}
+		cmds := n.Pipe.Cmds
+		if len(cmds) != 1 || len(cmds[0].Args) != 1 {
+			return fmt.Errorf("template: %s: range is only allowed over a field of the values", path)
+		}
+		if _, ok := cmds[0].Args[0].(*parse.FieldNode); !ok {
+			return fmt.Errorf("template: %s: range is only allowed over a field of the values", path)
+		}
+		return validateBranchNode(path, &n.BranchNode, true)
+	}
+	return nil
+}
+
+func validateBranchNode(path string, node *parse.BranchNode, inRange bool) error {
+	if err := validateTemplateNode(path, node.List, inRange); err != nil {
+		return err
+	}
+	return validateTemplateNode(path, node.ElseList, inRange)
+}
+
+// limitedBuffer is a buffer which fails the writes exceeding the limit in bytes.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("the rendered value is larger than %d bytes", b.limit)
+	}
+	return b.Buffer.Write(p)
+}
+
+// getRenderedCondition returns the rendered condition with the render errors of the clusters.
+func getRenderedCondition(renderErrs map[string]error) metav1.Condition {
+	if len(renderErrs) == 0 {
+		return getCondition(ManifestWorkReplicaSetConditionManifestworkRendered, workapiv1alpha1.ReasonAsExpected, "",
+			metav1.ConditionTrue)
+	}
+
+	clusters := []string{}
+	for cluster := range renderErrs {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	messages := []string{}
+	for i, cluster := range clusters {
+		if i == maxClustersInMessage {
+			messages = append(messages, fmt.Sprintf("and %d more", len(clusters)-maxClustersInMessage))
+			break
+		}
+		messages = append(messages, fmt.Sprintf("%s: %v", cluster, renderErrs[cluster]))
+	}
+	return getCondition(ManifestWorkReplicaSetConditionManifestworkRendered, ReasonRenderFailed,
+		fmt.Sprintf("Failed to render manifestworks for %d clusters: %s", len(clusters), strings.Join(messages, "; ")),
+		metav1.ConditionFalse)
+}
+
+// setRenderedCondition sets the rendered condition of the ManifestWorkReplicaSet, or removes it if the
+// manifestworks are not rendered per cluster.
+func setRenderedCondition(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, config *renderConfig, renderErrs map[string]error) {
+	if config == nil {
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionManifestworkRendered)
+		return
+	}
+	apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getRenderedCondition(renderErrs))
+}
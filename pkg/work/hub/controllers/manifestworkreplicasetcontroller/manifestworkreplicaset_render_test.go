@@ -0,0 +1,801 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	fakeaddonclient "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+const testDeployment = `{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {"name": "app", "namespace": "default"},
+  "spec": {
+    "replicas": 1,
+    "template": {
+      "spec": {
+        "containers": [{
+          "name": "app",
+          "image": "{{ index .ClusterLabels \"registry\" | default \"quay.io\" }}/app:{{ index .AddOnConfigs.app \"tag\" }}",
+          "env": [
+            {"name": "CLUSTER", "value": "{{ .ClusterName }}"},
+            {"name": "ID", "value": "{{ index .ClusterClaims \"id.k8s.io\" }}"}
+          ]
+        }]
+      }
+    }
+  }
+}`
+
+const testConfigMap = `{
+  "apiVersion": "v1",
+  "kind": "ConfigMap",
+  "metadata": {"name": "app", "namespace": "default"},
+  "data": {"host": "{{ .ClusterLabels.host }}"}
+}`
+
+// renderAnnotations returns the annotations of the ManifestWorkReplicaSet to render the manifestworks with the
+// templates and overrides.
+func renderAnnotations(template bool, overrides string) map[string]string {
+	annotations := map[string]string{}
+	if template {
+		annotations[TemplateAnnotationKey] = "true"
+	}
+	if len(overrides) > 0 {
+		annotations[OverridesAnnotationKey] = overrides
+	}
+	return annotations
+}
+
+func rawManifests(manifests ...string) []workv1.Manifest {
+	raws := []workv1.Manifest{}
+	for _, manifest := range manifests {
+		raws = append(raws, workv1.Manifest{RawExtension: runtime.RawExtension{Raw: []byte(manifest)}})
+	}
+	return raws
+}
+
+func newRenderCluster(name string, labels map[string]string, claims ...clusterv1.ManagedClusterClaim) *clusterv1.ManagedCluster {
+	return &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status:     clusterv1.ManagedClusterStatus{ClusterClaims: claims},
+	}
+}
+
+// newRenderDeployReconciler returns a deployReconciler with the clusters, the addon app on cluster1 using an
+// AddOnDeploymentConfig with variable tag exposed to the templates, and the placement of the clusters.
+func newRenderDeployReconciler(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	clusters ...*clusterv1.ManagedCluster) (*deployReconciler, *fakeworkclient.Clientset) {
+	fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet)
+	workInformerFactory := workinformers.NewSharedInformerFactory(fWorkClient, 0)
+	mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+	clusterNames := []string{}
+	for _, cluster := range clusters {
+		clusterNames = append(clusterNames, cluster.Name)
+	}
+	placement, placementDecision := helpertest.CreateTestPlacement("placement", "default", clusterNames...)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(fakeclusterclient.NewSimpleClientset(), 0)
+	if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+	for _, cluster := range clusters {
+		if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addOn := &addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "cluster1"},
+		Status: addonv1alpha1.ManagedClusterAddOnStatus{
+			ConfigReferences: []addonv1alpha1.ConfigReference{
+				{
+					ConfigGroupResource: addonv1alpha1.ConfigGroupResource{
+						Group:    addonv1alpha1.GroupName,
+						Resource: addOnDeploymentConfigResource,
+					},
+					DesiredConfig: &addonv1alpha1.ConfigSpecHash{
+						ConfigReferent: addonv1alpha1.ConfigReferent{Namespace: "default", Name: "app-config"},
+					},
+				},
+			},
+		},
+	}
+	addOnConfig := &addonv1alpha1.AddOnDeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app-config",
+			Namespace:   "default",
+			Annotations: map[string]string{TemplateValuesAnnotationKey: "true"},
+		},
+		Spec: addonv1alpha1.AddOnDeploymentConfigSpec{
+			CustomizedVariables: []addonv1alpha1.CustomizedVariable{{Name: "tag", Value: "v1"}},
+		},
+	}
+	addOnInformerFactory := addoninformers.NewSharedInformerFactory(fakeaddonclient.NewSimpleClientset(), 0)
+	if err := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer().GetStore().Add(addOn); err != nil {
+		t.Fatal(err)
+	}
+	if err := addOnInformerFactory.Addon().V1alpha1().AddOnDeploymentConfigs().Informer().GetStore().Add(addOnConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	return &deployReconciler{
+		workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+		manifestWorkLister:  mwLister,
+		placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+		placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+		clusterLister:       clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+		addOnLister:         addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+		addOnConfigLister:   addOnInformerFactory.Addon().V1alpha1().AddOnDeploymentConfigs().Lister(),
+	}, fWorkClient
+}
+
+func TestGetRenderConfig(t *testing.T) {
+	cases := []struct {
+		name              string
+		template          bool
+		overrides         string
+		expectedNil       bool
+		expectedOverrides int
+		expectedErr       bool
+	}{
+		{
+			name:        "not rendered",
+			expectedNil: true,
+		},
+		{
+			name:     "template",
+			template: true,
+		},
+		{
+			name: "overrides",
+			overrides: `[{"clusterSelector":{"matchLabels":{"env":"prod"}},"patches":[{"kind":"Deployment","patch":{"spec":{"replicas":3}}}]},
+				{"patches":[{"type":"JSON","patch":[{"op":"replace","path":"/data/host","value":"a"}]}]}]`,
+			expectedOverrides: 2,
+		},
+		{
+			name:        "invalid json",
+			overrides:   `[{`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid selector",
+			overrides:   `[{"clusterSelector":{"matchExpressions":[{"key":"env","operator":"Equal"}]},"patches":[{"patch":{}}]}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "empty patch",
+			overrides:   `[{"patches":[{"kind":"Deployment"}]}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid patch type",
+			overrides:   `[{"patches":[{"type":"Strategic","patch":{}}]}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid json patch",
+			overrides:   `[{"patches":[{"type":"JSON","patch":{"op":"add"}}]}]`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement",
+				renderAnnotations(c.template, c.overrides))
+			config, err := getRenderConfig(mwrSet)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.expectedNil {
+				if config != nil {
+					t.Errorf("expected nil config, but got %v", config)
+				}
+				return
+			}
+			if config.template != c.template {
+				t.Errorf("expected template %v, but got %v", c.template, config.template)
+			}
+			if len(config.overrides) != c.expectedOverrides {
+				t.Errorf("expected %d overrides, but got %d", c.expectedOverrides, len(config.overrides))
+			}
+		})
+	}
+}
+
+func TestRenderManifestWork(t *testing.T) {
+	overrides := `[
+		{"clusterSelector":{"matchLabels":{"env":"prod"}},"patches":[
+			{"group":"apps","kind":"Deployment","patch":{"spec":{"replicas":3}}},
+			{"kind":"ConfigMap","type":"JSON","patch":[{"op":"add","path":"/data/tier","value":"{{ .ClusterLabels.env }}"}]}
+		]},
+		{"patches":[{"kind":"Deployment","name":"other","patch":{"spec":{"replicas":5}}}]}
+	]`
+
+	cases := []struct {
+		name            string
+		template        bool
+		overrides       string
+		cluster         *clusterv1.ManagedCluster
+		clusterName     string
+		configHidden    bool
+		expectedErr     string
+		expectedObjects []map[string]interface{}
+	}{
+		{
+			name:     "render templates",
+			template: true,
+			cluster: newRenderCluster("cluster1", map[string]string{"host": "cluster1.example.com"},
+				clusterv1.ManagedClusterClaim{Name: "id.k8s.io", Value: "id1"}),
+			expectedObjects: []map[string]interface{}{
+				{
+					"replicas": int64(1),
+					"image":    "quay.io/app:v1",
+					"env":      []interface{}{"cluster1", "id1"},
+				},
+				{"data": map[string]interface{}{"host": "cluster1.example.com"}},
+			},
+		},
+		{
+			name:        "missing label",
+			template:    true,
+			cluster:     newRenderCluster("cluster1", nil),
+			expectedErr: "map has no entry for key \"host\"",
+		},
+		{
+			name:         "addon config not exposed",
+			template:     true,
+			cluster:      newRenderCluster("cluster1", map[string]string{"host": "cluster1.example.com"}),
+			configHidden: true,
+			expectedObjects: []map[string]interface{}{
+				{"image": "quay.io/app:"},
+			},
+		},
+		{
+			name:      "override and render the selected cluster",
+			template:  true,
+			overrides: overrides,
+			cluster: newRenderCluster("cluster1",
+				map[string]string{"env": "prod", "host": "prod.example.com", "registry": "registry.example.com"}),
+			expectedObjects: []map[string]interface{}{
+				{
+					"replicas": int64(3),
+					"image":    "registry.example.com/app:v1",
+					"env":      []interface{}{"cluster1", ""},
+				},
+				{"data": map[string]interface{}{"host": "prod.example.com", "tier": "prod"}},
+			},
+		},
+		{
+			name:      "override the cluster not selected",
+			overrides: overrides,
+			cluster:   newRenderCluster("cluster2", map[string]string{"env": "dev"}),
+			expectedObjects: []map[string]interface{}{
+				{"replicas": int64(1)},
+				{"data": map[string]interface{}{"host": "{{ .ClusterLabels.host }}"}},
+			},
+		},
+		{
+			name:        "override failure",
+			overrides:   `[{"patches":[{"kind":"ConfigMap","type":"JSON","patch":[{"op":"remove","path":"/data/missing"}]}]}]`,
+			cluster:     newRenderCluster("cluster1", nil),
+			expectedErr: "failed to override manifest 1",
+		},
+		{
+			name:        "cluster not found",
+			template:    true,
+			cluster:     newRenderCluster("cluster3", nil),
+			clusterName: "cluster4",
+			expectedErr: "managed cluster cluster4 is not found",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement",
+				renderAnnotations(c.template, c.overrides))
+			mwrSet.Spec.ManifestWorkTemplate.Workload.Manifests = rawManifests(testDeployment, testConfigMap)
+			reconciler, _ := newRenderDeployReconciler(t, mwrSet, c.cluster)
+			if c.configHidden {
+				addOnConfig, err := reconciler.addOnConfigLister.AddOnDeploymentConfigs("default").Get("app-config")
+				if err != nil {
+					t.Fatal(err)
+				}
+				addOnConfig.Annotations = nil
+			}
+			config, err := getRenderConfig(mwrSet)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clusterName := c.cluster.Name
+			if len(c.clusterName) > 0 {
+				clusterName = c.clusterName
+			}
+			mw, _ := CreateManifestWork(mwrSet, clusterName)
+			err = reconciler.renderManifestWork(config, mw)
+			if len(c.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+					t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the template of the ManifestWorkReplicaSet is not changed
+			if string(mwrSet.Spec.ManifestWorkTemplate.Workload.Manifests[0].Raw) != testDeployment {
+				t.Errorf("the template of the ManifestWorkReplicaSet is changed")
+			}
+
+			for i, expected := range c.expectedObjects {
+				obj := &unstructured.Unstructured{}
+				if err := obj.UnmarshalJSON(mw.Spec.Workload.Manifests[i].Raw); err != nil {
+					t.Fatal(err)
+				}
+				for key, value := range expected {
+					var actual interface{}
+					switch key {
+					case "replicas":
+						actual, _, _ = unstructured.NestedInt64(obj.Object, "spec", "replicas")
+					case "image":
+						containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+						actual = containers[0].(map[string]interface{})["image"]
+					case "env":
+						containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+						env := []interface{}{}
+						for _, e := range containers[0].(map[string]interface{})["env"].([]interface{}) {
+							env = append(env, e.(map[string]interface{})["value"])
+						}
+						actual = env
+					default:
+						actual = obj.Object[key]
+					}
+					if !reflect.DeepEqual(actual, value) {
+						t.Errorf("expected %s of manifest %d to be %v, but got %v", key, i, value, actual)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	values := &TemplateValues{
+		ClusterName:   "cluster1",
+		ClusterLabels: map[string]string{"region": "US-East"},
+	}
+
+	cases := []struct {
+		name        string
+		manifest    string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "no template",
+			manifest: `{"kind":"ConfigMap","data":{"a":"b","size":12345678901234567890}}`,
+			expected: `{"data":{"a":"b","size":12345678901234567890},"kind":"ConfigMap"}`,
+		},
+		{
+			name:     "functions",
+			manifest: `{"data":{"a":"{{ .ClusterLabels.region | lower | replace \"-\" \"\" }}","b":"{{ upper .ClusterName | trimPrefix \"CLUSTER\" }}"}}`,
+			expected: `{"data":{"a":"useast","b":"1"}}`,
+		},
+		{
+			name:     "templates in keys are not rendered",
+			manifest: `{"{{ .ClusterName }}":"{{ .ClusterName }}"}`,
+			expected: `{"{{ .ClusterName }}":"cluster1"}`,
+		},
+		{
+			name:     "rendered value is escaped",
+			manifest: `{"data":{"a":"{{ printf \"%s\" \"\\\", \\\"b\\\": \\\"c\" }}"}}`,
+			expected: `{"data":{"a":"\", \"b\": \"c"}}`,
+		},
+		{
+			name:        "required value",
+			manifest:    `{"data":{"a":"{{ index .ClusterLabels \"zone\" | required \"zone is required\" }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "unknown field",
+			manifest:    `{"data":{"a":"{{ .Cluster.Spec }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "unknown function",
+			manifest:    `{"data":{"a":"{{ env \"HOME\" }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name:     "range over field",
+			manifest: `{"data":{"a":"{{ range $k, $v := .ClusterLabels }}{{ $k }}={{ $v }}{{ end }}"}}`,
+			expected: `{"data":{"a":"region=US-East"}}`,
+		},
+		{
+			name:        "define",
+			manifest:    `{"data":{"a":"{{ define \"t\" }}{{ .ClusterName }}{{ end }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "block",
+			manifest:    `{"data":{"a":"{{ block \"t\" . }}{{ .ClusterName }}{{ end }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "template",
+			manifest:    `{"data":{"a":"{{ template \"a\" . }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "range over number",
+			manifest:    `{"data":{"a":"{{ range 1000000000 }}a{{ end }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "nested range",
+			manifest:    `{"data":{"a":"{{ range .ClusterLabels }}{{ range $.ClusterLabels }}a{{ end }}{{ end }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "rendered value too large",
+			manifest:    `{"data":{"a":"{{ printf \"%070000d\" 0 }}"}}`,
+			expectedErr: true,
+		},
+		{
+			name: "rendered manifest too large",
+			manifest: `{"data":{"a":"{{ printf \"%060000d\" 0 }}","b":"{{ printf \"%060000d\" 0 }}",` +
+				`"c":"{{ printf \"%060000d\" 0 }}","d":"{{ printf \"%060000d\" 0 }}","e":"{{ printf \"%060000d\" 0 }}",` +
+				`"f":"{{ printf \"%060000d\" 0 }}","g":"{{ printf \"%060000d\" 0 }}","h":"{{ printf \"%060000d\" 0 }}",` +
+				`"i":"{{ printf \"%060000d\" 0 }}"}}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := renderTemplates([]byte(c.manifest), values)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got %s", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(actual) != c.expected {
+				t.Errorf("expected %s, but got %s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetRenderedCondition(t *testing.T) {
+	condition := getRenderedCondition(nil)
+	if condition.Status != metav1.ConditionTrue || condition.Reason != workapiv1alpha1.ReasonAsExpected {
+		t.Errorf("unexpected condition %v", condition)
+	}
+
+	renderErrs := map[string]error{}
+	for i := 0; i < 12; i++ {
+		renderErrs[fmt.Sprintf("cluster%02d", i)] = fmt.Errorf("error%d", i)
+	}
+	condition = getRenderedCondition(renderErrs)
+	if condition.Status != metav1.ConditionFalse || condition.Reason != ReasonRenderFailed {
+		t.Errorf("unexpected condition %v", condition)
+	}
+	if !strings.HasPrefix(condition.Message, "Failed to render manifestworks for 12 clusters: cluster00: error0; cluster01: error1;") ||
+		!strings.HasSuffix(condition.Message, "cluster09: error9; and 2 more") {
+		t.Errorf("unexpected message %q", condition.Message)
+	}
+}
+
+func TestDeployReconcileWithRenderErrors(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement",
+		renderAnnotations(true, ""))
+	mwrSet.Spec.ManifestWorkTemplate.Workload.Manifests = rawManifests(testConfigMap)
+	reconciler, fWorkClient := newRenderDeployReconciler(t, mwrSet,
+		newRenderCluster("cluster1", map[string]string{"host": "cluster1.example.com"}),
+		newRenderCluster("cluster2", nil))
+
+	mwrSet, _, err := reconciler.reconcile(context.TODO(), mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	works, err := fWorkClient.WorkV1().ManifestWorks("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works.Items) != 1 || works.Items[0].Namespace != "cluster1" {
+		t.Fatalf("expected manifestwork created on cluster1 only, but got %v", works.Items)
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(works.Items[0].Spec.Workload.Manifests[0].Raw, &data); err != nil {
+		t.Fatal(err)
+	}
+	if host := data["data"].(map[string]interface{})["host"]; host != "cluster1.example.com" {
+		t.Errorf("expected rendered host, but got %v", host)
+	}
+
+	condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionManifestworkRendered)
+	if condition == nil || condition.Reason != ReasonRenderFailed ||
+		!strings.HasPrefix(condition.Message, "Failed to render manifestworks for 1 clusters: cluster2: failed to render manifest 0") {
+		t.Errorf("unexpected rendered condition %v", condition)
+	}
+	if mwrSet.Status.Summary.Total != 2 {
+		t.Errorf("expected total 2, but got %d", mwrSet.Status.Summary.Total)
+	}
+
+	// invalid overrides stop applying the manifestworks
+	mwrSet.Annotations[OverridesAnnotationKey] = "invalid"
+	mwrSet, _, err = reconciler.reconcile(context.TODO(), mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	condition = apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionManifestworkRendered)
+	if condition == nil || condition.Reason != ReasonInvalidOverrides {
+		t.Errorf("unexpected rendered condition %v", condition)
+	}
+
+	// the condition is removed once the manifestworks are not rendered per cluster
+	mwrSet.Annotations = nil
+	mwrSet, _, err = reconciler.reconcile(context.TODO(), mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionManifestworkRendered); condition != nil {
+		t.Errorf("expected rendered condition removed, but got %v", condition)
+	}
+}
+
+func TestRenderQueueKeys(t *testing.T) {
+	workInformerFactory := workinformers.NewSharedInformerFactory(fakeworkclient.NewSimpleClientset(), 0)
+	mwrSetInformer := workInformerFactory.Work().V1alpha1().ManifestWorkReplicaSets().Informer()
+	if err := mwrSetInformer.AddIndexers(cache.Indexers{
+		manifestWorkReplicaSetByPlacement: indexManifestWorkReplicaSetByPlacement,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(fakeclusterclient.NewSimpleClientset(), 0)
+	decisionInformer := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer()
+	if err := decisionInformer.AddIndexers(cache.Indexers{placementDecisionByCluster: indexPlacementDecisionByCluster}); err != nil {
+		t.Fatal(err)
+	}
+	addOnInformerFactory := addoninformers.NewSharedInformerFactory(fakeaddonclient.NewSimpleClientset(), 0)
+	addOnInformer := addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns().Informer()
+	if err := addOnInformer.AddIndexers(cache.Indexers{addOnByConfig: indexAddOnByConfig}); err != nil {
+		t.Fatal(err)
+	}
+
+	// rendered and overridden select cluster1 and cluster2, plain is not rendered and other selects cluster3
+	rendered := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("rendered", "default", "placement",
+		renderAnnotations(true, ""))
+	overridden := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("overridden", "default", "placement",
+		renderAnnotations(false, "[]"))
+	plain := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("plain", "default", "placement", nil)
+	other := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("other", "default", "other",
+		renderAnnotations(true, ""))
+	for _, mwrSet := range []*workapiv1alpha1.ManifestWorkReplicaSet{rendered, overridden, plain, other} {
+		if err := mwrSetInformer.GetStore().Add(mwrSet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, decision := helpertest.CreateTestPlacement("placement", "default", "cluster1", "cluster2")
+	_, otherDecision := helpertest.CreateTestPlacement("other", "default", "cluster3")
+	for _, d := range []*clusterv1beta1.PlacementDecision{decision, otherDecision} {
+		if err := decisionInformer.GetStore().Add(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addOn := &addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "cluster1"},
+		Status: addonv1alpha1.ManagedClusterAddOnStatus{
+			ConfigReferences: []addonv1alpha1.ConfigReference{
+				{
+					ConfigGroupResource: addonv1alpha1.ConfigGroupResource{
+						Group:    addonv1alpha1.GroupName,
+						Resource: addOnDeploymentConfigResource,
+					},
+					ConfigReferent: addonv1alpha1.ConfigReferent{Namespace: "default", Name: "app-config"},
+				},
+			},
+		},
+	}
+	if err := addOnInformer.GetStore().Add(addOn); err != nil {
+		t.Fatal(err)
+	}
+
+	controller := &ManifestWorkReplicaSetController{
+		manifestWorkReplicaSetIndexer: mwrSetInformer.GetIndexer(),
+		placeDecisionIndexer:          decisionInformer.GetIndexer(),
+		addOnIndexer:                  addOnInformer.GetIndexer(),
+	}
+	cases := []struct {
+		name         string
+		keys         []string
+		expectedKeys []string
+	}{
+		{
+			name:         "cluster",
+			keys:         controller.clusterQueueKeysFunc(newRenderCluster("cluster2", nil)),
+			expectedKeys: []string{"default/overridden", "default/rendered"},
+		},
+		{
+			name:         "cluster of another placement",
+			keys:         controller.clusterQueueKeysFunc(newRenderCluster("cluster3", nil)),
+			expectedKeys: []string{"default/other"},
+		},
+		{
+			name:         "cluster not selected",
+			keys:         controller.clusterQueueKeysFunc(newRenderCluster("cluster4", nil)),
+			expectedKeys: []string{},
+		},
+		{
+			name:         "addon",
+			keys:         controller.addOnQueueKeysFunc(addOn),
+			expectedKeys: []string{"default/overridden", "default/rendered"},
+		},
+		{
+			name: "addon config",
+			keys: controller.addOnConfigQueueKeysFunc(&addonv1alpha1.AddOnDeploymentConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+			}),
+			expectedKeys: []string{"default/overridden", "default/rendered"},
+		},
+		{
+			name: "addon config not referred",
+			keys: controller.addOnConfigQueueKeysFunc(&addonv1alpha1.AddOnDeploymentConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "unused", Namespace: "default"},
+			}),
+			expectedKeys: []string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if !sets.New[string](c.keys...).Equal(sets.New[string](c.expectedKeys...)) {
+				t.Errorf("expected keys %v, but got %v", c.expectedKeys, c.keys)
+			}
+		})
+	}
+}
+
+func TestRenderObjectsChanged(t *testing.T) {
+	newAddOn := func(configName string) *addonv1alpha1.ManagedClusterAddOn {
+		return &addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "cluster1"},
+			Status: addonv1alpha1.ManagedClusterAddOnStatus{
+				ConfigReferences: []addonv1alpha1.ConfigReference{
+					{
+						ConfigGroupResource: addonv1alpha1.ConfigGroupResource{
+							Group:    addonv1alpha1.GroupName,
+							Resource: addOnDeploymentConfigResource,
+						},
+						ConfigReferent: addonv1alpha1.ConfigReferent{Namespace: "default", Name: configName},
+					},
+				},
+			},
+		}
+	}
+	newAddOnConfig := func(generation int64, variables ...addonv1alpha1.CustomizedVariable) *addonv1alpha1.AddOnDeploymentConfig {
+		return &addonv1alpha1.AddOnDeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default", Generation: generation},
+			Spec:       addonv1alpha1.AddOnDeploymentConfigSpec{CustomizedVariables: variables},
+		}
+	}
+	heartbeat := newRenderCluster("cluster1", map[string]string{"env": "dev"})
+	heartbeat.Status.Conditions = []metav1.Condition{{Type: clusterv1.ManagedClusterConditionAvailable}}
+	exposed := newAddOnConfig(1, addonv1alpha1.CustomizedVariable{Name: "tag", Value: "v1"})
+	exposed.Annotations = map[string]string{TemplateValuesAnnotationKey: "true"}
+	condition := newAddOn("app-config")
+	condition.Status.Conditions = []metav1.Condition{{Type: "Available"}}
+
+	cases := []struct {
+		name     string
+		changed  func(oldObj, newObj interface{}) bool
+		oldObj   interface{}
+		newObj   interface{}
+		expected bool
+	}{
+		{
+			name:     "cluster status changes",
+			changed:  clusterTemplateValuesChanged,
+			oldObj:   newRenderCluster("cluster1", map[string]string{"env": "dev"}),
+			newObj:   heartbeat,
+			expected: false,
+		},
+		{
+			name:     "cluster labels change",
+			changed:  clusterTemplateValuesChanged,
+			oldObj:   newRenderCluster("cluster1", map[string]string{"env": "dev"}),
+			newObj:   newRenderCluster("cluster1", map[string]string{"env": "prod"}),
+			expected: true,
+		},
+		{
+			name:     "cluster claims change",
+			changed:  clusterTemplateValuesChanged,
+			oldObj:   newRenderCluster("cluster1", nil),
+			newObj:   newRenderCluster("cluster1", nil, clusterv1.ManagedClusterClaim{Name: "id.k8s.io", Value: "c1"}),
+			expected: true,
+		},
+		{
+			name:     "addon conditions change",
+			changed:  addOnConfigReferencesChanged,
+			oldObj:   newAddOn("app-config"),
+			newObj:   condition,
+			expected: false,
+		},
+		{
+			name:     "addon config references change",
+			changed:  addOnConfigReferencesChanged,
+			oldObj:   newAddOn("app-config"),
+			newObj:   newAddOn("app-config-v2"),
+			expected: true,
+		},
+		{
+			name:     "addon config generation changes",
+			changed:  addOnConfigVariablesChanged,
+			oldObj:   newAddOnConfig(1, addonv1alpha1.CustomizedVariable{Name: "tag", Value: "v1"}),
+			newObj:   newAddOnConfig(2, addonv1alpha1.CustomizedVariable{Name: "tag", Value: "v1"}),
+			expected: false,
+		},
+		{
+			name:     "addon config exposed",
+			changed:  addOnConfigVariablesChanged,
+			oldObj:   newAddOnConfig(1, addonv1alpha1.CustomizedVariable{Name: "tag", Value: "v1"}),
+			newObj:   exposed,
+			expected: true,
+		},
+		{
+			name:     "addon config variables change",
+			changed:  addOnConfigVariablesChanged,
+			oldObj:   newAddOnConfig(1, addonv1alpha1.CustomizedVariable{Name: "tag", Value: "v1"}),
+			newObj:   newAddOnConfig(2, addonv1alpha1.CustomizedVariable{Name: "tag", Value: "v2"}),
+			expected: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if changed := c.changed(c.oldObj, c.newObj); changed != c.expected {
+				t.Errorf("expected changed %v, but got %v", c.expected, changed)
+			}
+		})
+	}
+}
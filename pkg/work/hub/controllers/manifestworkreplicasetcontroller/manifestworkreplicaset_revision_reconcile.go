@@ -0,0 +1,344 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	appslisterv1 "k8s.io/client-go/listers/apps/v1"
+
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+const (
+	// RevisionAnnotationKey is the annotation on ManifestWork with the name of the ControllerRevision of the
+	// ManifestWorkReplicaSet template which the ManifestWork is created from. The ManifestWorks created before
+	// the revisions are introduced have no revision until the template changes.
+	RevisionAnnotationKey = "work.open-cluster-management.io/manifestworkreplicaset-revision"
+
+	// RollbackToAnnotationKey is the annotation on ManifestWorkReplicaSet with the name of a ControllerRevision
+	// to roll back to. The template of the revision is restored to the ManifestWorkReplicaSet and the annotation
+	// is removed, then the ManifestWorks are deployed with the restored template.
+	RollbackToAnnotationKey = "work.open-cluster-management.io/experimental-rollback-to"
+
+	// RevisionHistoryLimitAnnotationKey is the annotation on ManifestWorkReplicaSet with the number of old
+	// revisions kept for rollback. Defaults to 10 if it is not set or invalid. The revisions still used by any
+	// ManifestWork are always kept.
+	RevisionHistoryLimitAnnotationKey = "work.open-cluster-management.io/experimental-revision-history-limit"
+
+	// RevisionCollisionCountAnnotationKey is the annotation on ManifestWorkReplicaSet with the number of hash
+	// collisions of the revision names. It is increased by the controller when a revision with the same name but
+	// a different template exists, and is hashed with the template to get a new revision name.
+	RevisionCollisionCountAnnotationKey = "work.open-cluster-management.io/experimental-revision-collision-count"
+
+	// ManifestWorkReplicaSetConditionRevisionsUpdated is the condition of the revisions of the ManifestWorks. It
+	// is true if all the ManifestWorks run the current revision of the template, and the message has the number
+	// of clusters running each revision.
+	ManifestWorkReplicaSetConditionRevisionsUpdated = "RevisionsUpdated"
+
+	ReasonRevisionsUpdated  = "RevisionsUpdated"
+	ReasonRevisionsOutdated = "RevisionsOutdated"
+	ReasonRollbackFailed    = "RollbackFailed"
+
+	defaultRevisionHistoryLimit = 10
+)
+
+// revisionAnnotationKeys are the annotations of the ManifestWorkReplicaSet saved in the revisions, since they
+// change how the template is rendered.
+var revisionAnnotationKeys = []string{TemplateAnnotationKey, OverridesAnnotationKey}
+
+// revisionData is the data of a ControllerRevision of the ManifestWorkReplicaSet template.
+type revisionData struct {
+	Annotations          map[string]string       `json:"annotations,omitempty"`
+	ManifestWorkTemplate workv1.ManifestWorkSpec `json:"manifestWorkTemplate"`
+}
+
+// getRevisionData returns the data of the current revision of the ManifestWorkReplicaSet.
+func getRevisionData(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) ([]byte, error) {
+	data := revisionData{ManifestWorkTemplate: mwrSet.Spec.ManifestWorkTemplate}
+	for _, key := range revisionAnnotationKeys {
+		value, ok := mwrSet.Annotations[key]
+		if !ok {
+			continue
+		}
+		if data.Annotations == nil {
+			data.Annotations = map[string]string{}
+		}
+		data.Annotations[key] = value
+	}
+	return json.Marshal(data)
+}
+
+// getRevisionName returns the name of the current revision of the ManifestWorkReplicaSet, which is the name of
+// the ManifestWorkReplicaSet with the hash of the revision data and the collision count. The name of the
+// ManifestWorkReplicaSet is truncated to keep the revision name a valid object name.
+func getRevisionName(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (string, []byte, error) {
+	data, err := getRevisionData(mwrSet)
+	if err != nil {
+		return "", nil, err
+	}
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(data)
+	if collisionCount := getRevisionCollisionCount(mwrSet); collisionCount > 0 {
+		collisionCountBytes := make([]byte, 8)
+		binary.LittleEndian.PutUint32(collisionCountBytes, uint32(collisionCount))
+		_, _ = hasher.Write(collisionCountBytes)
+	}
+	hash := rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+
+	prefix := mwrSet.Name
+	if maxLength := validation.DNS1123SubdomainMaxLength - len(hash) - 1; len(prefix) > maxLength {
+		prefix = prefix[:maxLength]
+	}
+	return fmt.Sprintf("%s-%s", prefix, hash), data, nil
+}
+
+// getRevisionCollisionCount returns the number of hash collisions of the revision names of the
+// ManifestWorkReplicaSet. Defaults to 0 if it is not set or invalid.
+func getRevisionCollisionCount(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) int32 {
+	count, err := strconv.ParseInt(mwrSet.Annotations[RevisionCollisionCountAnnotationKey], 10, 32)
+	if err != nil || count < 0 {
+		return 0
+	}
+	return int32(count)
+}
+
+// getRevisionHistoryLimit returns the number of old revisions kept for the ManifestWorkReplicaSet.
+func getRevisionHistoryLimit(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) int {
+	limit, err := strconv.Atoi(mwrSet.Annotations[RevisionHistoryLimitAnnotationKey])
+	if err != nil || limit < 0 {
+		return defaultRevisionHistoryLimit
+	}
+	return limit
+}
+
+// revisionReconciler is to record the revisions of the ManifestWorkReplicaSet template as ControllerRevisions,
+// and to roll back the template to a revision.
+type revisionReconciler struct {
+	workClient         workclientset.Interface
+	kubeClient         kubernetes.Interface
+	revisionLister     appslisterv1.ControllerRevisionLister
+	manifestWorkLister worklisterv1.ManifestWorkLister
+}
+
+func (r *revisionReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+) (*workapiv1alpha1.ManifestWorkReplicaSet, reconcileState, error) {
+	revisions, err := r.revisionLister.ControllerRevisions(mwrSet.Namespace).List(labels.SelectorFromSet(
+		labels.Set{ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet)}))
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	rollbackFailed := false
+	if name, ok := mwrSet.Annotations[RollbackToAnnotationKey]; ok {
+		var revision *appsv1.ControllerRevision
+		for _, rev := range revisions {
+			if rev.Name == name {
+				revision = rev
+			}
+		}
+		if revision != nil {
+			// the ManifestWorkReplicaSet is reconciled again with the restored template once it is updated.
+			return mwrSet, reconcileStop, r.rollback(ctx, mwrSet, revision)
+		}
+		rollbackFailed = true
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(
+			ManifestWorkReplicaSetConditionRevisionsUpdated, ReasonRollbackFailed,
+			fmt.Sprintf("Revision %s of the ManifestWorkReplicaSet is not found", name), metav1.ConditionFalse))
+	}
+
+	name, data, err := getRevisionName(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	for _, revision := range revisions {
+		if revision.Name == name && !bytes.Equal(revision.Data.Raw, data) {
+			// the ManifestWorkReplicaSet is reconciled again with a new revision name once it is updated.
+			return mwrSet, reconcileStop, r.increaseCollisionCount(ctx, mwrSet)
+		}
+	}
+
+	current, err := r.syncCurrentRevision(ctx, mwrSet, name, data, revisions)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	manifestWorks, err := listManifestWorksByManifestWorkReplicaSet(mwrSet, r.manifestWorkLister)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	counts := map[string]int{}
+	for _, mw := range manifestWorks {
+		counts[mw.Annotations[RevisionAnnotationKey]]++
+	}
+
+	if err := r.truncateHistory(ctx, mwrSet, revisions, current, counts); err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	if !rollbackFailed {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getRevisionsCondition(current, revisions, counts))
+	}
+	return mwrSet, reconcileContinue, nil
+}
+
+// syncCurrentRevision creates the ControllerRevision of the current template, or moves it to the latest
+// revision number if the template is changed back to a previous revision.
+func (r *revisionReconciler) syncCurrentRevision(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	name string, data []byte, revisions []*appsv1.ControllerRevision) (*appsv1.ControllerRevision, error) {
+	var current *appsv1.ControllerRevision
+	var maxRevision int64
+	for _, revision := range revisions {
+		if revision.Revision > maxRevision {
+			maxRevision = revision.Revision
+		}
+		if revision.Name == name {
+			current = revision
+		}
+	}
+
+	switch {
+	case current == nil:
+		revision := &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: mwrSet.Namespace,
+				Labels:    map[string]string{ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet)},
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(mwrSet, workapiv1alpha1.GroupVersion.WithKind("ManifestWorkReplicaSet")),
+				},
+			},
+			Data:     runtime.RawExtension{Raw: data},
+			Revision: maxRevision + 1,
+		}
+		return r.kubeClient.AppsV1().ControllerRevisions(mwrSet.Namespace).Create(ctx, revision, metav1.CreateOptions{})
+	case current.Revision < maxRevision:
+		revision := current.DeepCopy()
+		revision.Revision = maxRevision + 1
+		return r.kubeClient.AppsV1().ControllerRevisions(mwrSet.Namespace).Update(ctx, revision, metav1.UpdateOptions{})
+	}
+	return current, nil
+}
+
+// truncateHistory deletes the oldest revisions exceeding the history limit. The current revision and the
+// revisions still used by the ManifestWorks are kept.
+func (r *revisionReconciler) truncateHistory(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	revisions []*appsv1.ControllerRevision, current *appsv1.ControllerRevision, counts map[string]int) error {
+	old := []*appsv1.ControllerRevision{}
+	for _, revision := range revisions {
+		if revision.Name == current.Name || counts[revision.Name] > 0 {
+			continue
+		}
+		old = append(old, revision)
+	}
+	sort.Slice(old, func(i, j int) bool { return old[i].Revision < old[j].Revision })
+
+	for i := 0; i < len(old)-getRevisionHistoryLimit(mwrSet); i++ {
+		err := r.kubeClient.AppsV1().ControllerRevisions(mwrSet.Namespace).Delete(ctx, old[i].Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// increaseCollisionCount increases the collision count of the ManifestWorkReplicaSet, so that the current
+// template gets a revision name different from the existing revision with the same hash.
+func (r *revisionReconciler) increaseCollisionCount(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) error {
+	updated := mwrSet.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[RevisionCollisionCountAnnotationKey] = strconv.Itoa(int(getRevisionCollisionCount(mwrSet)) + 1)
+
+	_, err := r.workClient.WorkV1alpha1().ManifestWorkReplicaSets(mwrSet.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+// rollback restores the template of the revision to the ManifestWorkReplicaSet and removes the rollback
+// annotation.
+func (r *revisionReconciler) rollback(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	revision *appsv1.ControllerRevision) error {
+	data := &revisionData{}
+	if err := json.Unmarshal(revision.Data.Raw, data); err != nil {
+		return fmt.Errorf("failed to decode revision %s: %w", revision.Name, err)
+	}
+
+	updated := mwrSet.DeepCopy()
+	updated.Spec.ManifestWorkTemplate = data.ManifestWorkTemplate
+	for _, key := range revisionAnnotationKeys {
+		if value, ok := data.Annotations[key]; ok {
+			updated.Annotations[key] = value
+		} else {
+			delete(updated.Annotations, key)
+		}
+	}
+	delete(updated.Annotations, RollbackToAnnotationKey)
+
+	_, err := r.workClient.WorkV1alpha1().ManifestWorkReplicaSets(mwrSet.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+// getRevisionsCondition returns the revisions condition with the number of ManifestWorks of each revision.
+func getRevisionsCondition(current *appsv1.ControllerRevision, revisions []*appsv1.ControllerRevision,
+	counts map[string]int) metav1.Condition {
+	numbers := map[string]int64{current.Name: current.Revision}
+	for _, revision := range revisions {
+		if revision.Name != current.Name {
+			numbers[revision.Name] = revision.Revision
+		}
+	}
+
+	total := 0
+	names := []string{}
+	for name, count := range counts {
+		total += count
+		if name != current.Name {
+			names = append(names, name)
+		}
+	}
+	// the newer revisions first, and the unknown revisions at last
+	sort.Slice(names, func(i, j int) bool {
+		if numbers[names[i]] != numbers[names[j]] {
+			return numbers[names[i]] > numbers[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	messages := []string{fmt.Sprintf("current revision %s (%d): %d/%d clusters", current.Name, current.Revision,
+		counts[current.Name], total)}
+	for _, name := range names {
+		switch {
+		case len(name) == 0:
+			messages = append(messages, fmt.Sprintf("no revision: %d clusters", counts[name]))
+		case numbers[name] == 0:
+			messages = append(messages, fmt.Sprintf("unknown revision %s: %d clusters", name, counts[name]))
+		default:
+			messages = append(messages, fmt.Sprintf("revision %s (%d): %d clusters", name, numbers[name], counts[name]))
+		}
+	}
+
+	message := strings.Join(messages, "; ")
+	if counts[current.Name] == total {
+		return getCondition(ManifestWorkReplicaSetConditionRevisionsUpdated, ReasonRevisionsUpdated, message, metav1.ConditionTrue)
+	}
+	return getCondition(ManifestWorkReplicaSetConditionRevisionsUpdated, ReasonRevisionsOutdated, message, metav1.ConditionFalse)
+}
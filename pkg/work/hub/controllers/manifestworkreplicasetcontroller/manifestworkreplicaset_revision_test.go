@@ -0,0 +1,476 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	kubeinformers "k8s.io/client-go/informers"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+// newRevision returns the ControllerRevision of the template of the ManifestWorkReplicaSet with the revision
+// number.
+func newRevision(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, number int64) *appsv1.ControllerRevision {
+	name, data, err := getRevisionName(mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: mwrSet.Namespace,
+			Labels:    map[string]string{ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet)},
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: number,
+	}
+}
+
+// newOldRevision returns a ControllerRevision of the ManifestWorkReplicaSet with a template different from
+// the current one.
+func newOldRevision(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, number int64) *appsv1.ControllerRevision {
+	old := mwrSet.DeepCopy()
+	old.Spec.ManifestWorkTemplate.Workload.Manifests = nil
+	revision := newRevision(t, old, number)
+	revision.Name = fmt.Sprintf("%s-old%d", mwrSet.Name, number)
+	return revision
+}
+
+func TestGetRevisionName(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", nil)
+	name, _, err := getRevisionName(mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		mwrSet func() *workapiv1alpha1.ManifestWorkReplicaSet
+		same   bool
+	}{
+		{
+			name: "unchanged",
+			mwrSet: func() *workapiv1alpha1.ManifestWorkReplicaSet {
+				return mwrSet.DeepCopy()
+			},
+			same: true,
+		},
+		{
+			name: "annotation not rendering the template",
+			mwrSet: func() *workapiv1alpha1.ManifestWorkReplicaSet {
+				w := mwrSet.DeepCopy()
+				w.Annotations = map[string]string{RolloutStrategyAnnotationKey: "All"}
+				return w
+			},
+			same: true,
+		},
+		{
+			name: "template annotation",
+			mwrSet: func() *workapiv1alpha1.ManifestWorkReplicaSet {
+				w := mwrSet.DeepCopy()
+				w.Annotations = map[string]string{TemplateAnnotationKey: "true"}
+				return w
+			},
+		},
+		{
+			name: "template changed",
+			mwrSet: func() *workapiv1alpha1.ManifestWorkReplicaSet {
+				w := mwrSet.DeepCopy()
+				w.Spec.ManifestWorkTemplate.Workload.Manifests = nil
+				return w
+			},
+		},
+		{
+			name: "collision count",
+			mwrSet: func() *workapiv1alpha1.ManifestWorkReplicaSet {
+				w := mwrSet.DeepCopy()
+				w.Annotations = map[string]string{RevisionCollisionCountAnnotationKey: "1"}
+				return w
+			},
+		},
+		{
+			name: "invalid collision count",
+			mwrSet: func() *workapiv1alpha1.ManifestWorkReplicaSet {
+				w := mwrSet.DeepCopy()
+				w.Annotations = map[string]string{RevisionCollisionCountAnnotationKey: "abc"}
+				return w
+			},
+			same: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, _, err := getRevisionName(c.mwrSet())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (actual == name) != c.same {
+				t.Errorf("expected same revision %v, but got %s and %s", c.same, name, actual)
+			}
+		})
+	}
+
+	t.Run("long name", func(t *testing.T) {
+		w := mwrSet.DeepCopy()
+		w.Name = strings.Repeat("a", validation.DNS1123SubdomainMaxLength)
+		actual, _, err := getRevisionName(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errs := validation.IsDNS1123Subdomain(actual); len(errs) > 0 {
+			t.Errorf("expected valid revision name, but got %s: %v", actual, errs)
+		}
+	})
+}
+
+func TestGetRevisionHistoryLimit(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		expected    int
+	}{
+		{
+			name:     "default",
+			expected: defaultRevisionHistoryLimit,
+		},
+		{
+			name:        "valid",
+			annotations: map[string]string{RevisionHistoryLimitAnnotationKey: "3"},
+			expected:    3,
+		},
+		{
+			name:        "zero",
+			annotations: map[string]string{RevisionHistoryLimitAnnotationKey: "0"},
+			expected:    0,
+		},
+		{
+			name:        "negative",
+			annotations: map[string]string{RevisionHistoryLimitAnnotationKey: "-1"},
+			expected:    defaultRevisionHistoryLimit,
+		},
+		{
+			name:        "invalid",
+			annotations: map[string]string{RevisionHistoryLimitAnnotationKey: "abc"},
+			expected:    defaultRevisionHistoryLimit,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", c.annotations)
+			actual := getRevisionHistoryLimit(mwrSet)
+			if actual != c.expected {
+				t.Errorf("expected %d, but got %d", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRevisionReconcile(t *testing.T) {
+	cases := []struct {
+		name            string
+		mwrSet          *workapiv1alpha1.ManifestWorkReplicaSet
+		revisions       func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*appsv1.ControllerRevision
+		works           func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []runtime.Object
+		expectedState   reconcileState
+		validateActions func(t *testing.T, kubeActions, workActions []clienttesting.Action)
+		validateStatus  func(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet)
+	}{
+		{
+			name:   "create the first revision",
+			mwrSet: helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", nil),
+			revisions: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*appsv1.ControllerRevision {
+				return nil
+			},
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []runtime.Object {
+				return nil
+			},
+			expectedState: reconcileContinue,
+			validateActions: func(t *testing.T, kubeActions, workActions []clienttesting.Action) {
+				testingcommon.AssertActions(t, kubeActions, "create")
+				testingcommon.AssertNoActions(t, workActions)
+				revision := kubeActions[0].(clienttesting.CreateActionImpl).Object.(*appsv1.ControllerRevision)
+				if revision.Revision != 1 {
+					t.Errorf("expected revision 1, but got %d", revision.Revision)
+				}
+				if len(revision.OwnerReferences) != 1 || revision.OwnerReferences[0].Kind != "ManifestWorkReplicaSet" {
+					t.Errorf("unexpected owner references %v", revision.OwnerReferences)
+				}
+			},
+			validateStatus: func(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) {
+				if !apimeta.IsStatusConditionTrue(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRevisionsUpdated) {
+					t.Errorf("unexpected conditions %v", mwrSet.Status.Conditions)
+				}
+			},
+		},
+		{
+			name:   "template changed back to an old revision",
+			mwrSet: helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", nil),
+			revisions: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*appsv1.ControllerRevision {
+				return []*appsv1.ControllerRevision{newRevision(t, mwrSet, 1), newOldRevision(t, mwrSet, 2)}
+			},
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []runtime.Object {
+				return []runtime.Object{helpertest.CreateTestManifestWorkWithAnnotations("test", "default", "cluster1",
+					map[string]string{RevisionAnnotationKey: "test-old2"})}
+			},
+			expectedState: reconcileContinue,
+			validateActions: func(t *testing.T, kubeActions, workActions []clienttesting.Action) {
+				testingcommon.AssertActions(t, kubeActions, "update")
+				revision := kubeActions[0].(clienttesting.UpdateActionImpl).Object.(*appsv1.ControllerRevision)
+				if revision.Revision != 3 {
+					t.Errorf("expected revision 3, but got %d", revision.Revision)
+				}
+			},
+			validateStatus: func(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) {
+				cond := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRevisionsUpdated)
+				if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonRevisionsOutdated {
+					t.Errorf("unexpected condition %v", cond)
+				}
+			},
+		},
+		{
+			name:   "hash collision",
+			mwrSet: helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", nil),
+			revisions: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*appsv1.ControllerRevision {
+				revision := newOldRevision(t, mwrSet, 1)
+				revision.Name, _, _ = getRevisionName(mwrSet)
+				return []*appsv1.ControllerRevision{revision}
+			},
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []runtime.Object {
+				return nil
+			},
+			expectedState: reconcileStop,
+			validateActions: func(t *testing.T, kubeActions, workActions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, kubeActions)
+				testingcommon.AssertActions(t, workActions, "update")
+				updated := workActions[0].(clienttesting.UpdateActionImpl).Object.(*workapiv1alpha1.ManifestWorkReplicaSet)
+				if count := updated.Annotations[RevisionCollisionCountAnnotationKey]; count != "1" {
+					t.Errorf("expected collision count 1, but got %q", count)
+				}
+			},
+			validateStatus: func(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) {},
+		},
+		{
+			name: "truncate the history",
+			mwrSet: helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", map[string]string{
+				RevisionHistoryLimitAnnotationKey: "1",
+			}),
+			revisions: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*appsv1.ControllerRevision {
+				return []*appsv1.ControllerRevision{
+					newOldRevision(t, mwrSet, 1),
+					newOldRevision(t, mwrSet, 2),
+					newOldRevision(t, mwrSet, 3),
+					newRevision(t, mwrSet, 4),
+				}
+			},
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []runtime.Object {
+				current, _, _ := getRevisionName(mwrSet)
+				return []runtime.Object{
+					helpertest.CreateTestManifestWorkWithAnnotations("test", "default", "cluster1",
+						map[string]string{RevisionAnnotationKey: "test-old1"}),
+					helpertest.CreateTestManifestWorkWithAnnotations("test", "default", "cluster2",
+						map[string]string{RevisionAnnotationKey: current}),
+				}
+			},
+			expectedState: reconcileContinue,
+			validateActions: func(t *testing.T, kubeActions, workActions []clienttesting.Action) {
+				// test-old1 is in use, and test-old3 is kept by the limit
+				testingcommon.AssertActions(t, kubeActions, "delete")
+				if name := kubeActions[0].(clienttesting.DeleteActionImpl).Name; name != "test-old2" {
+					t.Errorf("expected test-old2 deleted, but got %s", name)
+				}
+			},
+			validateStatus: func(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) {
+				if !apimeta.IsStatusConditionFalse(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRevisionsUpdated) {
+					t.Errorf("unexpected conditions %v", mwrSet.Status.Conditions)
+				}
+			},
+		},
+		{
+			name: "roll back to a revision",
+			mwrSet: helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", map[string]string{
+				RollbackToAnnotationKey: "test-rollback",
+				OverridesAnnotationKey:  "[]",
+			}),
+			revisions: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*appsv1.ControllerRevision {
+				old := mwrSet.DeepCopy()
+				old.Annotations = map[string]string{TemplateAnnotationKey: "true"}
+				old.Spec.ManifestWorkTemplate.Workload.Manifests = nil
+				revision := newRevision(t, old, 1)
+				revision.Name = "test-rollback"
+				return []*appsv1.ControllerRevision{revision, newRevision(t, mwrSet, 2)}
+			},
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []runtime.Object {
+				return nil
+			},
+			expectedState: reconcileStop,
+			validateActions: func(t *testing.T, kubeActions, workActions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, kubeActions)
+				testingcommon.AssertActions(t, workActions, "update")
+				updated := workActions[0].(clienttesting.UpdateActionImpl).Object.(*workapiv1alpha1.ManifestWorkReplicaSet)
+				if len(updated.Spec.ManifestWorkTemplate.Workload.Manifests) != 0 {
+					t.Errorf("expected template restored, but got %v", updated.Spec.ManifestWorkTemplate)
+				}
+				expected := map[string]string{TemplateAnnotationKey: "true"}
+				if !reflect.DeepEqual(updated.Annotations, expected) {
+					t.Errorf("expected annotations %v, but got %v", expected, updated.Annotations)
+				}
+			},
+			validateStatus: func(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) {},
+		},
+		{
+			name: "roll back to a revision not found",
+			mwrSet: helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", map[string]string{
+				RollbackToAnnotationKey: "test-notfound",
+			}),
+			revisions: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*appsv1.ControllerRevision {
+				return []*appsv1.ControllerRevision{newRevision(t, mwrSet, 1)}
+			},
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []runtime.Object {
+				return nil
+			},
+			expectedState: reconcileContinue,
+			validateActions: func(t *testing.T, kubeActions, workActions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, kubeActions)
+				testingcommon.AssertNoActions(t, workActions)
+			},
+			validateStatus: func(t *testing.T, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) {
+				cond := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRevisionsUpdated)
+				if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != ReasonRollbackFailed {
+					t.Errorf("unexpected condition %v", cond)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fakeWorkClient := fakeworkclient.NewSimpleClientset(c.mwrSet)
+			workInformers := workinformers.NewSharedInformerFactory(fakeWorkClient, 10*time.Minute)
+			for _, mw := range c.works(c.mwrSet) {
+				if err := workInformers.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			revisions := c.revisions(c.mwrSet)
+			objects := []runtime.Object{}
+			for _, revision := range revisions {
+				objects = append(objects, revision)
+			}
+			fakeKubeClient := fakekube.NewSimpleClientset(objects...)
+			kubeInformers := kubeinformers.NewSharedInformerFactory(fakeKubeClient, 10*time.Minute)
+			for _, revision := range revisions {
+				if err := kubeInformers.Apps().V1().ControllerRevisions().Informer().GetStore().Add(revision); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := &revisionReconciler{
+				workClient:         fakeWorkClient,
+				kubeClient:         fakeKubeClient,
+				revisionLister:     kubeInformers.Apps().V1().ControllerRevisions().Lister(),
+				manifestWorkLister: workInformers.Work().V1().ManifestWorks().Lister(),
+			}
+
+			mwrSet, state, err := r.reconcile(context.TODO(), c.mwrSet.DeepCopy())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state != c.expectedState {
+				t.Errorf("expected state %v, but got %v", c.expectedState, state)
+			}
+			c.validateActions(t, fakeKubeClient.Actions(), fakeWorkClient.Actions())
+			c.validateStatus(t, mwrSet)
+		})
+	}
+}
+
+func TestGetRevisionsCondition(t *testing.T) {
+	current := &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: "test-c"}, Revision: 3}
+	revisions := []*appsv1.ControllerRevision{
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-a"}, Revision: 1},
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-b"}, Revision: 2},
+		current,
+	}
+
+	cases := []struct {
+		name            string
+		counts          map[string]int
+		expectedStatus  metav1.ConditionStatus
+		expectedMessage string
+	}{
+		{
+			name:            "no manifestworks",
+			counts:          map[string]int{},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "current revision test-c (3): 0/0 clusters",
+		},
+		{
+			name:            "all updated",
+			counts:          map[string]int{"test-c": 2},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "current revision test-c (3): 2/2 clusters",
+		},
+		{
+			name:           "mixed revisions",
+			counts:         map[string]int{"test-a": 1, "test-b": 2, "test-c": 1, "test-x": 1, "": 1},
+			expectedStatus: metav1.ConditionFalse,
+			expectedMessage: "current revision test-c (3): 1/6 clusters; revision test-b (2): 2 clusters; " +
+				"revision test-a (1): 1 clusters; no revision: 1 clusters; unknown revision test-x: 1 clusters",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cond := getRevisionsCondition(current, revisions, c.counts)
+			if cond.Status != c.expectedStatus {
+				t.Errorf("expected status %s, but got %s", c.expectedStatus, cond.Status)
+			}
+			if cond.Message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, cond.Message)
+			}
+		})
+	}
+}
+
+// revision data should be decodable to restore the template
+func TestRevisionDataRoundTrip(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", map[string]string{
+		TemplateAnnotationKey:        "true",
+		RolloutStrategyAnnotationKey: "All",
+	})
+	data, err := getRevisionData(mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &revisionData{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Annotations, map[string]string{TemplateAnnotationKey: "true"}) {
+		t.Errorf("unexpected annotations %v", decoded.Annotations)
+	}
+	if len(decoded.ManifestWorkTemplate.Workload.Manifests) != len(mwrSet.Spec.ManifestWorkTemplate.Workload.Manifests) {
+		t.Errorf("unexpected template %v", decoded.ManifestWorkTemplate)
+	}
+}
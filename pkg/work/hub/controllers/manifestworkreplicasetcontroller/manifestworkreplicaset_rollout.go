@@ -0,0 +1,507 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+
+	clusterlister "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+const (
+	// RolloutStrategyAnnotationKey is the annotation on ManifestWorkReplicaSet which defines how the
+	// ManifestWorks are rolled out to the clusters. The value is a json encoded RolloutStrategy. All the
+	// ManifestWorks are created or updated at once if it is not set.
+	RolloutStrategyAnnotationKey = "work.open-cluster-management.io/experimental-rollout-strategy"
+
+	// RolloutAppliedTimeAnnotationKey is the annotation on ManifestWork recording when the current spec is
+	// applied by the rollout, which is the start of the rollout timeout of the cluster.
+	RolloutAppliedTimeAnnotationKey = "work.open-cluster-management.io/rollout-applied-time"
+
+	// ManifestWorkReplicaSetConditionRolloutProgressing is the condition of the rollout. It is true while the
+	// ManifestWorks are being rolled out, and false once the rollout is completed, paused or aborted.
+	ManifestWorkReplicaSetConditionRolloutProgressing = "RolloutProgressing"
+
+	ReasonRolloutProgressing     = "Progressing"
+	ReasonRolloutCompleted       = "Completed"
+	ReasonRolloutPaused          = "Paused"
+	ReasonRolloutAborted         = "Aborted"
+	ReasonRolloutInvalidStrategy = "InvalidStrategy"
+
+	// decisionGroupIndexLabel is the label on PlacementDecision set by the placement controller with the
+	// index of the decision group the decisions belong to.
+	decisionGroupIndexLabel = "cluster.open-cluster-management.io/decision-group-index"
+
+	// maxClustersInMessage is the max number of failed clusters listed in the rollout condition message
+	maxClustersInMessage = 10
+	// maxBatchesInMessage is the max number of batches listed in the rollout condition message
+	maxBatchesInMessage = 10
+)
+
+// RolloutType is the type of a rollout strategy.
+type RolloutType string
+
+const (
+	// RolloutAll updates all the clusters at once.
+	RolloutAll RolloutType = "All"
+	// RolloutRolling updates the clusters in batches of MaxConcurrency clusters ordered by name.
+	RolloutRolling RolloutType = "Rolling"
+	// RolloutProgressivePerGroup updates the clusters by the decision groups of the placements in the
+	// order of the group index. A group is split into batches of MaxConcurrency clusters if it is set.
+	RolloutProgressivePerGroup RolloutType = "ProgressivePerGroup"
+	// RolloutCanary updates the canary clusters first, then the other clusters in batches of MaxConcurrency
+	// clusters, or at once if MaxConcurrency is not set.
+	RolloutCanary RolloutType = "Canary"
+)
+
+// RolloutFailureAction is the action once the failed clusters exceed the MaxFailures.
+type RolloutFailureAction string
+
+const (
+	// RolloutFailurePause stops updating more clusters until the failed clusters recover.
+	RolloutFailurePause RolloutFailureAction = "Pause"
+	// RolloutFailureAbort stops updating more clusters until the template of the ManifestWorkReplicaSet changes.
+	RolloutFailureAbort RolloutFailureAction = "Abort"
+)
+
+// RolloutStrategy defines how the ManifestWorks are rolled out to the clusters. The next batch of clusters
+// is updated only after all the ManifestWorks of the previous batch succeed or fail.
+type RolloutStrategy struct {
+	// Type is the type of the rollout, defaults to All.
+	Type RolloutType `json:"type,omitempty"`
+	// MaxConcurrency is the number or percentage of the clusters in a batch. Defaults to 25% for Rolling.
+	MaxConcurrency *intstr.IntOrString `json:"maxConcurrency,omitempty"`
+	// CanaryClusters are the clusters updated first by the Canary rollout. Defaults to the first cluster
+	// ordered by name.
+	CanaryClusters []string `json:"canaryClusters,omitempty"`
+	// Timeout is the duration in which a ManifestWork should succeed after it is applied, otherwise the
+	// cluster fails. The rollout waits forever if it is not set.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// SuccessCondition is the condition type of the ManifestWork which should be True when it succeeds.
+	// Defaults to Available.
+	SuccessCondition string `json:"successCondition,omitempty"`
+	// SuccessFeedback is the name of a status feedback value which should be true on all the resources
+	// reporting it when the ManifestWork succeeds. It is checked in addition to the SuccessCondition.
+	SuccessFeedback string `json:"successFeedback,omitempty"`
+	// MaxFailures is the number or percentage of the clusters allowed to fail before the FailureAction is
+	// taken. Defaults to 0.
+	MaxFailures *intstr.IntOrString `json:"maxFailures,omitempty"`
+	// OnFailure is the action once the failed clusters exceed the MaxFailures, defaults to Pause.
+	OnFailure RolloutFailureAction `json:"onFailure,omitempty"`
+}
+
+// rolloutClock is the clock to check the rollout timeout.
+var rolloutClock = clock.Clock(clock.RealClock{})
+
+// getRolloutStrategy returns the rollout strategy of the ManifestWorkReplicaSet, or nil if it is not set.
+func getRolloutStrategy(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (*RolloutStrategy, error) {
+	value, ok := mwrSet.Annotations[RolloutStrategyAnnotationKey]
+	if !ok || len(value) == 0 {
+		return nil, nil
+	}
+
+	strategy := &RolloutStrategy{}
+	if err := json.Unmarshal([]byte(value), strategy); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %v", RolloutStrategyAnnotationKey, err)
+	}
+	switch strategy.Type {
+	case "":
+		strategy.Type = RolloutAll
+	case RolloutAll, RolloutRolling, RolloutProgressivePerGroup, RolloutCanary:
+	default:
+		return nil, fmt.Errorf("incorrect rollout type %q", strategy.Type)
+	}
+	switch strategy.OnFailure {
+	case "":
+		strategy.OnFailure = RolloutFailurePause
+	case RolloutFailurePause, RolloutFailureAbort:
+	default:
+		return nil, fmt.Errorf("incorrect rollout failure action %q", strategy.OnFailure)
+	}
+	if strategy.Type == RolloutRolling && strategy.MaxConcurrency == nil {
+		maxConcurrency := intstr.FromString("25%")
+		strategy.MaxConcurrency = &maxConcurrency
+	}
+	if len(strategy.SuccessCondition) == 0 {
+		strategy.SuccessCondition = workv1.WorkAvailable
+	}
+	if strategy.Timeout != nil && strategy.Timeout.Duration <= 0 {
+		return nil, fmt.Errorf("rollout timeout should be greater than 0")
+	}
+	if _, err := scaledValue(strategy.MaxConcurrency, 1, true); err != nil {
+		return nil, fmt.Errorf("incorrect maxConcurrency: %v", err)
+	}
+	if _, err := scaledValue(strategy.MaxFailures, 1, false); err != nil {
+		return nil, fmt.Errorf("incorrect maxFailures: %v", err)
+	}
+	return strategy, nil
+}
+
+// scaledValue returns the number of a number or percentage of the total.
+func scaledValue(value *intstr.IntOrString, total int, roundUp bool) (int, error) {
+	if value == nil {
+		return 0, nil
+	}
+	v, err := intstr.GetScaledValueFromIntOrPercent(value, total, roundUp)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("%s should not be negative", value.String())
+	}
+	return v, nil
+}
+
+// rolloutBatch is a batch of clusters updated together.
+type rolloutBatch struct {
+	name     string
+	clusters []string
+}
+
+// getRolloutBatches divides the clusters into the ordered batches of the strategy.
+func getRolloutBatches(
+	strategy *RolloutStrategy,
+	clusters sets.Set[string],
+	placements []*clusterv1beta1.Placement,
+	placeDecisionLister clusterlister.PlacementDecisionLister,
+) ([]rolloutBatch, error) {
+	names := sets.List(clusters)
+	// ignore the error since it is validated
+	batchSize, _ := scaledValue(strategy.MaxConcurrency, len(names), true)
+
+	switch strategy.Type {
+	case RolloutRolling:
+		return splitBatches("batch", names, batchSize), nil
+	case RolloutCanary:
+		canaries := sets.New[string](strategy.CanaryClusters...).Intersection(clusters)
+		if canaries.Len() == 0 && len(names) > 0 {
+			canaries.Insert(names[0])
+		}
+		batches := []rolloutBatch{{name: "canary", clusters: sets.List(canaries)}}
+		return append(batches, splitBatches("batch", sets.List(clusters.Difference(canaries)), batchSize)...), nil
+	case RolloutProgressivePerGroup:
+		groups, err := getDecisionGroups(clusters, placements, placeDecisionLister)
+		if err != nil {
+			return nil, err
+		}
+		indexes := []int{}
+		for index := range groups {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+
+		batches := []rolloutBatch{}
+		for _, index := range indexes {
+			batches = append(batches, splitBatches(fmt.Sprintf("group-%d", index), sets.List(groups[index]), batchSize)...)
+		}
+		return batches, nil
+	default:
+		return []rolloutBatch{{name: "all", clusters: names}}, nil
+	}
+}
+
+// splitBatches splits the clusters into batches of the size, all the clusters are in one batch if the
+// size is 0.
+func splitBatches(prefix string, clusters []string, size int) []rolloutBatch {
+	if size <= 0 || size >= len(clusters) {
+		return []rolloutBatch{{name: prefix, clusters: clusters}}
+	}
+	batches := []rolloutBatch{}
+	for i := 0; i < len(clusters); i += size {
+		end := i + size
+		if end > len(clusters) {
+			end = len(clusters)
+		}
+		batches = append(batches, rolloutBatch{
+			name:     fmt.Sprintf("%s-%d", prefix, len(batches)+1),
+			clusters: clusters[i:end],
+		})
+	}
+	return batches
+}
+
+// getDecisionGroups returns the clusters keyed by the index of the decision group. A cluster in multiple
+// placements belongs to the group with the smallest index, and a cluster in the PlacementDecisions without
+// the group label belongs to group 0.
+func getDecisionGroups(
+	clusters sets.Set[string],
+	placements []*clusterv1beta1.Placement,
+	placeDecisionLister clusterlister.PlacementDecisionLister,
+) (map[int]sets.Set[string], error) {
+	clusterGroups := map[string]int{}
+	for _, placement := range placements {
+		decisions, err := placeDecisionLister.PlacementDecisions(placement.Namespace).List(
+			labels.SelectorFromSet(labels.Set{clusterv1beta1.PlacementLabel: placement.Name}))
+		if err != nil {
+			return nil, err
+		}
+		for _, decision := range decisions {
+			index, err := strconv.Atoi(decision.Labels[decisionGroupIndexLabel])
+			if err != nil {
+				index = 0
+			}
+			for _, d := range decision.Status.Decisions {
+				if existing, ok := clusterGroups[d.ClusterName]; !ok || index < existing {
+					clusterGroups[d.ClusterName] = index
+				}
+			}
+		}
+	}
+
+	groups := map[int]sets.Set[string]{}
+	for cluster := range clusters {
+		index := clusterGroups[cluster]
+		if _, ok := groups[index]; !ok {
+			groups[index] = sets.New[string]()
+		}
+		groups[index].Insert(cluster)
+	}
+	return groups, nil
+}
+
+// clusterRolloutState is the rollout state of the ManifestWork on a cluster.
+type clusterRolloutState int
+
+const (
+	// clusterOutdated means the ManifestWork does not exist or is not updated with the template
+	clusterOutdated clusterRolloutState = iota
+	clusterProgressing
+	clusterSucceeded
+	clusterFailed
+)
+
+// getClusterRolloutState returns the rollout state of the ManifestWork, and the remaining duration before it
+// times out if it is progressing.
+func getClusterRolloutState(strategy *RolloutStrategy, required, existing *workv1.ManifestWork,
+) (clusterRolloutState, *time.Duration) {
+	if existing == nil || !existing.DeletionTimestamp.IsZero() || !manifestWorkUpdated(required, existing) {
+		return clusterOutdated, nil
+	}
+	if succeeded(strategy, existing) {
+		return clusterSucceeded, nil
+	}
+	if condition := apimeta.FindStatusCondition(existing.Status.Conditions, workv1.WorkDegraded); condition != nil &&
+		condition.Status == metav1.ConditionTrue && observedCurrentGeneration(condition, existing) {
+		return clusterFailed, nil
+	}
+	if strategy.Timeout == nil {
+		return clusterProgressing, nil
+	}
+
+	appliedTime := existing.CreationTimestamp.Time
+	if value, ok := existing.Annotations[RolloutAppliedTimeAnnotationKey]; ok {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			appliedTime = t
+		}
+	}
+	remaining := strategy.Timeout.Duration - rolloutClock.Since(appliedTime)
+	if remaining <= 0 {
+		return clusterFailed, nil
+	}
+	return clusterProgressing, &remaining
+}
+
+// manifestWorkUpdated returns true if the existing ManifestWork has the spec, labels and annotations of the
+// required one, the rollout applied time is ignored.
+func manifestWorkUpdated(required, existing *workv1.ManifestWork) bool {
+	required = required.DeepCopy()
+	delete(required.Annotations, RolloutAppliedTimeAnnotationKey)
+	return workapplier.ManifestWorkEqual(required, existing)
+}
+
+// succeeded returns true if the ManifestWork has the success condition of the current generation, and the
+// success feedback if it is required.
+func succeeded(strategy *RolloutStrategy, work *workv1.ManifestWork) bool {
+	condition := apimeta.FindStatusCondition(work.Status.Conditions, strategy.SuccessCondition)
+	if condition == nil || condition.Status != metav1.ConditionTrue || !observedCurrentGeneration(condition, work) {
+		return false
+	}
+	if len(strategy.SuccessFeedback) == 0 {
+		return true
+	}
+
+	found := false
+	for _, manifest := range work.Status.ResourceStatus.Manifests {
+		for _, value := range manifest.StatusFeedbacks.Values {
+			if value.Name != strategy.SuccessFeedback {
+				continue
+			}
+			found = true
+			switch {
+			case value.Value.Boolean != nil && *value.Value.Boolean:
+			case value.Value.String != nil && strings.EqualFold(*value.Value.String, "true"):
+			default:
+				return false
+			}
+		}
+	}
+	return found
+}
+
+// observedCurrentGeneration returns false if the condition is observed on a previous generation of the
+// ManifestWork. The condition without the observed generation is considered current.
+func observedCurrentGeneration(condition *metav1.Condition, work *workv1.ManifestWork) bool {
+	return condition.ObservedGeneration == 0 || condition.ObservedGeneration == work.Generation
+}
+
+// rolloutResult is the result of a rollout step.
+type rolloutResult struct {
+	// clustersToApply are the clusters whose ManifestWorks should be created or updated in this step
+	clustersToApply []string
+	// requeueAfter is the duration after which the earliest progressing cluster times out
+	requeueAfter *time.Duration
+	condition    metav1.Condition
+}
+
+// rollout decides the clusters to update in this step. The clusters of a batch are updated only if all the
+// clusters in the previous batches succeed or fail, and the failures do not exceed the max failures.
+func rollout(
+	mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	strategy *RolloutStrategy,
+	batches []rolloutBatch,
+	required map[string]*workv1.ManifestWork,
+	existing map[string]*workv1.ManifestWork,
+) rolloutResult {
+	result := rolloutResult{}
+	states := map[string]clusterRolloutState{}
+	total := 0
+	failed := []string{}
+	for _, batch := range batches {
+		for _, cluster := range batch.clusters {
+			state, remaining := getClusterRolloutState(strategy, required[cluster], existing[cluster])
+			states[cluster] = state
+			total++
+			if state == clusterFailed {
+				failed = append(failed, cluster)
+			}
+			if remaining != nil && (result.requeueAfter == nil || *remaining < *result.requeueAfter) {
+				result.requeueAfter = remaining
+			}
+		}
+	}
+
+	// ignore the error since it is validated
+	maxFailures, _ := scaledValue(strategy.MaxFailures, total, false)
+	exceeded := len(failed) > maxFailures
+	aborted := false
+	if strategy.OnFailure == RolloutFailureAbort {
+		previous := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRolloutProgressing)
+		aborted = exceeded || (previous != nil && previous.Reason == ReasonRolloutAborted &&
+			previous.ObservedGeneration == mwrSet.Generation)
+	}
+
+	current := -1
+	for i, batch := range batches {
+		done := true
+		for _, cluster := range batch.clusters {
+			switch states[cluster] {
+			case clusterOutdated:
+				if !exceeded && !aborted {
+					result.clustersToApply = append(result.clustersToApply, cluster)
+					states[cluster] = clusterProgressing
+					if strategy.Timeout != nil && (result.requeueAfter == nil || strategy.Timeout.Duration < *result.requeueAfter) {
+						result.requeueAfter = &strategy.Timeout.Duration
+					}
+				}
+				done = false
+			case clusterProgressing:
+				done = false
+			}
+		}
+		if !done {
+			current = i
+			break
+		}
+	}
+
+	progress := batchProgress(batches, states, current)
+	if len(failed) > 0 {
+		if len(failed) > maxClustersInMessage {
+			failed = append(failed[:maxClustersInMessage], fmt.Sprintf("and %d more", len(failed)-maxClustersInMessage))
+		}
+		progress = fmt.Sprintf("%s; failed clusters [%s]", progress, strings.Join(failed, ","))
+	}
+
+	switch {
+	case aborted:
+		result.condition = getRolloutCondition(ReasonRolloutAborted, metav1.ConditionFalse, fmt.Sprintf(
+			"Rollout is aborted, %d clusters failed exceeding max failures %d: %s", len(failed), maxFailures, progress))
+	case exceeded:
+		result.condition = getRolloutCondition(ReasonRolloutPaused, metav1.ConditionFalse, fmt.Sprintf(
+			"Rollout is paused, %d clusters failed exceeding max failures %d: %s", len(failed), maxFailures, progress))
+	case current >= 0:
+		result.condition = getRolloutCondition(ReasonRolloutProgressing, metav1.ConditionTrue, fmt.Sprintf(
+			"Rolling out batch %d of %d: %s", current+1, len(batches), progress))
+	default:
+		result.condition = getRolloutCondition(ReasonRolloutCompleted, metav1.ConditionFalse, fmt.Sprintf(
+			"Rollout is completed: %s", progress))
+	}
+	result.condition.ObservedGeneration = mwrSet.Generation
+	return result
+}
+
+// batchProgress returns the number of clusters in each state of the batches. At most maxBatchesInMessage
+// batches are listed starting from the current batch, and the others are summarized by their numbers.
+func batchProgress(batches []rolloutBatch, states map[string]clusterRolloutState, current int) string {
+	start, end := 0, len(batches)
+	if len(batches) > maxBatchesInMessage {
+		start = current
+		if start < 0 || start > len(batches)-maxBatchesInMessage {
+			start = len(batches) - maxBatchesInMessage
+		}
+		end = start + maxBatchesInMessage
+	}
+
+	progress := []string{}
+	if start > 0 {
+		progress = append(progress, fmt.Sprintf("%d previous batches", start))
+	}
+	for _, batch := range batches[start:end] {
+		counts := map[clusterRolloutState]int{}
+		for _, cluster := range batch.clusters {
+			counts[states[cluster]]++
+		}
+		progress = append(progress, fmt.Sprintf("%s %d/%d succeeded, %d progressing, %d failed, %d pending",
+			batch.name, counts[clusterSucceeded], len(batch.clusters), counts[clusterProgressing],
+			counts[clusterFailed], counts[clusterOutdated]))
+	}
+	if end < len(batches) {
+		progress = append(progress, fmt.Sprintf("and %d more", len(batches)-end))
+	}
+	return strings.Join(progress, "; ")
+}
+
+func getRolloutCondition(reason string, status metav1.ConditionStatus, message string) metav1.Condition {
+	return getCondition(ManifestWorkReplicaSetConditionRolloutProgressing, reason, message, status)
+}
+
+// requeueError is returned by a reconciler to reconcile the ManifestWorkReplicaSet again after a duration.
+type requeueError struct {
+	requeueAfter time.Duration
+	message      string
+}
+
+func (r *requeueError) Error() string {
+	return fmt.Sprintf("%s, requeue after %v", r.message, r.requeueAfter)
+}
+
+// roundUpSeconds rounds the duration up to seconds, so the requeued rollout is not checked right before the
+// timeout.
+func roundUpSeconds(d time.Duration) time.Duration {
+	return time.Duration(math.Ceil(d.Seconds())) * time.Second
+}
@@ -0,0 +1,626 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+
+	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+// newRolloutManifestWork returns the ManifestWork of the ManifestWorkReplicaSet on the cluster applied at the
+// given time, with the conditions of the given status.
+func newRolloutManifestWork(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, cluster string, appliedTime time.Time,
+	conditions map[string]metav1.ConditionStatus) *workv1.ManifestWork {
+	mw, _ := CreateManifestWork(mwrSet, cluster)
+	mw.Annotations[RolloutAppliedTimeAnnotationKey] = appliedTime.UTC().Format(time.RFC3339)
+	for conditionType, status := range conditions {
+		apimeta.SetStatusCondition(&mw.Status.Conditions, metav1.Condition{
+			Type:   conditionType,
+			Status: status,
+			Reason: "Test",
+		})
+	}
+	return mw
+}
+
+func TestGetRolloutStrategy(t *testing.T) {
+	twentyFivePercent := intstr.FromString("25%")
+	two := intstr.FromInt(2)
+
+	cases := []struct {
+		name             string
+		annotation       string
+		expectedStrategy *RolloutStrategy
+		expectedErr      bool
+	}{
+		{
+			name: "no strategy",
+		},
+		{
+			name:       "default values",
+			annotation: `{}`,
+			expectedStrategy: &RolloutStrategy{
+				Type:             RolloutAll,
+				SuccessCondition: workv1.WorkAvailable,
+				OnFailure:        RolloutFailurePause,
+			},
+		},
+		{
+			name:       "rolling with default max concurrency",
+			annotation: `{"type":"Rolling","timeout":"5m","onFailure":"Abort"}`,
+			expectedStrategy: &RolloutStrategy{
+				Type:             RolloutRolling,
+				MaxConcurrency:   &twentyFivePercent,
+				Timeout:          &metav1.Duration{Duration: 5 * time.Minute},
+				SuccessCondition: workv1.WorkAvailable,
+				OnFailure:        RolloutFailureAbort,
+			},
+		},
+		{
+			name:       "canary",
+			annotation: `{"type":"Canary","canaryClusters":["cluster1"],"maxFailures":2,"successCondition":"Ready"}`,
+			expectedStrategy: &RolloutStrategy{
+				Type:             RolloutCanary,
+				CanaryClusters:   []string{"cluster1"},
+				MaxFailures:      &two,
+				SuccessCondition: "Ready",
+				OnFailure:        RolloutFailurePause,
+			},
+		},
+		{
+			name:        "invalid json",
+			annotation:  `{"type":`,
+			expectedErr: true,
+		},
+		{
+			name:        "unknown type",
+			annotation:  `{"type":"BlueGreen"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "unknown failure action",
+			annotation:  `{"onFailure":"Rollback"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "negative timeout",
+			annotation:  `{"timeout":"-1m"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid max concurrency",
+			annotation:  `{"type":"Rolling","maxConcurrency":"half"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "negative max failures",
+			annotation:  `{"maxFailures":-1}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement",
+				map[string]string{RolloutStrategyAnnotationKey: c.annotation})
+			strategy, err := getRolloutStrategy(mwrSet)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(strategy, c.expectedStrategy) {
+				t.Errorf("expected strategy %+v, but got %+v", c.expectedStrategy, strategy)
+			}
+		})
+	}
+}
+
+func TestGetRolloutBatches(t *testing.T) {
+	placement, decision := helpertest.CreateTestPlacement("placement", "default", "cluster1", "cluster2", "cluster3")
+	decision.Labels[decisionGroupIndexLabel] = "1"
+	_, decision0 := helpertest.CreateTestPlacement("placement", "default", "cluster4", "cluster5", "cluster1")
+	decision0.Name = "placement-decision-0"
+	decision0.Labels[decisionGroupIndexLabel] = "0"
+
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(fakeclusterclient.NewSimpleClientset(), 0)
+	for _, d := range []*clusterv1beta1.PlacementDecision{decision, decision0} {
+		if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	two := intstr.FromInt(2)
+	fortyPercent := intstr.FromString("40%")
+	clusters := sets.New[string]("cluster1", "cluster2", "cluster3", "cluster4", "cluster5")
+
+	cases := []struct {
+		name            string
+		strategy        *RolloutStrategy
+		expectedBatches []rolloutBatch
+	}{
+		{
+			name:     "all",
+			strategy: &RolloutStrategy{Type: RolloutAll},
+			expectedBatches: []rolloutBatch{
+				{name: "all", clusters: []string{"cluster1", "cluster2", "cluster3", "cluster4", "cluster5"}},
+			},
+		},
+		{
+			name:     "rolling",
+			strategy: &RolloutStrategy{Type: RolloutRolling, MaxConcurrency: &fortyPercent},
+			expectedBatches: []rolloutBatch{
+				{name: "batch-1", clusters: []string{"cluster1", "cluster2"}},
+				{name: "batch-2", clusters: []string{"cluster3", "cluster4"}},
+				{name: "batch-3", clusters: []string{"cluster5"}},
+			},
+		},
+		{
+			name:     "canary with default canary cluster",
+			strategy: &RolloutStrategy{Type: RolloutCanary},
+			expectedBatches: []rolloutBatch{
+				{name: "canary", clusters: []string{"cluster1"}},
+				{name: "batch", clusters: []string{"cluster2", "cluster3", "cluster4", "cluster5"}},
+			},
+		},
+		{
+			name: "canary with max concurrency",
+			strategy: &RolloutStrategy{
+				Type:           RolloutCanary,
+				CanaryClusters: []string{"cluster3", "cluster6"},
+				MaxConcurrency: &two,
+			},
+			expectedBatches: []rolloutBatch{
+				{name: "canary", clusters: []string{"cluster3"}},
+				{name: "batch-1", clusters: []string{"cluster1", "cluster2"}},
+				{name: "batch-2", clusters: []string{"cluster4", "cluster5"}},
+			},
+		},
+		{
+			name:     "progressive per group",
+			strategy: &RolloutStrategy{Type: RolloutProgressivePerGroup},
+			expectedBatches: []rolloutBatch{
+				{name: "group-0", clusters: []string{"cluster1", "cluster4", "cluster5"}},
+				{name: "group-1", clusters: []string{"cluster2", "cluster3"}},
+			},
+		},
+		{
+			name:     "progressive per group with max concurrency",
+			strategy: &RolloutStrategy{Type: RolloutProgressivePerGroup, MaxConcurrency: &two},
+			expectedBatches: []rolloutBatch{
+				{name: "group-0-1", clusters: []string{"cluster1", "cluster4"}},
+				{name: "group-0-2", clusters: []string{"cluster5"}},
+				{name: "group-1", clusters: []string{"cluster2", "cluster3"}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			batches, err := getRolloutBatches(c.strategy, clusters, []*clusterv1beta1.Placement{placement},
+				clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(batches, c.expectedBatches) {
+				t.Errorf("expected batches %v, but got %v", c.expectedBatches, batches)
+			}
+		})
+	}
+}
+
+func TestRollout(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	rolloutClock = fakeClock
+	defer func() { rolloutClock = clock.RealClock{} }()
+	now := fakeClock.Now()
+
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", nil)
+	batches := []rolloutBatch{
+		{name: "batch-1", clusters: []string{"cluster1", "cluster2"}},
+		{name: "batch-2", clusters: []string{"cluster3", "cluster4"}},
+	}
+	required := map[string]*workv1.ManifestWork{}
+	for _, batch := range batches {
+		for _, cluster := range batch.clusters {
+			required[cluster], _ = CreateManifestWork(mwrSet, cluster)
+		}
+	}
+
+	available := map[string]metav1.ConditionStatus{workv1.WorkAvailable: metav1.ConditionTrue}
+	degraded := map[string]metav1.ConditionStatus{workv1.WorkDegraded: metav1.ConditionTrue}
+	progressing := map[string]metav1.ConditionStatus{workv1.WorkAvailable: metav1.ConditionFalse}
+	one := intstr.FromInt(1)
+	timeout := &metav1.Duration{Duration: 10 * time.Minute}
+
+	cases := []struct {
+		name                    string
+		strategy                *RolloutStrategy
+		existing                []*workv1.ManifestWork
+		previousCondition       *metav1.Condition
+		expectedClustersToApply []string
+		expectedRequeueAfter    *time.Duration
+		expectedReason          string
+		expectedMessage         string
+	}{
+		{
+			name:                    "start the first batch",
+			strategy:                &RolloutStrategy{Timeout: timeout},
+			expectedClustersToApply: []string{"cluster1", "cluster2"},
+			expectedRequeueAfter:    &timeout.Duration,
+			expectedReason:          ReasonRolloutProgressing,
+			expectedMessage: "Rolling out batch 1 of 2: batch-1 0/2 succeeded, 2 progressing, 0 failed, 0 pending; " +
+				"batch-2 0/2 succeeded, 0 progressing, 0 failed, 2 pending",
+		},
+		{
+			name:     "wait for the first batch",
+			strategy: &RolloutStrategy{Timeout: timeout},
+			existing: []*workv1.ManifestWork{
+				newRolloutManifestWork(mwrSet, "cluster1", now.Add(-time.Minute), available),
+				newRolloutManifestWork(mwrSet, "cluster2", now.Add(-4*time.Minute), progressing),
+			},
+			expectedRequeueAfter: pointer.Duration(6 * time.Minute),
+			expectedReason:       ReasonRolloutProgressing,
+			expectedMessage: "Rolling out batch 1 of 2: batch-1 1/2 succeeded, 1 progressing, 0 failed, 0 pending; " +
+				"batch-2 0/2 succeeded, 0 progressing, 0 failed, 2 pending",
+		},
+		{
+			name:     "start the next batch",
+			strategy: &RolloutStrategy{},
+			existing: []*workv1.ManifestWork{
+				newRolloutManifestWork(mwrSet, "cluster1", now, available),
+				newRolloutManifestWork(mwrSet, "cluster2", now, available),
+			},
+			expectedClustersToApply: []string{"cluster3", "cluster4"},
+			expectedReason:          ReasonRolloutProgressing,
+			expectedMessage: "Rolling out batch 2 of 2: batch-1 2/2 succeeded, 0 progressing, 0 failed, 0 pending; " +
+				"batch-2 0/2 succeeded, 2 progressing, 0 failed, 0 pending",
+		},
+		{
+			name:     "continue with failures not exceeding max failures",
+			strategy: &RolloutStrategy{Timeout: timeout, MaxFailures: &one},
+			existing: []*workv1.ManifestWork{
+				newRolloutManifestWork(mwrSet, "cluster1", now, available),
+				newRolloutManifestWork(mwrSet, "cluster2", now.Add(-time.Hour), progressing),
+			},
+			expectedClustersToApply: []string{"cluster3", "cluster4"},
+			expectedRequeueAfter:    &timeout.Duration,
+			expectedReason:          ReasonRolloutProgressing,
+			expectedMessage: "Rolling out batch 2 of 2: batch-1 1/2 succeeded, 0 progressing, 1 failed, 0 pending; " +
+				"batch-2 0/2 succeeded, 2 progressing, 0 failed, 0 pending; failed clusters [cluster2]",
+		},
+		{
+			name:     "pause with failures exceeding max failures",
+			strategy: &RolloutStrategy{OnFailure: RolloutFailurePause},
+			existing: []*workv1.ManifestWork{
+				newRolloutManifestWork(mwrSet, "cluster1", now, available),
+				newRolloutManifestWork(mwrSet, "cluster2", now, degraded),
+			},
+			expectedReason: ReasonRolloutPaused,
+			expectedMessage: "Rollout is paused, 1 clusters failed exceeding max failures 0: " +
+				"batch-1 1/2 succeeded, 0 progressing, 1 failed, 0 pending; " +
+				"batch-2 0/2 succeeded, 0 progressing, 0 failed, 2 pending; failed clusters [cluster2]",
+		},
+		{
+			name:     "abort with failures exceeding max failures",
+			strategy: &RolloutStrategy{OnFailure: RolloutFailureAbort},
+			existing: []*workv1.ManifestWork{
+				newRolloutManifestWork(mwrSet, "cluster1", now, available),
+				newRolloutManifestWork(mwrSet, "cluster2", now, degraded),
+			},
+			expectedReason: ReasonRolloutAborted,
+			expectedMessage: "Rollout is aborted, 1 clusters failed exceeding max failures 0: " +
+				"batch-1 1/2 succeeded, 0 progressing, 1 failed, 0 pending; " +
+				"batch-2 0/2 succeeded, 0 progressing, 0 failed, 2 pending; failed clusters [cluster2]",
+		},
+		{
+			name:     "keep aborted after the failed cluster recovers",
+			strategy: &RolloutStrategy{OnFailure: RolloutFailureAbort},
+			existing: []*workv1.ManifestWork{
+				newRolloutManifestWork(mwrSet, "cluster1", now, available),
+				newRolloutManifestWork(mwrSet, "cluster2", now, available),
+			},
+			previousCondition: &metav1.Condition{
+				Type:               ManifestWorkReplicaSetConditionRolloutProgressing,
+				Reason:             ReasonRolloutAborted,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: 1,
+			},
+			expectedReason: ReasonRolloutAborted,
+		},
+		{
+			name:     "restart the aborted rollout with a new generation",
+			strategy: &RolloutStrategy{OnFailure: RolloutFailureAbort},
+			existing: []*workv1.ManifestWork{
+				newRolloutManifestWork(mwrSet, "cluster1", now, available),
+				newRolloutManifestWork(mwrSet, "cluster2", now, available),
+			},
+			previousCondition: &metav1.Condition{
+				Type:               ManifestWorkReplicaSetConditionRolloutProgressing,
+				Reason:             ReasonRolloutAborted,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: 0,
+			},
+			expectedClustersToApply: []string{"cluster3", "cluster4"},
+			expectedReason:          ReasonRolloutProgressing,
+		},
+		{
+			name:     "complete",
+			strategy: &RolloutStrategy{},
+			existing: []*workv1.ManifestWork{
+				newRolloutManifestWork(mwrSet, "cluster1", now, available),
+				newRolloutManifestWork(mwrSet, "cluster2", now, available),
+				newRolloutManifestWork(mwrSet, "cluster3", now, available),
+				newRolloutManifestWork(mwrSet, "cluster4", now, available),
+			},
+			expectedReason: ReasonRolloutCompleted,
+			expectedMessage: "Rollout is completed: batch-1 2/2 succeeded, 0 progressing, 0 failed, 0 pending; " +
+				"batch-2 2/2 succeeded, 0 progressing, 0 failed, 0 pending",
+		},
+		{
+			name:     "update the clusters with an outdated spec in order",
+			strategy: &RolloutStrategy{},
+			existing: func() []*workv1.ManifestWork {
+				outdated := newRolloutManifestWork(mwrSet, "cluster1", now, available)
+				outdated.Spec.DeleteOption = &workv1.DeleteOption{PropagationPolicy: workv1.DeletePropagationPolicyTypeOrphan}
+				return []*workv1.ManifestWork{
+					outdated,
+					newRolloutManifestWork(mwrSet, "cluster2", now, available),
+					newRolloutManifestWork(mwrSet, "cluster3", now, available),
+				}
+			}(),
+			expectedClustersToApply: []string{"cluster1"},
+			expectedReason:          ReasonRolloutProgressing,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := mwrSet.DeepCopy()
+			if c.previousCondition != nil {
+				apimeta.SetStatusCondition(&mwrSet.Status.Conditions, *c.previousCondition)
+			}
+			if len(c.strategy.OnFailure) == 0 {
+				c.strategy.OnFailure = RolloutFailurePause
+			}
+			if len(c.strategy.SuccessCondition) == 0 {
+				c.strategy.SuccessCondition = workv1.WorkAvailable
+			}
+			existing := map[string]*workv1.ManifestWork{}
+			for _, mw := range c.existing {
+				existing[mw.Namespace] = mw
+			}
+
+			result := rollout(mwrSet, c.strategy, batches, required, existing)
+			if !reflect.DeepEqual(result.clustersToApply, c.expectedClustersToApply) {
+				t.Errorf("expected clusters to apply %v, but got %v", c.expectedClustersToApply, result.clustersToApply)
+			}
+			if !reflect.DeepEqual(result.requeueAfter, c.expectedRequeueAfter) {
+				t.Errorf("expected requeue after %v, but got %v", c.expectedRequeueAfter, result.requeueAfter)
+			}
+			if result.condition.Reason != c.expectedReason {
+				t.Errorf("expected reason %q, but got %q: %s", c.expectedReason, result.condition.Reason, result.condition.Message)
+			}
+			if len(c.expectedMessage) > 0 && result.condition.Message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, result.condition.Message)
+			}
+			if result.condition.ObservedGeneration != mwrSet.Generation {
+				t.Errorf("expected observed generation %d, but got %d", mwrSet.Generation, result.condition.ObservedGeneration)
+			}
+		})
+	}
+}
+
+func TestBatchProgress(t *testing.T) {
+	batches := []rolloutBatch{}
+	states := map[string]clusterRolloutState{}
+	for i := 0; i < 1000; i++ {
+		cluster := fmt.Sprintf("cluster%d", i)
+		batches = append(batches, rolloutBatch{name: fmt.Sprintf("batch-%d", i+1), clusters: []string{cluster}})
+		switch {
+		case i < 500:
+			states[cluster] = clusterSucceeded
+		case i == 500:
+			states[cluster] = clusterProgressing
+		default:
+			states[cluster] = clusterOutdated
+		}
+	}
+
+	cases := []struct {
+		name           string
+		current        int
+		expectedPrefix string
+		expectedSuffix string
+	}{
+		{
+			name:           "first batch",
+			current:        0,
+			expectedPrefix: "batch-1 1/1 succeeded",
+			expectedSuffix: "batch-10 1/1 succeeded, 0 progressing, 0 failed, 0 pending; and 990 more",
+		},
+		{
+			name:           "current batch",
+			current:        500,
+			expectedPrefix: "500 previous batches; batch-501 0/1 succeeded, 1 progressing",
+			expectedSuffix: "batch-510 0/1 succeeded, 0 progressing, 0 failed, 1 pending; and 490 more",
+		},
+		{
+			name:           "no current batch",
+			current:        -1,
+			expectedPrefix: "990 previous batches; batch-991 0/1 succeeded",
+			expectedSuffix: "batch-1000 0/1 succeeded, 0 progressing, 0 failed, 1 pending",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			progress := batchProgress(batches, states, c.current)
+			if !strings.HasPrefix(progress, c.expectedPrefix) || !strings.HasSuffix(progress, c.expectedSuffix) {
+				t.Errorf("expected progress starts with %q and ends with %q, but got %q", c.expectedPrefix, c.expectedSuffix, progress)
+			}
+			if segments := strings.Split(progress, "; "); len(segments) > maxBatchesInMessage+2 {
+				t.Errorf("expected at most %d batches listed, but got %d", maxBatchesInMessage, len(segments))
+			}
+		})
+	}
+}
+
+func TestSucceeded(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement", nil)
+	available := map[string]metav1.ConditionStatus{workv1.WorkAvailable: metav1.ConditionTrue}
+
+	withFeedback := func(values ...workv1.FeedbackValue) *workv1.ManifestWork {
+		mw := newRolloutManifestWork(mwrSet, "cluster1", time.Now(), available)
+		for _, value := range values {
+			mw.Status.ResourceStatus.Manifests = append(mw.Status.ResourceStatus.Manifests, workv1.ManifestCondition{
+				StatusFeedbacks: workv1.StatusFeedbackResult{Values: []workv1.FeedbackValue{value}},
+			})
+		}
+		return mw
+	}
+	boolValue := func(name string, value bool) workv1.FeedbackValue {
+		return workv1.FeedbackValue{Name: name, Value: workv1.FieldValue{Type: workv1.Boolean, Boolean: &value}}
+	}
+	stringValue := func(name string, value string) workv1.FeedbackValue {
+		return workv1.FeedbackValue{Name: name, Value: workv1.FieldValue{Type: workv1.String, String: &value}}
+	}
+
+	cases := []struct {
+		name     string
+		strategy *RolloutStrategy
+		work     *workv1.ManifestWork
+		expected bool
+	}{
+		{
+			name:     "available",
+			strategy: &RolloutStrategy{SuccessCondition: workv1.WorkAvailable},
+			work:     withFeedback(),
+			expected: true,
+		},
+		{
+			name:     "condition of the previous generation",
+			strategy: &RolloutStrategy{SuccessCondition: workv1.WorkAvailable},
+			work: func() *workv1.ManifestWork {
+				mw := withFeedback()
+				mw.Generation = 2
+				mw.Status.Conditions[0].ObservedGeneration = 1
+				return mw
+			}(),
+		},
+		{
+			name:     "custom condition not found",
+			strategy: &RolloutStrategy{SuccessCondition: "Ready"},
+			work:     withFeedback(),
+		},
+		{
+			name:     "feedback not found",
+			strategy: &RolloutStrategy{SuccessCondition: workv1.WorkAvailable, SuccessFeedback: "healthy"},
+			work:     withFeedback(boolValue("ready", true)),
+		},
+		{
+			name:     "feedback true",
+			strategy: &RolloutStrategy{SuccessCondition: workv1.WorkAvailable, SuccessFeedback: "healthy"},
+			work:     withFeedback(boolValue("healthy", true), stringValue("healthy", "True")),
+			expected: true,
+		},
+		{
+			name:     "feedback false on one resource",
+			strategy: &RolloutStrategy{SuccessCondition: workv1.WorkAvailable, SuccessFeedback: "healthy"},
+			work:     withFeedback(boolValue("healthy", true), stringValue("healthy", "false")),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := succeeded(c.strategy, c.work); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDeployReconcileWithRolloutStrategy(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSetWithAnnotations("test", "default", "placement",
+		map[string]string{RolloutStrategyAnnotationKey: `{"type":"Rolling","maxConcurrency":2,"timeout":"90s"}`})
+	fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet)
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fWorkClient, 1*time.Second)
+	mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+	placement, placementDecision := helpertest.CreateTestPlacement("placement", "default", "cls1", "cls2", "cls3")
+	fClusterClient := fakeclusterclient.NewSimpleClientset(placement, placementDecision)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactoryWithOptions(fClusterClient, 1*time.Second)
+	if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+
+	pmwDeployController := deployReconciler{
+		workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+		manifestWorkLister:  mwLister,
+		placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+		placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+	}
+
+	mwrSet, _, err := pmwDeployController.reconcile(context.TODO(), mwrSet)
+	rqe, ok := err.(*requeueError)
+	if !ok {
+		t.Fatalf("expected requeue error, but got %v", err)
+	}
+	if rqe.requeueAfter != 90*time.Second {
+		t.Errorf("expected requeue after 90s, but got %v", rqe.requeueAfter)
+	}
+
+	works, err := fWorkClient.WorkV1().ManifestWorks("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusters := []string{}
+	for _, mw := range works.Items {
+		clusters = append(clusters, mw.Namespace)
+		if _, ok := mw.Annotations[RolloutAppliedTimeAnnotationKey]; !ok {
+			t.Errorf("expected applied time annotation on manifestwork %s/%s", mw.Namespace, mw.Name)
+		}
+	}
+	if !reflect.DeepEqual(sets.New[string](clusters...), sets.New[string]("cls1", "cls2")) {
+		t.Errorf("expected manifestworks created on the first batch, but got %v", clusters)
+	}
+
+	condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRolloutProgressing)
+	if condition == nil || condition.Reason != ReasonRolloutProgressing {
+		t.Fatalf("expected rollout progressing condition, but got %v", mwrSet.Status.Conditions)
+	}
+	if !strings.HasPrefix(condition.Message, "Rolling out batch 1 of 2") {
+		t.Errorf("unexpected condition message %q", condition.Message)
+	}
+	if mwrSet.Status.Summary.Total != 3 {
+		t.Errorf("expected total 3, but got %d", mwrSet.Status.Summary.Total)
+	}
+}
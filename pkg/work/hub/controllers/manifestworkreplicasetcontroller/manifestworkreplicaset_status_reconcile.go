@@ -25,6 +25,7 @@ func (d *statusReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 		} else {
 			apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetManifestworkApplied(workapiv1alpha1.ReasonNotAsExpected, ""))
 		}
+		setFeedbackConditions(mwrSet, nil)
 
 		return mwrSet, reconcileContinue, nil
 	}
@@ -62,6 +63,7 @@ func (d *statusReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 	mwrSet.Status.Summary.Degraded = degradCount
 	mwrSet.Status.Summary.Progressing = processingCount
 	mwrSet.Status.Summary.Applied = appliedCount
+	setFeedbackConditions(mwrSet, manifestWorks)
 
 	if mwrSet.Status.Summary.Available == mwrSet.Status.Summary.Total &&
 		mwrSet.Status.Summary.Progressing == 0 && mwrSet.Status.Summary.Degraded == 0 {
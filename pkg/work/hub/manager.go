@@ -6,7 +6,11 @@ import (
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 
+	addonclientset "open-cluster-management.io/api/client/addon/clientset/versioned"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
 	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
@@ -27,37 +31,57 @@ func RunWorkHubManager(ctx context.Context, controllerContext *controllercmd.Con
 		return err
 	}
 
+	hubKubeClient, err := kubernetes.NewForConfig(controllerContext.KubeConfig)
+	if err != nil {
+		return err
+	}
+
+	hubAddOnClient, err := addonclientset.NewForConfig(controllerContext.KubeConfig)
+	if err != nil {
+		return err
+	}
+
 	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(hubClusterClient, 30*time.Minute)
+	addOnInformerFactory := addoninformers.NewSharedInformerFactory(hubAddOnClient, 30*time.Minute)
 	workInformerFactory := workinformers.NewSharedInformerFactory(hubWorkClient, 30*time.Minute)
 
-	// we need a separated filtered manifestwork informers so we only watch the manifestworks that manifestworkreplicaset cares.
-	// This could reduce a lot of memory consumptions
-	manifestWorkInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(hubWorkClient, 30*time.Minute, workinformers.WithTweakListOptions(
-		func(listOptions *metav1.ListOptions) {
-			selector := &metav1.LabelSelector{
-				MatchExpressions: []metav1.LabelSelectorRequirement{
-					{
-						Key:      manifestworkreplicasetcontroller.ManifestWorkReplicaSetControllerNameLabelKey,
-						Operator: metav1.LabelSelectorOpExists,
-					},
+	// we need separated filtered manifestwork and controllerrevision informers so we only watch the resources that
+	// manifestworkreplicaset cares. This could reduce a lot of memory consumptions
+	tweakListOptions := func(listOptions *metav1.ListOptions) {
+		selector := &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{
+					Key:      manifestworkreplicasetcontroller.ManifestWorkReplicaSetControllerNameLabelKey,
+					Operator: metav1.LabelSelectorOpExists,
 				},
-			}
-			listOptions.LabelSelector = metav1.FormatLabelSelector(selector)
-		},
-	))
+			},
+		}
+		listOptions.LabelSelector = metav1.FormatLabelSelector(selector)
+	}
+	manifestWorkInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(hubWorkClient, 30*time.Minute,
+		workinformers.WithTweakListOptions(tweakListOptions))
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(hubKubeClient, 30*time.Minute,
+		kubeinformers.WithTweakListOptions(tweakListOptions))
 
 	manifestWorkReplicaSetController := manifestworkreplicasetcontroller.NewManifestWorkReplicaSetController(
 		controllerContext.EventRecorder,
 		hubWorkClient,
+		hubKubeClient,
 		workInformerFactory.Work().V1alpha1().ManifestWorkReplicaSets(),
 		manifestWorkInformerFactory.Work().V1().ManifestWorks(),
 		clusterInformerFactory.Cluster().V1beta1().Placements(),
 		clusterInformerFactory.Cluster().V1beta1().PlacementDecisions(),
+		clusterInformerFactory.Cluster().V1().ManagedClusters(),
+		addOnInformerFactory.Addon().V1alpha1().ManagedClusterAddOns(),
+		addOnInformerFactory.Addon().V1alpha1().AddOnDeploymentConfigs(),
+		kubeInformerFactory.Apps().V1().ControllerRevisions(),
 	)
 
 	go clusterInformerFactory.Start(ctx.Done())
+	go addOnInformerFactory.Start(ctx.Done())
 	go workInformerFactory.Start(ctx.Done())
 	go manifestWorkInformerFactory.Start(ctx.Done())
+	go kubeInformerFactory.Start(ctx.Done())
 	go manifestWorkReplicaSetController.Run(ctx, 5)
 
 	<-ctx.Done()
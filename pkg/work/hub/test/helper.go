@@ -34,6 +34,15 @@ func CreateTestManifestWorkReplicaSet(name string, ns string, placementName stri
 	return mwrs
 }
 
+// CreateTestManifestWorkReplicaSetWithAnnotations returns a created ManifestWorkReplicaSet with the annotations
+func CreateTestManifestWorkReplicaSetWithAnnotations(name string, ns string, placementName string,
+	annotations map[string]string) *workapiv1alpha1.ManifestWorkReplicaSet {
+	mwrs := CreateTestManifestWorkReplicaSet(name, ns, placementName)
+	mwrs.Generation = 1
+	mwrs.Annotations = annotations
+	return mwrs
+}
+
 func CreateTestManifestWorks(name, namespace string, clusters ...string) []runtime.Object {
 	obj := spoketesting.NewUnstructured("v1", "kind", "test-ns", "test-name")
 	works := []runtime.Object{}
@@ -57,6 +66,14 @@ func CreateTestManifestWorks(name, namespace string, clusters ...string) []runti
 	return works
 }
 
+// CreateTestManifestWorkWithAnnotations returns the ManifestWork of the ManifestWorkReplicaSet on the cluster
+// with the annotations
+func CreateTestManifestWorkWithAnnotations(name, namespace, cluster string, annotations map[string]string) *workapiv1.ManifestWork {
+	mw := CreateTestManifestWorks(name, namespace, cluster)[0].(*workapiv1.ManifestWork)
+	mw.Annotations = annotations
+	return mw
+}
+
 // Return placement with predicate of label cluster name
 func CreateTestPlacement(name string, ns string, clusters ...string) (*clusterv1beta1.Placement, *clusterv1beta1.PlacementDecision) {
 	namereq := metav1.LabelSelectorRequirement{}
@@ -33,6 +33,7 @@ import (
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/statusfeedback"
 )
 
 var (
@@ -54,6 +55,7 @@ type ManifestWorkController struct {
 	restMapper                 meta.RESTMapper
 	appliers                   *apply.Appliers
 	validator                  auth.ExecutorValidator
+	statusReader               *statusfeedback.StatusReader
 }
 
 type applyResult struct {
@@ -61,6 +63,8 @@ type applyResult struct {
 	Error  error
 
 	resourceMeta workapiv1.ManifestResourceMeta
+	// pending is the message why the manifest is not applied yet when the manifests are applied in waves.
+	pending string
 }
 
 // NewManifestWorkController returns a ManifestWorkController
@@ -94,6 +98,7 @@ func NewManifestWorkController(
 		restMapper:                restMapper,
 		appliers:                  apply.NewAppliers(spokeDynamicClient, spokeKubeClient, spokeAPIExtensionClient),
 		validator:                 validator,
+		statusReader:              statusfeedback.NewStatusReader(),
 	}
 
 	return factory.New().
@@ -145,9 +150,17 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 	errs := []error{}
 	// Apply resources on spoke cluster.
 	resourceResults := make([]applyResult, len(manifestWork.Spec.Workload.Manifests))
+	var progressingCondition *metav1.Condition
 	err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-		resourceResults = m.applyManifests(
-			ctx, manifestWork.Spec.Workload.Manifests, manifestWork.Spec, controllerContext.Recorder(), *owner, resourceResults)
+		if applyWavesEnabled(manifestWork) {
+			var condition metav1.Condition
+			resourceResults, condition = m.applyManifestsInWaves(
+				ctx, manifestWork, controllerContext.Recorder(), *owner, resourceResults)
+			progressingCondition = &condition
+		} else {
+			resourceResults = m.applyManifests(
+				ctx, manifestWork.Spec.Workload.Manifests, manifestWork.Spec, controllerContext.Recorder(), *owner, resourceResults)
+		}
 
 		for _, result := range resourceResults {
 			if apierrors.IsConflict(result.Error) {
@@ -163,6 +176,7 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 
 	newManifestConditions := []workapiv1.ManifestCondition{}
 	var requeueTime = MaxRequeueDuration
+	pending, failed := false, false
 	for _, result := range resourceResults {
 		manifestCondition := workapiv1.ManifestCondition{
 			ResourceMeta: result.resourceMeta,
@@ -174,6 +188,13 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 
 		newManifestConditions = append(newManifestConditions, manifestCondition)
 
+		if len(result.pending) > 0 {
+			pending = true
+		}
+		if result.Error != nil {
+			failed = true
+		}
+
 		// If it is a forbidden error, after the condition is constructed, we set the error to nil
 		// and requeue the item
 		var authError *basic.NotAllowedError
@@ -204,14 +225,29 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 			Reason:             "AppliedManifestWorkFailed",
 			Message:            "Failed to apply manifest work",
 		}
-		if inCondition {
+		switch {
+		case inCondition:
 			appliedCondition.Status = metav1.ConditionTrue
 			appliedCondition.Reason = "AppliedManifestWorkComplete"
 			appliedCondition.Message = "Apply manifest work complete"
+		case pending && !failed:
+			appliedCondition.Reason = "AppliedManifestWorkInProgress"
+			appliedCondition.Message = "Apply manifest work in progress, waiting for the previous waves to be ready"
 		}
 		meta.SetStatusCondition(&manifestWork.Status.Conditions, appliedCondition)
 	}
 
+	// handle condition type Progressing if the manifests are applied in waves, and requeue the work to check
+	// the readiness of the resources again if a wave is not ready.
+	if progressingCondition != nil {
+		meta.SetStatusCondition(&manifestWork.Status.Conditions, *progressingCondition)
+		if progressingCondition.Status == metav1.ConditionTrue && WaveRequeueInterval < requeueTime {
+			requeueTime = WaveRequeueInterval
+		}
+	} else {
+		removeProgressingCondition(manifestWork)
+	}
+
 	// Update work status
 	updated, err := m.manifestWorkPatcher.PatchStatus(ctx, manifestWork, manifestWork.Status, oldManifestWork.Status)
 	if err != nil {
@@ -368,6 +404,15 @@ func allInCondition(conditionType string, manifests []workapiv1.ManifestConditio
 }
 
 func buildAppliedStatusCondition(result applyResult) metav1.Condition {
+	if len(result.pending) > 0 {
+		return metav1.Condition{
+			Type:    string(workapiv1.ManifestApplied),
+			Status:  metav1.ConditionFalse,
+			Reason:  "AppliedManifestPending",
+			Message: result.pending,
+		}
+	}
+
 	if result.Error != nil {
 		return metav1.Condition{
 			Type:    string(workapiv1.ManifestApplied),
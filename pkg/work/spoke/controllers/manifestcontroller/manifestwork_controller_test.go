@@ -31,6 +31,7 @@ import (
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
+	"open-cluster-management.io/ocm/pkg/work/spoke/statusfeedback"
 )
 
 type testController struct {
@@ -56,6 +57,7 @@ func newController(t *testing.T, work *workapiv1.ManifestWork, appliedWork *work
 		appliedManifestWorkLister: workInformerFactory.Work().V1().AppliedManifestWorks().Lister(),
 		restMapper:                mapper,
 		validator:                 basic.NewSARValidator(nil, spokeKubeClient),
+		statusReader:              statusfeedback.NewStatusReader(),
 	}
 
 	if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
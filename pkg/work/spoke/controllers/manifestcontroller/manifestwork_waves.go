@@ -0,0 +1,329 @@
+package manifestcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+)
+
+const (
+	// ApplyWavesAnnotationKey is the annotation on ManifestWork which enables applying the manifests in waves if
+	// it is "true". The manifests are ordered by their apply wave annotation and then by kind: the namespaces
+	// and CRDs first, then the service accounts and RBAC resources, then the configmaps, secrets and services,
+	// and the others at last. A wave is applied only after the resources of all the previous waves are ready.
+	ApplyWavesAnnotationKey = "work.open-cluster-management.io/experimental-apply-waves"
+
+	// ApplyWaveAnnotationKey is the annotation on a manifest with its apply wave, which is an integer and
+	// defaults to 0. The manifests in lower waves are applied first.
+	ApplyWaveAnnotationKey = "work.open-cluster-management.io/apply-wave"
+
+	// ReadinessRulesAnnotationKey is the annotation on ManifestWork which defines when the resources are ready.
+	// The value is a json encoded list of ReadinessRule. A resource is ready once it is applied, a CRD also
+	// requires to be established, and a resource with readiness rules also requires to match all its rules.
+	ReadinessRulesAnnotationKey = "work.open-cluster-management.io/experimental-readiness-rules"
+
+	ReasonWaveNotReady          = "WaveNotReady"
+	ReasonAllWavesReady         = "AllWavesReady"
+	ReasonInvalidReadinessRules = "InvalidReadinessRules"
+
+	// defaultKindTier is the tier of the kinds not in kindTiers.
+	defaultKindTier = 3
+)
+
+// WaveRequeueInterval is the interval to check the readiness of the resources again when a wave is not ready.
+var WaveRequeueInterval = 10 * time.Second
+
+// kindTiers are the tiers of the kinds applied in order within an apply wave.
+var kindTiers = map[schema.GroupKind]int{
+	{Group: "", Kind: "Namespace"}:                                    0,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: 0,
+	{Group: "", Kind: "ServiceAccount"}:                               1,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:         1,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:  1,
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:                1,
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:         1,
+	{Group: "", Kind: "ConfigMap"}:                                    2,
+	{Group: "", Kind: "Secret"}:                                       2,
+	{Group: "", Kind: "PersistentVolumeClaim"}:                        2,
+	{Group: "", Kind: "Service"}:                                      2,
+}
+
+// ReadinessRule defines the value of a field of a resource when the resource is ready.
+type ReadinessRule struct {
+	// ResourceIdentifier identifies the resource of a manifest.
+	ResourceIdentifier workapiv1.ResourceIdentifier `json:"resourceIdentifier"`
+	// Path is the json path of the field, in the same format as the json paths of the status feedback rules,
+	// e.g. .status.readyReplicas
+	Path string `json:"path"`
+	// Value is the string form of the field value when the resource is ready.
+	Value string `json:"value"`
+}
+
+// applyWave is the manifests applied together, ordered by the apply wave and the kind tier.
+type applyWave struct {
+	wave    int
+	tier    int
+	indexes []int
+}
+
+// applyWavesEnabled returns true if the manifests of the ManifestWork are applied in waves.
+func applyWavesEnabled(manifestWork *workapiv1.ManifestWork) bool {
+	return manifestWork.Annotations[ApplyWavesAnnotationKey] == "true"
+}
+
+// getReadinessRules returns the readiness rules of the ManifestWork.
+func getReadinessRules(manifestWork *workapiv1.ManifestWork) ([]ReadinessRule, error) {
+	value, ok := manifestWork.Annotations[ReadinessRulesAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	rules := []ReadinessRule{}
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode readiness rules: %w", err)
+	}
+	for i, rule := range rules {
+		if len(rule.ResourceIdentifier.Resource) == 0 || len(rule.ResourceIdentifier.Name) == 0 {
+			return nil, fmt.Errorf("resource and name of readiness rule %d are required", i)
+		}
+		if len(rule.Path) == 0 {
+			return nil, fmt.Errorf("path of readiness rule %d is empty", i)
+		}
+	}
+	return rules, nil
+}
+
+// getApplyWaves returns the apply waves of the manifests in order, and the errors of the manifests with an
+// invalid apply wave, which are not in any wave.
+func getApplyWaves(manifests []workapiv1.Manifest) ([]*applyWave, map[int]error) {
+	waves := map[[2]int]*applyWave{}
+	errs := map[int]error{}
+	for index, manifest := range manifests {
+		wave, tier := 0, defaultKindTier
+		// the manifests failed to decode are in the default wave, and the error is returned when they are applied
+		required := &unstructured.Unstructured{}
+		if err := required.UnmarshalJSON(manifest.Raw); err == nil {
+			if value, ok := required.GetAnnotations()[ApplyWaveAnnotationKey]; ok {
+				wave, err = strconv.Atoi(value)
+				if err != nil {
+					errs[index] = fmt.Errorf("invalid apply wave %q: %w", value, err)
+					continue
+				}
+			}
+			if t, ok := kindTiers[required.GroupVersionKind().GroupKind()]; ok {
+				tier = t
+			}
+		}
+
+		key := [2]int{wave, tier}
+		if _, ok := waves[key]; !ok {
+			waves[key] = &applyWave{wave: wave, tier: tier}
+		}
+		waves[key].indexes = append(waves[key].indexes, index)
+	}
+
+	sorted := []*applyWave{}
+	for _, wave := range waves {
+		sorted = append(sorted, wave)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].wave != sorted[j].wave {
+			return sorted[i].wave < sorted[j].wave
+		}
+		return sorted[i].tier < sorted[j].tier
+	})
+	return sorted, errs
+}
+
+// applyManifestsInWaves applies the manifests wave by wave until a wave is not ready, the manifests of the
+// following waves are not applied and their results are pending. It returns the results with the progressing
+// condition of the waves.
+func (m *ManifestWorkController) applyManifestsInWaves(
+	ctx context.Context,
+	manifestWork *workapiv1.ManifestWork,
+	recorder events.Recorder,
+	owner metav1.OwnerReference,
+	existingResults []applyResult) ([]applyResult, metav1.Condition) {
+	manifests := manifestWork.Spec.Workload.Manifests
+
+	rules, err := getReadinessRules(manifestWork)
+	if err != nil {
+		for index := range manifests {
+			existingResults[index] = m.pendingResult(index, manifests[index], "Waiting for the readiness rules to be fixed")
+		}
+		return existingResults, getProgressingCondition(manifestWork.Generation, metav1.ConditionFalse,
+			ReasonInvalidReadinessRules, err.Error())
+	}
+
+	// the resources of the manifests with an invalid apply wave are still tracked by the resource meta, so
+	// that the resources applied before are not deleted as no longer maintained by the manifestwork.
+	waves, errs := getApplyWaves(manifests)
+	for index, err := range errs {
+		existingResults[index] = applyResult{Error: err, resourceMeta: m.buildResourceMeta(index, manifests[index])}
+	}
+
+	for i, wave := range waves {
+		for _, index := range wave.indexes {
+			switch {
+			case existingResults[index].Result == nil:
+				existingResults[index] = m.applyOneManifest(ctx, index, manifests[index], manifestWork.Spec, recorder, owner)
+			case apierrors.IsConflict(existingResults[index].Error):
+				existingResults[index] = m.applyOneManifest(ctx, index, manifests[index], manifestWork.Spec, recorder, owner)
+			}
+		}
+
+		for _, index := range wave.indexes {
+			message := m.checkReadiness(ctx, existingResults[index], rules)
+			if len(message) == 0 {
+				continue
+			}
+
+			progress := fmt.Sprintf("Wave %d/%d is waiting for %s: %s", i+1, len(waves),
+				formatResourceMeta(existingResults[index].resourceMeta), message)
+			for _, next := range waves[i+1:] {
+				for _, index := range next.indexes {
+					existingResults[index] = m.pendingResult(index, manifests[index], progress)
+				}
+			}
+			return existingResults, getProgressingCondition(manifestWork.Generation, metav1.ConditionTrue,
+				ReasonWaveNotReady, progress)
+		}
+	}
+
+	return existingResults, getProgressingCondition(manifestWork.Generation, metav1.ConditionFalse,
+		ReasonAllWavesReady, fmt.Sprintf("All the %d waves are applied and ready", len(waves)))
+}
+
+// pendingResult returns the result of a manifest not applied yet, with the message why it is pending.
+func (m *ManifestWorkController) pendingResult(index int, manifest workapiv1.Manifest, message string) applyResult {
+	return applyResult{pending: message, resourceMeta: m.buildResourceMeta(index, manifest)}
+}
+
+// buildResourceMeta returns the resource meta of a manifest which is not applied.
+func (m *ManifestWorkController) buildResourceMeta(index int, manifest workapiv1.Manifest) workapiv1.ManifestResourceMeta {
+	required := &unstructured.Unstructured{}
+	if err := required.UnmarshalJSON(manifest.Raw); err != nil {
+		return workapiv1.ManifestResourceMeta{Ordinal: int32(index)}
+	}
+	// the resource type might not be served yet if its CRD is in a previous wave, and the resource meta
+	// is completed once it is applied.
+	resourceMeta, _, _ := helper.BuildResourceMeta(index, required, m.restMapper)
+	return resourceMeta
+}
+
+// checkReadiness returns an empty message if the resource of the result is ready, otherwise the message why
+// it is not ready.
+func (m *ManifestWorkController) checkReadiness(ctx context.Context, result applyResult, rules []ReadinessRule) string {
+	if result.Error != nil {
+		return fmt.Sprintf("failed to apply: %v", result.Error)
+	}
+
+	resourceMeta := result.resourceMeta
+	resourceRules := []ReadinessRule{}
+	for _, rule := range rules {
+		id := rule.ResourceIdentifier
+		if id.Group == resourceMeta.Group && id.Resource == resourceMeta.Resource &&
+			id.Namespace == resourceMeta.Namespace && id.Name == resourceMeta.Name {
+			resourceRules = append(resourceRules, rule)
+		}
+	}
+	isCRD := resourceMeta.Group == "apiextensions.k8s.io" && resourceMeta.Kind == "CustomResourceDefinition"
+	if !isCRD && len(resourceRules) == 0 {
+		return ""
+	}
+
+	gvr := schema.GroupVersionResource{Group: resourceMeta.Group, Version: resourceMeta.Version, Resource: resourceMeta.Resource}
+	obj, err := m.spokeDynamicClient.Resource(gvr).Namespace(resourceMeta.Namespace).Get(ctx, resourceMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Sprintf("failed to get: %v", err)
+	}
+
+	if isCRD && !crdEstablished(obj) {
+		return "not established"
+	}
+
+	for _, rule := range resourceRules {
+		values, err := m.statusReader.GetValuesByRule(obj, workapiv1.FeedbackRule{
+			Type:      workapiv1.JSONPathsType,
+			JsonPaths: []workapiv1.JsonPath{{Name: "readiness", Path: rule.Path}},
+		})
+		if err != nil {
+			return fmt.Sprintf("failed to read %s: %v", rule.Path, err)
+		}
+		value := ""
+		if len(values) > 0 {
+			value = helper.FormatFieldValue(values[0].Value)
+		}
+		if value != rule.Value {
+			return fmt.Sprintf("%s is %q, expected %q", rule.Path, value, rule.Value)
+		}
+	}
+	return ""
+}
+
+// crdEstablished returns true if the Established condition of the CRD is true.
+func crdEstablished(obj *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == "Established" && condition["status"] == string(metav1.ConditionTrue) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatResourceMeta returns the resource and the namespaced name of the resource meta.
+func formatResourceMeta(resourceMeta workapiv1.ManifestResourceMeta) string {
+	resource := resourceMeta.Resource
+	if len(resource) == 0 {
+		resource = strings.ToLower(resourceMeta.Kind)
+	}
+	if len(resourceMeta.Group) > 0 {
+		resource = fmt.Sprintf("%s.%s", resource, resourceMeta.Group)
+	}
+	if len(resourceMeta.Namespace) == 0 {
+		return fmt.Sprintf("%s %s", resource, resourceMeta.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", resource, resourceMeta.Namespace, resourceMeta.Name)
+}
+
+func getProgressingCondition(generation int64, status metav1.ConditionStatus, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               workapiv1.WorkProgressing,
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// removeProgressingCondition removes the progressing condition of the apply waves once they are disabled.
+func removeProgressingCondition(manifestWork *workapiv1.ManifestWork) {
+	condition := meta.FindStatusCondition(manifestWork.Status.Conditions, workapiv1.WorkProgressing)
+	if condition == nil {
+		return
+	}
+	switch condition.Reason {
+	case ReasonWaveNotReady, ReasonAllWavesReady, ReasonInvalidReadinessRules:
+		meta.RemoveStatusCondition(&manifestWork.Status.Conditions, workapiv1.WorkProgressing)
+	}
+}
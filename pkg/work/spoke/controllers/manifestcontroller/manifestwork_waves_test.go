@@ -0,0 +1,387 @@
+package manifestcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/controllers/appliedmanifestcontroller"
+	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
+)
+
+// newWaveUnstructured returns an unstructured object in the apply wave, or in the default wave if the wave
+// is empty.
+func newWaveUnstructured(apiVersion, kind, namespace, name, wave string) *unstructured.Unstructured {
+	obj := spoketesting.NewUnstructured(apiVersion, kind, namespace, name)
+	if len(wave) > 0 {
+		obj.SetAnnotations(map[string]string{ApplyWaveAnnotationKey: wave})
+	}
+	return obj
+}
+
+func TestGetApplyWaves(t *testing.T) {
+	objects := []*unstructured.Unstructured{
+		newWaveUnstructured("apps/v1", "Deployment", "ns1", "test", ""),
+		newWaveUnstructured("v1", "Namespace", "", "ns1", ""),
+		newWaveUnstructured("v1", "ConfigMap", "ns1", "test", ""),
+		newWaveUnstructured("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "foos.example.com", ""),
+		newWaveUnstructured("v1", "Secret", "ns1", "test", "1"),
+		newWaveUnstructured("rbac.authorization.k8s.io/v1", "Role", "ns1", "test", "-1"),
+		newWaveUnstructured("v1", "Secret", "ns1", "invalid", "a"),
+		newWaveUnstructured("example.com/v1", "Foo", "ns1", "test", ""),
+	}
+	work, _ := spoketesting.NewManifestWork(0, objects...)
+
+	waves, errs := getApplyWaves(work.Spec.Workload.Manifests)
+
+	expected := []*applyWave{
+		{wave: -1, tier: 1, indexes: []int{5}},
+		{wave: 0, tier: 0, indexes: []int{1, 3}},
+		{wave: 0, tier: 2, indexes: []int{2}},
+		{wave: 0, tier: 3, indexes: []int{0, 7}},
+		{wave: 1, tier: 2, indexes: []int{4}},
+	}
+	if !reflect.DeepEqual(waves, expected) {
+		actual := []applyWave{}
+		for _, wave := range waves {
+			actual = append(actual, *wave)
+		}
+		t.Errorf("unexpected waves %v", actual)
+	}
+	if len(errs) != 1 || errs[6] == nil {
+		t.Errorf("expected error of manifest 6, but got %v", errs)
+	}
+}
+
+func TestGetReadinessRules(t *testing.T) {
+	cases := []struct {
+		name          string
+		annotations   map[string]string
+		expectedRules int
+		expectedErr   string
+	}{
+		{
+			name: "no rules",
+		},
+		{
+			name: "valid rules",
+			annotations: map[string]string{ReadinessRulesAnnotationKey: `[
+				{"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"ns1","name":"test"},"path":".status.readyReplicas","value":"1"}
+			]`},
+			expectedRules: 1,
+		},
+		{
+			name:        "invalid json",
+			annotations: map[string]string{ReadinessRulesAnnotationKey: `{`},
+			expectedErr: "failed to decode readiness rules",
+		},
+		{
+			name: "no resource",
+			annotations: map[string]string{ReadinessRulesAnnotationKey: `[
+				{"resourceIdentifier":{"name":"test"},"path":".status.readyReplicas","value":"1"}
+			]`},
+			expectedErr: "resource and name of readiness rule 0 are required",
+		},
+		{
+			name: "no path",
+			annotations: map[string]string{ReadinessRulesAnnotationKey: `[
+				{"resourceIdentifier":{"resource":"deployments","name":"test"},"value":"1"}
+			]`},
+			expectedErr: "path of readiness rule 0 is empty",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work, _ := spoketesting.NewManifestWork(0)
+			work.Annotations = c.annotations
+			rules, err := getReadinessRules(work)
+			switch {
+			case len(c.expectedErr) > 0 && (err == nil || !strings.Contains(err.Error(), c.expectedErr)):
+				t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+			case len(c.expectedErr) == 0 && err != nil:
+				t.Fatal(err)
+			}
+			if len(rules) != c.expectedRules {
+				t.Errorf("expected %d rules, but got %d", c.expectedRules, len(rules))
+			}
+		})
+	}
+}
+
+func TestCRDEstablished(t *testing.T) {
+	cases := []struct {
+		name       string
+		conditions []interface{}
+		expected   bool
+	}{
+		{
+			name: "no conditions",
+		},
+		{
+			name: "established",
+			conditions: []interface{}{
+				map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+				map[string]interface{}{"type": "Established", "status": "True"},
+			},
+			expected: true,
+		},
+		{
+			name: "not established",
+			conditions: []interface{}{
+				map[string]interface{}{"type": "Established", "status": "False"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			obj := spoketesting.NewUnstructured("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "foos.example.com")
+			if c.conditions != nil {
+				if err := unstructured.SetNestedSlice(obj.Object, c.conditions, "status", "conditions"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if actual := crdEstablished(obj); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSyncWithApplyWaves(t *testing.T) {
+	deployment := spoketesting.NewUnstructuredWithContent("apps/v1", "Deployment", "ns1", "test",
+		map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}})
+
+	cases := []struct {
+		name                      string
+		annotations               map[string]string
+		workManifest              []*unstructured.Unstructured
+		expectedKubeAction        []string
+		expectedDynamicAction     []string
+		expectedManifestApplied   []metav1.ConditionStatus
+		expectedWorkApplied       metav1.ConditionStatus
+		expectedProgressingReason string
+		expectedProgressingStatus metav1.ConditionStatus
+		expectedMessage           string
+	}{
+		{
+			name:        "all waves ready",
+			annotations: map[string]string{ApplyWavesAnnotationKey: "true"},
+			workManifest: []*unstructured.Unstructured{
+				newWaveUnstructured("v1", "Secret", "ns1", "test", "1"),
+				deployment,
+			},
+			expectedKubeAction:        []string{"get", "create"},
+			expectedDynamicAction:     []string{"get", "create"},
+			expectedManifestApplied:   []metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionTrue},
+			expectedWorkApplied:       metav1.ConditionTrue,
+			expectedProgressingReason: ReasonAllWavesReady,
+			expectedProgressingStatus: metav1.ConditionFalse,
+			expectedMessage:           "All the 2 waves are applied and ready",
+		},
+		{
+			name: "readiness rule matched",
+			annotations: map[string]string{
+				ApplyWavesAnnotationKey: "true",
+				ReadinessRulesAnnotationKey: `[{"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"ns1","name":"test"},` +
+					`"path":".spec.replicas","value":"1"}]`,
+			},
+			workManifest: []*unstructured.Unstructured{
+				newWaveUnstructured("v1", "Secret", "ns1", "test", "1"),
+				deployment,
+			},
+			expectedKubeAction:        []string{"get", "create"},
+			expectedDynamicAction:     []string{"get", "create", "get"},
+			expectedManifestApplied:   []metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionTrue},
+			expectedWorkApplied:       metav1.ConditionTrue,
+			expectedProgressingReason: ReasonAllWavesReady,
+			expectedProgressingStatus: metav1.ConditionFalse,
+		},
+		{
+			name: "wave not ready",
+			annotations: map[string]string{
+				ApplyWavesAnnotationKey: "true",
+				ReadinessRulesAnnotationKey: `[{"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"ns1","name":"test"},` +
+					`"path":".status.readyReplicas","value":"1"}]`,
+			},
+			workManifest: []*unstructured.Unstructured{
+				newWaveUnstructured("v1", "Secret", "ns1", "test", "1"),
+				deployment,
+			},
+			expectedKubeAction:        []string{},
+			expectedDynamicAction:     []string{"get", "create", "get"},
+			expectedManifestApplied:   []metav1.ConditionStatus{metav1.ConditionFalse, metav1.ConditionTrue},
+			expectedWorkApplied:       metav1.ConditionFalse,
+			expectedProgressingReason: ReasonWaveNotReady,
+			expectedProgressingStatus: metav1.ConditionTrue,
+			expectedMessage: `Wave 1/2 is waiting for deployments.apps ns1/test: .status.readyReplicas is "", ` +
+				`expected "1"`,
+		},
+		{
+			name: "invalid readiness rules",
+			annotations: map[string]string{
+				ApplyWavesAnnotationKey:     "true",
+				ReadinessRulesAnnotationKey: `{`,
+			},
+			workManifest: []*unstructured.Unstructured{
+				newWaveUnstructured("v1", "Secret", "ns1", "test", "1"),
+				deployment,
+			},
+			expectedKubeAction:        []string{},
+			expectedDynamicAction:     []string{},
+			expectedManifestApplied:   []metav1.ConditionStatus{metav1.ConditionFalse, metav1.ConditionFalse},
+			expectedWorkApplied:       metav1.ConditionFalse,
+			expectedProgressingReason: ReasonInvalidReadinessRules,
+			expectedProgressingStatus: metav1.ConditionFalse,
+		},
+		{
+			name: "waves disabled",
+			workManifest: []*unstructured.Unstructured{
+				newWaveUnstructured("v1", "Secret", "ns1", "test", "1"),
+				deployment,
+			},
+			expectedKubeAction:      []string{"get", "create"},
+			expectedDynamicAction:   []string{"get", "create"},
+			expectedManifestApplied: []metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionTrue},
+			expectedWorkApplied:     metav1.ConditionTrue,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work, workKey := spoketesting.NewManifestWork(0, c.workManifest...)
+			work.Finalizers = []string{controllers.ManifestWorkFinalizer}
+			work.Annotations = c.annotations
+			controller := newController(t, work, nil, spoketesting.NewFakeRestMapper()).
+				withKubeObject().
+				withUnstructuredObject()
+
+			syncContext := testingcommon.NewFakeSyncContext(t, workKey)
+			if err := controller.toController().sync(context.TODO(), syncContext); err != nil {
+				t.Fatal(err)
+			}
+
+			testingcommon.AssertActions(t, controller.kubeClient.Actions(), c.expectedKubeAction...)
+			testingcommon.AssertActions(t, controller.dynamicClient.Actions(), c.expectedDynamicAction...)
+
+			var patch []byte
+			for _, action := range controller.workClient.Actions() {
+				if action.GetResource().Resource == "manifestworks" && action.GetVerb() == "patch" {
+					patch = action.(clienttesting.PatchActionImpl).Patch
+				}
+			}
+			actualWork := &workapiv1.ManifestWork{}
+			if err := json.Unmarshal(patch, actualWork); err != nil {
+				t.Fatal(err)
+			}
+
+			for index, status := range c.expectedManifestApplied {
+				assertManifestCondition(t, actualWork.Status.ResourceStatus.Manifests, int32(index),
+					string(workapiv1.ManifestApplied), status)
+			}
+			assertCondition(t, actualWork.Status.Conditions, workapiv1.WorkApplied, c.expectedWorkApplied)
+
+			progressing := meta.FindStatusCondition(actualWork.Status.Conditions, workapiv1.WorkProgressing)
+			switch {
+			case len(c.expectedProgressingReason) == 0 && progressing != nil:
+				t.Errorf("expected no progressing condition, but got %v", progressing)
+			case len(c.expectedProgressingReason) == 0:
+			case progressing == nil || progressing.Reason != c.expectedProgressingReason ||
+				progressing.Status != c.expectedProgressingStatus:
+				t.Errorf("expected progressing condition %s %s, but got %v",
+					c.expectedProgressingReason, c.expectedProgressingStatus, progressing)
+			case len(c.expectedMessage) > 0 && progressing.Message != c.expectedMessage:
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, progressing.Message)
+			}
+		})
+	}
+}
+
+func TestInvalidApplyWaveKeepsAppliedResource(t *testing.T) {
+	// syncWork applies the manifestwork with the secret in the apply wave, and returns the manifestwork with
+	// the patched status and the sync error.
+	syncWork := func(wave string) (*workapiv1.ManifestWork, error) {
+		work, workKey := spoketesting.NewManifestWork(0, newWaveUnstructured("v1", "Secret", "ns1", "test", wave))
+		work.Finalizers = []string{controllers.ManifestWorkFinalizer}
+		work.Annotations = map[string]string{ApplyWavesAnnotationKey: "true"}
+		controller := newController(t, work, nil, spoketesting.NewFakeRestMapper()).
+			withKubeObject().
+			withUnstructuredObject()
+		err := controller.toController().sync(context.TODO(), testingcommon.NewFakeSyncContext(t, workKey))
+		for _, action := range controller.workClient.Actions() {
+			if action.GetResource().Resource == "manifestworks" && action.GetVerb() == "patch" {
+				if err := json.Unmarshal(action.(clienttesting.PatchActionImpl).Patch, work); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return work, err
+	}
+
+	applied, err := syncWork("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertManifestCondition(t, applied.Status.ResourceStatus.Manifests, 0, string(workapiv1.ManifestApplied), metav1.ConditionTrue)
+
+	// the apply wave of the applied secret is changed to an invalid value
+	work, err := syncWork("abc")
+	if err == nil {
+		t.Errorf("expected error of the invalid apply wave")
+	}
+	assertManifestCondition(t, work.Status.ResourceStatus.Manifests, 0, string(workapiv1.ManifestApplied), metav1.ConditionFalse)
+	if !reflect.DeepEqual(work.Status.ResourceStatus.Manifests[0].ResourceMeta, applied.Status.ResourceStatus.Manifests[0].ResourceMeta) {
+		t.Errorf("expected resource meta %v, but got %v", applied.Status.ResourceStatus.Manifests[0].ResourceMeta,
+			work.Status.ResourceStatus.Manifests[0].ResourceMeta)
+	}
+
+	// the secret is still maintained by the manifestwork
+	appliedWork := spoketesting.NewAppliedManifestWork("test", 0, "")
+	appliedWork.Status.AppliedResources = []workapiv1.AppliedManifestResourceMeta{
+		{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "test"}, UID: "ns1-test"},
+	}
+	workClient := fakeworkclient.NewSimpleClientset(work, appliedWork)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 5*time.Minute)
+	if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
+		t.Fatal(err)
+	}
+	if err := workInformerFactory.Work().V1().AppliedManifestWorks().Informer().GetStore().Add(appliedWork); err != nil {
+		t.Fatal(err)
+	}
+	dynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+		spoketesting.NewUnstructuredSecret("ns1", "test", false, "ns1-test"))
+	appliedController := appliedmanifestcontroller.NewAppliedManifestWorkController(
+		eventstesting.NewTestingEventRecorder(t),
+		dynamicClient,
+		workInformerFactory.Work().V1().ManifestWorks(),
+		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("cluster1"),
+		workClient.WorkV1().AppliedManifestWorks(),
+		workInformerFactory.Work().V1().AppliedManifestWorks(),
+		"test")
+	if err := appliedController.Sync(context.TODO(), testingcommon.NewFakeSyncContext(t, work.Name)); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range dynamicClient.Actions() {
+		if action.GetVerb() == "delete" {
+			t.Errorf("expected the secret not deleted, but got %v", action)
+		}
+	}
+}
@@ -43,6 +43,17 @@ var benchmarkPlacement = clusterapiv1beta1.Placement{
 	},
 	Spec: clusterapiv1beta1.PlacementSpec{
 		NumberOfClusters: &noc,
+		Predicates: []clusterapiv1beta1.ClusterPredicate{
+			{
+				RequiredClusterSelector: clusterapiv1beta1.ClusterSelector{
+					LabelSelector: metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							clusterSetLabel: name,
+						},
+					},
+				},
+			},
+		},
 
 		PrioritizerPolicy: clusterapiv1beta1.PrioritizerPolicy{
 			Mode: clusterapiv1beta1.PrioritizerPolicyModeExact,
@@ -86,6 +97,7 @@ var CRDPaths = []string{
 	"./vendor/open-cluster-management.io/api/cluster/v1beta2/0000_01_clusters.open-cluster-management.io_managedclustersetbindings.crd.yaml",
 	"./vendor/open-cluster-management.io/api/cluster/v1beta1/0000_02_clusters.open-cluster-management.io_placements.crd.yaml",
 	"./vendor/open-cluster-management.io/api/cluster/v1beta1/0000_03_clusters.open-cluster-management.io_placementdecisions.crd.yaml",
+	"./vendor/open-cluster-management.io/api/addon/v1alpha1/0000_01_addon.open-cluster-management.io_managedclusteraddons.crd.yaml",
 }
 
 func BenchmarkSchedulePlacements100(b *testing.B) {
@@ -100,6 +112,10 @@ func BenchmarkSchedulePlacements10000(b *testing.B) {
 	benchmarkSchedulePlacements(b, 10000, 1000)
 }
 
+func BenchmarkSchedulePlacements1000With10000Clusters(b *testing.B) {
+	benchmarkSchedulePlacements(b, 1000, 10000)
+}
+
 func benchmarkSchedulePlacements(b *testing.B, pnum, cnum int) {
 	var err error
 	ctx, cancel := context.WithCancel(context.Background())
@@ -136,6 +152,7 @@ func benchmarkSchedulePlacements(b *testing.B, pnum, cnum int) {
 	go createPlacements(pnum)
 	assertPlacementDecisions(pnum, cancel)
 
+	b.ReportMetric(float64(pnum)/b.Elapsed().Seconds(), "placements/s")
 }
 
 func createNamespace(namespace string) {